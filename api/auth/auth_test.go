@@ -14,6 +14,7 @@ import (
 
 	"github.com/Adedunmol/answerly/api/auth"
 	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/profiles"
 	"github.com/Adedunmol/answerly/api/tokens"
 	"github.com/Adedunmol/answerly/database"
 	"github.com/Adedunmol/answerly/queue"
@@ -50,13 +51,16 @@ func assertResponseMessage(t *testing.T, got map[string]interface{}, wantMessage
 // ============================================================================
 
 type StubWalletStore struct {
-	Wallets    map[int64]database.Wallet
-	ShouldFail bool
+	Wallets map[int64]database.Wallet
+	// OrganizationWallets holds the wallets of organizations, by organization ID.
+	OrganizationWallets map[int64]database.Wallet
+	ShouldFail          bool
 }
 
 func NewStubWalletStore() *StubWalletStore {
 	return &StubWalletStore{
-		Wallets: make(map[int64]database.Wallet),
+		Wallets:             make(map[int64]database.Wallet),
+		OrganizationWallets: make(map[int64]database.Wallet),
 	}
 }
 
@@ -67,8 +71,8 @@ func (s *StubWalletStore) CreateWallet(ctx context.Context, userID int64) (datab
 
 	wallet := database.Wallet{
 		ID:      int64(len(s.Wallets) + 1),
-		UserID:  userID,
-		Balance: pgtype.Numeric{Int: &decimal.NewFromInt(0)},
+		UserID:  pgtype.Int8{Int64: userID, Valid: true},
+		Balance: database.NumericFromDecimal(decimal.Zero),
 	}
 
 	s.Wallets[userID] = wallet
@@ -98,7 +102,7 @@ func (s *StubWalletStore) TopUpWallet(ctx context.Context, userID int64, amount
 		return database.Wallet{}, errors.New("wallet not found")
 	}
 
-	wallet.Balance = wallet.Balance.Add(amount)
+	wallet.Balance = database.NumericFromDecimal(database.DecimalFromNumeric(wallet.Balance).Add(amount))
 	s.Wallets[userID] = wallet
 	return wallet, nil
 }
@@ -113,11 +117,61 @@ func (s *StubWalletStore) ChargeWallet(ctx context.Context, companyID int64, amo
 		return database.Wallet{}, errors.New("wallet not found")
 	}
 
-	wallet.Balance = wallet.Balance.Sub(amount)
+	wallet.Balance = database.NumericFromDecimal(database.DecimalFromNumeric(wallet.Balance).Sub(amount))
 	s.Wallets[companyID] = wallet
 	return wallet, nil
 }
 
+func (s *StubWalletStore) CreateOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	if s.ShouldFail {
+		return database.Wallet{}, errors.New("failed to create wallet")
+	}
+
+	wallet := database.Wallet{
+		ID:             int64(len(s.Wallets) + len(s.OrganizationWallets) + 1),
+		OrganizationID: pgtype.Int8{Int64: organizationID, Valid: true},
+		Balance:        database.NumericFromDecimal(decimal.Zero),
+	}
+
+	s.OrganizationWallets[organizationID] = wallet
+	return wallet, nil
+}
+
+func (s *StubWalletStore) GetOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	if s.ShouldFail {
+		return database.Wallet{}, errors.New("database error")
+	}
+
+	wallet, exists := s.OrganizationWallets[organizationID]
+	if !exists {
+		return database.Wallet{}, errors.New("wallet not found")
+	}
+
+	return wallet, nil
+}
+
+func (s *StubWalletStore) TopUpOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error) {
+	wallet, err := s.GetOrganizationWallet(ctx, organizationID)
+	if err != nil {
+		return database.Wallet{}, err
+	}
+
+	wallet.Balance = database.NumericFromDecimal(database.DecimalFromNumeric(wallet.Balance).Add(amount))
+	s.OrganizationWallets[organizationID] = wallet
+	return wallet, nil
+}
+
+func (s *StubWalletStore) ChargeOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error) {
+	wallet, err := s.GetOrganizationWallet(ctx, organizationID)
+	if err != nil {
+		return database.Wallet{}, err
+	}
+
+	wallet.Balance = database.NumericFromDecimal(database.DecimalFromNumeric(wallet.Balance).Sub(amount))
+	s.OrganizationWallets[organizationID] = wallet
+	return wallet, nil
+}
+
 type StubProfileStore struct {
 	Profiles   map[int64]bool
 	ShouldFail bool
@@ -138,6 +192,22 @@ func (s *StubProfileStore) CreateProfile(ctx context.Context, userID int64) erro
 	return nil
 }
 
+func (s *StubProfileStore) GetProfile(ctx context.Context, userID int64) (database.Profile, error) {
+	if s.ShouldFail {
+		return database.Profile{}, errors.New("database error")
+	}
+
+	if !s.Profiles[userID] {
+		return database.Profile{}, custom_errors.ErrNotFound
+	}
+
+	return database.Profile{UserID: userID}, nil
+}
+
+func (s *StubProfileStore) UpdateProfile(ctx context.Context, userID int64, profile profiles.UpdateProfileBody) (database.Profile, error) {
+	return s.GetProfile(ctx, userID)
+}
+
 type StubTokenService struct {
 	ShouldFailOTP   bool
 	ShouldFailToken bool
@@ -207,6 +277,13 @@ func (q *StubQueue) Enqueue(processor queue.Processor) error {
 	return nil
 }
 
+func (q *StubQueue) Dequeue(processor queue.Scheduled) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+	return nil
+}
+
 type StubOTPStore struct {
 	OTPs               map[string]string // key: userID-domain, value: otp
 	Expirations        map[string]time.Time
@@ -1470,7 +1547,7 @@ func TestGoogleSignUpHandler(t *testing.T) {
 				Email:         "existing@example.com",
 				GoogleID:      pgtype.Text{String: "google-123", Valid: true},
 				EmailVerified: pgtype.Bool{Bool: true, Valid: true},
-				Role:          pgtype.Text{String: "user", Valid: true},
+				Role:          "user",
 			},
 		}
 
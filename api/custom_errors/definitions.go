@@ -6,7 +6,11 @@ var (
 	ErrConflict          = errors.New("record already exists")
 	ErrNotFound          = errors.New("resource not found")
 	ErrUnauthorized      = errors.New("invalid credentials")
+	ErrForbidden         = errors.New("forbidden")
 	ErrInternalServer    = errors.New("internal server error")
 	ErrInsufficientFunds = errors.New("insufficient funds")
 	ErrInvalidOTP        = errors.New("invalid OTP")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrBudgetExhausted   = errors.New("survey budget exhausted")
+	ErrQuotaFull         = errors.New("survey quota full")
 )
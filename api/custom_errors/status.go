@@ -0,0 +1,31 @@
+package custom_errors
+
+import (
+	"errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"net/http"
+)
+
+// HTTPStatus maps the sentinel errors returned by stores to the status code a handler should respond with.
+// Validation errors are the caller's fault, anything else that is not one of the sentinels is
+// treated as an internal server error.
+func HTTPStatus(err error) int {
+	var validationErrors jsonutil.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrBudgetExhausted), errors.Is(err, ErrQuotaFull):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInsufficientFunds):
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"net/http"
 	"reflect"
+	"regexp"
 	"strings"
 )
 
@@ -53,8 +54,16 @@ func init() {
 		}
 		return name
 	})
+
+	// key is used for identifiers that end up inside oneof params (option ids, row ids, ...),
+	// so they must not contain spaces
+	_ = validate.RegisterValidation("key", func(fl validator.FieldLevel) bool {
+		return keyPattern.MatchString(fl.Field().String())
+	})
 }
 
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 func Validate(i any) error {
 	err := validate.Struct(i)
 	if err == nil {
@@ -67,31 +76,98 @@ func Validate(i any) error {
 		return invalidValidationError
 	}
 
-	var validationErrors []string
+	var validationErrors ValidationErrors
 	for _, fe := range err.(validator.ValidationErrors) {
 
 		message := getErrorMessage(fe)
 		validationErrors = append(validationErrors, fmt.Sprintf("%s: %s", fe.Field(), message))
 	}
 
-	return fmt.Errorf("validation failed: %s", strings.Join(validationErrors, ", "))
+	return validationErrors
+}
+
+// ValidateField validates a single value against a validator tag, e.g. an answer against the
+// options of the question it answers. field is used as the name in the error messages since a
+// bare value has no struct field to take it from.
+func ValidateField(field string, value any, tag string) error {
+	err := validate.Var(value, tag)
+	if err == nil {
+		return nil
+	}
+
+	var invalidValidationError *validator.InvalidValidationError
+	if errors.As(err, &invalidValidationError) {
+		return invalidValidationError
+	}
+
+	var validationErrors ValidationErrors
+	for _, fe := range err.(validator.ValidationErrors) {
+
+		// elements reached through dive report their index or key, e.g. "[2]"
+		name := field + fe.Field()
+		validationErrors = append(validationErrors, fmt.Sprintf("%s: %s", name, errorMessage(name, fe)))
+	}
+
+	return validationErrors
+}
+
+// ValidationErrors holds one "field: message" entry per failed check. Callers validating
+// nested values can unwrap it with errors.As and prefix each entry with the parent's path.
+type ValidationErrors []string
+
+func (v ValidationErrors) Error() string {
+	return fmt.Sprintf("validation failed: %s", strings.Join(v, ", "))
 }
 
 func getErrorMessage(e validator.FieldError) string {
+	return errorMessage(e.Field(), e)
+}
+
+func errorMessage(field string, e validator.FieldError) string {
 	switch e.Tag() {
 	case "required":
-		return fmt.Sprintf("%s is required", e.Field())
+		return fmt.Sprintf("%s is required", field)
+	case "required_if":
+		// the param names the other field and the value that makes this one required, e.g. "Decision reject"
+		if other := strings.Fields(e.Param()); len(other) == 2 {
+			return fmt.Sprintf("%s is required when %s is %s", field, strings.ToLower(other[0]), other[1])
+		}
+		return fmt.Sprintf("%s is required", field)
 	case "min":
-		return fmt.Sprintf("%s must be at least %s characters", e.Field(), e.Param())
+		return strings.TrimSpace(fmt.Sprintf("%s must be at least %s %s", field, e.Param(), unit(e.Kind())))
 	case "max":
-		return fmt.Sprintf("%s must be at most %s characters", e.Field(), e.Param())
+		return strings.TrimSpace(fmt.Sprintf("%s must be at most %s %s", field, e.Param(), unit(e.Kind())))
+	case "len":
+		return strings.TrimSpace(fmt.Sprintf("%s must be exactly %s %s", field, e.Param(), unit(e.Kind())))
 	case "email":
-		return fmt.Sprintf("%s must be a valid email address", e.Field())
+		return fmt.Sprintf("%s must be a valid email address", field)
 	case "gte":
-		return fmt.Sprintf("%s must be greater than or equal to %s", e.Field(), e.Param())
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, e.Param())
 	case "lte":
-		return fmt.Sprintf("%s must be less than or equal to %s", e.Field(), e.Param())
+		return fmt.Sprintf("%s must be less than or equal to %s", field, e.Param())
+	case "gtfield":
+		return fmt.Sprintf("%s must be greater than %s", field, e.Param())
+	case "gtefield":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, e.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", field, e.Param())
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicates", field)
+	case "key":
+		return fmt.Sprintf("%s must only contain letters, numbers, dashes and underscores", field)
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
+
+// unit names what min, max and len count for the kind of value they are applied to.
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	case reflect.String:
+		return "characters"
 	default:
-		return fmt.Sprintf("%s is invalid", e.Field())
+		return ""
 	}
 }
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"net/http"
+)
+
+// RequireRole only lets requests through whose claims carry one of the given roles.
+// It must be mounted after AuthMiddleware so the claims are already on the context.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			claims, ok := request.Context().Value("claims").(*tokens.Claims)
+			if !ok || claims == nil {
+				response := jsonutil.Response{
+					Status:  "error",
+					Message: "unauthorized",
+				}
+				jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+				return
+			}
+
+			if !allowed[claims.Role] {
+				response := jsonutil.Response{
+					Status:  "error",
+					Message: "you do not have permission to access this resource",
+				}
+				jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
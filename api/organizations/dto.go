@@ -0,0 +1,132 @@
+package organizations
+
+import (
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CreateOrganizationBody struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// InviteMemberBody invites an email to join an organization. Ownership is handed over by changing
+// the role of a member, so nobody is invited as an owner.
+type InviteMemberBody struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Role  string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+type AcceptInvitationBody struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type FundWalletBody struct {
+	Amount decimal.Decimal `json:"amount" validate:"required"`
+}
+
+type UpdateMemberBody struct {
+	Role string `json:"role" validate:"required,oneof=owner admin editor viewer"`
+}
+
+type Organization struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Role is the role of the researcher making the request.
+	Role      string    `json:"role"`
+	Members   []Member  `json:"members,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewOrganization(organization database.Organization, role database.OrganizationRole) Organization {
+	return Organization{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      string(role),
+		CreatedAt: organization.CreatedAt.Time,
+		UpdatedAt: organization.UpdatedAt.Time,
+	}
+}
+
+func NewOrganizations(rows []database.ListOrganizationsByMemberRow) []Organization {
+	result := make([]Organization, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, Organization{
+			ID:        row.ID,
+			Name:      row.Name,
+			Role:      string(row.Role),
+			CreatedAt: row.CreatedAt.Time,
+			UpdatedAt: row.UpdatedAt.Time,
+		})
+	}
+
+	return result
+}
+
+type Member struct {
+	UserID   int64     `json:"user_id"`
+	Email    string    `json:"email,omitempty"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func NewMembers(rows []database.ListOrganizationMembersRow) []Member {
+	result := make([]Member, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, Member{
+			UserID:   row.UserID,
+			Email:    row.Email,
+			Role:     string(row.Role),
+			JoinedAt: row.CreatedAt.Time,
+		})
+	}
+
+	return result
+}
+
+// Invitation leaves out the token, which only the invited email gets to see.
+type Invitation struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	Expired        bool      `json:"expired"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewInvitation(invitation database.OrganizationInvitation, now time.Time) Invitation {
+	return Invitation{
+		ID:             invitation.ID,
+		OrganizationID: invitation.OrganizationID,
+		Email:          invitation.Email,
+		Role:           string(invitation.Role),
+		Expired:        !invitation.ExpiresAt.Time.After(now),
+		ExpiresAt:      invitation.ExpiresAt.Time,
+		CreatedAt:      invitation.CreatedAt.Time,
+	}
+}
+
+func NewInvitations(invitations []database.OrganizationInvitation, now time.Time) []Invitation {
+	result := make([]Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		result = append(result, NewInvitation(invitation, now))
+	}
+
+	return result
+}
+
+type Wallet struct {
+	OrganizationID int64           `json:"organization_id"`
+	Balance        decimal.Decimal `json:"balance"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func NewWallet(wallet database.Wallet) Wallet {
+	return Wallet{
+		OrganizationID: wallet.OrganizationID.Int64,
+		Balance:        database.DecimalFromNumeric(wallet.Balance),
+		UpdatedAt:      wallet.UpdatedAt.Time,
+	}
+}
@@ -0,0 +1,620 @@
+package organizations
+
+import (
+	"context"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Handler struct {
+	Store Store
+	// Queue sends the emails inviting researchers to organizations.
+	Queue queue.Queue
+}
+
+func organizationIDParam(request *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(request, "organizationID"), 10, 64)
+}
+
+// membership loads the membership of the researcher making the request in the organization in the
+// URL, when their role there is at least the given one. Organizations they aren't a member of are
+// not found. When it can't, it writes the error response itself and returns false.
+func (h *Handler) membership(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request, role database.OrganizationRole) (database.OrganizationMember, bool) {
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return database.OrganizationMember{}, false
+	}
+
+	organizationID, err := organizationIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid organization id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.OrganizationMember{}, false
+	}
+
+	member, err := h.Store.GetMember(ctx, organizationID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.OrganizationMember{}, false
+	}
+
+	if !AtLeast(member.Role, role) {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: fmt.Sprintf("only %ss and above can do this", role),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return database.OrganizationMember{}, false
+	}
+
+	return member, true
+}
+
+// CreateOrganizationHandler creates an organization owned by the researcher making the request,
+// with an empty wallet of its own.
+func (h *Handler) CreateOrganizationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[CreateOrganizationBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	organization, err := h.Store.CreateOrganization(ctx, int64(userID), data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "organization created successfully",
+		Data:    NewOrganization(organization, database.OrganizationRoleOwner),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+// ListOrganizationsHandler returns the organizations the researcher is a member of, with their role
+// in each.
+func (h *Handler) ListOrganizationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	organizations, err := h.Store.ListOrganizations(ctx, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved organizations successfully",
+		Data:    NewOrganizations(organizations),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// GetOrganizationHandler returns an organization with its members to any of them.
+func (h *Handler) GetOrganizationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+
+	organization, err := h.Store.GetOrganization(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	members, err := h.Store.ListMembers(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	data := NewOrganization(organization, member.Role)
+	data.Members = NewMembers(members)
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved organization successfully",
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// GetWalletHandler returns the wallet of an organization to any of its members.
+func (h *Handler) GetWalletHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+
+	wallet, err := h.Store.GetWallet(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved organization wallet successfully",
+		Data:    NewWallet(wallet),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// FundWalletHandler lets an admin move money from their own wallet into that of their organization.
+func (h *Handler) FundWalletHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[FundWalletBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	if !data.Amount.IsPositive() {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: jsonutil.ValidationErrors{"amount: amount must be greater than 0"}.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := h.Store.FundWallet(ctx, member.OrganizationID, member.UserID, data.Amount)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "organization wallet funded successfully",
+		Data:    NewWallet(wallet),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// InviteMemberHandler invites an email to an organization and emails it the token to accept the
+// invitation with. Admins invite editors and viewers, owners also invite admins.
+func (h *Handler) InviteMemberHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[InviteMemberBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	if !CanManage(member.Role, database.OrganizationRole(data.Role)) {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: fmt.Sprintf("%ss can't invite %ss", member.Role, data.Role),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return
+	}
+
+	organization, err := h.Store.GetOrganization(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	token, err := NewInvitationToken()
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+
+	invitation, err := h.Store.CreateInvitation(ctx, member.OrganizationID, member.UserID, data, token, now.Add(InvitationExpiry))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	// the email is the only way the token reaches the invitee, so an invitation it can't be sent for
+	// is reported, it can be sent again by inviting the email again
+	err = h.Queue.Enqueue(&queue.EmailDeliveryPayload{
+		Name:     "email",
+		Template: "organization_invitation_mail",
+		Subject:  fmt.Sprintf("You're invited to join %s", organization.Name),
+		Email:    invitation.Email,
+		Data: struct {
+			Heading        string
+			Organization   string
+			Role           string
+			Token          string
+			ExpirationDays int
+		}{
+			Heading:        "You're invited",
+			Organization:   organization.Name,
+			Role:           string(invitation.Role),
+			Token:          invitation.Token,
+			ExpirationDays: int(InvitationExpiry.Hours() / 24),
+		},
+	})
+	if err != nil {
+		log.Printf("error enqueuing email task: %s", err)
+
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "the invitation was saved but its email couldn't be sent, invite the email again to retry",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "invitation sent successfully",
+		Data:    NewInvitation(invitation, now),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+// ListInvitationsHandler returns the invitations to an organization that haven't been accepted.
+func (h *Handler) ListInvitationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.Store.ListInvitations(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved invitations successfully",
+		Data:    NewInvitations(invitations, time.Now()),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// DeleteInvitationHandler withdraws an invitation that hasn't been accepted.
+func (h *Handler) DeleteInvitationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+
+	invitationID, err := strconv.ParseInt(chi.URLParam(request, "invitationID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid invitation id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeleteInvitation(ctx, member.OrganizationID, invitationID); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "invitation deleted successfully",
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// AcceptInvitationHandler makes the researcher making the request a member of the organization an
+// invitation sent to their email is for.
+func (h *Handler) AcceptInvitationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[AcceptInvitationBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.Store.AcceptInvitation(ctx, data.Token, int64(userID), claims.Email)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	organization, err := h.Store.GetOrganization(ctx, member.OrganizationID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "invitation accepted successfully",
+		Data:    NewOrganization(organization, member.Role),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// target loads the member in the URL of the organization the researcher making the request is a
+// member of. When it can't, it writes the error response itself and returns false.
+func (h *Handler) target(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request, organizationID int64) (database.OrganizationMember, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(request, "userID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid user id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.OrganizationMember{}, false
+	}
+
+	member, err := h.Store.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.OrganizationMember{}, false
+	}
+
+	return member, true
+}
+
+// UpdateMemberHandler changes the role of a member. Admins move members between editor and viewer,
+// owners between any roles, as long as the organization keeps an owner.
+func (h *Handler) UpdateMemberHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+
+	target, ok := h.target(ctx, responseWriter, request, member.OrganizationID)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[UpdateMemberBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	role := database.OrganizationRole(data.Role)
+
+	if !CanManage(member.Role, target.Role, role) {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: fmt.Sprintf("%ss can't change members from %s to %s", member.Role, target.Role, role),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return
+	}
+
+	updated, err := h.Store.UpdateMemberRole(ctx, member.OrganizationID, target.UserID, role)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "member updated successfully",
+		Data: Member{
+			UserID:   updated.UserID,
+			Role:     string(updated.Role),
+			JoinedAt: updated.CreatedAt.Time,
+		},
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// RemoveMemberHandler takes a member out of an organization. Any member may leave, admins remove
+// editors and viewers and owners anyone, as long as the organization keeps an owner.
+func (h *Handler) RemoveMemberHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	member, ok := h.membership(ctx, responseWriter, request, database.OrganizationRoleViewer)
+	if !ok {
+		return
+	}
+
+	target, ok := h.target(ctx, responseWriter, request, member.OrganizationID)
+	if !ok {
+		return
+	}
+
+	if target.UserID != member.UserID && !CanManage(member.Role, target.Role) {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: fmt.Sprintf("%ss can't remove %ss", member.Role, target.Role),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return
+	}
+
+	if err := h.Store.RemoveMember(ctx, member.OrganizationID, target.UserID); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "member removed successfully",
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
@@ -0,0 +1,647 @@
+package organizations_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/organizations"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Stub Organization Store
+// ============================================================================
+
+type StubOrganizationStore struct {
+	Organizations map[int64]database.Organization
+	// Members holds the role of each member of an organization.
+	Members     map[int64]map[int64]database.OrganizationRole
+	Invitations map[int64]database.OrganizationInvitation
+	// Balances holds the wallets of the organizations, UserBalances those of their members.
+	Balances     map[int64]decimal.Decimal
+	UserBalances map[int64]decimal.Decimal
+}
+
+func NewStubOrganizationStore() *StubOrganizationStore {
+	return &StubOrganizationStore{
+		Organizations: make(map[int64]database.Organization),
+		Members:       make(map[int64]map[int64]database.OrganizationRole),
+		Invitations:   make(map[int64]database.OrganizationInvitation),
+		Balances:      make(map[int64]decimal.Decimal),
+		UserBalances:  make(map[int64]decimal.Decimal),
+	}
+}
+
+func (s *StubOrganizationStore) CreateOrganization(ctx context.Context, ownerID int64, body organizations.CreateOrganizationBody) (database.Organization, error) {
+	organization := database.Organization{
+		ID:   int64(len(s.Organizations) + 1),
+		Name: body.Name,
+	}
+
+	s.Organizations[organization.ID] = organization
+	s.Members[organization.ID] = map[int64]database.OrganizationRole{ownerID: database.OrganizationRoleOwner}
+	s.Balances[organization.ID] = decimal.Zero
+
+	return organization, nil
+}
+
+func (s *StubOrganizationStore) GetOrganization(ctx context.Context, id int64) (database.Organization, error) {
+	organization, exists := s.Organizations[id]
+	if !exists {
+		return database.Organization{}, custom_errors.ErrNotFound
+	}
+
+	return organization, nil
+}
+
+func (s *StubOrganizationStore) ListOrganizations(ctx context.Context, userID int64) ([]database.ListOrganizationsByMemberRow, error) {
+	var result []database.ListOrganizationsByMemberRow
+	for id, members := range s.Members {
+		if role, member := members[userID]; member {
+			result = append(result, database.ListOrganizationsByMemberRow{ID: id, Name: s.Organizations[id].Name, Role: role})
+		}
+	}
+
+	return result, nil
+}
+
+func (s *StubOrganizationStore) GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error) {
+	role, exists := s.Members[organizationID][userID]
+	if !exists {
+		return database.OrganizationMember{}, custom_errors.ErrNotFound
+	}
+
+	return database.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: role}, nil
+}
+
+func (s *StubOrganizationStore) ListMembers(ctx context.Context, organizationID int64) ([]database.ListOrganizationMembersRow, error) {
+	var result []database.ListOrganizationMembersRow
+	for userID, role := range s.Members[organizationID] {
+		result = append(result, database.ListOrganizationMembersRow{UserID: userID, Email: fmt.Sprintf("user%d@example.com", userID), Role: role})
+	}
+
+	return result, nil
+}
+
+// owners counts the owners an organization would have with a member changed to the given role, or
+// removed when it is empty.
+func (s *StubOrganizationStore) owners(organizationID, userID int64, role database.OrganizationRole) int {
+	owners := 0
+	for id, current := range s.Members[organizationID] {
+		if id == userID {
+			current = role
+		}
+		if current == database.OrganizationRoleOwner {
+			owners++
+		}
+	}
+
+	return owners
+}
+
+func (s *StubOrganizationStore) UpdateMemberRole(ctx context.Context, organizationID, userID int64, role database.OrganizationRole) (database.OrganizationMember, error) {
+	if _, exists := s.Members[organizationID][userID]; !exists {
+		return database.OrganizationMember{}, custom_errors.ErrNotFound
+	}
+
+	if s.owners(organizationID, userID, role) == 0 {
+		return database.OrganizationMember{}, custom_errors.ErrConflict
+	}
+
+	s.Members[organizationID][userID] = role
+	return database.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: role}, nil
+}
+
+func (s *StubOrganizationStore) RemoveMember(ctx context.Context, organizationID, userID int64) error {
+	if _, exists := s.Members[organizationID][userID]; !exists {
+		return custom_errors.ErrNotFound
+	}
+
+	if s.owners(organizationID, userID, "") == 0 {
+		return custom_errors.ErrConflict
+	}
+
+	delete(s.Members[organizationID], userID)
+	return nil
+}
+
+func (s *StubOrganizationStore) CreateInvitation(ctx context.Context, organizationID, invitedBy int64, body organizations.InviteMemberBody, token string, expiresAt time.Time) (database.OrganizationInvitation, error) {
+	invitation := database.OrganizationInvitation{
+		ID:             int64(len(s.Invitations) + 1),
+		OrganizationID: organizationID,
+		Email:          body.Email,
+		Role:           database.OrganizationRole(body.Role),
+		Token:          token,
+		InvitedBy:      pgtype.Int8{Int64: invitedBy, Valid: true},
+		ExpiresAt:      pgtype.Timestamp{Time: expiresAt, Valid: true},
+	}
+
+	s.Invitations[invitation.ID] = invitation
+	return invitation, nil
+}
+
+func (s *StubOrganizationStore) ListInvitations(ctx context.Context, organizationID int64) ([]database.OrganizationInvitation, error) {
+	var result []database.OrganizationInvitation
+	for _, invitation := range s.Invitations {
+		if invitation.OrganizationID == organizationID && !invitation.AcceptedAt.Valid {
+			result = append(result, invitation)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *StubOrganizationStore) DeleteInvitation(ctx context.Context, organizationID, id int64) error {
+	invitation, exists := s.Invitations[id]
+	if !exists || invitation.OrganizationID != organizationID || invitation.AcceptedAt.Valid {
+		return custom_errors.ErrNotFound
+	}
+
+	delete(s.Invitations, id)
+	return nil
+}
+
+func (s *StubOrganizationStore) AcceptInvitation(ctx context.Context, token string, userID int64, email string) (database.OrganizationMember, error) {
+	for id, invitation := range s.Invitations {
+		if invitation.Token != token || invitation.Email != email || invitation.AcceptedAt.Valid || !invitation.ExpiresAt.Time.After(time.Now()) {
+			continue
+		}
+
+		if _, exists := s.Members[invitation.OrganizationID][userID]; exists {
+			return database.OrganizationMember{}, custom_errors.ErrConflict
+		}
+
+		invitation.AcceptedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		s.Invitations[id] = invitation
+		s.Members[invitation.OrganizationID][userID] = invitation.Role
+
+		return database.OrganizationMember{OrganizationID: invitation.OrganizationID, UserID: userID, Role: invitation.Role}, nil
+	}
+
+	return database.OrganizationMember{}, custom_errors.ErrNotFound
+}
+
+func (s *StubOrganizationStore) GetWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	balance, exists := s.Balances[organizationID]
+	if !exists {
+		return database.Wallet{}, custom_errors.ErrNotFound
+	}
+
+	return database.Wallet{
+		OrganizationID: pgtype.Int8{Int64: organizationID, Valid: true},
+		Balance:        database.NumericFromDecimal(balance),
+	}, nil
+}
+
+func (s *StubOrganizationStore) FundWallet(ctx context.Context, organizationID, userID int64, amount decimal.Decimal) (database.Wallet, error) {
+	if s.UserBalances[userID].LessThan(amount) {
+		return database.Wallet{}, custom_errors.ErrInsufficientFunds
+	}
+
+	s.UserBalances[userID] = s.UserBalances[userID].Sub(amount)
+	s.Balances[organizationID] = s.Balances[organizationID].Add(amount)
+
+	return s.GetWallet(ctx, organizationID)
+}
+
+type StubQueue struct {
+	Tasks      []queue.Processor
+	ShouldFail bool
+}
+
+func (q *StubQueue) Enqueue(processor queue.Processor) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+
+	q.Tasks = append(q.Tasks, processor)
+	return nil
+}
+
+func (q *StubQueue) Dequeue(processor queue.Scheduled) error {
+	return nil
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+const (
+	ownerID  = 1
+	adminID  = 2
+	editorID = 3
+	viewerID = 4
+)
+
+func newRequest(method, target string, body []byte, userID int, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+
+	claims := &tokens.Claims{
+		UserID: userID,
+		Email:  fmt.Sprintf("user%d@example.com", userID),
+		Role:   "researcher",
+	}
+	ctx := context.WithValue(req.Context(), "claims", claims)
+
+	routeCtx := chi.NewRouteContext()
+	for key, value := range params {
+		routeCtx.URLParams.Add(key, value)
+	}
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+
+	return req.WithContext(ctx)
+}
+
+func assertResponseCode(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("response code = %d, want %d", got, want)
+	}
+}
+
+// newOrganizationStore has organization 1 with a member of every role.
+func newOrganizationStore() *StubOrganizationStore {
+	store := NewStubOrganizationStore()
+	store.Organizations[1] = database.Organization{ID: 1, Name: "Acme Research"}
+	store.Members[1] = map[int64]database.OrganizationRole{
+		ownerID:  database.OrganizationRoleOwner,
+		adminID:  database.OrganizationRoleAdmin,
+		editorID: database.OrganizationRoleEditor,
+		viewerID: database.OrganizationRoleViewer,
+	}
+	store.Balances[1] = decimal.Zero
+
+	return store
+}
+
+// ============================================================================
+// Role Tests
+// ============================================================================
+
+func TestCanManage(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  database.OrganizationRole
+		roles []database.OrganizationRole
+		want  bool
+	}{
+		{"owners manage owners", database.OrganizationRoleOwner, []database.OrganizationRole{database.OrganizationRoleOwner}, true},
+		{"admins manage editors and viewers", database.OrganizationRoleAdmin, []database.OrganizationRole{database.OrganizationRoleEditor, database.OrganizationRoleViewer}, true},
+		{"admins don't manage admins", database.OrganizationRoleAdmin, []database.OrganizationRole{database.OrganizationRoleAdmin}, false},
+		{"admins don't promote to owner", database.OrganizationRoleAdmin, []database.OrganizationRole{database.OrganizationRoleViewer, database.OrganizationRoleOwner}, false},
+		{"editors manage nobody", database.OrganizationRoleEditor, []database.OrganizationRole{database.OrganizationRoleViewer}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := organizations.CanManage(tt.role, tt.roles...); got != tt.want {
+				t.Errorf("CanManage(%s, %v) = %v, want %v", tt.role, tt.roles, got, tt.want)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// Organization Tests
+// ============================================================================
+
+func TestCreateOrganizationHandler(t *testing.T) {
+
+	t.Run("makes the researcher the owner", func(t *testing.T) {
+		store := NewStubOrganizationStore()
+		handler := &organizations.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/organizations", []byte(`{"name": "Acme Research"}`), ownerID, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateOrganizationHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if role := store.Members[1][ownerID]; role != database.OrganizationRoleOwner {
+			t.Errorf("role = %q, want owner", role)
+		}
+	})
+
+	t.Run("returns 400 without a name", func(t *testing.T) {
+		handler := &organizations.Handler{Store: NewStubOrganizationStore()}
+
+		req := newRequest(http.MethodPost, "/organizations", []byte(`{}`), ownerID, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateOrganizationHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+func TestGetOrganizationHandler(t *testing.T) {
+
+	t.Run("returns the organization to a viewer", func(t *testing.T) {
+		handler := &organizations.Handler{Store: newOrganizationStore()}
+
+		req := newRequest(http.MethodGet, "/organizations/1", nil, viewerID, map[string]string{"organizationID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetOrganizationHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+	})
+
+	t.Run("returns 404 to a researcher who isn't a member", func(t *testing.T) {
+		handler := &organizations.Handler{Store: newOrganizationStore()}
+
+		req := newRequest(http.MethodGet, "/organizations/1", nil, 9, map[string]string{"organizationID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetOrganizationHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// Wallet Tests
+// ============================================================================
+
+func TestFundWalletHandler(t *testing.T) {
+
+	fund := func(store *StubOrganizationStore, userID int, body string) *httptest.ResponseRecorder {
+		handler := &organizations.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/organizations/1/wallet/fund", []byte(body), userID, map[string]string{"organizationID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.FundWalletHandler(rec, req)
+		return rec
+	}
+
+	t.Run("moves money from the admin's wallet into the organization's", func(t *testing.T) {
+		store := newOrganizationStore()
+		store.UserBalances[adminID] = decimal.NewFromInt(100)
+
+		rec := fund(store, adminID, `{"amount": "40"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got := store.Balances[1]; !got.Equal(decimal.NewFromInt(40)) {
+			t.Errorf("organization balance = %s, want 40", got)
+		}
+
+		if got := store.UserBalances[adminID]; !got.Equal(decimal.NewFromInt(60)) {
+			t.Errorf("balance = %s, want 60", got)
+		}
+	})
+
+	t.Run("returns 403 to an editor", func(t *testing.T) {
+		store := newOrganizationStore()
+		store.UserBalances[editorID] = decimal.NewFromInt(100)
+
+		rec := fund(store, editorID, `{"amount": "40"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 400 for an amount that isn't positive", func(t *testing.T) {
+		rec := fund(newOrganizationStore(), adminID, `{"amount": "-40"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 402 when the admin's wallet can't cover it", func(t *testing.T) {
+		rec := fund(newOrganizationStore(), adminID, `{"amount": "40"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusPaymentRequired)
+	})
+}
+
+// ============================================================================
+// Invitation Tests
+// ============================================================================
+
+func TestInviteMemberHandler(t *testing.T) {
+
+	invite := func(store *StubOrganizationStore, q *StubQueue, userID int, body string) *httptest.ResponseRecorder {
+		handler := &organizations.Handler{Store: store, Queue: q}
+
+		req := newRequest(http.MethodPost, "/organizations/1/invitations", []byte(body), userID, map[string]string{"organizationID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.InviteMemberHandler(rec, req)
+		return rec
+	}
+
+	t.Run("emails the invitation with its token", func(t *testing.T) {
+		store := newOrganizationStore()
+		q := &StubQueue{}
+
+		rec := invite(store, q, adminID, `{"email": "new@example.com", "role": "editor"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if len(q.Tasks) != 1 {
+			t.Fatalf("queued %d tasks, want 1", len(q.Tasks))
+		}
+
+		email, ok := q.Tasks[0].(*queue.EmailDeliveryPayload)
+		if !ok || email.Email != "new@example.com" {
+			t.Fatalf("task = %+v, want an email to new@example.com", q.Tasks[0])
+		}
+
+		if bytes.Contains(rec.Body.Bytes(), []byte(store.Invitations[1].Token)) {
+			t.Error("the response holds the token, want it only in the email")
+		}
+	})
+
+	t.Run("returns 403 when an admin invites an admin", func(t *testing.T) {
+		rec := invite(newOrganizationStore(), &StubQueue{}, adminID, `{"email": "new@example.com", "role": "admin"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 400 when inviting an owner", func(t *testing.T) {
+		rec := invite(newOrganizationStore(), &StubQueue{}, ownerID, `{"email": "new@example.com", "role": "owner"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 403 to an editor", func(t *testing.T) {
+		rec := invite(newOrganizationStore(), &StubQueue{}, editorID, `{"email": "new@example.com", "role": "viewer"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 500 when the email can't be queued", func(t *testing.T) {
+		rec := invite(newOrganizationStore(), &StubQueue{ShouldFail: true}, adminID, `{"email": "new@example.com", "role": "editor"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusInternalServerError)
+	})
+}
+
+func TestAcceptInvitationHandler(t *testing.T) {
+
+	// newInvitedStore has user 5 invited to organization 1 as an editor
+	newInvitedStore := func() *StubOrganizationStore {
+		store := newOrganizationStore()
+		store.Invitations[1] = database.OrganizationInvitation{
+			ID:             1,
+			OrganizationID: 1,
+			Email:          "user5@example.com",
+			Role:           database.OrganizationRoleEditor,
+			Token:          "secret",
+			ExpiresAt:      pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true},
+		}
+
+		return store
+	}
+
+	accept := func(store *StubOrganizationStore, userID int) *httptest.ResponseRecorder {
+		handler := &organizations.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/organizations/invitations/accept", []byte(`{"token": "secret"}`), userID, nil)
+		rec := httptest.NewRecorder()
+
+		handler.AcceptInvitationHandler(rec, req)
+		return rec
+	}
+
+	t.Run("makes the invited email a member with its role", func(t *testing.T) {
+		store := newInvitedStore()
+
+		rec := accept(store, 5)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if role := store.Members[1][5]; role != database.OrganizationRoleEditor {
+			t.Errorf("role = %q, want editor", role)
+		}
+	})
+
+	t.Run("returns 404 to another email", func(t *testing.T) {
+		rec := accept(newInvitedStore(), 6)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 404 for an expired invitation", func(t *testing.T) {
+		store := newInvitedStore()
+		invitation := store.Invitations[1]
+		invitation.ExpiresAt = pgtype.Timestamp{Time: time.Now().Add(-time.Hour), Valid: true}
+		store.Invitations[1] = invitation
+
+		rec := accept(store, 5)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// Member Tests
+// ============================================================================
+
+func TestUpdateMemberHandler(t *testing.T) {
+
+	update := func(store *StubOrganizationStore, userID int, memberID, role string) *httptest.ResponseRecorder {
+		handler := &organizations.Handler{Store: store}
+
+		params := map[string]string{"organizationID": "1", "userID": memberID}
+		req := newRequest(http.MethodPatch, "/organizations/1/members/"+memberID, []byte(`{"role": "`+role+`"}`), userID, params)
+		rec := httptest.NewRecorder()
+
+		handler.UpdateMemberHandler(rec, req)
+		return rec
+	}
+
+	t.Run("lets an admin make an editor a viewer", func(t *testing.T) {
+		store := newOrganizationStore()
+
+		rec := update(store, adminID, "3", "viewer")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if role := store.Members[1][editorID]; role != database.OrganizationRoleViewer {
+			t.Errorf("role = %q, want viewer", role)
+		}
+	})
+
+	t.Run("returns 403 when an admin promotes someone to admin", func(t *testing.T) {
+		rec := update(newOrganizationStore(), adminID, "3", "admin")
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("lets an owner hand over ownership", func(t *testing.T) {
+		store := newOrganizationStore()
+
+		rec := update(store, ownerID, "2", "owner")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+	})
+
+	t.Run("returns 409 when the last owner steps down", func(t *testing.T) {
+		store := newOrganizationStore()
+
+		rec := update(store, ownerID, "1", "admin")
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+
+		if role := store.Members[1][ownerID]; role != database.OrganizationRoleOwner {
+			t.Errorf("role = %q, want owner", role)
+		}
+	})
+}
+
+func TestRemoveMemberHandler(t *testing.T) {
+
+	remove := func(store *StubOrganizationStore, userID int, memberID string) *httptest.ResponseRecorder {
+		handler := &organizations.Handler{Store: store}
+
+		params := map[string]string{"organizationID": "1", "userID": memberID}
+		req := newRequest(http.MethodDelete, "/organizations/1/members/"+memberID, nil, userID, params)
+		rec := httptest.NewRecorder()
+
+		handler.RemoveMemberHandler(rec, req)
+		return rec
+	}
+
+	t.Run("lets a viewer leave", func(t *testing.T) {
+		store := newOrganizationStore()
+
+		rec := remove(store, viewerID, "4")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if _, member := store.Members[1][viewerID]; member {
+			t.Error("the viewer is still a member")
+		}
+	})
+
+	t.Run("returns 403 when an editor removes a viewer", func(t *testing.T) {
+		rec := remove(newOrganizationStore(), editorID, "4")
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 403 when an admin removes the owner", func(t *testing.T) {
+		rec := remove(newOrganizationStore(), adminID, "1")
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 409 when the last owner leaves", func(t *testing.T) {
+		rec := remove(newOrganizationStore(), ownerID, "1")
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
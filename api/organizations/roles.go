@@ -0,0 +1,58 @@
+package organizations
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/Adedunmol/answerly/database"
+	"time"
+)
+
+// InvitationExpiry is how long an invitation to an organization can be accepted for.
+const InvitationExpiry = 7 * 24 * time.Hour
+
+// roleRanks orders the roles of an organization. Each role may do what the ones below it may:
+// viewers see the surveys and wallet of the organization, editors also create, change and publish
+// its surveys, admins also fund its wallet and manage its members, and owners also manage admins
+// and other owners.
+var roleRanks = map[database.OrganizationRole]int{
+	database.OrganizationRoleViewer: 1,
+	database.OrganizationRoleEditor: 2,
+	database.OrganizationRoleAdmin:  3,
+	database.OrganizationRoleOwner:  4,
+}
+
+// AtLeast tells whether a role may do what another may.
+func AtLeast(role, other database.OrganizationRole) bool {
+	return roleRanks[role] >= roleRanks[other]
+}
+
+// CanManage tells whether a member may change the role of another, or remove them, to or from the
+// given roles. Admins manage editors and viewers, owners manage everyone.
+func CanManage(role database.OrganizationRole, roles ...database.OrganizationRole) bool {
+	if role == database.OrganizationRoleOwner {
+		return true
+	}
+
+	if role != database.OrganizationRoleAdmin {
+		return false
+	}
+
+	for _, other := range roles {
+		if AtLeast(other, database.OrganizationRoleAdmin) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewInvitationToken returns the secret an invited email accepts an invitation with.
+func NewInvitationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating invitation token: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,42 @@
+package organizations
+
+import (
+	"github.com/Adedunmol/answerly/api/middlewares"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func SetupRoutes(r *chi.Mux, queue queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	organizationsRouter := chi.NewRouter()
+
+	store := NewOrganizationStore(queries, db)
+	tokenService := tokens.NewTokenService()
+
+	handler := Handler{
+		Store: store,
+		Queue: queue,
+	}
+
+	organizationsRouter.Use(middlewares.AuthMiddleware(tokenService))
+	organizationsRouter.Use(middlewares.RequireRole("researcher"))
+
+	organizationsRouter.Post("/", handler.CreateOrganizationHandler)
+	organizationsRouter.Get("/", handler.ListOrganizationsHandler)
+	organizationsRouter.Post("/invitations/accept", handler.AcceptInvitationHandler)
+	organizationsRouter.Get("/{organizationID}", handler.GetOrganizationHandler)
+	organizationsRouter.Get("/{organizationID}/wallet", handler.GetWalletHandler)
+	organizationsRouter.Post("/{organizationID}/wallet/fund", handler.FundWalletHandler)
+	organizationsRouter.Post("/{organizationID}/invitations", handler.InviteMemberHandler)
+	organizationsRouter.Get("/{organizationID}/invitations", handler.ListInvitationsHandler)
+	organizationsRouter.Delete("/{organizationID}/invitations/{invitationID}", handler.DeleteInvitationHandler)
+	organizationsRouter.Patch("/{organizationID}/members/{userID}", handler.UpdateMemberHandler)
+	organizationsRouter.Delete("/{organizationID}/members/{userID}", handler.RemoveMemberHandler)
+
+	r.Mount("/organizations", organizationsRouter)
+
+	return
+}
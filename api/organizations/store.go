@@ -0,0 +1,354 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/wallets"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type Store interface {
+	CreateOrganization(ctx context.Context, ownerID int64, body CreateOrganizationBody) (database.Organization, error)
+	GetOrganization(ctx context.Context, id int64) (database.Organization, error)
+	ListOrganizations(ctx context.Context, userID int64) ([]database.ListOrganizationsByMemberRow, error)
+	GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error)
+	ListMembers(ctx context.Context, organizationID int64) ([]database.ListOrganizationMembersRow, error)
+	UpdateMemberRole(ctx context.Context, organizationID, userID int64, role database.OrganizationRole) (database.OrganizationMember, error)
+	RemoveMember(ctx context.Context, organizationID, userID int64) error
+	CreateInvitation(ctx context.Context, organizationID, invitedBy int64, body InviteMemberBody, token string, expiresAt time.Time) (database.OrganizationInvitation, error)
+	ListInvitations(ctx context.Context, organizationID int64) ([]database.OrganizationInvitation, error)
+	DeleteInvitation(ctx context.Context, organizationID, id int64) error
+	AcceptInvitation(ctx context.Context, token string, userID int64, email string) (database.OrganizationMember, error)
+	GetWallet(ctx context.Context, organizationID int64) (database.Wallet, error)
+	FundWallet(ctx context.Context, organizationID, userID int64, amount decimal.Decimal) (database.Wallet, error)
+}
+
+const UniqueViolation = "23505"
+
+type Repository struct {
+	queries    *database.Queries
+	db         *pgxpool.Pool
+	transactor database.Transactor
+	wallets    wallets.Store
+}
+
+func NewOrganizationStore(queries *database.Queries, db *pgxpool.Pool) *Repository {
+
+	return &Repository{
+		queries:    queries,
+		db:         db,
+		transactor: database.NewDBTransactor(db),
+		wallets:    wallets.NewWalletStore(queries),
+	}
+}
+
+// CreateOrganization creates an organization with the researcher as its owner and an empty wallet,
+// in one transaction.
+func (r *Repository) CreateOrganization(ctx context.Context, ownerID int64, body CreateOrganizationBody) (database.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var organization database.Organization
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		organization, err = q.CreateOrganization(ctx, body.Name)
+		if err != nil {
+			return fmt.Errorf("error creating organization: %v", err)
+		}
+
+		_, err = q.CreateOrganizationMember(ctx, database.CreateOrganizationMemberParams{
+			OrganizationID: organization.ID,
+			UserID:         ownerID,
+			Role:           database.OrganizationRoleOwner,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating organization member: %v", err)
+		}
+
+		if _, err := r.wallets.CreateOrganizationWallet(ctx, organization.ID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Organization{}, err
+	}
+
+	return organization, nil
+}
+
+func (r *Repository) GetOrganization(ctx context.Context, id int64) (database.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	organization, err := r.queries.GetOrganization(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Organization{}, custom_errors.ErrNotFound
+		}
+		return database.Organization{}, fmt.Errorf("error getting organization: %v", err)
+	}
+
+	return organization, nil
+}
+
+func (r *Repository) ListOrganizations(ctx context.Context, userID int64) ([]database.ListOrganizationsByMemberRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	organizations, err := r.queries.ListOrganizationsByMember(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organizations: %v", err)
+	}
+
+	return organizations, nil
+}
+
+// GetMember returns the membership of a user in an organization, which fails with
+// custom_errors.ErrNotFound when they aren't a member.
+func (r *Repository) GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	member, err := r.queries.GetOrganizationMember(ctx, database.GetOrganizationMemberParams{
+		OrganizationID: organizationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.OrganizationMember{}, custom_errors.ErrNotFound
+		}
+		return database.OrganizationMember{}, fmt.Errorf("error getting organization member: %v", err)
+	}
+
+	return member, nil
+}
+
+func (r *Repository) ListMembers(ctx context.Context, organizationID int64) ([]database.ListOrganizationMembersRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	members, err := r.queries.ListOrganizationMembers(ctx, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organization members: %v", err)
+	}
+
+	return members, nil
+}
+
+// UpdateMemberRole changes the role of a member. It fails with custom_errors.ErrConflict when that
+// would leave the organization without an owner.
+func (r *Repository) UpdateMemberRole(ctx context.Context, organizationID, userID int64, role database.OrganizationRole) (database.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var member database.OrganizationMember
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		if err := q.LockOrganization(ctx, organizationID); err != nil {
+			return fmt.Errorf("error locking organization: %v", err)
+		}
+
+		var err error
+		member, err = q.UpdateOrganizationMemberRole(ctx, database.UpdateOrganizationMemberRoleParams{
+			Role:           role,
+			OrganizationID: organizationID,
+			UserID:         userID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error updating organization member: %v", err)
+		}
+
+		return keepOwner(ctx, q, organizationID)
+	})
+	if err != nil {
+		return database.OrganizationMember{}, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember takes a user out of an organization. Like UpdateMemberRole, it fails with
+// custom_errors.ErrConflict when that would leave the organization without an owner.
+func (r *Repository) RemoveMember(ctx context.Context, organizationID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		if err := q.LockOrganization(ctx, organizationID); err != nil {
+			return fmt.Errorf("error locking organization: %v", err)
+		}
+
+		rows, err := q.DeleteOrganizationMember(ctx, database.DeleteOrganizationMemberParams{
+			OrganizationID: organizationID,
+			UserID:         userID,
+		})
+		if err != nil {
+			return fmt.Errorf("error removing organization member: %v", err)
+		}
+
+		if rows == 0 {
+			return custom_errors.ErrNotFound
+		}
+
+		return keepOwner(ctx, q, organizationID)
+	})
+}
+
+// keepOwner fails a change to the members of an organization that left it without an owner.
+func keepOwner(ctx context.Context, q *database.Queries, organizationID int64) error {
+	owners, err := q.CountOrganizationOwners(ctx, organizationID)
+	if err != nil {
+		return fmt.Errorf("error counting organization owners: %v", err)
+	}
+
+	if owners == 0 {
+		return fmt.Errorf("%w: an organization must keep at least one owner", custom_errors.ErrConflict)
+	}
+
+	return nil
+}
+
+// CreateInvitation invites an email to an organization, renewing the invitation it already has.
+func (r *Repository) CreateInvitation(ctx context.Context, organizationID, invitedBy int64, body InviteMemberBody, token string, expiresAt time.Time) (database.OrganizationInvitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitation, err := r.queries.CreateOrganizationInvitation(ctx, database.CreateOrganizationInvitationParams{
+		OrganizationID: organizationID,
+		Email:          body.Email,
+		Role:           database.OrganizationRole(body.Role),
+		Token:          token,
+		InvitedBy:      pgtype.Int8{Int64: invitedBy, Valid: true},
+		ExpiresAt:      pgtype.Timestamp{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return database.OrganizationInvitation{}, fmt.Errorf("error creating organization invitation: %v", err)
+	}
+
+	return invitation, nil
+}
+
+func (r *Repository) ListInvitations(ctx context.Context, organizationID int64) ([]database.OrganizationInvitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitations, err := r.queries.ListOrganizationInvitations(ctx, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organization invitations: %v", err)
+	}
+
+	return invitations, nil
+}
+
+func (r *Repository) DeleteInvitation(ctx context.Context, organizationID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.queries.DeleteOrganizationInvitation(ctx, database.DeleteOrganizationInvitationParams{
+		ID:             id,
+		OrganizationID: organizationID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting organization invitation: %v", err)
+	}
+
+	if rows == 0 {
+		return custom_errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes a user a member of the organization they were invited to, with the role
+// they were invited as. It fails with custom_errors.ErrNotFound when the token isn't a pending
+// invitation of their email, and with custom_errors.ErrConflict when they are already a member.
+func (r *Repository) AcceptInvitation(ctx context.Context, token string, userID int64, email string) (database.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var member database.OrganizationMember
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		invitation, err := q.AcceptOrganizationInvitation(ctx, database.AcceptOrganizationInvitationParams{
+			Token: token,
+			Email: email,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("%w: the invitation doesn't exist, has expired or was already accepted", custom_errors.ErrNotFound)
+			}
+			return fmt.Errorf("error accepting organization invitation: %v", err)
+		}
+
+		member, err = q.CreateOrganizationMember(ctx, database.CreateOrganizationMemberParams{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+		})
+		if err != nil {
+			var e *pgconn.PgError
+			if errors.As(err, &e) && e.Code == UniqueViolation {
+				return fmt.Errorf("%w: already a member of the organization", custom_errors.ErrConflict)
+			}
+			return fmt.Errorf("error creating organization member: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.OrganizationMember{}, err
+	}
+
+	return member, nil
+}
+
+func (r *Repository) GetWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	return r.wallets.GetOrganizationWallet(ctx, organizationID)
+}
+
+// FundWallet moves an amount from the wallet of a member into that of their organization, in one
+// transaction. It fails with custom_errors.ErrInsufficientFunds when the member's wallet can't
+// cover it.
+func (r *Repository) FundWallet(ctx context.Context, organizationID, userID int64, amount decimal.Decimal) (database.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var wallet database.Wallet
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := r.wallets.ChargeWallet(ctx, userID, amount); err != nil {
+			return err
+		}
+
+		var err error
+		wallet, err = r.wallets.TopUpOrganizationWallet(ctx, organizationID, amount)
+		return err
+	})
+	if err != nil {
+		return database.Wallet{}, err
+	}
+
+	return wallet, nil
+}
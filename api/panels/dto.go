@@ -0,0 +1,206 @@
+package panels
+
+import (
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CreatePanelBody struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description"`
+	// SurveyID is the survey the panel starts from, its first wave.
+	SurveyID int64 `json:"survey_id" validate:"required,gt=0"`
+	// IntervalDays is how long after one wave opens the next one does, unless it is given a time.
+	IntervalDays int32 `json:"interval_days" validate:"required,gte=1,lte=365"`
+}
+
+// InviteParticipantsBody picks the wave whose respondents are invited to the panel, every wave
+// when it is left out.
+type InviteParticipantsBody struct {
+	Wave *int32 `json:"wave" validate:"omitempty,gte=1"`
+}
+
+// CreateWaveBody schedules the next wave of a panel. OpensAt defaults to the interval of the panel
+// after the last wave opened, ClosesAt to as long after that as the last wave stayed open.
+type CreateWaveBody struct {
+	Title    string     `json:"title" validate:"omitempty,max=255"`
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+type Panel struct {
+	ID           int64     `json:"id"`
+	ResearcherID int64     `json:"researcher_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	IntervalDays int32     `json:"interval_days"`
+	Waves        []Wave    `json:"waves,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func NewPanel(panel database.Panel) Panel {
+	return Panel{
+		ID:           panel.ID,
+		ResearcherID: panel.ResearcherID,
+		Name:         panel.Name,
+		Description:  panel.Description.String,
+		IntervalDays: panel.IntervalDays,
+		CreatedAt:    panel.CreatedAt.Time,
+		UpdatedAt:    panel.UpdatedAt.Time,
+	}
+}
+
+func NewPanels(panels []database.Panel) []Panel {
+	result := make([]Panel, 0, len(panels))
+	for _, panel := range panels {
+		result = append(result, NewPanel(panel))
+	}
+
+	return result
+}
+
+type Wave struct {
+	Wave     int32      `json:"wave"`
+	SurveyID int64      `json:"survey_id"`
+	Title    string     `json:"title"`
+	Status   string     `json:"status"`
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+func NewWave(wave database.ListPanelWavesRow) Wave {
+	data := Wave{
+		Wave:     wave.Wave,
+		SurveyID: wave.SurveyID,
+		Title:    wave.Title,
+		Status:   string(wave.Status),
+	}
+
+	if wave.OpensAt.Valid {
+		data.OpensAt = &wave.OpensAt.Time
+	}
+
+	if wave.ClosesAt.Valid {
+		data.ClosesAt = &wave.ClosesAt.Time
+	}
+
+	return data
+}
+
+func NewWaves(waves []database.ListPanelWavesRow) []Wave {
+	result := make([]Wave, 0, len(waves))
+	for _, wave := range waves {
+		result = append(result, NewWave(wave))
+	}
+
+	return result
+}
+
+// Participant is a respondent of a panel as the researcher knows them, by an ID that stays the same
+// in every wave.
+type Participant struct {
+	ParticipantID string `json:"participant_id"`
+	// Wave is the wave they were invited from, they can answer every wave after it.
+	Wave int32 `json:"wave"`
+}
+
+type Invitation struct {
+	Invited      int           `json:"invited"`
+	Participants []Participant `json:"participants"`
+}
+
+func NewInvitation(rows []database.EnrollPanelParticipantsRow) Invitation {
+	invitation := Invitation{Invited: len(rows), Participants: make([]Participant, 0, len(rows))}
+	for _, row := range rows {
+		invitation.Participants = append(invitation.Participants, Participant{ParticipantID: row.ParticipantID, Wave: row.Wave})
+	}
+
+	return invitation
+}
+
+// WaveAttrition is how many of the participants who could answer a wave did. Participants invited
+// from a wave count as having answered it.
+type WaveAttrition struct {
+	Wave         int32 `json:"wave"`
+	SurveyID     int64 `json:"survey_id"`
+	Participants int32 `json:"participants"`
+	Started      int32 `json:"started"`
+	Completed    int32 `json:"completed"`
+	// DroppedOut is how many participants didn't submit the wave, AttritionRate the percentage of
+	// the participants they are.
+	DroppedOut    int32           `json:"dropped_out"`
+	AttritionRate decimal.Decimal `json:"attrition_rate"`
+}
+
+func NewAttrition(rows []database.GetPanelAttritionRow) []WaveAttrition {
+	result := make([]WaveAttrition, 0, len(rows))
+	for _, row := range rows {
+		dropped := row.Participants - row.Completed
+		result = append(result, WaveAttrition{
+			Wave:          row.Wave,
+			SurveyID:      row.SurveyID,
+			Participants:  row.Participants,
+			Started:       row.Started,
+			Completed:     row.Completed,
+			DroppedOut:    dropped,
+			AttritionRate: percentage(dropped, row.Participants),
+		})
+	}
+
+	return result
+}
+
+// PanelResults are the results of every question the waves of a panel asked, wave by wave.
+type PanelResults struct {
+	PanelID   int64         `json:"panel_id"`
+	Waves     []WaveSummary `json:"waves"`
+	Questions []ItemResult  `json:"questions"`
+}
+
+type WaveSummary struct {
+	Wave      int32 `json:"wave"`
+	SurveyID  int64 `json:"survey_id"`
+	Responses int32 `json:"responses"`
+}
+
+// ItemResult follows a question of the panel through the waves that asked it. ItemID is the ID of
+// the question in the wave it was first asked in, Title and Type are as the last wave asked it.
+type ItemResult struct {
+	ItemID int64      `json:"item_id"`
+	Title  string     `json:"title"`
+	Type   string     `json:"type"`
+	Waves  []ItemWave `json:"waves"`
+}
+
+// ItemWave is the result of a question in one wave, with how it changed since the wave before when
+// that one asked it too.
+type ItemWave struct {
+	Wave     int32 `json:"wave"`
+	SurveyID int64 `json:"survey_id"`
+	surveys.QuestionResult
+	Change *WaveChange `json:"change,omitempty"`
+}
+
+// WaveChange is how the answers to a question moved from one wave to the next: by how many
+// percentage points each option was picked more or less often, and by how much the mean moved.
+type WaveChange struct {
+	FromWave int32            `json:"from_wave"`
+	Choices  []ChoiceChange   `json:"choices,omitempty"`
+	Rows     []RowChange      `json:"rows,omitempty"`
+	Mean     *decimal.Decimal `json:"mean,omitempty"`
+}
+
+type ChoiceChange struct {
+	ID     string          `json:"id"`
+	Label  string          `json:"label"`
+	Points decimal.Decimal `json:"percentage_points"`
+}
+
+type RowChange struct {
+	ID      string         `json:"id"`
+	Label   string         `json:"label"`
+	Choices []ChoiceChange `json:"choices"`
+}
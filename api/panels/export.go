@@ -0,0 +1,140 @@
+package panels
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PanelAnswer is one answer a participant gave in a wave of a panel, as a line of its JSONL
+// export. ItemID is the question it goes back to, the same in every wave, so answers to it can be
+// lined up across waves under the participant.
+type PanelAnswer struct {
+	ParticipantID string          `json:"participant_id"`
+	Wave          int32           `json:"wave"`
+	SurveyID      int64           `json:"survey_id"`
+	ResponseID    int64           `json:"response_id"`
+	SubmittedAt   *time.Time      `json:"submitted_at"`
+	ItemID        int64           `json:"item_id"`
+	QuestionID    int64           `json:"question_id"`
+	Value         json.RawMessage `json:"value"`
+}
+
+func NewPanelAnswer(row database.ListPanelAnswersRow) PanelAnswer {
+	answer := PanelAnswer{
+		ParticipantID: row.ParticipantID,
+		Wave:          row.Wave,
+		SurveyID:      row.SurveyID,
+		ResponseID:    row.ResponseID,
+		ItemID:        row.ItemID,
+		QuestionID:    row.QuestionID,
+		Value:         row.Value,
+	}
+
+	if row.SubmittedAt.Valid {
+		answer.SubmittedAt = &row.SubmittedAt.Time
+	}
+
+	return answer
+}
+
+// ExportHeader is the header row of the CSV export of a panel, which has a row per answer.
+var ExportHeader = []string{"participant_id", "wave", "survey_id", "response_id", "submitted_at", "item_id", "question_id", "value"}
+
+func exportRecord(row database.ListPanelAnswersRow) []string {
+	submittedAt := ""
+	if row.SubmittedAt.Valid {
+		submittedAt = row.SubmittedAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		row.ParticipantID, strconv.Itoa(int(row.Wave)), strconv.FormatInt(row.SurveyID, 10),
+		strconv.FormatInt(row.ResponseID, 10), submittedAt, strconv.FormatInt(row.ItemID, 10),
+		strconv.FormatInt(row.QuestionID, 10), string(row.Value),
+	}
+}
+
+func writeExport(w io.Writer, format string, rows []database.ListPanelAnswersRow) error {
+	if format == surveys.ExportJSONL {
+		encoder := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := encoder.Encode(NewPanelAnswer(row)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ExportHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := writer.Write(exportRecord(row)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportAnswersHandler exports every answer the participants of a panel submitted, a row per
+// answer in long format, as CSV or JSONL. Answers are linked across waves by participant ID and
+// item ID; respondents who never became participants are left out.
+func (h *Handler) ExportAnswersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	format, err := surveys.ParseExportFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.Store.ListAnswers(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == surveys.ExportJSONL {
+		contentType = "application/x-ndjson"
+	}
+
+	responseWriter.Header().Set("Content-Type", contentType)
+	responseWriter.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="panel-%d-answers.%s"`, panel.ID, format))
+	responseWriter.WriteHeader(http.StatusOK)
+
+	if err := writeExport(responseWriter, format, rows); err != nil {
+		log.Printf("error exporting answers to panel %d: %s", panel.ID, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	return
+}
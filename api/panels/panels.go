@@ -0,0 +1,542 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Handler struct {
+	Store Store
+	// Queue schedules the waves of panels and sends the emails inviting participants.
+	Queue queue.Queue
+}
+
+func panelIDParam(request *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(request, "panelID"), 10, 64)
+}
+
+// ownedPanel loads the panel in the URL when it belongs to the researcher making the request.
+// When it can't, it writes the error response itself and returns false.
+func (h *Handler) ownedPanel(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Panel, bool) {
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return database.Panel{}, false
+	}
+
+	panelID, err := panelIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid panel id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.Panel{}, false
+	}
+
+	panel, err := h.Store.GetPanel(ctx, panelID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.Panel{}, false
+	}
+
+	return panel, true
+}
+
+// CreatePanelHandler starts a panel from one of the researcher's surveys, which becomes its first
+// wave. Anonymous surveys can't start one, as their respondents can't be followed across waves.
+func (h *Handler) CreatePanelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[CreatePanelBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.GetSurvey(ctx, data.SurveyID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if survey.Anonymous {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "an anonymous survey can't start a panel, its respondents can't be followed across waves",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return
+	}
+
+	panel, err := h.Store.CreatePanel(ctx, int64(userID), data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "panel created successfully",
+		Data:    NewPanel(panel),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+func (h *Handler) ListPanelsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	panels, err := h.Store.ListPanels(ctx, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved panels successfully",
+		Data:    NewPanels(panels),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// GetPanelHandler returns a panel with its waves.
+func (h *Handler) GetPanelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	waves, err := h.Store.ListWaves(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	data := NewPanel(panel)
+	data.Waves = NewWaves(waves)
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved panel successfully",
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// InviteParticipantsHandler invites the respondents who submitted an earlier wave of a panel to
+// take part in it, and emails them that they were. The wave may be given in the body, which is
+// optional; inviting the same respondent again does nothing.
+func (h *Handler) InviteParticipantsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	var data InviteParticipantsBody
+
+	if request.ContentLength != 0 {
+		var err error
+		data, err = jsonutil.UnmarshalJsonResponse[InviteParticipantsBody](request)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+	}
+
+	participants, err := h.Store.InviteParticipants(ctx, panel.ID, data.Wave)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	for _, participant := range participants {
+		sendPanelEmail(h.Queue, participant.Email, panel, "You're invited to a research panel",
+			"Thanks for answering it. You can now take part in its next waves, and we'll email you whenever one opens.")
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "invited panel participants successfully",
+		Data:    NewInvitation(participants),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// WaveSchedule works out when the next wave of a panel opens and closes. Times left out of the
+// body follow the last wave: the next one opens the interval of the panel after it did, or after
+// now when that has passed, and stays open as long as it did.
+func WaveSchedule(panel database.Panel, last database.ListPanelWavesRow, body CreateWaveBody, now time.Time) surveys.Schedule {
+	interval := time.Duration(panel.IntervalDays) * 24 * time.Hour
+
+	lastOpened := last.OpensAt
+	if !lastOpened.Valid {
+		lastOpened = last.PublishedAt
+	}
+
+	opensAt := body.OpensAt
+	if opensAt == nil {
+		at := now.Add(interval)
+		if lastOpened.Valid && lastOpened.Time.Add(interval).After(now) {
+			at = lastOpened.Time.Add(interval)
+		}
+		opensAt = &at
+	}
+
+	closesAt := body.ClosesAt
+	if closesAt == nil && lastOpened.Valid && last.ClosesAt.Valid {
+		at := opensAt.Add(last.ClosesAt.Time.Sub(lastOpened.Time))
+		closesAt = &at
+	}
+
+	return surveys.NewSchedule(surveys.ScheduleSurveyBody{OpensAt: opensAt, ClosesAt: closesAt})
+}
+
+// CreateWaveHandler schedules the next wave of a panel: a draft copy of the last wave, which opens
+// and closes by itself like any scheduled survey, and whose participants are emailed when it
+// opens. The body is optional.
+func (h *Handler) CreateWaveHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	var data CreateWaveBody
+
+	if request.ContentLength != 0 {
+		var err error
+		data, err = jsonutil.UnmarshalJsonResponse[CreateWaveBody](request)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+	}
+
+	waves, err := h.Store.ListWaves(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if len(waves) == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "the panel has no wave to follow, its first survey was deleted",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return
+	}
+
+	last := waves[len(waves)-1]
+
+	lastSurvey, err := h.Store.GetSurveyByID(ctx, last.SurveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if lastSurvey.Anonymous {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "the last wave is anonymous, its respondents can't be followed into another",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	schedule := WaveSchedule(panel, last, data, now)
+
+	if err := surveys.ValidateSchedule(schedule, database.Survey{Status: database.SurveyStatusDraft}, now); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.CreateWave(ctx, panel, last, data.Title)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	wave := last.Wave + 1
+
+	tasks := surveys.ScheduleTasks(survey.ID, survey.Status, schedule)
+	invite := &queue.PanelWavePayload{PanelID: panel.ID, Wave: wave, SurveyID: survey.ID, At: *schedule.OpensAt}
+	tasks[invite.TaskID()] = invite
+
+	// like any schedule, the tasks go on the queue before it is saved. A wave whose tasks can't be
+	// queued stays an unscheduled draft the researcher can schedule themselves.
+	for _, task := range tasks {
+		if err := h.Queue.Enqueue(task); err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: fmt.Sprintf("wave %d was created as survey %d but could not be scheduled: %s", wave, survey.ID, err),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	survey, err = h.Store.ScheduleSurvey(ctx, survey.ID, panel.ResearcherID, schedule)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "panel wave scheduled successfully",
+		Data: NewWave(database.ListPanelWavesRow{
+			Wave:     wave,
+			SurveyID: survey.ID,
+			Title:    survey.Title,
+			Status:   survey.Status,
+			OpensAt:  survey.OpensAt,
+			ClosesAt: survey.ClosesAt,
+		}),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+// GetAttritionHandler reports, for every wave of a panel, how many of the participants who could
+// answer it did and how many dropped out.
+func (h *Handler) GetAttritionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	rows, err := h.Store.GetAttrition(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved panel attrition successfully",
+		Data:    NewAttrition(rows),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// GetResultsHandler returns the results of every wave of a panel that has opened, question by
+// question, with how the answers to each changed from one wave to the next.
+func (h *Handler) GetResultsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	panel, ok := h.ownedPanel(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	waves, err := h.Store.ListWaves(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	items, err := h.Store.ListItems(ctx, panel.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	now := time.Now()
+
+	var results []WaveResults
+
+	for _, wave := range waves {
+		// nobody has answered a wave that hasn't opened yet
+		if wave.Status == database.SurveyStatusDraft {
+			continue
+		}
+
+		questions, err := h.Store.ListQuestions(ctx, wave.SurveyID)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		rows, err := h.Store.GetResults(ctx, wave.SurveyID, surveys.ResultsFilter{}, now)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		waveResults, err := surveys.NewResults(wave.SurveyID, surveys.QuestionHistory{Current: questions}, rows)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+
+		results = append(results, WaveResults{Wave: wave.Wave, Results: waveResults, Items: items})
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved panel results successfully",
+		Data:    NewPanelResults(panel.ID, results),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// sendPanelEmail emails a participant about a panel. Failing to queue the email is only logged, as
+// whatever it tells them has already happened.
+func sendPanelEmail(q queue.Queue, email string, panel database.Panel, subject, message string) {
+	err := q.Enqueue(&queue.EmailDeliveryPayload{
+		Name:     "email",
+		Template: "panel_mail",
+		Subject:  subject,
+		Email:    email,
+		Data: struct {
+			Heading string
+			Title   string
+			Message string
+		}{
+			Heading: subject,
+			Title:   panel.Name,
+			Message: message,
+		},
+	})
+
+	if err != nil {
+		log.Printf("error enqueuing email task: %s", err)
+	}
+}
@@ -0,0 +1,828 @@
+package panels_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/panels"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const researcherID = 1
+
+// ============================================================================
+// Stub Panel Store
+// ============================================================================
+
+type StubPanelStore struct {
+	Panels  map[int64]database.Panel
+	Waves   map[int64][]database.ListPanelWavesRow
+	Surveys map[int64]database.Survey
+	// Respondents holds who submitted each wave, by survey, with their email
+	Respondents map[int64]map[string]string
+	// Participants holds the participants of each panel by email, with the wave they were invited from
+	Participants map[int64]map[string]int32
+	Attrition    []database.GetPanelAttritionRow
+	Answers      []database.ListPanelAnswersRow
+	Items        map[int64]int64
+	Questions    map[int64][]database.Question
+	Results      map[int64][]database.ListSurveyResultsRow
+}
+
+func NewStubPanelStore() *StubPanelStore {
+	return &StubPanelStore{
+		Panels:       make(map[int64]database.Panel),
+		Waves:        make(map[int64][]database.ListPanelWavesRow),
+		Surveys:      make(map[int64]database.Survey),
+		Respondents:  make(map[int64]map[string]string),
+		Participants: make(map[int64]map[string]int32),
+		Items:        make(map[int64]int64),
+		Questions:    make(map[int64][]database.Question),
+		Results:      make(map[int64][]database.ListSurveyResultsRow),
+	}
+}
+
+func (s *StubPanelStore) CreatePanel(ctx context.Context, researcherID int64, body panels.CreatePanelBody) (database.Panel, error) {
+	for _, waves := range s.Waves {
+		for _, wave := range waves {
+			if wave.SurveyID == body.SurveyID {
+				return database.Panel{}, custom_errors.ErrConflict
+			}
+		}
+	}
+
+	panel := database.Panel{
+		ID:           int64(len(s.Panels) + 1),
+		ResearcherID: researcherID,
+		Name:         body.Name,
+		IntervalDays: body.IntervalDays,
+	}
+	s.Panels[panel.ID] = panel
+
+	survey := s.Surveys[body.SurveyID]
+	s.Waves[panel.ID] = []database.ListPanelWavesRow{{Wave: 1, SurveyID: survey.ID, Title: survey.Title, Status: survey.Status}}
+
+	return panel, nil
+}
+
+func (s *StubPanelStore) GetPanel(ctx context.Context, id, researcherID int64) (database.Panel, error) {
+	panel, exists := s.Panels[id]
+	if !exists || panel.ResearcherID != researcherID {
+		return database.Panel{}, custom_errors.ErrNotFound
+	}
+
+	return panel, nil
+}
+
+func (s *StubPanelStore) ListPanels(ctx context.Context, researcherID int64) ([]database.Panel, error) {
+	var result []database.Panel
+	for _, panel := range s.Panels {
+		if panel.ResearcherID == researcherID {
+			result = append(result, panel)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *StubPanelStore) ListWaves(ctx context.Context, panelID int64) ([]database.ListPanelWavesRow, error) {
+	return s.Waves[panelID], nil
+}
+
+func (s *StubPanelStore) CreateWave(ctx context.Context, panel database.Panel, from database.ListPanelWavesRow, title string) (database.Survey, error) {
+	source := s.Surveys[from.SurveyID]
+
+	survey := source
+	survey.ID = int64(len(s.Surveys) + 1)
+	survey.Status = database.SurveyStatusDraft
+	survey.OpensAt = pgtype.Timestamp{}
+	survey.ClosesAt = pgtype.Timestamp{}
+	survey.PublishedAt = pgtype.Timestamp{}
+	if title != "" {
+		survey.Title = title
+	}
+	s.Surveys[survey.ID] = survey
+
+	s.Waves[panel.ID] = append(s.Waves[panel.ID], database.ListPanelWavesRow{
+		Wave: from.Wave + 1, SurveyID: survey.ID, Title: survey.Title, Status: survey.Status,
+	})
+
+	return survey, nil
+}
+
+func (s *StubPanelStore) InviteParticipants(ctx context.Context, panelID int64, wave *int32) ([]database.EnrollPanelParticipantsRow, error) {
+	if s.Participants[panelID] == nil {
+		s.Participants[panelID] = make(map[string]int32)
+	}
+
+	var enrolled []database.EnrollPanelParticipantsRow
+	for _, row := range s.Waves[panelID] {
+		if wave != nil && row.Wave != *wave {
+			continue
+		}
+
+		emails := make([]string, 0, len(s.Respondents[row.SurveyID]))
+		for email := range s.Respondents[row.SurveyID] {
+			emails = append(emails, email)
+		}
+		slices.Sort(emails)
+
+		for _, email := range emails {
+			if _, exists := s.Participants[panelID][email]; exists {
+				continue
+			}
+			s.Participants[panelID][email] = row.Wave
+			enrolled = append(enrolled, database.EnrollPanelParticipantsRow{
+				ParticipantID: s.Respondents[row.SurveyID][email],
+				Wave:          row.Wave,
+				Email:         email,
+			})
+		}
+	}
+
+	return enrolled, nil
+}
+
+func (s *StubPanelStore) ListInvitees(ctx context.Context, panelID int64, wave int32) ([]database.ListPanelWaveInviteesRow, error) {
+	var invitees []database.ListPanelWaveInviteesRow
+	for email, from := range s.Participants[panelID] {
+		if from < wave {
+			invitees = append(invitees, database.ListPanelWaveInviteesRow{Email: email})
+		}
+	}
+
+	return invitees, nil
+}
+
+func (s *StubPanelStore) GetAttrition(ctx context.Context, panelID int64) ([]database.GetPanelAttritionRow, error) {
+	return s.Attrition, nil
+}
+
+func (s *StubPanelStore) ListItems(ctx context.Context, panelID int64) (map[int64]int64, error) {
+	return s.Items, nil
+}
+
+func (s *StubPanelStore) ListAnswers(ctx context.Context, panelID int64) ([]database.ListPanelAnswersRow, error) {
+	return s.Answers, nil
+}
+
+func (s *StubPanelStore) GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error) {
+	survey, exists := s.Surveys[id]
+	if !exists || survey.ResearcherID != researcherID {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubPanelStore) GetSurveyByID(ctx context.Context, id int64) (database.Survey, error) {
+	survey, exists := s.Surveys[id]
+	if !exists {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubPanelStore) ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule surveys.Schedule) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	survey.OpensAt, survey.ClosesAt = pgtype.Timestamp{}, pgtype.Timestamp{}
+	if schedule.OpensAt != nil {
+		survey.OpensAt = pgtype.Timestamp{Time: *schedule.OpensAt, Valid: true}
+	}
+	if schedule.ClosesAt != nil {
+		survey.ClosesAt = pgtype.Timestamp{Time: *schedule.ClosesAt, Valid: true}
+	}
+	s.Surveys[id] = survey
+
+	return survey, nil
+}
+
+func (s *StubPanelStore) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	return s.Questions[surveyID], nil
+}
+
+func (s *StubPanelStore) GetResults(ctx context.Context, surveyID int64, filter surveys.ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error) {
+	return s.Results[surveyID], nil
+}
+
+// StubQueue keeps the tasks on it by ID, the way asynq does for tasks given one.
+type StubQueue struct {
+	Tasks      []queue.Processor
+	Scheduled  map[string]queue.Scheduled
+	ShouldFail bool
+}
+
+func NewStubQueue() *StubQueue {
+	return &StubQueue{Scheduled: make(map[string]queue.Scheduled)}
+}
+
+func (q *StubQueue) Enqueue(processor queue.Processor) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+
+	if scheduled, ok := processor.(queue.Scheduled); ok {
+		q.Scheduled[scheduled.TaskID()] = scheduled
+		return nil
+	}
+
+	q.Tasks = append(q.Tasks, processor)
+	return nil
+}
+
+func (q *StubQueue) Dequeue(processor queue.Scheduled) error {
+	delete(q.Scheduled, processor.TaskID())
+	return nil
+}
+
+// recipients returns who the emails on the queue go to, in order.
+func (q *StubQueue) recipients() []string {
+	var recipients []string
+	for _, task := range q.Tasks {
+		if email, ok := task.(*queue.EmailDeliveryPayload); ok {
+			recipients = append(recipients, email.Email)
+		}
+	}
+	slices.Sort(recipients)
+
+	return recipients
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+func newRequest(method, target string, body []byte, userID int, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+
+	claims := &tokens.Claims{
+		UserID: userID,
+		Email:  "researcher@example.com",
+		Role:   "researcher",
+	}
+	ctx := context.WithValue(req.Context(), "claims", claims)
+
+	routeCtx := chi.NewRouteContext()
+	for key, value := range params {
+		routeCtx.URLParams.Add(key, value)
+	}
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+
+	return req.WithContext(ctx)
+}
+
+func assertResponseCode(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("response code = %d, want %d", got, want)
+	}
+}
+
+// newPanelStore has panel 1 follow survey 1, which opened a week ago for three days and was
+// submitted by two respondents.
+func newPanelStore() *StubPanelStore {
+	store := NewStubPanelStore()
+
+	opened := time.Now().AddDate(0, 0, -7).UTC().Truncate(time.Second)
+	store.Surveys[1] = database.Survey{
+		ID:           1,
+		ResearcherID: researcherID,
+		Title:        "Monthly wellbeing",
+		Status:       database.SurveyStatusClosed,
+		OpensAt:      pgtype.Timestamp{Time: opened, Valid: true},
+		ClosesAt:     pgtype.Timestamp{Time: opened.AddDate(0, 0, 3), Valid: true},
+	}
+
+	store.Panels[1] = database.Panel{ID: 1, ResearcherID: researcherID, Name: "Wellbeing panel", IntervalDays: 30}
+	store.Waves[1] = []database.ListPanelWavesRow{{
+		Wave:     1,
+		SurveyID: 1,
+		Title:    "Monthly wellbeing",
+		Status:   database.SurveyStatusClosed,
+		OpensAt:  store.Surveys[1].OpensAt,
+		ClosesAt: store.Surveys[1].ClosesAt,
+	}}
+
+	store.Respondents[1] = map[string]string{"ada@example.com": "p_0000000000000001", "bola@example.com": "p_0000000000000002"}
+
+	return store
+}
+
+// ============================================================================
+// CreatePanelHandler Tests
+// ============================================================================
+
+func TestCreatePanelHandler(t *testing.T) {
+
+	create := func(store *StubPanelStore, body string) *httptest.ResponseRecorder {
+		handler := &panels.Handler{Store: store, Queue: NewStubQueue()}
+
+		req := newRequest(http.MethodPost, "/panels", []byte(body), researcherID, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreatePanelHandler(rec, req)
+
+		return rec
+	}
+
+	t.Run("starts a panel from a survey", func(t *testing.T) {
+		store := NewStubPanelStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: researcherID, Title: "Monthly wellbeing"}
+
+		rec := create(store, `{"name": "Wellbeing panel", "survey_id": 1, "interval_days": 30}`)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if waves := store.Waves[1]; len(waves) != 1 || waves[0].SurveyID != 1 {
+			t.Errorf("waves = %+v, want survey 1 as wave 1", waves)
+		}
+	})
+
+	t.Run("returns 409 for an anonymous survey", func(t *testing.T) {
+		store := NewStubPanelStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: researcherID, Anonymous: true}
+
+		rec := create(store, `{"name": "Wellbeing panel", "survey_id": 1, "interval_days": 30}`)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 409 for a survey that is already a wave", func(t *testing.T) {
+		rec := create(newPanelStore(), `{"name": "Another panel", "survey_id": 1, "interval_days": 7}`)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := NewStubPanelStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 2}
+
+		rec := create(store, `{"name": "Wellbeing panel", "survey_id": 1, "interval_days": 30}`)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 400 without an interval", func(t *testing.T) {
+		rec := create(NewStubPanelStore(), `{"name": "Wellbeing panel", "survey_id": 1}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+// ============================================================================
+// InviteParticipantsHandler Tests
+// ============================================================================
+
+func TestInviteParticipantsHandler(t *testing.T) {
+
+	invite := func(store *StubPanelStore, q *StubQueue, body string) *httptest.ResponseRecorder {
+		handler := &panels.Handler{Store: store, Queue: q}
+
+		req := newRequest(http.MethodPost, "/panels/1/participants", []byte(body), researcherID, map[string]string{"panelID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.InviteParticipantsHandler(rec, req)
+
+		return rec
+	}
+
+	t.Run("invites the respondents of earlier waves and emails them", func(t *testing.T) {
+		store := newPanelStore()
+		q := NewStubQueue()
+
+		rec := invite(store, q, "")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data panels.Invitation `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+		}
+
+		if got.Data.Invited != 2 || got.Data.Participants[0].ParticipantID != "p_0000000000000001" {
+			t.Errorf("invitation = %+v, want both respondents under their participant IDs", got.Data)
+		}
+
+		if recipients := q.recipients(); !slices.Equal(recipients, []string{"ada@example.com", "bola@example.com"}) {
+			t.Errorf("recipients = %v, want both respondents", recipients)
+		}
+	})
+
+	t.Run("leaves participants out of a second invitation", func(t *testing.T) {
+		store := newPanelStore()
+		invite(store, NewStubQueue(), "")
+
+		q := NewStubQueue()
+		rec := invite(store, q, `{"wave": 1}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if len(q.recipients()) != 0 {
+			t.Errorf("recipients = %v, want none", q.recipients())
+		}
+	})
+
+	t.Run("returns 404 for another researcher's panel", func(t *testing.T) {
+		store := newPanelStore()
+		panel := store.Panels[1]
+		panel.ResearcherID = 2
+		store.Panels[1] = panel
+
+		rec := invite(store, NewStubQueue(), "")
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// CreateWaveHandler Tests
+// ============================================================================
+
+func TestCreateWaveHandler(t *testing.T) {
+
+	createWave := func(store *StubPanelStore, q *StubQueue, body string) *httptest.ResponseRecorder {
+		handler := &panels.Handler{Store: store, Queue: q}
+
+		req := newRequest(http.MethodPost, "/panels/1/waves", []byte(body), researcherID, map[string]string{"panelID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateWaveHandler(rec, req)
+
+		return rec
+	}
+
+	t.Run("schedules a copy of the last wave the interval after it opened", func(t *testing.T) {
+		store := newPanelStore()
+		q := NewStubQueue()
+
+		rec := createWave(store, q, "")
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		waves := store.Waves[1]
+		if len(waves) != 2 || waves[1].Wave != 2 {
+			t.Fatalf("waves = %+v, want a second wave", waves)
+		}
+
+		survey := store.Surveys[waves[1].SurveyID]
+		opensAt := store.Surveys[1].OpensAt.Time.AddDate(0, 0, 30)
+		if !survey.OpensAt.Time.Equal(opensAt) || !survey.ClosesAt.Time.Equal(opensAt.AddDate(0, 0, 3)) {
+			t.Errorf("schedule = %s to %s, want %s for three days", survey.OpensAt.Time, survey.ClosesAt.Time, opensAt)
+		}
+
+		want := []string{
+			(&queue.PanelWavePayload{SurveyID: survey.ID, At: opensAt}).TaskID(),
+			(&queue.SurveyOpenPayload{SurveyID: survey.ID, At: opensAt}).TaskID(),
+			(&queue.SurveyClosePayload{SurveyID: survey.ID, At: opensAt.AddDate(0, 0, 3)}).TaskID(),
+		}
+		for _, id := range want {
+			if _, ok := q.Scheduled[id]; !ok {
+				t.Errorf("tasks = %v, want %s", q.Scheduled, id)
+			}
+		}
+	})
+
+	t.Run("opens the wave when asked to", func(t *testing.T) {
+		store := newPanelStore()
+		opensAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+		rec := createWave(store, NewStubQueue(), `{"title": "Wave 2", "opens_at": "`+opensAt.Format(time.RFC3339)+`"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		survey := store.Surveys[store.Waves[1][1].SurveyID]
+		if survey.Title != "Wave 2" || !survey.OpensAt.Time.Equal(opensAt) {
+			t.Errorf("survey = %q opening %s, want Wave 2 opening %s", survey.Title, survey.OpensAt.Time, opensAt)
+		}
+	})
+
+	t.Run("returns 400 for a time in the past", func(t *testing.T) {
+		store := newPanelStore()
+
+		rec := createWave(store, NewStubQueue(), `{"opens_at": "2020-01-01T00:00:00Z"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		if len(store.Waves[1]) != 1 {
+			t.Errorf("waves = %+v, want no new wave", store.Waves[1])
+		}
+	})
+
+	t.Run("returns 409 when the last wave is anonymous", func(t *testing.T) {
+		store := newPanelStore()
+		survey := store.Surveys[1]
+		survey.Anonymous = true
+		store.Surveys[1] = survey
+
+		rec := createWave(store, NewStubQueue(), "")
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("leaves the wave unscheduled when its tasks can't be queued", func(t *testing.T) {
+		store := newPanelStore()
+		q := NewStubQueue()
+		q.ShouldFail = true
+
+		rec := createWave(store, q, "")
+
+		assertResponseCode(t, rec.Code, http.StatusInternalServerError)
+
+		if survey := store.Surveys[store.Waves[1][1].SurveyID]; survey.OpensAt.Valid {
+			t.Errorf("opens_at = %s, want the wave unscheduled", survey.OpensAt.Time)
+		}
+	})
+}
+
+// ============================================================================
+// WaveSchedule Tests
+// ============================================================================
+
+func TestWaveSchedule(t *testing.T) {
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	panel := database.Panel{IntervalDays: 30}
+
+	t.Run("opens the interval after now when that after the last wave has passed", func(t *testing.T) {
+		last := database.ListPanelWavesRow{PublishedAt: pgtype.Timestamp{Time: now.AddDate(0, -2, 0), Valid: true}}
+
+		schedule := panels.WaveSchedule(panel, last, panels.CreateWaveBody{}, now)
+
+		if !schedule.OpensAt.Equal(now.AddDate(0, 0, 30)) || schedule.ClosesAt != nil {
+			t.Errorf("schedule = %+v, want 30 days from now and no close", schedule)
+		}
+	})
+
+	t.Run("keeps a close time given", func(t *testing.T) {
+		closesAt := now.AddDate(0, 1, 10)
+		last := database.ListPanelWavesRow{
+			OpensAt:  pgtype.Timestamp{Time: now.AddDate(0, 0, -1), Valid: true},
+			ClosesAt: pgtype.Timestamp{Time: now.AddDate(0, 0, 6), Valid: true},
+		}
+
+		schedule := panels.WaveSchedule(panel, last, panels.CreateWaveBody{ClosesAt: &closesAt}, now)
+
+		if !schedule.OpensAt.Equal(now.AddDate(0, 0, 29)) || !schedule.ClosesAt.Equal(closesAt) {
+			t.Errorf("schedule = %s to %s, want 29 days from now to %s", schedule.OpensAt, schedule.ClosesAt, closesAt)
+		}
+	})
+}
+
+// ============================================================================
+// HandleWaveTask Tests
+// ============================================================================
+
+func TestHandleWaveTask(t *testing.T) {
+
+	opensAt := time.Now().UTC().Truncate(time.Second)
+
+	run := func(store *StubPanelStore, q *StubQueue) error {
+		handler := &panels.TaskHandler{Store: store, Queue: q}
+
+		task, err := (&queue.PanelWavePayload{PanelID: 1, Wave: 2, SurveyID: 2, At: opensAt}).Process()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return handler.HandleWaveTask(context.Background(), asynq.NewTask(task.Type(), task.Payload()))
+	}
+
+	// newWaveStore has wave 2 of the panel scheduled to open now, with both respondents of wave 1
+	// participants and a third invited from wave 2
+	newWaveStore := func(status database.SurveyStatus) *StubPanelStore {
+		store := newPanelStore()
+		store.Surveys[2] = database.Survey{
+			ID: 2, ResearcherID: researcherID, Status: status,
+			OpensAt: pgtype.Timestamp{Time: opensAt, Valid: true},
+		}
+		store.Participants[1] = map[string]int32{"ada@example.com": 1, "bola@example.com": 1, "chidi@example.com": 2}
+
+		return store
+	}
+
+	t.Run("emails the participants once the wave opened", func(t *testing.T) {
+		q := NewStubQueue()
+
+		if err := run(newWaveStore(database.SurveyStatusPublished), q); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if recipients := q.recipients(); !slices.Equal(recipients, []string{"ada@example.com", "bola@example.com"}) {
+			t.Errorf("recipients = %v, want the participants of wave 1", recipients)
+		}
+	})
+
+	t.Run("waits for a wave that hasn't opened yet", func(t *testing.T) {
+		q := NewStubQueue()
+
+		err := run(newWaveStore(database.SurveyStatusDraft), q)
+
+		if !errors.Is(err, panels.ErrWaveNotOpen) || len(q.Tasks) != 0 {
+			t.Errorf("err = %v with %d tasks, want the task retried", err, len(q.Tasks))
+		}
+	})
+
+	t.Run("leaves a rescheduled wave alone", func(t *testing.T) {
+		store := newWaveStore(database.SurveyStatusDraft)
+		survey := store.Surveys[2]
+		survey.OpensAt.Time = opensAt.Add(time.Hour)
+		store.Surveys[2] = survey
+		q := NewStubQueue()
+
+		if err := run(store, q); err != nil || len(q.Tasks) != 0 {
+			t.Errorf("err = %v with %d tasks, want nothing done", err, len(q.Tasks))
+		}
+	})
+}
+
+// ============================================================================
+// GetAttritionHandler Tests
+// ============================================================================
+
+func TestGetAttritionHandler(t *testing.T) {
+
+	t.Run("reports the participants each wave lost", func(t *testing.T) {
+		store := newPanelStore()
+		store.Attrition = []database.GetPanelAttritionRow{
+			{Wave: 1, SurveyID: 1, Participants: 40, Started: 40, Completed: 40},
+			{Wave: 2, SurveyID: 2, Participants: 40, Started: 35, Completed: 30},
+		}
+		handler := &panels.Handler{Store: store, Queue: NewStubQueue()}
+
+		req := newRequest(http.MethodGet, "/panels/1/attrition", nil, researcherID, map[string]string{"panelID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetAttritionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data []panels.WaveAttrition `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Data) != 2 {
+			t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+		}
+
+		if wave := got.Data[1]; wave.DroppedOut != 10 || !wave.AttritionRate.Equal(decimal.NewFromInt(25)) {
+			t.Errorf("wave 2 = %+v, want 10 dropped out, 25%%", wave)
+		}
+	})
+}
+
+// ============================================================================
+// Panel results Tests
+// ============================================================================
+
+func TestNewPanelResults(t *testing.T) {
+
+	choice := func(id string, percentage int64) surveys.ChoiceResult {
+		return surveys.ChoiceResult{ID: id, Label: id, Percentage: decimal.NewFromInt(percentage)}
+	}
+
+	wave := func(number int32, surveyID int64, questions ...surveys.QuestionResult) panels.WaveResults {
+		return panels.WaveResults{
+			Wave:    number,
+			Results: surveys.Results{SurveyID: surveyID, Responses: 10, Questions: questions},
+			// questions 11 and 21 are copies of question 1, 12 one of question 2
+			Items: map[int64]int64{11: 1, 21: 1, 12: 2},
+		}
+	}
+
+	mood := func(questionID int64, yes, no int64) surveys.QuestionResult {
+		return surveys.QuestionResult{QuestionID: questionID, Title: "Mood", Type: "single_choice", Choices: []surveys.ChoiceResult{choice("good", yes), choice("bad", no)}}
+	}
+
+	sleep := func(questionID int64, mean float64) surveys.QuestionResult {
+		return surveys.QuestionResult{QuestionID: questionID, Title: "Sleep", Type: "numeric", Stats: &surveys.NumericStats{Mean: decimal.NewFromFloat(mean)}}
+	}
+
+	t.Run("compares each question with the wave before", func(t *testing.T) {
+		results := panels.NewPanelResults(1, []panels.WaveResults{
+			wave(1, 1, mood(1, 60, 40), sleep(2, 7)),
+			wave(2, 2, mood(11, 45, 55), sleep(12, 6.5)),
+		})
+
+		if len(results.Questions) != 2 || results.Questions[0].ItemID != 1 || len(results.Questions[0].Waves) != 2 {
+			t.Fatalf("questions = %+v, want both followed over two waves", results.Questions)
+		}
+
+		change := results.Questions[0].Waves[1].Change
+		if change == nil || change.FromWave != 1 || !change.Choices[0].Points.Equal(decimal.NewFromInt(-15)) {
+			t.Errorf("mood change = %+v, want good down 15 points from wave 1", change)
+		}
+
+		change = results.Questions[1].Waves[1].Change
+		if change == nil || change.Mean == nil || !change.Mean.Equal(decimal.NewFromFloat(-0.5)) {
+			t.Errorf("sleep change = %+v, want the mean down 0.5", change)
+		}
+
+		if results.Questions[0].Waves[0].Change != nil {
+			t.Errorf("wave 1 change = %+v, want none", results.Questions[0].Waves[0].Change)
+		}
+	})
+
+	t.Run("doesn't compare across a wave that left the question out", func(t *testing.T) {
+		results := panels.NewPanelResults(1, []panels.WaveResults{
+			wave(1, 1, mood(1, 60, 40)),
+			wave(2, 2, sleep(12, 6.5)),
+			wave(3, 3, mood(21, 50, 50)),
+		})
+
+		if waves := results.Questions[0].Waves; len(waves) != 2 || waves[1].Change != nil {
+			t.Errorf("mood = %+v, want wave 3 without a change", waves)
+		}
+	})
+}
+
+// ============================================================================
+// ExportAnswersHandler Tests
+// ============================================================================
+
+func TestExportAnswersHandler(t *testing.T) {
+
+	newExportStore := func() *StubPanelStore {
+		store := newPanelStore()
+		submitted := pgtype.Timestamp{Time: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), Valid: true}
+		store.Answers = []database.ListPanelAnswersRow{
+			{ParticipantID: "p_0000000000000001", Wave: 1, SurveyID: 1, ResponseID: 7, SubmittedAt: submitted, ItemID: 1, QuestionID: 1, Value: []byte(`{"option_id": "good"}`)},
+			{ParticipantID: "p_0000000000000001", Wave: 2, SurveyID: 2, ResponseID: 19, SubmittedAt: submitted, ItemID: 1, QuestionID: 11, Value: []byte(`{"option_id": "bad"}`)},
+		}
+
+		return store
+	}
+
+	export := func(store *StubPanelStore, target string) *httptest.ResponseRecorder {
+		handler := &panels.Handler{Store: store, Queue: NewStubQueue()}
+
+		req := newRequest(http.MethodGet, target, nil, researcherID, map[string]string{"panelID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.ExportAnswersHandler(rec, req)
+
+		return rec
+	}
+
+	t.Run("exports an answer per row in CSV", func(t *testing.T) {
+		rec := export(newExportStore(), "/panels/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+		if err != nil || len(rows) != 3 {
+			t.Fatalf("rows = %v (%v), want a header and two answers", rows, err)
+		}
+
+		if !slices.Equal(rows[0], panels.ExportHeader) {
+			t.Errorf("header = %v, want %v", rows[0], panels.ExportHeader)
+		}
+
+		want := []string{"p_0000000000000001", "2", "2", "19", "2026-10-01T09:00:00Z", "1", "11", `{"option_id": "bad"}`}
+		if !slices.Equal(rows[2], want) {
+			t.Errorf("row = %v, want %v", rows[2], want)
+		}
+	})
+
+	t.Run("exports an answer per line in JSONL", func(t *testing.T) {
+		rec := export(newExportStore(), "/panels/1/export?format=jsonl")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var answer panels.PanelAnswer
+		if err := json.Unmarshal([]byte(strings.SplitN(rec.Body.String(), "\n", 2)[0]), &answer); err != nil {
+			t.Fatalf("invalid line in %q: %v", rec.Body.String(), err)
+		}
+
+		if answer.ParticipantID != "p_0000000000000001" || answer.ItemID != 1 || answer.Wave != 1 {
+			t.Errorf("answer = %+v, want wave 1 of the participant", answer)
+		}
+	})
+
+	t.Run("returns 400 for an unknown format", func(t *testing.T) {
+		rec := export(newExportStore(), "/panels/1/export?format=xlsx")
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,115 @@
+package panels
+
+import (
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/shopspring/decimal"
+)
+
+// WaveResults are the results of one wave of a panel, with the question each of its questions goes
+// back to.
+type WaveResults struct {
+	Wave    int32
+	Results surveys.Results
+	Items   map[int64]int64
+}
+
+// NewPanelResults follows every question through the waves of a panel, given in order. Questions
+// are matched across waves by the question they go back to, so the copy of a question in a later
+// wave is compared with the one before it, and questions come in the order they were first asked.
+func NewPanelResults(panelID int64, waves []WaveResults) PanelResults {
+	results := PanelResults{PanelID: panelID, Waves: []WaveSummary{}, Questions: []ItemResult{}}
+
+	index := make(map[int64]int)
+	// last holds the wave that last asked each question and its result there
+	last := make(map[int64]ItemWave)
+
+	for _, wave := range waves {
+		results.Waves = append(results.Waves, WaveSummary{
+			Wave:      wave.Wave,
+			SurveyID:  wave.Results.SurveyID,
+			Responses: wave.Results.Responses,
+		})
+
+		for _, question := range wave.Results.Questions {
+			item := Item(wave.Items, question.QuestionID)
+
+			i, ok := index[item]
+			if !ok {
+				i = len(results.Questions)
+				index[item] = i
+				results.Questions = append(results.Questions, ItemResult{ItemID: item})
+			}
+
+			current := ItemWave{Wave: wave.Wave, SurveyID: wave.Results.SurveyID, QuestionResult: question}
+
+			// only the wave right before is compared with, a question left out of a wave starts over
+			if previous, ok := last[item]; ok && previous.Wave == wave.Wave-1 && previous.Type == question.Type {
+				current.Change = newWaveChange(previous, current)
+			}
+
+			results.Questions[i].Title = question.Title
+			results.Questions[i].Type = question.Type
+			results.Questions[i].Waves = append(results.Questions[i].Waves, current)
+			last[item] = current
+		}
+	}
+
+	return results
+}
+
+func newWaveChange(previous, current ItemWave) *WaveChange {
+	change := WaveChange{
+		FromWave: previous.Wave,
+		Choices:  choiceChanges(previous.Choices, current.Choices),
+	}
+
+	for _, row := range current.Rows {
+		for _, before := range previous.Rows {
+			if before.ID == row.ID {
+				change.Rows = append(change.Rows, RowChange{
+					ID:      row.ID,
+					Label:   row.Label,
+					Choices: choiceChanges(before.Choices, row.Choices),
+				})
+				break
+			}
+		}
+	}
+
+	if previous.Stats != nil && current.Stats != nil {
+		mean := current.Stats.Mean.Sub(previous.Stats.Mean)
+		change.Mean = &mean
+	}
+
+	return &change
+}
+
+// choiceChanges returns by how many percentage points each option was picked more or less often,
+// leaving out options the previous wave didn't offer.
+func choiceChanges(previous, current []surveys.ChoiceResult) []ChoiceChange {
+	var changes []ChoiceChange
+
+	for _, choice := range current {
+		for _, before := range previous {
+			if before.ID == choice.ID {
+				changes = append(changes, ChoiceChange{
+					ID:     choice.ID,
+					Label:  choice.Label,
+					Points: choice.Percentage.Sub(before.Percentage),
+				})
+				break
+			}
+		}
+	}
+
+	return changes
+}
+
+// percentage returns count as a percentage of base, rounded to 2 decimal places.
+func percentage(count, base int32) decimal.Decimal {
+	if base == 0 {
+		return decimal.Zero
+	}
+
+	return decimal.NewFromInt32(count).Mul(decimal.NewFromInt(100)).DivRound(decimal.NewFromInt32(base), 2)
+}
@@ -0,0 +1,49 @@
+package panels
+
+import (
+	"github.com/Adedunmol/answerly/api/middlewares"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func SetupRoutes(r *chi.Mux, queue queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	panelsRouter := chi.NewRouter()
+
+	store := NewPanelStore(queries, db)
+	tokenService := tokens.NewTokenService()
+
+	handler := Handler{
+		Store: store,
+		Queue: queue,
+	}
+
+	panelsRouter.Use(middlewares.AuthMiddleware(tokenService))
+	panelsRouter.Use(middlewares.RequireRole("researcher"))
+
+	panelsRouter.Post("/", handler.CreatePanelHandler)
+	panelsRouter.Get("/", handler.ListPanelsHandler)
+	panelsRouter.Get("/{panelID}", handler.GetPanelHandler)
+	panelsRouter.Post("/{panelID}/participants", handler.InviteParticipantsHandler)
+	panelsRouter.Post("/{panelID}/waves", handler.CreateWaveHandler)
+	panelsRouter.Get("/{panelID}/attrition", handler.GetAttritionHandler)
+	panelsRouter.Get("/{panelID}/results", handler.GetResultsHandler)
+	panelsRouter.Get("/{panelID}/export", handler.ExportAnswersHandler)
+
+	r.Mount("/panels", panelsRouter)
+
+	return
+}
+
+func SetupTasks(worker queue.Worker, q queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	handler := TaskHandler{
+		Store: NewPanelStore(queries, db),
+		Queue: q,
+	}
+
+	worker.HandleFunc(queue.TypePanelWave, handler.HandleWaveTask)
+}
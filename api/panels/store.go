@@ -0,0 +1,326 @@
+package panels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Store interface {
+	CreatePanel(ctx context.Context, researcherID int64, body CreatePanelBody) (database.Panel, error)
+	GetPanel(ctx context.Context, id, researcherID int64) (database.Panel, error)
+	ListPanels(ctx context.Context, researcherID int64) ([]database.Panel, error)
+	ListWaves(ctx context.Context, panelID int64) ([]database.ListPanelWavesRow, error)
+	CreateWave(ctx context.Context, panel database.Panel, from database.ListPanelWavesRow, title string) (database.Survey, error)
+	InviteParticipants(ctx context.Context, panelID int64, wave *int32) ([]database.EnrollPanelParticipantsRow, error)
+	ListInvitees(ctx context.Context, panelID int64, wave int32) ([]database.ListPanelWaveInviteesRow, error)
+	GetAttrition(ctx context.Context, panelID int64) ([]database.GetPanelAttritionRow, error)
+	ListItems(ctx context.Context, panelID int64) (map[int64]int64, error)
+	ListAnswers(ctx context.Context, panelID int64) ([]database.ListPanelAnswersRow, error)
+	GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error)
+	GetSurveyByID(ctx context.Context, id int64) (database.Survey, error)
+	ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule surveys.Schedule) (database.Survey, error)
+	ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error)
+	GetResults(ctx context.Context, surveyID int64, filter surveys.ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error)
+}
+
+const UniqueViolation = "23505"
+
+type Repository struct {
+	queries    *database.Queries
+	db         *pgxpool.Pool
+	transactor database.Transactor
+	surveys    surveys.Store
+}
+
+func NewPanelStore(queries *database.Queries, db *pgxpool.Pool) *Repository {
+
+	return &Repository{
+		queries:    queries,
+		db:         db,
+		transactor: database.NewDBTransactor(db),
+		surveys:    surveys.NewSurveyStore(queries, db),
+	}
+}
+
+// CreatePanel starts a panel with the survey as its first wave. It fails with
+// custom_errors.ErrConflict when the survey is already a wave of a panel.
+func (r *Repository) CreatePanel(ctx context.Context, researcherID int64, body CreatePanelBody) (database.Panel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var panel database.Panel
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		panel, err = q.CreatePanel(ctx, database.CreatePanelParams{
+			ResearcherID: researcherID,
+			Name:         body.Name,
+			Description:  pgtype.Text{String: body.Description, Valid: len(body.Description) > 0},
+			IntervalDays: body.IntervalDays,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating panel: %v", err)
+		}
+
+		_, err = q.CreatePanelWave(ctx, database.CreatePanelWaveParams{
+			PanelID:  panel.ID,
+			Wave:     1,
+			SurveyID: body.SurveyID,
+		})
+		if err != nil {
+			var e *pgconn.PgError
+			if errors.As(err, &e) && e.Code == UniqueViolation {
+				return fmt.Errorf("%w: survey %d is already a wave of a panel", custom_errors.ErrConflict, body.SurveyID)
+			}
+			return fmt.Errorf("error creating panel wave: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Panel{}, err
+	}
+
+	return panel, nil
+}
+
+func (r *Repository) GetPanel(ctx context.Context, id, researcherID int64) (database.Panel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	panel, err := r.queries.GetPanel(ctx, database.GetPanelParams{
+		ID:           id,
+		ResearcherID: researcherID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Panel{}, custom_errors.ErrNotFound
+		}
+		return database.Panel{}, fmt.Errorf("error getting panel: %v", err)
+	}
+
+	return panel, nil
+}
+
+func (r *Repository) ListPanels(ctx context.Context, researcherID int64) ([]database.Panel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	panels, err := r.queries.ListPanels(ctx, researcherID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing panels: %v", err)
+	}
+
+	return panels, nil
+}
+
+func (r *Repository) ListWaves(ctx context.Context, panelID int64) ([]database.ListPanelWavesRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	waves, err := r.queries.ListPanelWaves(ctx, panelID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing panel waves: %v", err)
+	}
+
+	return waves, nil
+}
+
+// CreateWave copies a wave of a panel into a draft that becomes the wave after it, and links every
+// copied question to the question it goes back to, so its answers can be compared across waves. It
+// fails with custom_errors.ErrConflict when that wave was created since the waves were read.
+func (r *Repository) CreateWave(ctx context.Context, panel database.Panel, from database.ListPanelWavesRow, title string) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var survey database.Survey
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		survey, err = r.surveys.CloneSurvey(ctx, from.SurveyID, panel.ResearcherID, surveys.CloneSurveyBody{Title: title})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreatePanelWave(ctx, database.CreatePanelWaveParams{
+			PanelID:  panel.ID,
+			Wave:     from.Wave + 1,
+			SurveyID: survey.ID,
+		})
+		if err != nil {
+			var e *pgconn.PgError
+			if errors.As(err, &e) && e.Code == UniqueViolation {
+				return fmt.Errorf("%w: wave %d of the panel was already created", custom_errors.ErrConflict, from.Wave+1)
+			}
+			return fmt.Errorf("error creating panel wave: %v", err)
+		}
+
+		return linkQuestions(ctx, q, panel.ID, from.SurveyID, survey.ID)
+	})
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	return survey, nil
+}
+
+// linkQuestions links the questions of a copied wave to the ones they go back to. The copy asks the
+// questions of the source in the same order, so they pair up by their place in the list.
+func linkQuestions(ctx context.Context, q *database.Queries, panelID, sourceID, copyID int64) error {
+	sources, err := q.ListQuestionsBySurvey(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("error listing questions: %v", err)
+	}
+
+	copies, err := q.ListQuestionsBySurvey(ctx, copyID)
+	if err != nil {
+		return fmt.Errorf("error listing questions: %v", err)
+	}
+
+	if len(copies) != len(sources) {
+		return fmt.Errorf("error linking questions: survey %d has %d questions, its copy %d", sourceID, len(sources), len(copies))
+	}
+
+	links, err := q.ListPanelQuestions(ctx, panelID)
+	if err != nil {
+		return fmt.Errorf("error listing panel questions: %v", err)
+	}
+
+	items := panelItems(links)
+
+	for i, source := range sources {
+		err := q.CreatePanelQuestion(ctx, database.CreatePanelQuestionParams{
+			QuestionID: copies[i].ID,
+			PanelID:    panelID,
+			ItemID:     Item(items, source.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("error linking question: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func panelItems(links []database.PanelQuestion) map[int64]int64 {
+	items := make(map[int64]int64, len(links))
+	for _, link := range links {
+		items[link.QuestionID] = link.ItemID
+	}
+
+	return items
+}
+
+// Item returns the question a question of a panel goes back to, the one it was first asked as.
+func Item(items map[int64]int64, questionID int64) int64 {
+	if item, ok := items[questionID]; ok {
+		return item
+	}
+
+	return questionID
+}
+
+// InviteParticipants adds the respondents who submitted a wave of a panel, or any wave when none is
+// given, to its participants. Respondents who already are one are left as they were, and only the
+// ones added are returned.
+func (r *Repository) InviteParticipants(ctx context.Context, panelID int64, wave *int32) ([]database.EnrollPanelParticipantsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	params := database.EnrollPanelParticipantsParams{PanelID: panelID}
+	if wave != nil {
+		params.Wave = pgtype.Int4{Int32: *wave, Valid: true}
+	}
+
+	participants, err := r.queries.EnrollPanelParticipants(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("error inviting panel participants: %v", err)
+	}
+
+	return participants, nil
+}
+
+func (r *Repository) ListInvitees(ctx context.Context, panelID int64, wave int32) ([]database.ListPanelWaveInviteesRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitees, err := r.queries.ListPanelWaveInvitees(ctx, database.ListPanelWaveInviteesParams{
+		PanelID: panelID,
+		Wave:    wave,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing panel invitees: %v", err)
+	}
+
+	return invitees, nil
+}
+
+func (r *Repository) GetAttrition(ctx context.Context, panelID int64) ([]database.GetPanelAttritionRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	attrition, err := r.queries.GetPanelAttrition(ctx, panelID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting panel attrition: %v", err)
+	}
+
+	return attrition, nil
+}
+
+// ListItems returns the question each copied question of a panel goes back to, keyed by question.
+func (r *Repository) ListItems(ctx context.Context, panelID int64) (map[int64]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	links, err := r.queries.ListPanelQuestions(ctx, panelID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing panel questions: %v", err)
+	}
+
+	return panelItems(links), nil
+}
+
+func (r *Repository) ListAnswers(ctx context.Context, panelID int64) ([]database.ListPanelAnswersRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	answers, err := r.queries.ListPanelAnswers(ctx, panelID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing panel answers: %v", err)
+	}
+
+	return answers, nil
+}
+
+func (r *Repository) GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error) {
+	return r.surveys.GetSurvey(ctx, id, researcherID)
+}
+
+func (r *Repository) GetSurveyByID(ctx context.Context, id int64) (database.Survey, error) {
+	return r.surveys.GetSurveyByID(ctx, id)
+}
+
+func (r *Repository) ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule surveys.Schedule) (database.Survey, error) {
+	return r.surveys.ScheduleSurvey(ctx, id, researcherID, schedule)
+}
+
+func (r *Repository) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	return r.surveys.ListQuestions(ctx, surveyID)
+}
+
+func (r *Repository) GetResults(ctx context.Context, surveyID int64, filter surveys.ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error) {
+	return r.surveys.GetResults(ctx, surveyID, filter, now)
+}
@@ -0,0 +1,76 @@
+package panels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/hibiken/asynq"
+	"log"
+)
+
+type TaskHandler struct {
+	Store Store
+	// Queue sends the emails inviting participants to a wave.
+	Queue queue.Queue
+}
+
+// ErrWaveNotOpen is returned while a wave scheduled to open hasn't yet, so the task is retried
+// after the task that opens it has run.
+var ErrWaveNotOpen = errors.New("panel wave has not opened yet")
+
+// HandleWaveTask emails the participants of a panel who can answer a wave that it has opened. The
+// task runs when the wave is scheduled to open, so it waits for a draft still scheduled to open
+// at that time to be published. A wave that was rescheduled or didn't open is left alone.
+func (h *TaskHandler) HandleWaveTask(ctx context.Context, t *asynq.Task) error {
+	var payload queue.PanelWavePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("error decoding panel wave payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	survey, err := h.Store.GetSurveyByID(ctx, payload.SurveyID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			log.Printf("panel wave survey %d no longer exists", payload.SurveyID)
+			return nil
+		}
+		return fmt.Errorf("error getting survey %d: %w", payload.SurveyID, err)
+	}
+
+	scheduled := survey.OpensAt.Valid && survey.OpensAt.Time.Equal(payload.At)
+
+	switch {
+	case survey.Status == database.SurveyStatusDraft && scheduled:
+		return fmt.Errorf("survey %d: %w", survey.ID, ErrWaveNotOpen)
+	case survey.Status != database.SurveyStatusPublished || !scheduled:
+		log.Printf("panel wave survey %d did not open as scheduled at %s", survey.ID, payload.At)
+		return nil
+	}
+
+	panel, err := h.Store.GetPanel(ctx, payload.PanelID, survey.ResearcherID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			log.Printf("panel %d no longer exists", payload.PanelID)
+			return nil
+		}
+		return fmt.Errorf("error getting panel %d: %w", payload.PanelID, err)
+	}
+
+	invitees, err := h.Store.ListInvitees(ctx, panel.ID, payload.Wave)
+	if err != nil {
+		return fmt.Errorf("error listing invitees of panel %d: %w", panel.ID, err)
+	}
+
+	// the emails go out once, a failure to queue one of them is only logged
+	for _, invitee := range invitees {
+		sendPanelEmail(h.Queue, invitee.Email, panel, "A new wave is open",
+			fmt.Sprintf("Wave %d is open. Thanks for staying with us, your answers matter most when we can follow them over time.", payload.Wave))
+	}
+
+	log.Printf("invited %d participants of panel %d to wave %d", len(invitees), panel.ID, payload.Wave)
+
+	return nil
+}
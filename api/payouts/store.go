@@ -0,0 +1,334 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/wallets"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type Store interface {
+	HoldResponse(ctx context.Context, response database.Response) (database.Payout, error)
+	PayResponse(ctx context.Context, response database.Response) (database.Payout, error)
+	ReturnResponse(ctx context.Context, response database.Response) (database.Payout, error)
+	PayScreenOut(ctx context.Context, response database.Response) (database.Payout, error)
+	GetPayout(ctx context.Context, responseID int64) (database.Payout, error)
+}
+
+type Repository struct {
+	queries    *database.Queries
+	db         *pgxpool.Pool
+	transactor database.Transactor
+	wallets    wallets.Store
+}
+
+func NewPayoutStore(queries *database.Queries, db *pgxpool.Pool) *Repository {
+
+	return &Repository{
+		queries:    queries,
+		db:         db,
+		transactor: database.NewDBTransactor(db),
+		wallets:    wallets.NewWalletStore(queries),
+	}
+}
+
+// HoldResponse sets the reward for a submitted response aside in its survey's escrow while the
+// response awaits review. The money stays in the escrow, but no other response can claim it, until
+// PayResponse releases it to the respondent or ReturnResponse gives it back. Holding the same
+// response again returns the first hold.
+//
+// It fails with custom_errors.ErrNotFound when the survey holds no escrow or the response is a test
+// response, which never moves money, and with custom_errors.ErrBudgetExhausted when the escrow
+// can't cover another response.
+func (r *Repository) HoldResponse(ctx context.Context, response database.Response) (database.Payout, error) {
+	return r.pay(ctx, response, database.PayoutKindReward, database.PayoutStatusHeld)
+}
+
+// PayResponse moves the reward for an accepted response from its survey's escrow to the
+// respondent's wallet, releasing the hold on it when there is one. The payout record, the escrow
+// debit and the wallet credit are written in one transaction, and the payout is unique per
+// response, so paying the same response again returns the first payout without moving any money.
+//
+// It fails with custom_errors.ErrNotFound when the survey holds no escrow or the response is a test
+// response, with custom_errors.ErrBudgetExhausted when the escrow can't cover another response and with
+// custom_errors.ErrConflict when the reward was returned to the escrow.
+func (r *Repository) PayResponse(ctx context.Context, response database.Response) (database.Payout, error) {
+	return r.pay(ctx, response, database.PayoutKindReward, database.PayoutStatusPaid)
+}
+
+// PayScreenOut pays the screen-out fee of a survey to a respondent its screener questions turned
+// away. It works like PayResponse, except that the fee is paid as it is, with no platform fee on
+// top, and that it fails with custom_errors.ErrNotFound when the survey pays no screen-out fee.
+func (r *Repository) PayScreenOut(ctx context.Context, response database.Response) (database.Payout, error) {
+	return r.pay(ctx, response, database.PayoutKindScreenOut, database.PayoutStatusPaid)
+}
+
+// ReturnResponse gives the reward held for a rejected response back to its survey's escrow, where
+// it can pay for another response. When the survey was closed since, the escrow refunds it to the
+// researcher's wallet instead. Returning the same response again returns the first return.
+//
+// It fails with custom_errors.ErrNotFound when nothing is held for the response and with
+// custom_errors.ErrConflict when the reward was paid out already.
+func (r *Repository) ReturnResponse(ctx context.Context, response database.Response) (database.Payout, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var payout database.Payout
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		payout, err = q.GetPayoutByResponse(ctx, response.ID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error getting payout: %v", err)
+		}
+
+		switch payout.Status {
+		case database.PayoutStatusReturned:
+			return nil
+		case database.PayoutStatusPaid:
+			return custom_errors.ErrConflict
+		}
+
+		escrow, err := q.GetEscrowBySurvey(ctx, response.SurveyID)
+		if err != nil {
+			return fmt.Errorf("error getting escrow: %v", err)
+		}
+
+		payout, err = q.UpdatePayoutStatus(ctx, database.UpdatePayoutStatusParams{
+			NextStatus:    database.PayoutStatusReturned,
+			ID:            payout.ID,
+			CurrentStatus: database.PayoutStatusHeld,
+		})
+		if err != nil {
+			// a concurrent request released or returned it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error updating payout: %v", err)
+		}
+
+		cost := holdCost(escrow, payout)
+
+		escrow, err = q.ReturnEscrow(ctx, database.ReturnEscrowParams{
+			Cost:     database.NumericFromDecimal(cost),
+			SurveyID: response.SurveyID,
+		})
+		if err != nil {
+			return fmt.Errorf("error returning escrow: %v", err)
+		}
+
+		// a settled escrow has nothing left to pay for, so the money goes back to the wallet that funded it
+		if escrow.Status == database.EscrowStatusSettled {
+			if _, err := wallets.TopUp(ctx, r.wallets, escrow.ResearcherID, escrow.OrganizationID, cost); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Payout{}, err
+	}
+
+	return payout, nil
+}
+
+func (r *Repository) pay(ctx context.Context, response database.Response, kind database.PayoutKind, status database.PayoutStatus) (database.Payout, error) {
+	// test responses are the researcher walking through their own survey and never move money
+	if response.IsTest {
+		return database.Payout{}, custom_errors.ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var payout database.Payout
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		escrow, err := q.GetEscrowBySurvey(ctx, response.SurveyID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error getting escrow: %v", err)
+		}
+
+		// the respondent is paid amount, the escrow gives up cost
+		amount := database.DecimalFromNumeric(escrow.RewardPerResponse)
+		cost := amount.Add(database.DecimalFromNumeric(escrow.FeePerResponse))
+
+		if kind == database.PayoutKindScreenOut {
+			amount = database.DecimalFromNumeric(escrow.ScreenOutFee)
+			cost = amount
+
+			if !amount.IsPositive() {
+				return custom_errors.ErrNotFound
+			}
+		}
+
+		payout, err = q.GetPayoutByResponse(ctx, response.ID)
+		if err == nil {
+			// the reward was set aside on submission, paying it releases the hold
+			if payout.Status == database.PayoutStatusHeld && status == database.PayoutStatusPaid {
+				return r.release(ctx, q, escrow, &payout)
+			}
+
+			if payout.Status == database.PayoutStatusReturned {
+				return custom_errors.ErrConflict
+			}
+
+			// the response was paid or held before, hand back that payout and leave the money alone
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("error getting payout: %v", err)
+		}
+
+		// checked before anything is written so a caller can carry on without the payout
+		remaining := database.DecimalFromNumeric(escrow.Amount).
+			Sub(database.DecimalFromNumeric(escrow.Spent)).
+			Sub(database.DecimalFromNumeric(escrow.Reserved))
+		if escrow.Status != database.EscrowStatusHeld || remaining.LessThan(cost) {
+			return custom_errors.ErrBudgetExhausted
+		}
+
+		respondentID, err := payee(ctx, q, response)
+		if err != nil {
+			return err
+		}
+
+		payout, err = q.CreatePayout(ctx, database.CreatePayoutParams{
+			ResponseID:   response.ID,
+			SurveyID:     response.SurveyID,
+			RespondentID: respondentID,
+			Amount:       database.NumericFromDecimal(amount),
+			Kind:         kind,
+			Status:       status,
+		})
+		if err != nil {
+			// a concurrent request paid it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				payout, err = q.GetPayoutByResponse(ctx, response.ID)
+				if err != nil {
+					return fmt.Errorf("error getting payout: %v", err)
+				}
+				return nil
+			}
+			return fmt.Errorf("error creating payout: %v", err)
+		}
+
+		if status == database.PayoutStatusHeld {
+			_, err = q.ReserveEscrow(ctx, database.ReserveEscrowParams{
+				Cost:     database.NumericFromDecimal(cost),
+				SurveyID: response.SurveyID,
+			})
+		} else {
+			_, err = q.SpendEscrow(ctx, database.SpendEscrowParams{
+				Cost:     database.NumericFromDecimal(cost),
+				SurveyID: response.SurveyID,
+			})
+		}
+		if err != nil {
+			// the escrow was settled or spent by a concurrent request since it was read
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error spending escrow: %v", err)
+		}
+
+		if status == database.PayoutStatusHeld {
+			return nil
+		}
+
+		if _, err := r.wallets.TopUpWallet(ctx, respondentID, amount); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Payout{}, err
+	}
+
+	return payout, nil
+}
+
+// payee returns the respondent a response is paid to. A response to an anonymous survey doesn't
+// carry its respondent, so they are read from its identity, which only payouts may do.
+func payee(ctx context.Context, q *database.Queries, response database.Response) (int64, error) {
+	if response.RespondentID.Valid {
+		return response.RespondentID.Int64, nil
+	}
+
+	respondentID, err := q.GetRespondentIdentity(ctx, response.ID)
+	if err != nil {
+		return 0, fmt.Errorf("error getting respondent identity: %v", err)
+	}
+
+	return respondentID, nil
+}
+
+// release pays out a reward held in escrow. The hold was taken while the escrow was open, so it is
+// paid even if the survey was closed since.
+func (r *Repository) release(ctx context.Context, q *database.Queries, escrow database.Escrow, payout *database.Payout) error {
+	released, err := q.UpdatePayoutStatus(ctx, database.UpdatePayoutStatusParams{
+		NextStatus:    database.PayoutStatusPaid,
+		ID:            payout.ID,
+		CurrentStatus: database.PayoutStatusHeld,
+	})
+	if err != nil {
+		// a concurrent request released or returned it first
+		if errors.Is(err, pgx.ErrNoRows) {
+			return custom_errors.ErrConflict
+		}
+		return fmt.Errorf("error updating payout: %v", err)
+	}
+	*payout = released
+
+	_, err = q.ReleaseEscrow(ctx, database.ReleaseEscrowParams{
+		Cost:     database.NumericFromDecimal(holdCost(escrow, released)),
+		SurveyID: escrow.SurveyID,
+	})
+	if err != nil {
+		return fmt.Errorf("error releasing escrow: %v", err)
+	}
+
+	if _, err := r.wallets.TopUpWallet(ctx, released.RespondentID, database.DecimalFromNumeric(released.Amount)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// holdCost is what the escrow set aside for a held reward: the reward and the platform fee on it.
+func holdCost(escrow database.Escrow, payout database.Payout) decimal.Decimal {
+	return database.DecimalFromNumeric(payout.Amount).Add(database.DecimalFromNumeric(escrow.FeePerResponse))
+}
+
+func (r *Repository) GetPayout(ctx context.Context, responseID int64) (database.Payout, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	payout, err := r.queries.GetPayoutByResponse(ctx, responseID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Payout{}, custom_errors.ErrNotFound
+		}
+		return database.Payout{}, fmt.Errorf("error getting payout: %v", err)
+	}
+
+	return payout, nil
+}
@@ -10,4 +10,7 @@ type UpdateProfileBody struct {
 	University  string    `json:"university"`
 	Faculty     string    `json:"faculty"`
 	Location    string    `json:"location"`
+	// Locale is the language the user would rather answer surveys in, over the ones their browser
+	// asks for. An empty one clears it.
+	Locale *string `json:"locale"`
 }
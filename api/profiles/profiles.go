@@ -2,8 +2,10 @@ package profiles
 
 import (
 	"context"
+	"fmt"
 	"github.com/Adedunmol/answerly/api/jsonutil"
 	"github.com/Adedunmol/answerly/api/tokens"
+	"golang.org/x/text/language"
 	"net/http"
 )
 
@@ -70,6 +72,22 @@ func (h *Handler) UpdateProfileHandler(responseWriter http.ResponseWriter, reque
 		return
 	}
 
+	if data.Locale != nil && *data.Locale != "" {
+		tag, err := language.Parse(*data.Locale)
+		if err != nil || tag == language.Und {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: fmt.Sprintf("locale: %q is not a valid locale", *data.Locale),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+
+		// stored the way surveys store their locales, so the two compare equal
+		locale := tag.String()
+		data.Locale = &locale
+	}
+
 	profile, err := h.Store.UpdateProfile(ctx, int64(userID), data)
 	if err != nil {
 		response := jsonutil.Response{
@@ -96,6 +96,9 @@ func (s *StubProfileStore) UpdateProfile(ctx context.Context, userID int64, data
 	if data.Location != "" {
 		profile.Location = pgtype.Text{String: data.Location, Valid: true}
 	}
+	if data.Locale != nil {
+		profile.Locale = pgtype.Text{String: *data.Locale, Valid: *data.Locale != ""}
+	}
 
 	s.Profiles[userID] = profile
 	return profile, nil
@@ -307,6 +310,74 @@ func TestUpdateProfileHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("stores the preferred locale in canonical form", func(t *testing.T) {
+		store := NewStubProfileStore()
+		store.Profiles[1] = database.Profile{
+			ID:     1,
+			UserID: 1,
+		}
+
+		handler := &profiles.Handler{
+			Store: store,
+		}
+
+		data := []byte(`{"locale": "pt_br"}`)
+
+		req := httptest.NewRequest(http.MethodPatch, "/profile", bytes.NewBuffer(data))
+		rec := httptest.NewRecorder()
+
+		claims := &tokens.Claims{
+			UserID: 1,
+			Email:  "john@example.com",
+		}
+		ctx := context.WithValue(req.Context(), "claims", claims)
+		req = req.WithContext(ctx)
+
+		handler.UpdateProfileHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if locale := store.Profiles[1].Locale.String; locale != "pt-BR" {
+			t.Errorf("expected Locale to be 'pt-BR', got '%s'", locale)
+		}
+	})
+
+	t.Run("returns 400 for an invalid locale", func(t *testing.T) {
+		store := NewStubProfileStore()
+		store.Profiles[1] = database.Profile{
+			ID:     1,
+			UserID: 1,
+		}
+
+		handler := &profiles.Handler{
+			Store: store,
+		}
+
+		data := []byte(`{"locale": "not a locale"}`)
+
+		req := httptest.NewRequest(http.MethodPatch, "/profile", bytes.NewBuffer(data))
+		rec := httptest.NewRecorder()
+
+		claims := &tokens.Claims{
+			UserID: 1,
+			Email:  "john@example.com",
+		}
+		ctx := context.WithValue(req.Context(), "claims", claims)
+		req = req.WithContext(ctx)
+
+		handler.UpdateProfileHandler(rec, req)
+
+		var got map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+		assertResponseStatus(t, got, "error")
+
+		if store.Profiles[1].Locale.Valid {
+			t.Error("expected the locale to be left unset")
+		}
+	})
+
 	t.Run("returns 401 when userID is 0", func(t *testing.T) {
 		handler := &profiles.Handler{
 			Store: NewStubProfileStore(),
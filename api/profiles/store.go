@@ -60,6 +60,11 @@ func (r *Repository) UpdateProfile(ctx context.Context, userID int64, profile Up
 		}
 	}
 
+	var locale string
+	if profile.Locale != nil {
+		locale = *profile.Locale
+	}
+
 	data, err := r.queries.UpdateProfile(ctx, database.UpdateProfileParams{
 		FirstName:   pgtype.Text{String: profile.FirstName, Valid: len(profile.FirstName) > 0},
 		LastName:    pgtype.Text{String: profile.LastName, Valid: len(profile.LastName) > 0},
@@ -67,6 +72,7 @@ func (r *Repository) UpdateProfile(ctx context.Context, userID int64, profile Up
 		Gender:      gender,
 		University:  pgtype.Text{String: profile.University, Valid: len(profile.University) > 0},
 		Location:    pgtype.Text{String: profile.Location, Valid: len(profile.Location) > 0},
+		Locale:      pgtype.Text{String: locale, Valid: profile.Locale != nil},
 		UserID:      userID,
 	})
 
@@ -0,0 +1,132 @@
+package responses_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAnonymousStore() *StubResponseStore {
+	store := newPublishedStore()
+	survey := store.Surveys[1]
+	survey.Anonymous = true
+	store.Surveys[1] = survey
+
+	return store
+}
+
+// ============================================================================
+// Anonymous survey Tests
+// ============================================================================
+
+func TestAnonymousResponses(t *testing.T) {
+
+	t.Run("starts a response under a pseudonym", func(t *testing.T) {
+		store := newAnonymousStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		response := store.Responses[1]
+		if response.RespondentID.Valid || !response.Pseudonym.Valid || store.Identities[1] != 1 {
+			t.Errorf("response = %+v with identity %d, want a pseudonym and respondent 1 kept apart", response, store.Identities[1])
+		}
+	})
+
+	t.Run("lets the respondent answer and submit it", func(t *testing.T) {
+		store := newAnonymousStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		handler.StartResponseHandler(httptest.NewRecorder(), req)
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}]}`)
+		req = newRequest(http.MethodPost, data, 1, map[string]string{"surveyID": "1", "responseID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if store.Responses[1].Status != database.ResponseStatusSubmitted {
+			t.Errorf("status = %s, want submitted", store.Responses[1].Status)
+		}
+	})
+
+	t.Run("keeps the response from other users", func(t *testing.T) {
+		store := newAnonymousStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		handler.StartResponseHandler(httptest.NewRecorder(), req)
+
+		req = newRequest(http.MethodGet, nil, 2, map[string]string{"surveyID": "1", "responseID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.ResumeResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 409 when the respondent starts again after submitting", func(t *testing.T) {
+		store := newAnonymousStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		handler.StartResponseHandler(httptest.NewRecorder(), req)
+
+		response := store.Responses[1]
+		response.Status = database.ResponseStatusSubmitted
+		store.Responses[1] = response
+
+		req = newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("shows reviewers the pseudonym in place of the respondent", func(t *testing.T) {
+		store := newReviewStore()
+		response := store.Responses[1]
+		response.RespondentID = pgtype.Int8{}
+		response.Pseudonym = pgtype.Text{String: "r_00000000000000aa", Valid: true}
+		store.Responses[1] = response
+		store.Identities[1] = 101
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, nil, researcherID, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.ListReviewsHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data responses.ReviewPage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Data.Responses) != 2 {
+			t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+		}
+
+		anonymous := got.Data.Responses[0]
+		if anonymous.RespondentID != nil || anonymous.Pseudonym != "r_00000000000000aa" {
+			t.Errorf("review = %+v, want only the pseudonym", anonymous)
+		}
+
+		if strings.Contains(rec.Body.String(), `"respondent_id":101`) {
+			t.Errorf("body = %s, want the respondent left out", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"time"
+)
+
+type AnswerBody struct {
+	QuestionID int64           `json:"question_id" validate:"required"`
+	Value      json.RawMessage `json:"value" validate:"required"`
+	// Display is where the question and its options were shown, see surveys.Display. It is
+	// worked out from the response's seed, never taken from the respondent.
+	Display json.RawMessage `json:"-"`
+}
+
+type SubmitResponseBody struct {
+	Answers []AnswerBody `json:"answers" validate:"required,dive"`
+}
+
+// NextQuestionBody carries the answers given so far and the question the respondent is on,
+// 0 before the first question.
+type NextQuestionBody struct {
+	Answers []AnswerBody `json:"answers" validate:"dive"`
+	After   int64        `json:"after" validate:"gte=0"`
+}
+
+// SaveAnswersBody carries a page of answers to save and the last question on it, the one the
+// respondent continues after.
+type SaveAnswersBody struct {
+	Answers []AnswerBody `json:"answers" validate:"dive"`
+	After   int64        `json:"after" validate:"gte=0"`
+}
+
+// ReviewBody is a researcher's decision on a response. Rejections need a reason.
+type ReviewBody struct {
+	Decision string `json:"decision" validate:"required,oneof=approve reject"`
+	Reason   string `json:"reason" validate:"required_if=Decision reject,max=1000"`
+}
+
+// BulkReviewBody applies one decision to several responses at once.
+type BulkReviewBody struct {
+	ResponseIDs []int64 `json:"response_ids" validate:"required,min=1,max=100,unique,dive,gt=0"`
+	Decision    string  `json:"decision" validate:"required,oneof=approve reject"`
+	Reason      string  `json:"reason" validate:"required_if=Decision reject,max=1000"`
+}
+
+// Review is the outcome of a review. ReviewerID is 0 when no researcher made the call.
+type Review struct {
+	Status     database.ReviewStatus
+	Reason     string
+	ReviewerID int64
+}
+
+// ReviewFilter picks the responses listed for review. An empty Status lists the ones awaiting
+// review.
+type ReviewFilter struct {
+	Status database.ReviewStatus
+	After  int64
+	Limit  int32
+}
+
+type Response struct {
+	ID       int64  `json:"id"`
+	SurveyID int64  `json:"survey_id"`
+	Status   string `json:"status"`
+	// SurveyVersion is the version of the survey's questions the response is answered against.
+	SurveyVersion int32 `json:"survey_version"`
+	// ReviewStatus is set once the response is submitted: pending until the quality checks have
+	// run, then approved or needs_review.
+	ReviewStatus string `json:"review_status,omitempty"`
+	// ReviewReason is why the researcher rejected the response.
+	ReviewReason string     `json:"review_reason,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	SubmittedAt  *time.Time `json:"submitted_at"`
+	// ExpiresAt is when a response in progress expires unless the respondent saves it again.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Test is set for the test responses researchers make walking through their own surveys.
+	Test bool `json:"test,omitempty"`
+}
+
+type StartResponseData struct {
+	Response  Response           `json:"response"`
+	Survey    surveys.Survey     `json:"survey"`
+	Questions []surveys.Question `json:"questions"`
+}
+
+// PurgeData tells how many test responses were deleted.
+type PurgeData struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// NextQuestionData is the question to show next. Once Done, there is none; when the respondent's
+// screener answers disqualified them, ScreenedOut is set and Response is the ended response.
+type NextQuestionData struct {
+	Question    *surveys.Question `json:"question"`
+	Done        bool              `json:"done"`
+	ScreenedOut bool              `json:"screened_out"`
+	Response    *Response         `json:"response,omitempty"`
+}
+
+// SavedResponseData is a response in progress as the respondent left it: the answers saved so far,
+// the IDs of saved answers that were dropped because they no longer fit the survey's questions or
+// branching, and the question to continue with.
+type SavedResponseData struct {
+	Response Response         `json:"response"`
+	Answers  []AnswerBody     `json:"answers"`
+	Dropped  []int64          `json:"dropped"`
+	Next     NextQuestionData `json:"next"`
+}
+
+func NewResponse(response database.Response) Response {
+	data := Response{
+		ID:            response.ID,
+		SurveyID:      response.SurveyID,
+		Status:        string(response.Status),
+		SurveyVersion: response.SurveyVersion,
+		StartedAt:     response.StartedAt.Time,
+		Test:          response.IsTest,
+	}
+
+	if response.ReviewStatus.Valid {
+		data.ReviewStatus = string(response.ReviewStatus.ReviewStatus)
+		data.ReviewReason = response.ReviewReason.String
+	}
+
+	if response.SubmittedAt.Valid {
+		data.SubmittedAt = &response.SubmittedAt.Time
+	}
+
+	if response.Status == database.ResponseStatusInProgress && response.ExpiresAt.Valid {
+		data.ExpiresAt = &response.ExpiresAt.Time
+	}
+
+	return data
+}
+
+// ResponseReview is a submitted response as a researcher reviewing it sees it. Answers are keyed
+// by question ID. A response to an anonymous survey has a pseudonym in place of its respondent.
+type ResponseReview struct {
+	ID            int64                      `json:"id"`
+	RespondentID  *int64                     `json:"respondent_id,omitempty"`
+	Pseudonym     string                     `json:"pseudonym,omitempty"`
+	SubmittedAt   *time.Time                 `json:"submitted_at"`
+	ReviewStatus  string                     `json:"review_status"`
+	QualityReport json.RawMessage            `json:"quality_report,omitempty"`
+	ReviewReason  string                     `json:"review_reason,omitempty"`
+	ReviewedAt    *time.Time                 `json:"reviewed_at"`
+	Answers       map[string]json.RawMessage `json:"answers"`
+}
+
+// ReviewPage is a page of responses to review. NextAfter is passed back as after to get the next
+// page, it is left out on the last one.
+type ReviewPage struct {
+	Responses []ResponseReview `json:"responses"`
+	NextAfter int64            `json:"next_after,omitempty"`
+}
+
+func NewResponseReview(row database.ListResponsesForReviewRow) (ResponseReview, error) {
+	data := ResponseReview{
+		ID:            row.ID,
+		Pseudonym:     row.Pseudonym.String,
+		ReviewStatus:  string(row.ReviewStatus.ReviewStatus),
+		QualityReport: row.QualityReport,
+		ReviewReason:  row.ReviewReason.String,
+	}
+
+	if row.RespondentID.Valid {
+		data.RespondentID = &row.RespondentID.Int64
+	}
+
+	if row.SubmittedAt.Valid {
+		data.SubmittedAt = &row.SubmittedAt.Time
+	}
+
+	if row.ReviewedAt.Valid {
+		data.ReviewedAt = &row.ReviewedAt.Time
+	}
+
+	if err := json.Unmarshal(row.Answers, &data.Answers); err != nil {
+		return ResponseReview{}, fmt.Errorf("error decoding answers: %v", err)
+	}
+
+	return data, nil
+}
+
+func NewReviewPage(rows []database.ListResponsesForReviewRow, limit int32) (ReviewPage, error) {
+	page := ReviewPage{Responses: make([]ResponseReview, 0, len(rows))}
+
+	for _, row := range rows {
+		review, err := NewResponseReview(row)
+		if err != nil {
+			return ReviewPage{}, err
+		}
+		page.Responses = append(page.Responses, review)
+	}
+
+	if len(rows) > 0 && int32(len(rows)) == limit {
+		page.NextAfter = rows[len(rows)-1].ID
+	}
+
+	return page, nil
+}
+
+func NewResponses(responses []database.Response) []Response {
+	data := make([]Response, 0, len(responses))
+	for _, response := range responses {
+		data = append(data, NewResponse(response))
+	}
+
+	return data
+}
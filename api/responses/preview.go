@@ -0,0 +1,87 @@
+package responses
+
+import (
+	"context"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"net/http"
+)
+
+// StartTestResponseHandler starts a test response for a researcher walking through their own
+// survey, in any status. The survey is shown as a respondent would see it, in the locale asked for
+// like any other, but targeting, quotas and panels are skipped, and every call starts a new test
+// response.
+func (h *Handler) StartTestResponseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, userID, ok := h.reviewedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	surveyResponse, err := h.Store.CreateTestResponse(ctx, survey.ID, userID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.startResponseData(ctx, request, surveyResponse, survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "test response started successfully",
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+// PurgeTestResponsesHandler deletes every test response to a survey, whoever made it.
+func (h *Handler) PurgeTestResponsesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, _, ok := h.reviewedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.Store.PurgeTestResponses(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "test responses deleted successfully",
+		Data:    PurgeData{Deleted: deleted},
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
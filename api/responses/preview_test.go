@@ -0,0 +1,194 @@
+package responses_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newPreviewStore holds a draft survey, which only its researcher can walk through.
+func newPreviewStore() *StubResponseStore {
+	store := newPublishedStore()
+	survey := store.Surveys[1]
+	survey.Status = database.SurveyStatusDraft
+	store.Surveys[1] = survey
+
+	return store
+}
+
+// ============================================================================
+// Preview Tests
+// ============================================================================
+
+func TestStartTestResponseHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1"}
+
+	t.Run("starts a test response to a draft survey", func(t *testing.T) {
+		store := newPreviewStore()
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodPost, nil, researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.StartTestResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		var got struct {
+			Data responses.StartResponseData `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if !got.Data.Response.Test || len(got.Data.Questions) != 2 {
+			t.Errorf("data = %+v, want a test response with both questions", got.Data)
+		}
+	})
+
+	t.Run("starts a new test response every time", func(t *testing.T) {
+		store := newPreviewStore()
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		for range 2 {
+			rec := httptest.NewRecorder()
+			handler.StartTestResponseHandler(rec, newRequest(http.MethodPost, nil, researcherID, params))
+			assertResponseCode(t, rec.Code, http.StatusCreated)
+		}
+
+		if len(store.Responses) != 2 {
+			t.Errorf("responses = %d, want 2", len(store.Responses))
+		}
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := newPreviewStore()
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodPost, nil, 8, params)
+		rec := httptest.NewRecorder()
+
+		handler.StartTestResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+
+		if len(store.Responses) != 0 {
+			t.Errorf("responses = %d, want none", len(store.Responses))
+		}
+	})
+}
+
+func TestSubmitTestResponse(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "responseID": "1"}
+	data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}, {"question_id": 2, "value": {"value": 25}}]}`)
+
+	t.Run("checks the test response right away without paying it", func(t *testing.T) {
+		store := newPreviewStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusInProgress, IsTest: true}
+		tasks := &StubQueue{}
+		handler := &responses.Handler{Store: store, Queue: tasks, Preview: true}
+
+		req := newRequest(http.MethodPost, data, researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if review := store.Responses[1].ReviewStatus.ReviewStatus; review != database.ReviewStatusApproved {
+			t.Errorf("review status = %s, want approved", review)
+		}
+
+		if len(store.Paid) != 0 || len(tasks.Tasks) != 0 {
+			t.Errorf("paid = %v and queued %d tasks, want neither", store.Paid, len(tasks.Tasks))
+		}
+	})
+
+	t.Run("still validates the answers", func(t *testing.T) {
+		store := newPreviewStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusInProgress, IsTest: true}
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodPost, []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "car"}}]}`), researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for a real response in preview", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodPost, data, researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 404 for a test response outside preview", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusInProgress, IsTest: true}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, data, researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+func TestPurgeTestResponsesHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1"}
+
+	t.Run("deletes only the test responses", func(t *testing.T) {
+		store := newPreviewStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusSubmitted}
+		store.Responses[2] = database.Response{ID: 2, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusSubmitted, IsTest: true}
+		store.Responses[3] = database.Response{ID: 3, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusInProgress, IsTest: true}
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodDelete, nil, researcherID, params)
+		rec := httptest.NewRecorder()
+
+		handler.PurgeTestResponsesHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data responses.PurgeData `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if _, kept := store.Responses[1]; got.Data.Deleted != 2 || len(store.Responses) != 1 || !kept {
+			t.Errorf("deleted %d leaving %v, want 2 deleted and the real response kept", got.Data.Deleted, store.Responses)
+		}
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := newPreviewStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(researcherID), Status: database.ResponseStatusSubmitted, IsTest: true}
+		handler := &responses.Handler{Store: store, Preview: true}
+
+		req := newRequest(http.MethodDelete, nil, 8, params)
+		rec := httptest.NewRecorder()
+
+		handler.PurgeTestResponsesHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+
+		if len(store.Responses) != 1 {
+			t.Errorf("responses = %d, want 1", len(store.Responses))
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"net/http"
+)
+
+// savedAnswers loads the answers saved to a response, keyed by question.
+func (h *Handler) savedAnswers(ctx context.Context, responseID int64) (map[int64]json.RawMessage, error) {
+	rows, err := h.Store.ListAnswers(ctx, responseID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers := make(map[int64]json.RawMessage, len(rows))
+	for _, row := range rows {
+		answers[row.QuestionID] = row.Value
+	}
+
+	return answers, nil
+}
+
+// savedResponse builds what the respondent is shown of their saved response.
+func savedResponse(surveyResponse database.Response, answers map[int64]json.RawMessage, dropped []int64, next NextQuestionData) SavedResponseData {
+	data := SavedResponseData{
+		Response: NewResponse(surveyResponse),
+		Answers:  answerBodies(answers),
+		Dropped:  dropped,
+		Next:     next,
+	}
+
+	if next.Response != nil {
+		data.Response = *next.Response
+	}
+
+	if data.Dropped == nil {
+		data.Dropped = []int64{}
+	}
+
+	return data
+}
+
+// SaveAnswersHandler saves a page of answers so the respondent can stop and pick the response up
+// later, from any device. Saved answers the page takes off the respondent's path are dropped.
+func (h *Handler) SaveAnswersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	data, err := jsonutil.UnmarshalJsonResponse[SaveAnswersBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	surveyResponse, survey, ok := h.inProgressResponse(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, surveyResponse.SurveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+	questions = surveys.Arrange(questions, surveyResponse.OrderSeed)
+
+	questions, err = h.localized(ctx, request, survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	page, err := answerMap(data.Answers)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.savedAnswers(ctx, surveyResponse.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	answers, dropped, err := surveys.MergeAnswers(questions, saved, page)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	// the page is checked like the next question endpoint checks answers, before anything is saved
+	if _, _, err := surveys.NextQuestion(questions, answers, data.After); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	surveyResponse, err = h.Store.SaveAnswers(ctx, surveyResponse.ID, respondentID(request), displayed(answerBodies(page), questions), dropped, data.After)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	next, err := h.nextQuestion(ctx, surveyResponse, respondentID(request), questions, answers, data.After)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "answers saved successfully",
+		Data:    savedResponse(surveyResponse, answers, dropped, next),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// ResumeResponseHandler picks a saved response back up where the respondent stopped. The saved
+// answers are checked against the survey's current questions and branching first, the ones that
+// no longer fit are dropped and the respondent continues from what is left.
+func (h *Handler) ResumeResponseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	surveyResponse, survey, ok := h.inProgressResponse(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, surveyResponse.SurveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+	questions = surveys.Arrange(questions, surveyResponse.OrderSeed)
+
+	questions, err = h.localized(ctx, request, survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := h.savedAnswers(ctx, surveyResponse.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	answers, dropped, err := surveys.PruneAnswers(questions, saved)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	after, err := surveys.ResumeAfter(questions, answers, surveyResponse.LastQuestionID.Int64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	// coming back counts as activity too, so the response doesn't expire while it is being answered
+	surveyResponse, err = h.Store.SaveAnswers(ctx, surveyResponse.ID, respondentID(request), nil, dropped, after)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	next, err := h.nextQuestion(ctx, surveyResponse, respondentID(request), questions, answers, after)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "response resumed successfully",
+		Data:    savedResponse(surveyResponse, answers, dropped, next),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
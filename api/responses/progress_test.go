@@ -0,0 +1,245 @@
+package responses_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// newSavedStore asks how long the commute takes only of those who take the bus, and has a response
+// in progress that saved the first page.
+func newSavedStore() *StubResponseStore {
+	store := newPublishedStore()
+	store.Questions[1].Logic = []byte(`{"display_if": {"question_id": 1, "operator": "equals", "value": "bus"}}`)
+	store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress,
+		LastQuestionID: pgtype.Int8{Int64: 1, Valid: true}}
+	store.Answers[1] = []responses.AnswerBody{{QuestionID: 1, Value: json.RawMessage(`{"option_id": "bus"}`)}}
+
+	return store
+}
+
+func savedResponseData(t *testing.T, rec *httptest.ResponseRecorder) responses.SavedResponseData {
+	t.Helper()
+
+	var got struct {
+		Data responses.SavedResponseData `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+	}
+
+	return got.Data
+}
+
+// ============================================================================
+// SaveAnswersHandler Tests
+// ============================================================================
+
+func TestSaveAnswersHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "responseID": "1"}
+
+	t.Run("saves a page and returns the question after it", func(t *testing.T) {
+		store := newSavedStore()
+		store.Answers[1] = nil
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPut, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SaveAnswersHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		got := savedResponseData(t, rec)
+		if got.Next.Question == nil || got.Next.Question.ID != 2 {
+			t.Errorf("next = %+v, want question 2", got.Next)
+		}
+
+		if len(store.Answers[1]) != 1 || store.Responses[1].LastQuestionID.Int64 != 1 {
+			t.Errorf("saved %v up to question %d, want the first page", store.Answers[1], store.Responses[1].LastQuestionID.Int64)
+		}
+	})
+
+	t.Run("drops saved answers on a branch the respondent no longer takes", func(t *testing.T) {
+		store := newSavedStore()
+		store.Answers[1] = append(store.Answers[1], responses.AnswerBody{QuestionID: 2, Value: json.RawMessage(`{"value": 25}`)})
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}], "after": 1}`)
+		req := newRequest(http.MethodPut, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SaveAnswersHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		got := savedResponseData(t, rec)
+		if len(got.Dropped) != 1 || got.Dropped[0] != 2 || !got.Next.Done {
+			t.Errorf("dropped %v, next %+v, want question 2 dropped and the survey done", got.Dropped, got.Next)
+		}
+
+		if len(store.Answers[1]) != 1 || string(store.Answers[1][0].Value) != `{"option_id": "walk"}` {
+			t.Errorf("answers = %v, want only the new answer to question 1", store.Answers[1])
+		}
+	})
+
+	t.Run("returns 400 for an invalid answer without saving", func(t *testing.T) {
+		store := newSavedStore()
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 2, "value": {"value": 900}}], "after": 2}`)
+		req := newRequest(http.MethodPut, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SaveAnswersHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		if len(store.Answers[1]) != 1 {
+			t.Errorf("answers = %v, want nothing saved", store.Answers[1])
+		}
+	})
+
+	t.Run("returns 409 once the response has expired", func(t *testing.T) {
+		store := newSavedStore()
+		response := store.Responses[1]
+		response.Status = database.ResponseStatusExpired
+		store.Responses[1] = response
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPut, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SaveAnswersHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("records where the question and its options were shown", func(t *testing.T) {
+		store := newSavedStore()
+		store.Answers[1] = nil
+		store.Questions[0].Config = []byte(`{"randomize": true, "options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}, {"id": "none", "label": "Neither", "anchored": true}]}`)
+		response := store.Responses[1]
+		response.OrderSeed = 7
+		store.Responses[1] = response
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPut, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SaveAnswersHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		want := surveys.Displays(surveys.Arrange(store.Questions, 7))[1]
+
+		var display surveys.Display
+		if len(store.Answers[1]) != 1 || json.Unmarshal(store.Answers[1][0].Display, &display) != nil {
+			t.Fatalf("answers = %+v, want the answer saved with where it was shown", store.Answers[1])
+		}
+
+		if display.Position != 1 || !slices.Equal(display.Options, want.Options) || display.Options[2] != "none" {
+			t.Errorf("display = %+v, want %+v", display, want)
+		}
+	})
+}
+
+// ============================================================================
+// ResumeResponseHandler Tests
+// ============================================================================
+
+func TestResumeResponseHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "responseID": "1"}
+
+	t.Run("continues after the last saved question", func(t *testing.T) {
+		store := newSavedStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.ResumeResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		got := savedResponseData(t, rec)
+		if len(got.Answers) != 1 || got.Next.Question == nil || got.Next.Question.ID != 2 {
+			t.Errorf("answers %v, next %+v, want question 2 after the saved answer", got.Answers, got.Next)
+		}
+
+		if got.Response.ExpiresAt == nil {
+			t.Errorf("expected the expiry to be pushed back")
+		}
+	})
+
+	t.Run("drops saved answers the current branching skips", func(t *testing.T) {
+		store := newSavedStore()
+		// saved before question 2 was limited to bus riders
+		store.Answers[1] = []responses.AnswerBody{
+			{QuestionID: 1, Value: json.RawMessage(`{"option_id": "walk"}`)},
+			{QuestionID: 2, Value: json.RawMessage(`{"value": 25}`)},
+		}
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress,
+			LastQuestionID: pgtype.Int8{Int64: 2, Valid: true}}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.ResumeResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		got := savedResponseData(t, rec)
+		if len(got.Dropped) != 1 || got.Dropped[0] != 2 || !got.Next.Done {
+			t.Errorf("dropped %v, next %+v, want question 2 dropped and the survey done", got.Dropped, got.Next)
+		}
+
+		if len(store.Answers[1]) != 1 {
+			t.Errorf("answers = %v, want the dropped answer deleted", store.Answers[1])
+		}
+	})
+
+	t.Run("returns 404 for another user's response", func(t *testing.T) {
+		store := newSavedStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, nil, 2, params)
+		rec := httptest.NewRecorder()
+
+		handler.ResumeResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+func TestSubmitSavedResponse(t *testing.T) {
+
+	t.Run("counts the answers saved along the way", func(t *testing.T) {
+		store := newSavedStore()
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 2, "value": {"value": 25}}]}`)
+		req := newRequest(http.MethodPost, data, 1, map[string]string{"surveyID": "1", "responseID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if len(store.Answers[1]) != 2 {
+			t.Errorf("answers = %v, want both questions answered", store.Answers[1])
+		}
+	})
+}
@@ -0,0 +1,654 @@
+package responses
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type Handler struct {
+	Store Store
+	// Queue runs the quality checks of submitted responses in the background. Without one, they
+	// run as part of the submission.
+	Queue queue.Queue
+	// Preview makes the handler work on the test responses researchers make walking through their
+	// own surveys instead of on real ones. The survey can then be in any status.
+	Preview bool
+}
+
+func (h *Handler) StartResponseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	surveyID, err := strconv.ParseInt(chi.URLParam(request, "surveyID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.GetPublishedSurvey(ctx, surveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	allowed, err := h.Store.CanAnswerSurvey(ctx, surveyID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "this survey is a panel wave open only to the panel's participants",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return
+	}
+
+	targeting, err := surveys.ParseTargeting(survey.Targeting)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	cells, err := h.Store.ListQuotaCells(ctx, surveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	var cellIDs []int64
+
+	if !targeting.IsEmpty() || len(cells) > 0 {
+		audience, err := h.Store.GetAudience(ctx, int64(userID))
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+
+		if err := targeting.Eligible(audience, time.Now()); err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+			return
+		}
+
+		// users whose quota cells are already full would only be turned away on submit
+		matched, err := surveys.MatchQuotaCells(cells, audience, time.Now())
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+			return
+		}
+
+		for _, cell := range matched {
+			cellIDs = append(cellIDs, cell.ID)
+		}
+	}
+
+	statusCode := http.StatusCreated
+
+	surveyResponse, err := h.Store.CreateResponse(ctx, surveyID, int64(userID), cellIDs)
+	if err != nil {
+		if !errors.Is(err, custom_errors.ErrConflict) {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		// starting again hands back the response that is still in progress
+		surveyResponse, err = h.Store.FindResponse(ctx, surveyID, int64(userID))
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		// a response left to expire can be started over
+		if surveyResponse.Status == database.ResponseStatusExpired {
+			surveyResponse, err = h.Store.RestartResponse(ctx, surveyResponse.ID, int64(userID), cellIDs)
+			if err != nil {
+				response := jsonutil.Response{
+					Status:  "error",
+					Message: err.Error(),
+				}
+				jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+				return
+			}
+		}
+
+		if surveyResponse.Status != database.ResponseStatusInProgress {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: "you have already responded to this survey",
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+			return
+		}
+
+		statusCode = http.StatusOK
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, surveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := h.startResponseData(ctx, request, surveyResponse, survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "response started successfully",
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, statusCode)
+	return
+}
+
+// startResponseData shows a response just started with the survey and its questions as the
+// respondent sees them, in the order of the response and in their language.
+func (h *Handler) startResponseData(ctx context.Context, request *http.Request, surveyResponse database.Response, survey database.Survey, questions []database.Question) (StartResponseData, error) {
+	locale, err := h.locale(ctx, request, survey)
+	if err != nil {
+		return StartResponseData{}, err
+	}
+
+	localized, err := surveys.LocalizeSurvey(survey, locale)
+	if err != nil {
+		return StartResponseData{}, err
+	}
+
+	questions, err = surveys.LocalizeQuestions(surveys.Arrange(questions, surveyResponse.OrderSeed), locale)
+	if err != nil {
+		return StartResponseData{}, err
+	}
+
+	data := StartResponseData{
+		Response:  NewResponse(surveyResponse),
+		Survey:    surveys.NewSurvey(localized),
+		Questions: surveys.NewQuestions(questions),
+	}
+	data.Survey.Locale = locale
+
+	return data, nil
+}
+
+// locale picks the locale of a survey to show the respondent making the request: the one they ask
+// for with the locale query parameter, the one saved in their profile, or the closest to the
+// languages their browser accepts, falling back to the survey's default locale.
+func (h *Handler) locale(ctx context.Context, request *http.Request, survey database.Survey) (string, error) {
+	if len(survey.Locales) == 0 {
+		return survey.DefaultLocale, nil
+	}
+
+	preferred, err := h.Store.PreferredLocale(ctx, respondentID(request))
+	if err != nil {
+		return "", err
+	}
+
+	wants := surveys.Languages(request.URL.Query().Get("locale"), preferred, request.Header.Get("Accept-Language"))
+	return surveys.NegotiateLocale(survey, wants), nil
+}
+
+// localized puts the questions of a survey in the locale it is shown to the respondent in, see
+// locale.
+func (h *Handler) localized(ctx context.Context, request *http.Request, survey database.Survey, questions []database.Question) ([]database.Question, error) {
+	locale, err := h.locale(ctx, request, survey)
+	if err != nil {
+		return nil, err
+	}
+
+	return surveys.LocalizeQuestions(questions, locale)
+}
+
+// inProgressResponse loads the response named in the URL for the respondent making the request,
+// with the survey it answers, and makes sure it can still be answered. When it can't, it writes the
+// error response itself and returns false.
+func (h *Handler) inProgressResponse(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Response, database.Survey, bool) {
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return database.Response{}, database.Survey{}, false
+	}
+
+	surveyID, err := strconv.ParseInt(chi.URLParam(request, "surveyID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.Response{}, database.Survey{}, false
+	}
+
+	responseID, err := strconv.ParseInt(chi.URLParam(request, "responseID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid response id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.Response{}, database.Survey{}, false
+	}
+
+	surveyResponse, err := h.Store.GetResponse(ctx, responseID, int64(userID))
+	if err == nil && (surveyResponse.SurveyID != surveyID || surveyResponse.IsTest != h.Preview) {
+		err = custom_errors.ErrNotFound
+	}
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.Response{}, database.Survey{}, false
+	}
+
+	if surveyResponse.Status != database.ResponseStatusInProgress {
+		message := "this response has already been submitted"
+		switch surveyResponse.Status {
+		case database.ResponseStatusScreenedOut:
+			message = "this response has been screened out"
+		case database.ResponseStatusExpired:
+			message = "this response has expired, start the survey again to answer it"
+		}
+
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: message,
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return database.Response{}, database.Survey{}, false
+	}
+
+	// a survey being previewed needn't be published, only still the researcher's
+	if h.Preview {
+		survey, err := h.Store.GetSurvey(ctx, surveyID, int64(userID))
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return database.Response{}, database.Survey{}, false
+		}
+
+		return surveyResponse, survey, true
+	}
+
+	survey, err := h.Store.GetPublishedSurvey(ctx, surveyID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: "this survey is no longer accepting responses",
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+			return database.Response{}, database.Survey{}, false
+		}
+
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return database.Response{}, database.Survey{}, false
+	}
+
+	return surveyResponse, survey, true
+}
+
+// respondentID is the user acting on a response loaded by inProgressResponse, which checked the
+// response is theirs. Responses to anonymous surveys don't carry their respondent, so the stores
+// are handed this instead.
+func respondentID(request *http.Request) int64 {
+	return int64(request.Context().Value("claims").(*tokens.Claims).UserID)
+}
+
+// answerMap keys answers by the question they answer, rejecting a question answered twice.
+func answerMap(answers []AnswerBody) (map[int64]json.RawMessage, error) {
+	answerMap := make(map[int64]json.RawMessage, len(answers))
+	for _, answer := range answers {
+		if _, exists := answerMap[answer.QuestionID]; exists {
+			return nil, fmt.Errorf("question %d is answered more than once", answer.QuestionID)
+		}
+		answerMap[answer.QuestionID] = answer.Value
+	}
+
+	return answerMap, nil
+}
+
+func (h *Handler) NextQuestionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	data, err := jsonutil.UnmarshalJsonResponse[NextQuestionBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	surveyResponse, survey, ok := h.inProgressResponse(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, surveyResponse.SurveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+	questions = surveys.Arrange(questions, surveyResponse.OrderSeed)
+
+	questions, err = h.localized(ctx, request, survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	answers, err := answerMap(data.Answers)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	next, err := h.nextQuestion(ctx, surveyResponse, respondentID(request), questions, answers, data.After)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved next question successfully",
+		Data:    next,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// nextQuestion works out the question to show after the question with ID after from the answers
+// given so far. When the path ends early because the respondent's screener answers disqualify
+// them, the response is screened out with those answers.
+func (h *Handler) nextQuestion(ctx context.Context, surveyResponse database.Response, respondentID int64, questions []database.Question, answers map[int64]json.RawMessage, after int64) (NextQuestionData, error) {
+	question, found, err := surveys.NextQuestion(questions, answers, after)
+	if err != nil {
+		return NextQuestionData{}, err
+	}
+
+	next := NextQuestionData{Done: !found}
+	if found {
+		nextQuestion := surveys.NewQuestion(question)
+		next.Question = &nextQuestion
+		return next, nil
+	}
+
+	next.ScreenedOut, err = surveys.ScreenedOut(questions, answers)
+	if err != nil || !next.ScreenedOut {
+		return next, err
+	}
+
+	surveyResponse, err = h.Store.ScreenOutResponse(ctx, surveyResponse.ID, respondentID, displayed(answerBodies(answers), questions))
+	if err != nil {
+		return NextQuestionData{}, err
+	}
+
+	screenedOut := NewResponse(surveyResponse)
+	next.Response = &screenedOut
+
+	return next, nil
+}
+
+// answerBodies lists answers keyed by question in question order.
+func answerBodies(answers map[int64]json.RawMessage) []AnswerBody {
+	bodies := make([]AnswerBody, 0, len(answers))
+	for questionID, value := range answers {
+		bodies = append(bodies, AnswerBody{QuestionID: questionID, Value: value})
+	}
+
+	slices.SortFunc(bodies, func(a, b AnswerBody) int { return cmp.Compare(a.QuestionID, b.QuestionID) })
+
+	return bodies
+}
+
+// displayed records on each answer where its question and options were shown, going by the
+// questions as surveys.Arrange put them for the respondent.
+func displayed(answers []AnswerBody, questions []database.Question) []AnswerBody {
+	displays := surveys.Displays(questions)
+
+	bodies := make([]AnswerBody, 0, len(answers))
+	for _, answer := range answers {
+		if display, ok := displays[answer.QuestionID]; ok {
+			answer.Display, _ = json.Marshal(display)
+		}
+		bodies = append(bodies, answer)
+	}
+
+	return bodies
+}
+
+func (h *Handler) SubmitResponseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	data, err := jsonutil.UnmarshalJsonResponse[SubmitResponseBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	surveyResponse, _, ok := h.inProgressResponse(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, surveyResponse.SurveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+	questions = surveys.Arrange(questions, surveyResponse.OrderSeed)
+
+	page, err := answerMap(data.Answers)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	// the answers saved along the way count too, so only the last page needs sending
+	saved, err := h.savedAnswers(ctx, surveyResponse.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	answers, dropped, err := surveys.MergeAnswers(questions, saved, page)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	if err := surveys.ValidateAnswers(questions, answers); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	screenedOut, err := surveys.ScreenedOut(questions, answers)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	if len(dropped) > 0 {
+		_, err = h.Store.SaveAnswers(ctx, surveyResponse.ID, respondentID(request), nil, dropped, surveyResponse.LastQuestionID.Int64)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+	}
+
+	// test responses are checked right away, so the researcher sees how theirs would be reviewed
+	check := h.Queue == nil || surveyResponse.IsTest
+
+	message := "response submitted successfully"
+	if screenedOut {
+		message = "response screened out"
+		surveyResponse, err = h.Store.ScreenOutResponse(ctx, surveyResponse.ID, respondentID(request), displayed(data.Answers, questions))
+	} else {
+		surveyResponse, err = h.Store.SubmitResponse(ctx, surveyResponse.ID, respondentID(request), displayed(data.Answers, questions), check)
+	}
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if !screenedOut && !check {
+		err = h.Queue.Enqueue(&queue.ResponseQualityPayload{ResponseID: surveyResponse.ID})
+		if err != nil {
+			log.Printf("error enqueuing quality check task: %s", err)
+		}
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: message,
+		Data:    NewResponse(surveyResponse),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
@@ -0,0 +1,1018 @@
+package responses_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Stub Response Store
+// ============================================================================
+
+type StubResponseStore struct {
+	Surveys   map[int64]database.Survey
+	Questions []database.Question
+	Responses map[int64]database.Response
+	Answers   map[int64][]responses.AnswerBody
+	Audiences map[int64]surveys.Audience
+	Cells     []database.QuotaCell
+	// Exhausted makes approvals fail as if the survey's escrow could not pay another reward
+	Exhausted bool
+	// Flagged makes the quality checks flag every response for review
+	Flagged bool
+	// Paid and Returned record the responses whose held rewards were paid out or returned
+	Paid     []int64
+	Returned []int64
+	// Submitted holds how many days ago each submitted response was submitted
+	Submitted map[int64]int32
+	// Held records the quota cells each response holds a slot in
+	Held map[int64][]int64
+	// Identities holds who started each response to an anonymous survey
+	Identities map[int64]int64
+	// Panelists holds the users each later panel wave is open to, by survey
+	Panelists map[int64][]int64
+}
+
+func NewStubResponseStore() *StubResponseStore {
+	return &StubResponseStore{
+		Surveys:    make(map[int64]database.Survey),
+		Responses:  make(map[int64]database.Response),
+		Answers:    make(map[int64][]responses.AnswerBody),
+		Audiences:  make(map[int64]surveys.Audience),
+		Submitted:  make(map[int64]int32),
+		Held:       make(map[int64][]int64),
+		Identities: make(map[int64]int64),
+	}
+}
+
+func respondent(id int64) pgtype.Int8 {
+	return pgtype.Int8{Int64: id, Valid: true}
+}
+
+// owner is who started a response, read from its identity when it is anonymous.
+func (s *StubResponseStore) owner(response database.Response) int64 {
+	if response.RespondentID.Valid {
+		return response.RespondentID.Int64
+	}
+
+	return s.Identities[response.ID]
+}
+
+func (s *StubResponseStore) GetPublishedSurvey(ctx context.Context, surveyID int64) (database.Survey, error) {
+	survey, exists := s.Surveys[surveyID]
+	if !exists || survey.Status != database.SurveyStatusPublished {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubResponseStore) CanAnswerSurvey(ctx context.Context, surveyID, respondentID int64) (bool, error) {
+	panelists, wave := s.Panelists[surveyID]
+	return !wave || slices.Contains(panelists, respondentID), nil
+}
+
+func (s *StubResponseStore) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	return s.Questions, nil
+}
+
+func (s *StubResponseStore) GetAudience(ctx context.Context, userID int64) (surveys.Audience, error) {
+	return s.Audiences[userID], nil
+}
+
+func (s *StubResponseStore) PreferredLocale(ctx context.Context, userID int64) (string, error) {
+	return s.Audiences[userID].Profile.Locale.String, nil
+}
+
+func (s *StubResponseStore) ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error) {
+	return s.Cells, nil
+}
+
+func (s *StubResponseStore) CreateResponse(ctx context.Context, surveyID, respondentID int64, cellIDs []int64) (database.Response, error) {
+	for _, response := range s.Responses {
+		if response.SurveyID == surveyID && s.owner(response) == respondentID && !response.IsTest {
+			return database.Response{}, custom_errors.ErrConflict
+		}
+	}
+
+	response := database.Response{
+		ID:           int64(len(s.Responses) + 1),
+		SurveyID:     surveyID,
+		RespondentID: respondent(respondentID),
+		Status:       database.ResponseStatusInProgress,
+	}
+
+	if s.Surveys[surveyID].Anonymous {
+		response.RespondentID = pgtype.Int8{}
+		response.Pseudonym = pgtype.Text{String: fmt.Sprintf("r_%016x", response.ID), Valid: true}
+		s.Identities[response.ID] = respondentID
+	}
+
+	s.Responses[response.ID] = response
+	s.Held[response.ID] = cellIDs
+	return response, nil
+}
+
+func (s *StubResponseStore) RestartResponse(ctx context.Context, id, respondentID int64, cellIDs []int64) (database.Response, error) {
+	response, err := s.GetResponse(ctx, id, respondentID)
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	if response.Status != database.ResponseStatusExpired {
+		return database.Response{}, custom_errors.ErrConflict
+	}
+
+	response.Status = database.ResponseStatusInProgress
+	response.LastQuestionID = pgtype.Int8{}
+	s.Responses[id] = response
+	s.Held[id] = cellIDs
+	delete(s.Answers, id)
+	return response, nil
+}
+
+func (s *StubResponseStore) CreateTestResponse(ctx context.Context, surveyID, researcherID int64) (database.Response, error) {
+	response := database.Response{
+		ID:           int64(len(s.Responses) + 1),
+		SurveyID:     surveyID,
+		RespondentID: respondent(researcherID),
+		Status:       database.ResponseStatusInProgress,
+		IsTest:       true,
+	}
+
+	s.Responses[response.ID] = response
+	return response, nil
+}
+
+func (s *StubResponseStore) PurgeTestResponses(ctx context.Context, surveyID int64) (int64, error) {
+	var deleted int64
+	for id, response := range s.Responses {
+		if response.SurveyID == surveyID && response.IsTest {
+			delete(s.Responses, id)
+			delete(s.Answers, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *StubResponseStore) GetResponse(ctx context.Context, id, respondentID int64) (database.Response, error) {
+	response, exists := s.Responses[id]
+	if !exists || s.owner(response) != respondentID {
+		return database.Response{}, custom_errors.ErrNotFound
+	}
+
+	return response, nil
+}
+
+func (s *StubResponseStore) FindResponse(ctx context.Context, surveyID, respondentID int64) (database.Response, error) {
+	for _, response := range s.Responses {
+		if response.SurveyID == surveyID && s.owner(response) == respondentID && !response.IsTest {
+			return response, nil
+		}
+	}
+
+	return database.Response{}, custom_errors.ErrNotFound
+}
+
+func (s *StubResponseStore) ListAnswers(ctx context.Context, responseID int64) ([]database.Answer, error) {
+	var answers []database.Answer
+	for _, answer := range s.Answers[responseID] {
+		answers = append(answers, database.Answer{ResponseID: responseID, QuestionID: answer.QuestionID, Value: answer.Value})
+	}
+
+	return answers, nil
+}
+
+// saveAnswers upserts answers to a response like the answers table does.
+func (s *StubResponseStore) saveAnswers(id int64, answers []responses.AnswerBody) {
+	for _, answer := range answers {
+		replaced := false
+		for i, saved := range s.Answers[id] {
+			if saved.QuestionID == answer.QuestionID {
+				s.Answers[id][i] = answer
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			s.Answers[id] = append(s.Answers[id], answer)
+		}
+	}
+}
+
+func (s *StubResponseStore) SaveAnswers(ctx context.Context, id, respondentID int64, answers []responses.AnswerBody, dropped []int64, lastQuestionID int64) (database.Response, error) {
+	response, err := s.GetResponse(ctx, id, respondentID)
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	if response.Status != database.ResponseStatusInProgress {
+		return database.Response{}, custom_errors.ErrConflict
+	}
+
+	var kept []responses.AnswerBody
+	for _, answer := range s.Answers[id] {
+		if !slices.Contains(dropped, answer.QuestionID) {
+			kept = append(kept, answer)
+		}
+	}
+	s.Answers[id] = kept
+	s.saveAnswers(id, answers)
+
+	response.LastQuestionID = pgtype.Int8{Int64: lastQuestionID, Valid: lastQuestionID != 0}
+	response.ExpiresAt = pgtype.Timestamp{Time: time.Now().Add(72 * time.Hour), Valid: true}
+	s.Responses[id] = response
+	return response, nil
+}
+
+func (s *StubResponseStore) ExpireResponses(ctx context.Context, limit int32) ([]database.Response, error) {
+	var expired []database.Response
+
+	for id, response := range s.Responses {
+		if int32(len(expired)) == limit {
+			break
+		}
+
+		if response.Status != database.ResponseStatusInProgress || !response.ExpiresAt.Valid || response.ExpiresAt.Time.After(time.Now()) {
+			continue
+		}
+
+		response.Status = database.ResponseStatusExpired
+		s.Responses[id] = response
+		delete(s.Held, id)
+		expired = append(expired, response)
+	}
+
+	return expired, nil
+}
+
+func (s *StubResponseStore) SubmitResponse(ctx context.Context, id, respondentID int64, answers []responses.AnswerBody, check bool) (database.Response, error) {
+	response, err := s.GetResponse(ctx, id, respondentID)
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	if response.Status != database.ResponseStatusInProgress {
+		return database.Response{}, custom_errors.ErrConflict
+	}
+
+	previous := response
+
+	response.Status = database.ResponseStatusSubmitted
+	response.ReviewStatus = database.NullReviewStatus{ReviewStatus: database.ReviewStatusPending, Valid: true}
+	s.Responses[id] = response
+
+	if check {
+		response, err = s.CheckResponse(ctx, id)
+		if err != nil {
+			// the submission is rolled back with the checks
+			s.Responses[id] = previous
+			return database.Response{}, err
+		}
+	}
+
+	s.saveAnswers(id, answers)
+	return response, nil
+}
+
+func (s *StubResponseStore) CheckResponse(ctx context.Context, id int64) (database.Response, error) {
+	response, exists := s.Responses[id]
+	if !exists {
+		return database.Response{}, custom_errors.ErrNotFound
+	}
+
+	if response.ReviewStatus.ReviewStatus != database.ReviewStatusPending {
+		return response, nil
+	}
+
+	status := database.ReviewStatusApproved
+	if s.Flagged {
+		status = database.ReviewStatusNeedsReview
+	}
+
+	if status == database.ReviewStatusApproved && s.Exhausted && !response.IsTest {
+		return database.Response{}, custom_errors.ErrBudgetExhausted
+	}
+
+	response.ReviewStatus = database.NullReviewStatus{ReviewStatus: status, Valid: true}
+	s.Responses[id] = response
+
+	// test responses never move money
+	if status == database.ReviewStatusApproved && !response.IsTest {
+		s.Paid = append(s.Paid, id)
+	}
+	return response, nil
+}
+
+func (s *StubResponseStore) GetSurvey(ctx context.Context, surveyID, researcherID int64) (database.Survey, error) {
+	survey, exists := s.Surveys[surveyID]
+	if !exists || survey.ResearcherID != researcherID {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubResponseStore) ListReviews(ctx context.Context, surveyID int64, filter responses.ReviewFilter) ([]database.ListResponsesForReviewRow, error) {
+	var rows []database.ListResponsesForReviewRow
+
+	for id := int64(1); id <= int64(len(s.Responses)) && int32(len(rows)) < filter.Limit; id++ {
+		response, exists := s.Responses[id]
+		if !exists || response.SurveyID != surveyID || response.ID <= filter.After || !response.ReviewStatus.Valid {
+			continue
+		}
+
+		review := response.ReviewStatus.ReviewStatus
+		if filter.Status == "" && review != database.ReviewStatusPending && review != database.ReviewStatusNeedsReview {
+			continue
+		}
+		if filter.Status != "" && review != filter.Status {
+			continue
+		}
+
+		rows = append(rows, database.ListResponsesForReviewRow{
+			ID:           response.ID,
+			RespondentID: response.RespondentID,
+			Pseudonym:    response.Pseudonym,
+			ReviewStatus: response.ReviewStatus,
+			Answers:      []byte(`{"1": {"option_id": "bus"}}`),
+		})
+	}
+
+	return rows, nil
+}
+
+func (s *StubResponseStore) ReviewResponses(ctx context.Context, surveyID int64, responseIDs []int64, review responses.Review) ([]database.Response, error) {
+	for _, id := range responseIDs {
+		response, exists := s.Responses[id]
+		if !exists || response.SurveyID != surveyID {
+			return nil, custom_errors.ErrNotFound
+		}
+
+		if status := response.ReviewStatus.ReviewStatus; status != database.ReviewStatusPending && status != database.ReviewStatusNeedsReview {
+			return nil, custom_errors.ErrConflict
+		}
+	}
+
+	var reviewed []database.Response
+	for _, id := range responseIDs {
+		response := s.Responses[id]
+		response.ReviewStatus = database.NullReviewStatus{ReviewStatus: review.Status, Valid: true}
+		response.ReviewReason = pgtype.Text{String: review.Reason, Valid: review.Reason != ""}
+		s.Responses[id] = response
+
+		if review.Status == database.ReviewStatusApproved {
+			s.Paid = append(s.Paid, id)
+		} else {
+			s.Returned = append(s.Returned, id)
+		}
+
+		reviewed = append(reviewed, response)
+	}
+
+	return reviewed, nil
+}
+
+func (s *StubResponseStore) ListStaleReviews(ctx context.Context, olderThanDays, limit int32) ([]database.ListStaleReviewsRow, error) {
+	var rows []database.ListStaleReviewsRow
+
+	for id, days := range s.Submitted {
+		response := s.Responses[id]
+		status := response.ReviewStatus.ReviewStatus
+
+		if days > olderThanDays && (status == database.ReviewStatusPending || status == database.ReviewStatusNeedsReview) {
+			rows = append(rows, database.ListStaleReviewsRow{ID: id, SurveyID: response.SurveyID})
+		}
+	}
+
+	return rows, nil
+}
+
+func (s *StubResponseStore) ScreenOutResponse(ctx context.Context, id, respondentID int64, answers []responses.AnswerBody) (database.Response, error) {
+	response, err := s.GetResponse(ctx, id, respondentID)
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	if response.Status != database.ResponseStatusInProgress {
+		return database.Response{}, custom_errors.ErrConflict
+	}
+
+	response.Status = database.ResponseStatusScreenedOut
+	s.Responses[id] = response
+	s.saveAnswers(id, answers)
+	delete(s.Held, id)
+	return response, nil
+}
+
+type StubQueue struct {
+	Tasks      []queue.Processor
+	ShouldFail bool
+}
+
+func (q *StubQueue) Enqueue(processor queue.Processor) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+	q.Tasks = append(q.Tasks, processor)
+	return nil
+}
+
+func (q *StubQueue) Dequeue(processor queue.Scheduled) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+	return nil
+}
+
+// ============================================================================
+// Test Helpers
+// ============================================================================
+
+func assertResponseCode(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("response code = %d, want %d", got, want)
+	}
+}
+
+func newRequest(method string, body []byte, userID int, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/surveys/1/responses", bytes.NewBuffer(body))
+
+	claims := &tokens.Claims{
+		UserID: userID,
+		Email:  "respondent@example.com",
+		Role:   "user",
+	}
+	ctx := context.WithValue(req.Context(), "claims", claims)
+
+	routeCtx := chi.NewRouteContext()
+	for key, value := range params {
+		routeCtx.URLParams.Add(key, value)
+	}
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+
+	return req.WithContext(ctx)
+}
+
+func newPublishedStore() *StubResponseStore {
+	store := NewStubResponseStore()
+	store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 9, Title: "Commute", Status: database.SurveyStatusPublished}
+	store.Questions = []database.Question{
+		{
+			ID:       1,
+			SurveyID: 1,
+			Type:     database.QuestionTypeSingleChoice,
+			Title:    "How do you get to campus?",
+			Required: true,
+			Config:   []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}]}`),
+		},
+		{
+			ID:       2,
+			SurveyID: 1,
+			Type:     database.QuestionTypeNumeric,
+			Title:    "How many minutes does it take?",
+			Required: false,
+			Config:   []byte(`{"min": 0, "max": 300}`),
+		},
+	}
+
+	return store
+}
+
+// newScreenedStore puts a screener in front of the published survey that screens out everyone
+// who walks to campus.
+func newScreenedStore() *StubResponseStore {
+	store := newPublishedStore()
+	store.Questions[0].Screener = true
+	store.Questions[0].Logic = []byte(`{"screen_out_if": {"question_id": 1, "operator": "equals", "value": "walk"}}`)
+	store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+
+	return store
+}
+
+// ============================================================================
+// StartResponseHandler Tests
+// ============================================================================
+
+func TestStartResponseHandler(t *testing.T) {
+
+	t.Run("starts a response to a published survey", func(t *testing.T) {
+		store := newPublishedStore()
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if len(store.Responses) != 1 {
+			t.Fatalf("expected 1 response, got %d", len(store.Responses))
+		}
+	})
+
+	t.Run("shows the survey in the language the browser asks for", func(t *testing.T) {
+		store := newPublishedStore()
+		survey := store.Surveys[1]
+		survey.DefaultLocale, survey.Locales = "en", []string{"fr"}
+		survey.Translations = []byte(`{"fr": {"title": "Trajet"}}`)
+		store.Surveys[1] = survey
+		store.Questions[0].Translations = []byte(`{"fr": {"title": "Comment venez-vous ?", "options": {"bus": "Autobus", "walk": "À pied"}}}`)
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		req.Header.Set("Accept-Language", "fr-FR,fr;q=0.9,en;q=0.8")
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		var got struct {
+			Data responses.StartResponseData `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if got.Data.Survey.Title != "Trajet" || got.Data.Survey.Locale != "fr" {
+			t.Errorf("survey = %q in %q, want the French title", got.Data.Survey.Title, got.Data.Survey.Locale)
+		}
+
+		var config surveys.SingleChoiceConfig
+		_ = json.Unmarshal(got.Data.Questions[0].Config, &config)
+
+		if got.Data.Questions[0].Title != "Comment venez-vous ?" || config.Options[0].ID != "bus" || config.Options[0].Label != "Autobus" {
+			t.Errorf("question = %+v, want it in French with the option IDs kept", got.Data.Questions[0])
+		}
+	})
+
+	t.Run("returns the in progress response when started again", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+	})
+
+	t.Run("returns 409 once the user has submitted", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusSubmitted}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 403 with a reason when the user is not targeted", func(t *testing.T) {
+		store := newPublishedStore()
+		survey := store.Surveys[1]
+		survey.Targeting = []byte(`{"min_age": 18, "universities": ["University of Lagos"]}`)
+		store.Surveys[1] = survey
+		store.Audiences[1] = surveys.Audience{
+			Profile: database.Profile{
+				DateOfBirth: pgtype.Date{Time: time.Now().AddDate(-16, 0, 0), Valid: true},
+				University:  pgtype.Text{String: "University of Lagos", Valid: true},
+			},
+		}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		var got map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+
+		if message, _ := got["message"].(string); !strings.Contains(message, "aged 18 or older") {
+			t.Errorf("message = %q, want the age reason", message)
+		}
+	})
+
+	t.Run("starts a response for a targeted user", func(t *testing.T) {
+		store := newPublishedStore()
+		survey := store.Surveys[1]
+		survey.Targeting = []byte(`{"min_age": 18, "universities": ["University of Lagos"]}`)
+		store.Surveys[1] = survey
+		store.Audiences[1] = surveys.Audience{
+			Profile: database.Profile{
+				DateOfBirth: pgtype.Date{Time: time.Now().AddDate(-21, 0, 0), Valid: true},
+				University:  pgtype.Text{String: "university of lagos ", Valid: true},
+			},
+		}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+	})
+
+	t.Run("returns 403 when the user's quota cell is full", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Cells = []database.QuotaCell{
+			{ID: 1, SurveyID: 1, Name: "female", Criteria: []byte(`{"genders": ["female"]}`), Capacity: 50, Filled: 50},
+			{ID: 2, SurveyID: 1, Name: "male", Criteria: []byte(`{"genders": ["male"]}`), Capacity: 50, Filled: 12},
+		}
+		store.Audiences[1] = surveys.Audience{
+			Profile: database.Profile{Gender: database.NullGender{Gender: database.GenderFemale, Valid: true}},
+		}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 403 when the survey is a panel wave the user isn't invited to", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Panelists = map[int64][]int64{1: {2}}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+
+		req = newRequest(http.MethodPost, nil, 2, map[string]string{"surveyID": "1"})
+		rec = httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+	})
+
+	t.Run("starts a response when the user's quota cell is open", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Cells = []database.QuotaCell{
+			{ID: 1, SurveyID: 1, Name: "female", Criteria: []byte(`{"genders": ["female"]}`), Capacity: 50, Filled: 50},
+			{ID: 2, SurveyID: 1, Name: "male", Criteria: []byte(`{"genders": ["male"]}`), Capacity: 50, Filled: 12},
+		}
+		store.Audiences[1] = surveys.Audience{
+			Profile: database.Profile{Gender: database.NullGender{Gender: database.GenderMale, Valid: true}},
+		}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if held := store.Held[1]; len(held) != 1 || held[0] != 2 {
+			t.Errorf("held cells = %v, want a slot in the male cell", held)
+		}
+	})
+
+	t.Run("starts an expired response over", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusExpired}
+		store.Answers[1] = []responses.AnswerBody{{QuestionID: 1, Value: json.RawMessage(`{"option_id": "bus"}`)}}
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if store.Responses[1].Status != database.ResponseStatusInProgress || len(store.Answers[1]) != 0 {
+			t.Errorf("response = %+v with answers %v, want it in progress without answers", store.Responses[1], store.Answers[1])
+		}
+	})
+
+	t.Run("returns 404 for a draft survey", func(t *testing.T) {
+		store := newPublishedStore()
+		survey := store.Surveys[1]
+		survey.Status = database.SurveyStatusDraft
+		store.Surveys[1] = survey
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.StartResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// SubmitResponseHandler Tests
+// ============================================================================
+
+func TestSubmitResponseHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "responseID": "1"}
+
+	t.Run("records a screened out respondent instead of submitting", func(t *testing.T) {
+		store := newScreenedStore()
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if status := store.Responses[1].Status; status != database.ResponseStatusScreenedOut {
+			t.Errorf("response status = %s, want screened_out", status)
+		}
+	})
+
+	t.Run("submits valid answers", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}, {"question_id": 2, "value": {"value": 25}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if store.Responses[1].Status != database.ResponseStatusSubmitted {
+			t.Errorf("status = %s, want submitted", store.Responses[1].Status)
+		}
+
+		if review := store.Responses[1].ReviewStatus.ReviewStatus; review != database.ReviewStatusApproved {
+			t.Errorf("review status = %s, want approved", review)
+		}
+	})
+
+	t.Run("leaves a flagged response for review", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		store.Flagged = true
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data responses.Response `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.Data.ReviewStatus != string(database.ReviewStatusNeedsReview) {
+			t.Errorf("review status = %q, want needs_review", got.Data.ReviewStatus)
+		}
+	})
+
+	t.Run("queues the quality checks when there is a queue", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		tasks := &StubQueue{}
+		handler := &responses.Handler{Store: store, Queue: tasks}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if review := store.Responses[1].ReviewStatus.ReviewStatus; review != database.ReviewStatusPending {
+			t.Errorf("review status = %s, want pending", review)
+		}
+
+		if len(tasks.Tasks) != 1 {
+			t.Fatalf("got %d tasks, want 1", len(tasks.Tasks))
+		}
+
+		if payload, ok := tasks.Tasks[0].(*queue.ResponseQualityPayload); !ok || payload.ResponseID != 1 {
+			t.Errorf("task = %+v, want a quality check of response 1", tasks.Tasks[0])
+		}
+	})
+
+	t.Run("returns 400 with field errors for bad answers", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 2, "value": {"value": 900}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		var got map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		message, _ := got["message"].(string)
+		for _, want := range []string{"answers[1]: question 1 is required", "answers[2].value: value must be less than or equal to 300"} {
+			if !strings.Contains(message, want) {
+				t.Errorf("message = %q, want it to contain %q", message, want)
+			}
+		}
+	})
+
+	t.Run("returns 409 for a second submission", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusSubmitted}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 409 when the survey can't pay another reward", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		store.Exhausted = true
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+
+		if store.Responses[1].Status != database.ResponseStatusInProgress {
+			t.Errorf("status = %s, want in_progress", store.Responses[1].Status)
+		}
+	})
+
+	t.Run("returns 404 for another user's response", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(2), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}]}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.SubmitResponseHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// NextQuestionHandler Tests
+// ============================================================================
+
+func TestNextQuestionHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "responseID": "1"}
+
+	t.Run("returns the question after the current one", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.NextQuestionHandler(rec, req)
+
+		var got struct {
+			Data responses.NextQuestionData `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got.Data.Done || got.Data.Question == nil || got.Data.Question.ID != 2 {
+			t.Errorf("next = %+v, want question 2", got.Data)
+		}
+	})
+
+	t.Run("ends the attempt when a screener disqualifies the respondent", func(t *testing.T) {
+		store := newScreenedStore()
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "walk"}}], "after": 1}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.NextQuestionHandler(rec, req)
+
+		var got struct {
+			Data responses.NextQuestionData `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !got.Data.Done || !got.Data.ScreenedOut || got.Data.Question != nil {
+			t.Fatalf("next = %+v, want the attempt screened out", got.Data)
+		}
+
+		if status := store.Responses[1].Status; status != database.ResponseStatusScreenedOut {
+			t.Errorf("response status = %s, want screened_out", status)
+		}
+	})
+
+	t.Run("carries on when the screener passes", func(t *testing.T) {
+		store := newScreenedStore()
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.NextQuestionHandler(rec, req)
+
+		var got struct {
+			Data responses.NextQuestionData `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got.Data.ScreenedOut || got.Data.Question == nil || got.Data.Question.ID != 2 {
+			t.Errorf("next = %+v, want question 2", got.Data)
+		}
+	})
+
+	t.Run("returns 409 once the response is screened out", func(t *testing.T) {
+		store := newScreenedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusScreenedOut}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "bus"}}], "after": 1}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.NextQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 400 for an invalid answer so far", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress}
+		handler := &responses.Handler{Store: store}
+
+		data := []byte(`{"answers": [{"question_id": 1, "value": {"option_id": "train"}}], "after": 1}`)
+		req := newRequest(http.MethodPost, data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.NextQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
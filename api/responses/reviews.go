@@ -0,0 +1,268 @@
+package responses
+
+import (
+	"context"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultReviewLimit = 50
+	MaxReviewLimit     = 100
+)
+
+// ParseReviewFilter reads which responses to list for review from the query string: review_status,
+// after, the ID of the last response of the previous page, and limit.
+func ParseReviewFilter(query url.Values) (ReviewFilter, error) {
+	filter := ReviewFilter{Limit: DefaultReviewLimit}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	if status := query.Get("review_status"); status != "" {
+		if err := jsonutil.ValidateField("review_status", status, "oneof=pending needs_review approved rejected"); err != nil {
+			validationErrors = append(validationErrors, err.(jsonutil.ValidationErrors)...)
+		}
+		filter.Status = database.ReviewStatus(status)
+	}
+
+	if after := query.Get("after"); after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || id < 0 {
+			validationErrors = append(validationErrors, "after: after must be a response id")
+		}
+		filter.After = id
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxReviewLimit {
+			validationErrors = append(validationErrors, fmt.Sprintf("limit: limit must be a number between 1 and %d", MaxReviewLimit))
+		}
+		filter.Limit = int32(n)
+	}
+
+	if len(validationErrors) > 0 {
+		return ReviewFilter{}, validationErrors
+	}
+
+	return filter, nil
+}
+
+// NewReview turns a researcher's decision into the review it settles a response with.
+func NewReview(decision, reason string, reviewerID int64) (Review, error) {
+	review := Review{
+		Status:     database.ReviewStatusApproved,
+		Reason:     strings.TrimSpace(reason),
+		ReviewerID: reviewerID,
+	}
+
+	if decision == "reject" {
+		review.Status = database.ReviewStatusRejected
+
+		// a reason of only spaces gets past the body's validation
+		if review.Reason == "" {
+			return Review{}, jsonutil.ValidationErrors{"reason: reason is required when decision is reject"}
+		}
+	}
+
+	return review, nil
+}
+
+// reviewedSurvey loads the survey named in the URL for the researcher reviewing its responses or
+// walking through it. When it can't, it writes the error response itself and returns false.
+func (h *Handler) reviewedSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Survey, int64, bool) {
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return database.Survey{}, 0, false
+	}
+
+	surveyID, err := strconv.ParseInt(chi.URLParam(request, "surveyID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.Survey{}, 0, false
+	}
+
+	survey, err := h.Store.GetSurvey(ctx, surveyID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.Survey{}, 0, false
+	}
+
+	return survey, int64(userID), true
+}
+
+func (h *Handler) ListReviewsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, _, ok := h.reviewedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	filter, err := ParseReviewFilter(request.URL.Query())
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.Store.ListReviews(ctx, survey.ID, filter)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	page, err := NewReviewPage(rows, filter.Limit)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved responses successfully",
+		Data:    page,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) ReviewResponseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	data, err := jsonutil.UnmarshalJsonResponse[ReviewBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, reviewerID, ok := h.reviewedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	responseID, err := strconv.ParseInt(chi.URLParam(request, "responseID"), 10, 64)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid response id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	review, err := NewReview(data.Decision, data.Reason, reviewerID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	reviewed, err := h.Store.ReviewResponses(ctx, survey.ID, []int64{responseID}, review)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "response reviewed successfully",
+		Data:    NewResponse(reviewed[0]),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// BulkReviewHandler applies one decision to several responses. Either all of them are reviewed
+// or, when any can't be, none are.
+func (h *Handler) BulkReviewHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	data, err := jsonutil.UnmarshalJsonResponse[BulkReviewBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, reviewerID, ok := h.reviewedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	review, err := NewReview(data.Decision, data.Reason, reviewerID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	reviewed, err := h.Store.ReviewResponses(ctx, survey.ID, data.ResponseIDs, review)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "responses reviewed successfully",
+		Data:    NewResponses(reviewed),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
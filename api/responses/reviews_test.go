@@ -0,0 +1,222 @@
+package responses_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/database"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const researcherID = 9
+
+// newReviewStore holds three submitted responses to the published survey: one flagged by the
+// quality checks, one still pending them and one approved already.
+func newReviewStore() *StubResponseStore {
+	store := newPublishedStore()
+
+	for id, review := range map[int64]database.ReviewStatus{
+		1: database.ReviewStatusNeedsReview,
+		2: database.ReviewStatusPending,
+		3: database.ReviewStatusApproved,
+	} {
+		store.Responses[id] = database.Response{
+			ID:           id,
+			SurveyID:     1,
+			RespondentID: respondent(id + 100),
+			Status:       database.ResponseStatusSubmitted,
+			ReviewStatus: database.NullReviewStatus{ReviewStatus: review, Valid: true},
+		}
+	}
+
+	return store
+}
+
+func TestListReviewsHandler(t *testing.T) {
+
+	list := func(store *StubResponseStore, target string, userID int) (*httptest.ResponseRecorder, responses.ReviewPage) {
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, nil, userID, map[string]string{"surveyID": "1"})
+		req.URL.RawQuery = target
+		rec := httptest.NewRecorder()
+
+		handler.ListReviewsHandler(rec, req)
+
+		var got struct {
+			Data responses.ReviewPage `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		return rec, got.Data
+	}
+
+	t.Run("lists the responses awaiting review", func(t *testing.T) {
+		rec, page := list(newReviewStore(), "", researcherID)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if len(page.Responses) != 2 || page.Responses[0].ID != 1 || page.Responses[1].ID != 2 {
+			t.Fatalf("responses = %+v, want 1 and 2", page.Responses)
+		}
+
+		if _, ok := page.Responses[0].Answers["1"]; !ok {
+			t.Errorf("answers = %v, want the answer to question 1", page.Responses[0].Answers)
+		}
+	})
+
+	t.Run("pages through the responses", func(t *testing.T) {
+		_, page := list(newReviewStore(), "limit=1", researcherID)
+
+		if len(page.Responses) != 1 || page.NextAfter != 1 {
+			t.Fatalf("page = %+v, want response 1 and a next page", page)
+		}
+
+		_, page = list(newReviewStore(), "limit=1&after=1", researcherID)
+
+		if len(page.Responses) != 1 || page.Responses[0].ID != 2 {
+			t.Errorf("page = %+v, want response 2", page)
+		}
+	})
+
+	t.Run("filters by review status", func(t *testing.T) {
+		_, page := list(newReviewStore(), "review_status=approved", researcherID)
+
+		if len(page.Responses) != 1 || page.Responses[0].ID != 3 {
+			t.Errorf("responses = %+v, want 3", page.Responses)
+		}
+	})
+
+	t.Run("returns 400 for an unknown review status", func(t *testing.T) {
+		rec, _ := list(newReviewStore(), "review_status=maybe", researcherID)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		rec, _ := list(newReviewStore(), "", 2)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+func TestReviewResponseHandler(t *testing.T) {
+
+	review := func(store *StubResponseStore, responseID string, body string) *httptest.ResponseRecorder {
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, []byte(body), researcherID, map[string]string{"surveyID": "1", "responseID": responseID})
+		rec := httptest.NewRecorder()
+
+		handler.ReviewResponseHandler(rec, req)
+		return rec
+	}
+
+	t.Run("approves a flagged response and pays it", func(t *testing.T) {
+		store := newReviewStore()
+
+		rec := review(store, "1", `{"decision": "approve"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if status := store.Responses[1].ReviewStatus.ReviewStatus; status != database.ReviewStatusApproved {
+			t.Errorf("review status = %s, want approved", status)
+		}
+
+		if !reflect.DeepEqual(store.Paid, []int64{1}) {
+			t.Errorf("paid = %v, want response 1", store.Paid)
+		}
+	})
+
+	t.Run("rejects a response and returns its reward", func(t *testing.T) {
+		store := newReviewStore()
+
+		rec := review(store, "1", `{"decision": "reject", "reason": "Answers contradict each other"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if response := store.Responses[1]; response.ReviewStatus.ReviewStatus != database.ReviewStatusRejected || response.ReviewReason.String != "Answers contradict each other" {
+			t.Errorf("response = %+v, want it rejected with the reason", response)
+		}
+
+		if !reflect.DeepEqual(store.Returned, []int64{1}) {
+			t.Errorf("returned = %v, want response 1", store.Returned)
+		}
+	})
+
+	t.Run("returns 400 for a rejection without a reason", func(t *testing.T) {
+		for _, body := range []string{`{"decision": "reject"}`, `{"decision": "reject", "reason": "   "}`} {
+			store := newReviewStore()
+
+			rec := review(store, "1", body)
+
+			assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+			if len(store.Returned) != 0 {
+				t.Errorf("returned = %v, want nothing", store.Returned)
+			}
+		}
+	})
+
+	t.Run("returns 409 for a response reviewed already", func(t *testing.T) {
+		rec := review(newReviewStore(), "3", `{"decision": "reject", "reason": "Too fast"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 404 for a response to another survey", func(t *testing.T) {
+		store := newReviewStore()
+		response := store.Responses[1]
+		response.SurveyID = 2
+		store.Responses[1] = response
+
+		rec := review(store, "1", `{"decision": "approve"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+func TestBulkReviewHandler(t *testing.T) {
+
+	review := func(store *StubResponseStore, body string) *httptest.ResponseRecorder {
+		handler := &responses.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, []byte(body), researcherID, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.BulkReviewHandler(rec, req)
+		return rec
+	}
+
+	t.Run("approves several responses at once", func(t *testing.T) {
+		store := newReviewStore()
+
+		rec := review(store, `{"response_ids": [1, 2], "decision": "approve"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !reflect.DeepEqual(store.Paid, []int64{1, 2}) {
+			t.Errorf("paid = %v, want responses 1 and 2", store.Paid)
+		}
+	})
+
+	t.Run("reviews none when one can't be reviewed", func(t *testing.T) {
+		store := newReviewStore()
+
+		rec := review(store, `{"response_ids": [1, 3], "decision": "reject", "reason": "Straight-lined"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+
+		if status := store.Responses[1].ReviewStatus.ReviewStatus; status != database.ReviewStatusNeedsReview {
+			t.Errorf("review status = %s, want needs_review", status)
+		}
+	})
+
+	t.Run("returns 400 without responses", func(t *testing.T) {
+		rec := review(newReviewStore(), `{"response_ids": [], "decision": "approve"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,99 @@
+package responses
+
+import (
+	"github.com/Adedunmol/answerly/api/middlewares"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log"
+	"os"
+	"strconv"
+)
+
+func SetupRoutes(r *chi.Mux, queue queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	responsesRouter := chi.NewRouter()
+
+	store := NewResponseStore(queries, db)
+	tokenService := tokens.NewTokenService()
+
+	handler := Handler{
+		Store: store,
+	}
+
+	// quality checks run in the background unless they are set to run with the submission
+	if os.Getenv("QUALITY_CHECKS") != "sync" {
+		handler.Queue = queue
+	}
+
+	responsesRouter.Use(middlewares.AuthMiddleware(tokenService))
+
+	responsesRouter.Group(func(respondentRouter chi.Router) {
+		respondentRouter.Use(middlewares.RequireRole("user"))
+
+		respondentRouter.Post("/", handler.StartResponseHandler)
+		respondentRouter.Get("/{responseID}", handler.ResumeResponseHandler)
+		respondentRouter.Put("/{responseID}/answers", handler.SaveAnswersHandler)
+		respondentRouter.Post("/{responseID}/next", handler.NextQuestionHandler)
+		respondentRouter.Post("/{responseID}/submit", handler.SubmitResponseHandler)
+	})
+
+	responsesRouter.Group(func(researcherRouter chi.Router) {
+		researcherRouter.Use(middlewares.RequireRole("researcher"))
+
+		researcherRouter.Get("/", handler.ListReviewsHandler)
+		researcherRouter.Post("/review", handler.BulkReviewHandler)
+		researcherRouter.Post("/{responseID}/review", handler.ReviewResponseHandler)
+	})
+
+	r.Mount("/surveys/{surveyID}/responses", responsesRouter)
+
+	// researchers walk through their own surveys as respondents would, with test responses that
+	// never touch money, quotas or results
+	previewHandler := Handler{
+		Store:   store,
+		Preview: true,
+	}
+
+	previewRouter := chi.NewRouter()
+
+	previewRouter.Use(middlewares.AuthMiddleware(tokenService))
+	previewRouter.Use(middlewares.RequireRole("researcher"))
+
+	previewRouter.Post("/", previewHandler.StartTestResponseHandler)
+	previewRouter.Delete("/", previewHandler.PurgeTestResponsesHandler)
+	previewRouter.Get("/{responseID}", previewHandler.ResumeResponseHandler)
+	previewRouter.Put("/{responseID}/answers", previewHandler.SaveAnswersHandler)
+	previewRouter.Post("/{responseID}/next", previewHandler.NextQuestionHandler)
+	previewRouter.Post("/{responseID}/submit", previewHandler.SubmitResponseHandler)
+
+	r.Mount("/surveys/{surveyID}/preview/responses", previewRouter)
+
+	return
+}
+
+func SetupTasks(worker queue.Worker, db *pgxpool.Pool, queries *database.Queries) {
+
+	handler := TaskHandler{
+		Store:           NewResponseStore(queries, db),
+		AutoApproveDays: DefaultAutoApproveDays,
+	}
+
+	if days, err := strconv.Atoi(os.Getenv("REVIEW_AUTO_APPROVE_DAYS")); err == nil && days > 0 {
+		handler.AutoApproveDays = int32(days)
+	}
+
+	worker.HandleFunc(queue.TypeResponseQualityCheck, handler.HandleQualityCheckTask)
+	worker.HandleFunc(queue.TypeReviewAutoApproval, handler.HandleAutoApprovalTask)
+	worker.HandleFunc(queue.TypeResponseExpiry, handler.HandleExpiryTask)
+
+	if err := worker.Schedule("@hourly", &queue.ReviewAutoApprovalPayload{}); err != nil {
+		log.Printf("error scheduling review auto-approval: %s", err)
+	}
+
+	if err := worker.Schedule("@every 15m", &queue.ResponseExpiryPayload{}); err != nil {
+		log.Printf("error scheduling response expiry: %s", err)
+	}
+}
@@ -0,0 +1,880 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/payouts"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type Store interface {
+	GetPublishedSurvey(ctx context.Context, surveyID int64) (database.Survey, error)
+	CanAnswerSurvey(ctx context.Context, surveyID, respondentID int64) (bool, error)
+	ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error)
+	GetAudience(ctx context.Context, userID int64) (surveys.Audience, error)
+	PreferredLocale(ctx context.Context, userID int64) (string, error)
+	ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error)
+	CreateResponse(ctx context.Context, surveyID, respondentID int64, cellIDs []int64) (database.Response, error)
+	RestartResponse(ctx context.Context, id, respondentID int64, cellIDs []int64) (database.Response, error)
+	CreateTestResponse(ctx context.Context, surveyID, researcherID int64) (database.Response, error)
+	PurgeTestResponses(ctx context.Context, surveyID int64) (int64, error)
+	GetResponse(ctx context.Context, id, respondentID int64) (database.Response, error)
+	FindResponse(ctx context.Context, surveyID, respondentID int64) (database.Response, error)
+	ListAnswers(ctx context.Context, responseID int64) ([]database.Answer, error)
+	SaveAnswers(ctx context.Context, id, respondentID int64, answers []AnswerBody, dropped []int64, lastQuestionID int64) (database.Response, error)
+	ExpireResponses(ctx context.Context, limit int32) ([]database.Response, error)
+	SubmitResponse(ctx context.Context, id, respondentID int64, answers []AnswerBody, check bool) (database.Response, error)
+	CheckResponse(ctx context.Context, id int64) (database.Response, error)
+	GetSurvey(ctx context.Context, surveyID, researcherID int64) (database.Survey, error)
+	ListReviews(ctx context.Context, surveyID int64, filter ReviewFilter) ([]database.ListResponsesForReviewRow, error)
+	ReviewResponses(ctx context.Context, surveyID int64, responseIDs []int64, review Review) ([]database.Response, error)
+	ListStaleReviews(ctx context.Context, olderThanDays, limit int32) ([]database.ListStaleReviewsRow, error)
+	ScreenOutResponse(ctx context.Context, id, respondentID int64, answers []AnswerBody) (database.Response, error)
+}
+
+const UniqueViolation = "23505"
+
+type Repository struct {
+	queries    *database.Queries
+	db         *pgxpool.Pool
+	transactor database.Transactor
+	payouts    payouts.Store
+	surveys    surveys.Store
+	checks     []surveys.QualityCheck
+}
+
+func NewResponseStore(queries *database.Queries, db *pgxpool.Pool) *Repository {
+
+	return &Repository{
+		queries:    queries,
+		db:         db,
+		transactor: database.NewDBTransactor(db),
+		payouts:    payouts.NewPayoutStore(queries, db),
+		surveys:    surveys.NewSurveyStore(queries, db),
+		checks:     surveys.QualityChecks,
+	}
+}
+
+func (r *Repository) GetPublishedSurvey(ctx context.Context, surveyID int64) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.GetPublishedSurvey(ctx, surveyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error getting survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+// CanAnswerSurvey reports whether a respondent may answer a survey as far as panels go. The waves of
+// a panel after the first are only open to the participants invited from an earlier one.
+func (r *Repository) CanAnswerSurvey(ctx context.Context, surveyID, respondentID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	allowed, err := r.queries.CanAnswerSurvey(ctx, database.CanAnswerSurveyParams{
+		SurveyID:     surveyID,
+		RespondentID: respondentID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error checking panel participation: %v", err)
+	}
+
+	return allowed, nil
+}
+
+func (r *Repository) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	questions, err := r.queries.ListQuestionsBySurvey(ctx, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing questions: %v", err)
+	}
+
+	return questions, nil
+}
+
+// GetAudience loads what a survey's targeting rules are checked against.
+func (r *Repository) GetAudience(ctx context.Context, userID int64) (surveys.Audience, error) {
+	return r.surveys.GetAudience(ctx, userID)
+}
+
+// PreferredLocale returns the locale a user would rather answer surveys in, empty when they have
+// not picked one.
+func (r *Repository) PreferredLocale(ctx context.Context, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	profile, err := r.queries.GetProfile(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting profile: %v", err)
+	}
+
+	return profile.Locale.String, nil
+}
+
+func (r *Repository) ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error) {
+	return r.surveys.ListQuotaCells(ctx, surveyID)
+}
+
+// CreateResponse starts a response and holds a slot for it in each of the given quota cells in one
+// transaction, so a respondent is never let in without the slots their response will fill. A
+// response to an anonymous survey is stored under a fresh pseudonym, with the respondent kept in
+// its identity and their demographics copied as they are now. It fails with
+// custom_errors.ErrConflict when the respondent already has a response to the survey and with
+// custom_errors.ErrQuotaFull when one of the cells filled up since it was read.
+func (r *Repository) CreateResponse(ctx context.Context, surveyID, respondentID int64, cellIDs []int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		pseudonym, err := surveys.NewPseudonym()
+		if err != nil {
+			return err
+		}
+
+		response, err = q.CreateResponse(ctx, database.CreateResponseParams{
+			RespondentID: respondentID,
+			Pseudonym:    pseudonym,
+			SurveyID:     surveyID,
+		})
+		if err != nil {
+			var e *pgconn.PgError
+			if errors.As(err, &e) && e.Code == UniqueViolation {
+				return custom_errors.ErrConflict
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error creating response: %v", err)
+		}
+
+		if response.Pseudonym.Valid {
+			if err := recordIdentity(ctx, q, response, respondentID); err != nil {
+				return err
+			}
+		}
+
+		return holdQuotaCells(ctx, q, response.ID, cellIDs)
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// RestartResponse starts an expired response over, deleting the answers it was left with and
+// holding its quota slots again. It fails like CreateResponse does, and with
+// custom_errors.ErrConflict when the response hasn't expired.
+func (r *Repository) RestartResponse(ctx context.Context, id, respondentID int64, cellIDs []int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		response, err = q.RestartResponse(ctx, database.RestartResponseParams{
+			ID:           id,
+			RespondentID: respondentID,
+		})
+		if err != nil {
+			// another request restarted it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error restarting response: %v", err)
+		}
+
+		if err := q.DeleteAnswersByResponse(ctx, id); err != nil {
+			return fmt.Errorf("error deleting answers: %v", err)
+		}
+
+		return holdQuotaCells(ctx, q, response.ID, cellIDs)
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// CreateTestResponse starts a test response to a survey for the researcher walking through it. Test
+// responses hold no quota slots and a researcher can start as many as they like.
+func (r *Repository) CreateTestResponse(ctx context.Context, surveyID, researcherID int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	response, err := r.queries.CreateTestResponse(ctx, database.CreateTestResponseParams{
+		RespondentID: researcherID,
+		SurveyID:     surveyID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Response{}, custom_errors.ErrNotFound
+		}
+		return database.Response{}, fmt.Errorf("error creating test response: %v", err)
+	}
+
+	return response, nil
+}
+
+// PurgeTestResponses deletes every test response to a survey with its answers and returns how many
+// there were.
+func (r *Repository) PurgeTestResponses(ctx context.Context, surveyID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	deleted, err := r.queries.DeleteTestResponses(ctx, surveyID)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting test responses: %v", err)
+	}
+
+	return deleted, nil
+}
+
+// recordIdentity keeps who started a response to an anonymous survey apart from the response, with
+// a copy of their demographics for its results.
+func recordIdentity(ctx context.Context, q *database.Queries, response database.Response, respondentID int64) error {
+	err := q.CreateRespondentIdentity(ctx, database.CreateRespondentIdentityParams{
+		ResponseID:   response.ID,
+		SurveyID:     response.SurveyID,
+		RespondentID: respondentID,
+	})
+	if err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.Code == UniqueViolation {
+			return custom_errors.ErrConflict
+		}
+		return fmt.Errorf("error recording respondent identity: %v", err)
+	}
+
+	err = q.CreateResponseProfile(ctx, database.CreateResponseProfileParams{
+		ResponseID:   response.ID,
+		RespondentID: respondentID,
+	})
+	if err != nil {
+		return fmt.Errorf("error copying respondent profile: %v", err)
+	}
+
+	return nil
+}
+
+// holdQuotaCells holds a slot in each of the cells for a response in progress.
+func holdQuotaCells(ctx context.Context, q *database.Queries, responseID int64, cellIDs []int64) error {
+	if len(cellIDs) == 0 {
+		return nil
+	}
+
+	held, err := q.HoldQuotaCells(ctx, database.HoldQuotaCellsParams{
+		Ids:        cellIDs,
+		ResponseID: responseID,
+	})
+	if err != nil {
+		return fmt.Errorf("error holding quota cells: %v", err)
+	}
+
+	// a cell that filled up since it was read is left out, the whole start is rolled back
+	if len(held) != len(cellIDs) {
+		return custom_errors.ErrQuotaFull
+	}
+
+	return nil
+}
+
+func (r *Repository) GetResponse(ctx context.Context, id, respondentID int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	response, err := r.queries.GetResponse(ctx, database.GetResponseParams{
+		ID:           id,
+		RespondentID: respondentID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Response{}, custom_errors.ErrNotFound
+		}
+		return database.Response{}, fmt.Errorf("error getting response: %v", err)
+	}
+
+	return response, nil
+}
+
+func (r *Repository) FindResponse(ctx context.Context, surveyID, respondentID int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	response, err := r.queries.GetResponseBySurveyAndRespondent(ctx, database.GetResponseBySurveyAndRespondentParams{
+		SurveyID:     surveyID,
+		RespondentID: respondentID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Response{}, custom_errors.ErrNotFound
+		}
+		return database.Response{}, fmt.Errorf("error getting response: %v", err)
+	}
+
+	return response, nil
+}
+
+func (r *Repository) ListAnswers(ctx context.Context, responseID int64) ([]database.Answer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	answers, err := r.queries.ListAnswersByResponse(ctx, responseID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing answers: %v", err)
+	}
+
+	return answers, nil
+}
+
+// SaveAnswers stores a page of answers to a response in progress, deletes the saved answers that
+// were dropped from it and records the last question the respondent reached, in one transaction.
+// Saving pushes the expiry of the response back by its survey's resume window.
+//
+// It fails with custom_errors.ErrConflict when the response is no longer in progress.
+func (r *Repository) SaveAnswers(ctx context.Context, id, respondentID int64, answers []AnswerBody, dropped []int64, lastQuestionID int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		// the response row is locked first, so it can't expire or be submitted halfway through
+		response, err = q.SaveResponseProgress(ctx, database.SaveResponseProgressParams{
+			LastQuestionID: pgtype.Int8{Int64: lastQuestionID, Valid: lastQuestionID != 0},
+			ID:             id,
+			RespondentID:   respondentID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error saving response progress: %v", err)
+		}
+
+		if len(dropped) > 0 {
+			err := q.DeleteAnswers(ctx, database.DeleteAnswersParams{
+				ResponseID:  id,
+				QuestionIds: dropped,
+			})
+			if err != nil {
+				return fmt.Errorf("error deleting answers: %v", err)
+			}
+		}
+
+		for _, answer := range answers {
+			err := q.UpsertAnswer(ctx, database.UpsertAnswerParams{
+				ResponseID: id,
+				QuestionID: answer.QuestionID,
+				Value:      answer.Value,
+				Display:    answer.Display,
+			})
+			if err != nil {
+				return fmt.Errorf("error saving answer: %v", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// ExpireResponses expires up to limit responses left in progress past their survey's resume
+// window and frees the quota slots they held, in one transaction.
+func (r *Repository) ExpireResponses(ctx context.Context, limit int32) ([]database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var expired []database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		expired, err = q.ExpireResponses(ctx, limit)
+		if err != nil {
+			return fmt.Errorf("error expiring responses: %v", err)
+		}
+
+		for _, response := range expired {
+			_, err := q.ReleaseQuotaHolds(ctx, database.ReleaseQuotaHoldsParams{
+				ResponseID: response.ID,
+				Fill:       false,
+			})
+			if err != nil {
+				return fmt.Errorf("error releasing quota holds: %v", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+// SubmitResponse stores the answers, marks the response as submitted, counts it toward its quota
+// cells and sets its reward aside in escrow in one transaction, so a response is never left
+// submitted with only part of its answers, uncounted or without the money to pay it. The response
+// is left pending review; with check set, CheckResponse runs in the same transaction, otherwise it
+// is up to the caller to have it run later. Surveys without escrow have no reward to set aside, and
+// test responses neither count toward quotas nor set any reward aside.
+//
+// It fails with custom_errors.ErrQuotaFull when one of the respondent's quota cells filled up
+// since they started and with custom_errors.ErrBudgetExhausted when the escrow can't cover
+// another reward.
+func (r *Repository) SubmitResponse(ctx context.Context, id, respondentID int64, answers []AnswerBody, check bool) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		for _, answer := range answers {
+			err := q.UpsertAnswer(ctx, database.UpsertAnswerParams{
+				ResponseID: id,
+				QuestionID: answer.QuestionID,
+				Value:      answer.Value,
+				Display:    answer.Display,
+			})
+			if err != nil {
+				return fmt.Errorf("error saving answer: %v", err)
+			}
+		}
+
+		var err error
+		response, err = q.SubmitResponse(ctx, database.SubmitResponseParams{
+			ID:           id,
+			RespondentID: respondentID,
+		})
+		if err != nil {
+			// another request submitted it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error submitting response: %v", err)
+		}
+
+		if !response.IsTest {
+			if err := r.fillQuotaCells(ctx, q, response, respondentID); err != nil {
+				return err
+			}
+		}
+
+		_, err = r.payouts.HoldResponse(ctx, response)
+		if err != nil && !errors.Is(err, custom_errors.ErrNotFound) {
+			return err
+		}
+
+		if check {
+			response, err = r.CheckResponse(ctx, response.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// CheckResponse runs the quality checks on a submitted response that is pending review and
+// settles it. A response that passes them all is approved and its reward is paid, one that fails
+// any is flagged as needing review and its reward stays set aside until a researcher reviews it.
+// Either way the report is stored with the response. Surveys without escrow have no reward to pay.
+//
+// A response that was settled already is returned as it is, so running the checks again, as a
+// retried task would, changes nothing.
+func (r *Repository) CheckResponse(ctx context.Context, id int64) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		response, err = q.GetResponseByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error getting response: %v", err)
+		}
+
+		if response.ReviewStatus.ReviewStatus != database.ReviewStatusPending {
+			return nil
+		}
+
+		submission, err := r.submission(ctx, q, response)
+		if err != nil {
+			return err
+		}
+
+		report := surveys.ScoreResponse(submission, r.checks)
+
+		rawReport, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("error encoding quality report: %v", err)
+		}
+
+		status := database.ReviewStatusApproved
+		if report.Flagged {
+			status = database.ReviewStatusNeedsReview
+		}
+
+		settled, err := q.SettleResponseReview(ctx, database.SettleResponseReviewParams{
+			ReviewStatus:  database.NullReviewStatus{ReviewStatus: status, Valid: true},
+			QualityReport: rawReport,
+			ID:            response.ID,
+		})
+		if err != nil {
+			// another run of the checks settled it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				response, err = q.GetResponseByID(ctx, id)
+				if err != nil {
+					return fmt.Errorf("error getting response: %v", err)
+				}
+				return nil
+			}
+			return fmt.Errorf("error settling response: %v", err)
+		}
+		response = settled
+
+		if status != database.ReviewStatusApproved {
+			return nil
+		}
+
+		_, err = r.payouts.PayResponse(ctx, response)
+		if err != nil && !errors.Is(err, custom_errors.ErrNotFound) {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// submission gathers what the quality checks look at for a submitted response.
+func (r *Repository) submission(ctx context.Context, q *database.Queries, response database.Response) (surveys.Submission, error) {
+	survey, err := q.GetSurveyByID(ctx, response.SurveyID)
+	if err != nil {
+		return surveys.Submission{}, fmt.Errorf("error getting survey: %v", err)
+	}
+
+	settings, err := surveys.ParseQualitySettings(survey.QualityChecks)
+	if err != nil {
+		return surveys.Submission{}, err
+	}
+
+	questions, err := q.ListQuestionsBySurvey(ctx, response.SurveyID)
+	if err != nil {
+		return surveys.Submission{}, fmt.Errorf("error listing questions: %v", err)
+	}
+
+	rows, err := q.ListAnswersByResponse(ctx, response.ID)
+	if err != nil {
+		return surveys.Submission{}, fmt.Errorf("error listing answers: %v", err)
+	}
+
+	answers := make(map[int64]json.RawMessage, len(rows))
+	for _, row := range rows {
+		answers[row.QuestionID] = row.Value
+	}
+
+	median, err := q.GetSurveyMedianDuration(ctx, database.GetSurveyMedianDurationParams{
+		SurveyID:  response.SurveyID,
+		ExcludeID: response.ID,
+	})
+	if err != nil {
+		return surveys.Submission{}, fmt.Errorf("error getting median duration: %v", err)
+	}
+
+	return surveys.Submission{
+		Questions:      questions,
+		Answers:        answers,
+		Duration:       response.SubmittedAt.Time.Sub(response.StartedAt.Time),
+		MedianDuration: time.Duration(median.MedianSeconds * float64(time.Second)),
+		Sample:         median.Responses,
+		Settings:       settings,
+	}, nil
+}
+
+// fillQuotaCells counts a submitted response toward every quota cell its respondent falls in.
+// A response holding slots in its cells since it started just turns them into filled ones. Others,
+// started before their survey held slots, are only counted while the cells are below capacity, and
+// the rows are locked while they are, so respondents finishing at the same time can never push a
+// cell past its capacity.
+func (r *Repository) fillQuotaCells(ctx context.Context, q *database.Queries, response database.Response, respondentID int64) error {
+	held, err := q.ReleaseQuotaHolds(ctx, database.ReleaseQuotaHoldsParams{
+		ResponseID: response.ID,
+		Fill:       true,
+	})
+	if err != nil {
+		return fmt.Errorf("error filling held quota cells: %v", err)
+	}
+
+	if len(held) > 0 {
+		return nil
+	}
+
+	cells, err := q.ListQuotaCellsBySurvey(ctx, response.SurveyID)
+	if err != nil {
+		return fmt.Errorf("error listing quota cells: %v", err)
+	}
+
+	if len(cells) == 0 {
+		return nil
+	}
+
+	audience, err := r.surveys.GetAudience(ctx, respondentID)
+	if err != nil {
+		return err
+	}
+
+	matched, err := surveys.MatchQuotaCells(cells, audience, time.Now())
+	if err != nil {
+		if errors.Is(err, surveys.ErrNotEligible) {
+			return custom_errors.ErrQuotaFull
+		}
+		return err
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	cellIDs := make([]int64, 0, len(matched))
+	for _, cell := range matched {
+		cellIDs = append(cellIDs, cell.ID)
+	}
+
+	filled, err := q.FillQuotaCells(ctx, cellIDs)
+	if err != nil {
+		return fmt.Errorf("error filling quota cells: %v", err)
+	}
+
+	// a cell that filled up since it was read is left out, the whole submission is rolled back
+	if len(filled) != len(cellIDs) {
+		return custom_errors.ErrQuotaFull
+	}
+
+	return nil
+}
+
+// ScreenOutResponse ends a response whose screener answers disqualify the respondent. The answers
+// given so far are kept for the survey's stats, the quota slots the response held are freed and
+// the survey's screen-out fee, if it has one, is paid in the same transaction. A survey whose escrow can't cover the fee still screens the
+// respondent out, just without paying.
+func (r *Repository) ScreenOutResponse(ctx context.Context, id, respondentID int64, answers []AnswerBody) (database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var response database.Response
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		for _, answer := range answers {
+			err := q.UpsertAnswer(ctx, database.UpsertAnswerParams{
+				ResponseID: id,
+				QuestionID: answer.QuestionID,
+				Value:      answer.Value,
+				Display:    answer.Display,
+			})
+			if err != nil {
+				return fmt.Errorf("error saving answer: %v", err)
+			}
+		}
+
+		var err error
+		response, err = q.ScreenOutResponse(ctx, database.ScreenOutResponseParams{
+			ID:           id,
+			RespondentID: respondentID,
+		})
+		if err != nil {
+			// another request ended it first
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrConflict
+			}
+			return fmt.Errorf("error screening out response: %v", err)
+		}
+
+		_, err = q.ReleaseQuotaHolds(ctx, database.ReleaseQuotaHoldsParams{
+			ResponseID: response.ID,
+			Fill:       false,
+		})
+		if err != nil {
+			return fmt.Errorf("error releasing quota holds: %v", err)
+		}
+
+		_, err = r.payouts.PayScreenOut(ctx, response)
+		if err != nil && !errors.Is(err, custom_errors.ErrNotFound) && !errors.Is(err, custom_errors.ErrBudgetExhausted) {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Response{}, err
+	}
+
+	return response, nil
+}
+
+// GetSurvey loads a survey of the researcher's, in any status.
+func (r *Repository) GetSurvey(ctx context.Context, surveyID, researcherID int64) (database.Survey, error) {
+	return r.surveys.GetSurvey(ctx, surveyID, researcherID)
+}
+
+func (r *Repository) ListReviews(ctx context.Context, surveyID int64, filter ReviewFilter) ([]database.ListResponsesForReviewRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	params := database.ListResponsesForReviewParams{
+		SurveyID: surveyID,
+		After:    filter.After,
+		PageSize: filter.Limit,
+	}
+
+	if filter.Status != "" {
+		params.ReviewStatus = database.NullReviewStatus{ReviewStatus: filter.Status, Valid: true}
+	}
+
+	rows, err := r.queries.ListResponsesForReview(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("error listing responses for review: %v", err)
+	}
+
+	return rows, nil
+}
+
+// ReviewResponses approves or rejects responses to a survey that are awaiting review, all of them
+// or none. Approving a response pays its reward to the respondent, rejecting it returns the reward
+// to the survey's escrow.
+//
+// It fails with custom_errors.ErrNotFound when a response isn't one of the survey's and with
+// custom_errors.ErrConflict when one was reviewed already.
+func (r *Repository) ReviewResponses(ctx context.Context, surveyID int64, responseIDs []int64, review Review) ([]database.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	reviewed := make([]database.Response, 0, len(responseIDs))
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		for _, id := range responseIDs {
+			response, err := q.ReviewResponse(ctx, database.ReviewResponseParams{
+				ReviewStatus: database.NullReviewStatus{ReviewStatus: review.Status, Valid: true},
+				ReviewReason: pgtype.Text{String: review.Reason, Valid: len(review.Reason) > 0},
+				ReviewedBy:   pgtype.Int8{Int64: review.ReviewerID, Valid: review.ReviewerID != 0},
+				ID:           id,
+				SurveyID:     surveyID,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return r.unreviewable(ctx, q, surveyID, id)
+				}
+				return fmt.Errorf("error reviewing response: %v", err)
+			}
+
+			if review.Status == database.ReviewStatusApproved {
+				_, err = r.payouts.PayResponse(ctx, response)
+			} else {
+				_, err = r.payouts.ReturnResponse(ctx, response)
+			}
+			if err != nil && !errors.Is(err, custom_errors.ErrNotFound) {
+				return err
+			}
+
+			reviewed = append(reviewed, response)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewed, nil
+}
+
+// unreviewable tells why a response could not be reviewed.
+func (r *Repository) unreviewable(ctx context.Context, q *database.Queries, surveyID, id int64) error {
+	response, err := q.GetResponseByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("response %d: %w", id, custom_errors.ErrNotFound)
+		}
+		return fmt.Errorf("error getting response: %v", err)
+	}
+
+	if response.SurveyID != surveyID {
+		return fmt.Errorf("response %d: %w", id, custom_errors.ErrNotFound)
+	}
+
+	return fmt.Errorf("response %d is not awaiting review: %w", id, custom_errors.ErrConflict)
+}
+
+func (r *Repository) ListStaleReviews(ctx context.Context, olderThanDays, limit int32) ([]database.ListStaleReviewsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.queries.ListStaleReviews(ctx, database.ListStaleReviewsParams{
+		OlderThanDays: olderThanDays,
+		PageSize:      limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing stale reviews: %v", err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,124 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/hibiken/asynq"
+	"log"
+)
+
+// DefaultAutoApproveDays is how long a response may wait for review before it is approved
+// without one.
+const DefaultAutoApproveDays = 7
+
+// autoApproveBatch is how many responses one run of the auto-approval job looks at a time.
+const autoApproveBatch = 100
+
+// expiryBatch is how many responses the expiry job expires in one transaction.
+const expiryBatch = 100
+
+type TaskHandler struct {
+	Store Store
+	// AutoApproveDays is how many days a response may wait for review before the auto-approval
+	// job approves it.
+	AutoApproveDays int32
+}
+
+// HandleQualityCheckTask runs the quality checks on a submitted response. A response that no
+// longer exists has nothing left to check, so the task is dropped instead of retried.
+func (h *TaskHandler) HandleQualityCheckTask(ctx context.Context, t *asynq.Task) error {
+	var payload queue.ResponseQualityPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("error decoding response quality payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	response, err := h.Store.CheckResponse(ctx, payload.ResponseID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			log.Printf("response %d to check no longer exists", payload.ResponseID)
+			return nil
+		}
+		return fmt.Errorf("error checking response %d: %w", payload.ResponseID, err)
+	}
+
+	log.Printf("response %d checked: %s", response.ID, response.ReviewStatus.ReviewStatus)
+
+	return nil
+}
+
+// HandleAutoApprovalTask approves the responses that have been awaiting review for longer than
+// AutoApproveDays, so respondents aren't left waiting on researchers who never get to them. Each
+// response is approved on its own, one that can't be doesn't hold up the others.
+func (h *TaskHandler) HandleAutoApprovalTask(ctx context.Context, t *asynq.Task) error {
+	approved, failed := 0, 0
+
+	for {
+		stale, err := h.Store.ListStaleReviews(ctx, h.AutoApproveDays, autoApproveBatch)
+		if err != nil {
+			return err
+		}
+
+		progress := false
+		for _, row := range stale {
+			review := Review{
+				Status: database.ReviewStatusApproved,
+				Reason: fmt.Sprintf("approved automatically after %d days without a review", h.AutoApproveDays),
+			}
+
+			_, err := h.Store.ReviewResponses(ctx, row.SurveyID, []int64{row.ID}, review)
+			if err != nil {
+				// reviewed by a researcher since it was listed
+				if errors.Is(err, custom_errors.ErrConflict) {
+					continue
+				}
+				log.Printf("error auto-approving response %d: %s", row.ID, err)
+				failed++
+				continue
+			}
+
+			approved++
+			progress = true
+		}
+
+		// responses that keep failing would be listed again, so stop once a batch approves none
+		if len(stale) < autoApproveBatch || !progress {
+			break
+		}
+	}
+
+	log.Printf("auto-approved %d responses, %d failed", approved, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("error auto-approving %d responses", failed)
+	}
+
+	return nil
+}
+
+// HandleExpiryTask expires the responses left unfinished past their survey's resume window and
+// frees the quota slots they held, a batch at a time until none are left.
+func (h *TaskHandler) HandleExpiryTask(ctx context.Context, t *asynq.Task) error {
+	expired := 0
+
+	for {
+		responses, err := h.Store.ExpireResponses(ctx, expiryBatch)
+		if err != nil {
+			return err
+		}
+
+		expired += len(responses)
+
+		if len(responses) < expiryBatch {
+			break
+		}
+	}
+
+	log.Printf("expired %d responses", expired)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package responses_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+	"time"
+)
+
+func qualityTask(t *testing.T, responseID int64) *asynq.Task {
+	t.Helper()
+
+	task, err := (&queue.ResponseQualityPayload{ResponseID: responseID}).Process()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return task
+}
+
+func TestHandleQualityCheckTask(t *testing.T) {
+
+	t.Run("settles a pending response", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusSubmitted,
+			ReviewStatus: database.NullReviewStatus{ReviewStatus: database.ReviewStatusPending, Valid: true}}
+		handler := &responses.TaskHandler{Store: store}
+
+		if err := handler.HandleQualityCheckTask(context.Background(), qualityTask(t, 1)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if review := store.Responses[1].ReviewStatus.ReviewStatus; review != database.ReviewStatusApproved {
+			t.Errorf("review status = %s, want approved", review)
+		}
+	})
+
+	t.Run("drops the task of a response that no longer exists", func(t *testing.T) {
+		handler := &responses.TaskHandler{Store: newPublishedStore()}
+
+		if err := handler.HandleQualityCheckTask(context.Background(), qualityTask(t, 1)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("retries when the reward can't be paid", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusSubmitted,
+			ReviewStatus: database.NullReviewStatus{ReviewStatus: database.ReviewStatusPending, Valid: true}}
+		store.Exhausted = true
+		handler := &responses.TaskHandler{Store: store}
+
+		if err := handler.HandleQualityCheckTask(context.Background(), qualityTask(t, 1)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("rejects a malformed payload without retrying", func(t *testing.T) {
+		handler := &responses.TaskHandler{Store: newPublishedStore()}
+
+		task := asynq.NewTask(queue.TypeResponseQualityCheck, json.RawMessage(`{"ResponseID": "one"}`))
+
+		if err := handler.HandleQualityCheckTask(context.Background(), task); !errors.Is(err, asynq.SkipRetry) {
+			t.Errorf("error = %v, want it to skip retries", err)
+		}
+	})
+}
+
+func TestHandleAutoApprovalTask(t *testing.T) {
+
+	t.Run("approves the responses left unreviewed for too long", func(t *testing.T) {
+		store := newReviewStore()
+		store.Submitted = map[int64]int32{1: 10, 2: 3, 3: 30}
+		handler := &responses.TaskHandler{Store: store, AutoApproveDays: 7}
+
+		task, _ := (&queue.ReviewAutoApprovalPayload{}).Process()
+
+		if err := handler.HandleAutoApprovalTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if status := store.Responses[1].ReviewStatus.ReviewStatus; status != database.ReviewStatusApproved {
+			t.Errorf("response 1 review status = %s, want approved", status)
+		}
+
+		if status := store.Responses[2].ReviewStatus.ReviewStatus; status != database.ReviewStatusPending {
+			t.Errorf("response 2 review status = %s, want pending", status)
+		}
+
+		if len(store.Paid) != 1 {
+			t.Errorf("paid = %v, want only response 1", store.Paid)
+		}
+	})
+}
+
+func TestHandleExpiryTask(t *testing.T) {
+
+	t.Run("expires the responses left past their resume window", func(t *testing.T) {
+		store := newPublishedStore()
+		store.Responses[1] = database.Response{ID: 1, SurveyID: 1, RespondentID: respondent(1), Status: database.ResponseStatusInProgress,
+			ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(-time.Hour), Valid: true}}
+		store.Responses[2] = database.Response{ID: 2, SurveyID: 1, RespondentID: respondent(2), Status: database.ResponseStatusInProgress,
+			ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true}}
+		store.Held[1] = []int64{1}
+		handler := &responses.TaskHandler{Store: store}
+
+		task, _ := (&queue.ResponseExpiryPayload{}).Process()
+
+		if err := handler.HandleExpiryTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if status := store.Responses[1].Status; status != database.ResponseStatusExpired {
+			t.Errorf("response 1 status = %s, want expired", status)
+		}
+
+		if status := store.Responses[2].Status; status != database.ResponseStatusInProgress {
+			t.Errorf("response 2 status = %s, want in_progress", status)
+		}
+
+		if _, held := store.Held[1]; held {
+			t.Errorf("expected the quota slot of response 1 to be freed")
+		}
+	})
+}
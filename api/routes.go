@@ -3,6 +3,10 @@ package api
 import (
 	"github.com/Adedunmol/answerly/api/auth"
 	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/organizations"
+	"github.com/Adedunmol/answerly/api/panels"
+	"github.com/Adedunmol/answerly/api/responses"
+	"github.com/Adedunmol/answerly/api/surveys"
 	"github.com/Adedunmol/answerly/database"
 	"github.com/Adedunmol/answerly/queue"
 	"github.com/go-chi/chi/v5"
@@ -34,6 +38,17 @@ func Routes(queries *database.Queries, queue queue.Queue, pool *pgxpool.Pool) *c
 	})
 
 	auth.SetupRoutes(r, queue, pool, queries)
+	surveys.SetupRoutes(r, queue, pool, queries)
+	responses.SetupRoutes(r, queue, pool, queries)
+	panels.SetupRoutes(r, queue, pool, queries)
+	organizations.SetupRoutes(r, queue, pool, queries)
 
 	return r
 }
+
+// Tasks registers the handlers of the background tasks the api enqueues.
+func Tasks(worker queue.Worker, q queue.Queue, queries *database.Queries, pool *pgxpool.Pool) {
+	responses.SetupTasks(worker, pool, queries)
+	surveys.SetupTasks(worker, q, pool, queries)
+	panels.SetupTasks(worker, q, pool, queries)
+}
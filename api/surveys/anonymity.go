@@ -0,0 +1,127 @@
+package surveys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// MinCellSize is the fewest respondents to an anonymous survey a demographic breakdown of its
+// results counts before showing the count. A smaller group could be singled out from the profile
+// attributes it was picked by.
+const MinCellSize = 5
+
+// NewPseudonym returns a random name for a response to an anonymous survey, which the researcher
+// sees in place of the respondent. It says nothing about who they are, and differs from one
+// survey to the next.
+func NewPseudonym() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating pseudonym: %v", err)
+	}
+
+	return "r_" + hex.EncodeToString(b), nil
+}
+
+// SuppressResults withholds the counts of results narrowed down by demographics that fall below
+// MinCellSize. Stats worked out from fewer answers are left out, as are the positions choices were
+// shown at, which split their counts further.
+func SuppressResults(results *Results) {
+	for i := range results.Questions {
+		question := &results.Questions[i]
+
+		if len(question.Choices) > 0 {
+			suppressCells([][]*ChoiceResult{cellsOf(question.Choices)})
+		}
+
+		rows := make([][]*ChoiceResult, 0, len(question.Rows))
+		for j := range question.Rows {
+			rows = append(rows, cellsOf(question.Rows[j].Choices))
+		}
+		suppressCells(rows)
+
+		if question.Stats != nil && question.Stats.Count < MinCellSize {
+			question.Stats = nil
+		}
+	}
+}
+
+// SuppressCrossTab withholds the counts of a cross-tab that fall below MinCellSize. Both its rows
+// and its columns are checked, as the total of either could give a withheld count away.
+func SuppressCrossTab(crossTab *CrossTab) {
+	var lines, columns [][]*ChoiceResult
+
+	for i := range crossTab.Rows {
+		row := cellsOf(crossTab.Rows[i].Choices)
+		lines = append(lines, row)
+
+		// every row has a cell for each column, in the same order
+		for j, cell := range row {
+			if j == len(columns) {
+				columns = append(columns, nil)
+			}
+			columns[j] = append(columns[j], cell)
+		}
+	}
+
+	suppressCells(append(lines, columns...))
+}
+
+// cellsOf returns pointers to the choices, so suppressCells can change them in place.
+func cellsOf(choices []ChoiceResult) []*ChoiceResult {
+	cells := make([]*ChoiceResult, len(choices))
+	for i := range choices {
+		cells[i] = &choices[i]
+	}
+
+	return cells
+}
+
+// suppressCells withholds every count from 1 up to MinCellSize in the lines, each a group of
+// counts whose total is known. A count withheld on its own in a line could still be worked out
+// by taking the others from the total, so the smallest other count in that line is withheld
+// with it, until no line is left with a single one withheld.
+func suppressCells(lines [][]*ChoiceResult) {
+	for _, line := range lines {
+		for _, cell := range line {
+			if cell.Count > 0 && cell.Count < MinCellSize {
+				cell.Suppressed = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, line := range lines {
+			var suppressed int
+			var smallest *ChoiceResult
+
+			for _, cell := range line {
+				switch {
+				case cell.Suppressed:
+					suppressed++
+				case cell.Count > 0 && (smallest == nil || cell.Count < smallest.Count):
+					smallest = cell
+				}
+			}
+
+			if suppressed == 1 && smallest != nil {
+				smallest.Suppressed = true
+				changed = true
+			}
+		}
+	}
+
+	for _, line := range lines {
+		for _, cell := range line {
+			cell.Positions = nil
+
+			if cell.Suppressed {
+				cell.Count = 0
+				cell.Percentage = decimal.Zero
+			}
+		}
+	}
+}
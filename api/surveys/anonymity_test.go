@@ -0,0 +1,243 @@
+package surveys_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newAnonymousExportStore() *StubSurveyStore {
+	store := newExportStore(true)
+
+	survey := store.Surveys[1]
+	survey.Anonymous = true
+	store.Surveys[1] = survey
+
+	for i := range store.Exports[1] {
+		store.Exports[1][i].Pseudonym = pgtype.Text{String: "r_0123456789abcdef", Valid: true}
+	}
+
+	return store
+}
+
+// newAnonymousResultsStore has 12 female respondents answer the yes/no question of newResultsStore.
+func newAnonymousResultsStore() *StubSurveyStore {
+	store := newResultsStore(true)
+
+	survey := store.Surveys[1]
+	survey.Anonymous = true
+	store.Surveys[1] = survey
+
+	store.Results[1] = []database.ListSurveyResultsRow{
+		{Kind: "total", Count: 12},
+		{Kind: "answered", QuestionID: 1, Count: 12},
+		{Kind: "choice", QuestionID: 1, Choice: "yes", Count: 10},
+		{Kind: "choice", QuestionID: 1, Choice: "no", Count: 2},
+		{Kind: "position", QuestionID: 1, Part: "yes", Choice: "1", Count: 10},
+	}
+
+	return store
+}
+
+func choiceCounts(choices []surveys.ChoiceResult) []int32 {
+	counts := make([]int32, 0, len(choices))
+	for _, choice := range choices {
+		counts = append(counts, choice.Count)
+	}
+
+	return counts
+}
+
+// ============================================================================
+// Anonymous export Tests
+// ============================================================================
+
+func TestAnonymousExport(t *testing.T) {
+
+	t.Run("exports pseudonyms and no demographics in CSV", func(t *testing.T) {
+		rec := export(t, newAnonymousExportStore(), "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+		if err != nil || len(rows) != 3 {
+			t.Fatalf("rows = %v (%v), want a header and two responses", rows, err)
+		}
+
+		column := slices.Index(rows[0], "pseudonym")
+		if column == -1 || rows[1][column] != "r_0123456789abcdef" {
+			t.Errorf("header %v, row %v, want the pseudonym", rows[0], rows[1])
+		}
+
+		for _, demographic := range []string{"age", "gender", "university"} {
+			if slices.Contains(rows[0], demographic) {
+				t.Errorf("header %v has %s, want no demographics", rows[0], demographic)
+			}
+		}
+	})
+
+	t.Run("exports pseudonyms and no demographics in JSONL", func(t *testing.T) {
+		rec := export(t, newAnonymousExportStore(), "/surveys/1/export?format=jsonl")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var line surveys.ExportLine
+		if err := json.Unmarshal([]byte(strings.SplitN(rec.Body.String(), "\n", 2)[0]), &line); err != nil {
+			t.Fatalf("invalid line in %q: %v", rec.Body.String(), err)
+		}
+
+		if line.Pseudonym != "r_0123456789abcdef" || line.Demographics != nil {
+			t.Errorf("line = %+v, want the pseudonym without demographics", line)
+		}
+	})
+
+	t.Run("leaves pseudonyms out for other surveys", func(t *testing.T) {
+		rec := export(t, newExportStore(true), "/surveys/1/export")
+
+		if strings.Contains(rec.Body.String(), "pseudonym") {
+			t.Errorf("body = %s, want no pseudonym column", rec.Body.String())
+		}
+	})
+}
+
+// ============================================================================
+// Anonymous results Tests
+// ============================================================================
+
+func TestAnonymousResults(t *testing.T) {
+
+	t.Run("withholds small counts of results filtered by demographics", func(t *testing.T) {
+		code, results := getResults(t, newAnonymousResultsStore(), "/surveys/1/results?gender=female")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		// no is too small to show, and yes would give it away when taken from the 12 who answered
+		choices := results.Questions[0].Choices
+		if !choices[0].Suppressed || !choices[1].Suppressed || !slices.Equal(choiceCounts(choices), []int32{0, 0}) {
+			t.Errorf("choices = %+v, want both withheld", choices)
+		}
+
+		if choices[0].Positions != nil {
+			t.Errorf("positions = %v, want none", choices[0].Positions)
+		}
+	})
+
+	t.Run("shows the counts of results that aren't filtered by demographics", func(t *testing.T) {
+		code, results := getResults(t, newAnonymousResultsStore(), "/surveys/1/results")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if counts := choiceCounts(results.Questions[0].Choices); !slices.Equal(counts, []int32{10, 2}) {
+			t.Errorf("counts = %v, want 10 and 2", counts)
+		}
+	})
+
+	t.Run("returns 403 when too few responses match the filters", func(t *testing.T) {
+		store := newAnonymousResultsStore()
+		store.Results[1][0].Count = surveys.MinCellSize - 1
+
+		code, _ := getResults(t, store, "/surveys/1/results?faculty=law")
+
+		assertResponseCode(t, code, http.StatusForbidden)
+	})
+
+	t.Run("withholds small cells of a cross-tab by an attribute", func(t *testing.T) {
+		store := newAnonymousResultsStore()
+		store.CrossTabs[1] = []database.CrossTabulateResultsRow{
+			{RowKey: "yes", ColumnKey: "law", Count: 20},
+			{RowKey: "yes", ColumnKey: "science", Count: 9},
+			{RowKey: "no", ColumnKey: "law", Count: 6},
+			{RowKey: "no", ColumnKey: "science", Count: 3},
+		}
+
+		code, results := getResults(t, store, "/surveys/1/results?crosstab=1&by=faculty")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if results.CrossTab == nil || len(results.CrossTab.Rows) != 2 {
+			t.Fatalf("results = %+v, want a cross-tab", results)
+		}
+
+		// rows follow the options, columns the faculties in order
+		if small := results.CrossTab.Rows[1].Choices[1]; small.ID != "science" || !small.Suppressed || small.Count != 0 {
+			t.Errorf("no, science = %+v, want it withheld", small)
+		}
+	})
+
+	t.Run("shows a cross-tab by another question in full", func(t *testing.T) {
+		code, results := getResults(t, newAnonymousResultsStore(), "/surveys/1/results?crosstab=1&by=2")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		for _, row := range results.CrossTab.Rows {
+			for _, choice := range row.Choices {
+				if choice.Suppressed {
+					t.Errorf("%s, %s = %+v, want it shown", row.ID, choice.ID, choice)
+				}
+			}
+		}
+	})
+}
+
+// ============================================================================
+// SuppressCrossTab Tests
+// ============================================================================
+
+func TestSuppressCrossTab(t *testing.T) {
+
+	cell := func(id string, count int32) surveys.ChoiceResult {
+		return surveys.ChoiceResult{ID: id, Count: count}
+	}
+
+	t.Run("withholds the cells too small to show and enough others to hide them", func(t *testing.T) {
+		crossTab := surveys.CrossTab{Rows: []surveys.RowResult{
+			{ID: "yes", Total: 33, Choices: []surveys.ChoiceResult{cell("law", 20), cell("science", 9), cell("arts", 4)}},
+			{ID: "no", Total: 19, Choices: []surveys.ChoiceResult{cell("law", 6), cell("science", 7), cell("arts", 6)}},
+		}}
+
+		surveys.SuppressCrossTab(&crossTab)
+
+		var suppressed []string
+		for _, row := range crossTab.Rows {
+			for _, choice := range row.Choices {
+				if choice.Suppressed {
+					suppressed = append(suppressed, row.ID+" "+choice.ID)
+					if choice.Count != 0 {
+						t.Errorf("%s %s = %d, want the count withheld", row.ID, choice.ID, choice.Count)
+					}
+				}
+			}
+		}
+
+		// yes arts is too small and yes science hides it in its row, then no science and no arts
+		// hide those two in their columns
+		want := []string{"yes science", "yes arts", "no science", "no arts"}
+		if !slices.Equal(suppressed, want) {
+			t.Errorf("suppressed = %v, want %v", suppressed, want)
+		}
+
+		if crossTab.Rows[0].Choices[0].Count != 20 || crossTab.Rows[1].Choices[0].Count != 6 {
+			t.Errorf("law = %d and %d, want them shown", crossTab.Rows[0].Choices[0].Count, crossTab.Rows[1].Choices[0].Count)
+		}
+	})
+
+	t.Run("shows zeros", func(t *testing.T) {
+		crossTab := surveys.CrossTab{Rows: []surveys.RowResult{
+			{ID: "yes", Total: 12, Choices: []surveys.ChoiceResult{cell("law", 12), cell("arts", 0)}},
+		}}
+
+		surveys.SuppressCrossTab(&crossTab)
+
+		for _, choice := range crossTab.Rows[0].Choices {
+			if choice.Suppressed {
+				t.Errorf("%s = %+v, want it shown", choice.ID, choice)
+			}
+		}
+	})
+}
@@ -0,0 +1,365 @@
+package surveys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Option is one selectable choice of a question. Answers refer to options by ID, so the ID
+// must stay stable when the label is reworded.
+type Option struct {
+	ID    string `json:"id" validate:"required,max=64,key"`
+	Label string `json:"label" validate:"required,max=255"`
+	// Anchored keeps the option in its place when the options around it are shuffled, like a
+	// "None of the above" that stays last.
+	Anchored bool `json:"anchored,omitempty"`
+}
+
+// QuestionConfig is the type specific part of a question, stored in questions.config.
+// Every question type has its own config shape and its own answer shape.
+type QuestionConfig interface {
+	// ValidateAnswer checks a raw answer against the config. required tells whether the
+	// question must be answered in full (e.g. every row of a matrix).
+	ValidateAnswer(raw json.RawMessage, required bool) error
+}
+
+type SingleChoiceConfig struct {
+	Options []Option `json:"options" validate:"required,min=2,unique=ID,dive"`
+	// Randomize shows the options in a different order to every respondent, see Arrange.
+	Randomize bool `json:"randomize,omitempty"`
+}
+
+type SingleChoiceAnswer struct {
+	OptionID string `json:"option_id"`
+}
+
+type MultipleChoiceConfig struct {
+	Options []Option `json:"options" validate:"required,min=2,unique=ID,dive"`
+	// MinSelections and MaxSelections bound how many options may be picked, 0 means no bound.
+	MinSelections int  `json:"min_selections" validate:"gte=0"`
+	MaxSelections int  `json:"max_selections" validate:"omitempty,gtefield=MinSelections"`
+	Randomize     bool `json:"randomize,omitempty"`
+}
+
+type MultipleChoiceAnswer struct {
+	OptionIDs []string `json:"option_ids"`
+}
+
+type LikertConfig struct {
+	Points int      `json:"points" validate:"required,gte=2,lte=11"`
+	Labels []string `json:"labels" validate:"omitempty,dive,required,max=255"`
+}
+
+type LikertAnswer struct {
+	Value int `json:"value"`
+}
+
+type NumericConfig struct {
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max" validate:"gtfield=Min"`
+	Integer bool    `json:"integer"`
+}
+
+type NumericAnswer struct {
+	Value *float64 `json:"value"`
+}
+
+type TextConfig struct {
+	MinLength int `json:"min_length" validate:"gte=0"`
+	MaxLength int `json:"max_length" validate:"required,gtefield=MinLength,lte=10000"`
+}
+
+type TextAnswer struct {
+	Text string `json:"text"`
+}
+
+type RankingConfig struct {
+	Options   []Option `json:"options" validate:"required,min=2,unique=ID,dive"`
+	Randomize bool     `json:"randomize,omitempty"`
+}
+
+type RankingAnswer struct {
+	// Ranking holds every option ID, most preferred first.
+	Ranking []string `json:"ranking"`
+}
+
+type MatrixConfig struct {
+	Rows    []Option `json:"rows" validate:"required,min=1,unique=ID,dive"`
+	Columns []Option `json:"columns" validate:"required,min=2,unique=ID,dive"`
+	// RandomizeRows shows the rows in a different order to every respondent, the columns keep
+	// theirs as they usually form a scale.
+	RandomizeRows bool `json:"randomize_rows,omitempty"`
+}
+
+type MatrixAnswer struct {
+	// Rows maps a row ID to the column ID picked for it.
+	Rows map[string]string `json:"rows"`
+}
+
+// ParseQuestionConfig decodes and validates the config of a question of the given type.
+func ParseQuestionConfig(questionType database.QuestionType, raw json.RawMessage) (QuestionConfig, error) {
+	var config QuestionConfig
+
+	switch questionType {
+	case database.QuestionTypeSingleChoice:
+		config = &SingleChoiceConfig{}
+	case database.QuestionTypeMultipleChoice:
+		config = &MultipleChoiceConfig{}
+	case database.QuestionTypeLikert:
+		config = &LikertConfig{}
+	case database.QuestionTypeNumeric:
+		config = &NumericConfig{}
+	case database.QuestionTypeText:
+		config = &TextConfig{}
+	case database.QuestionTypeRanking:
+		config = &RankingConfig{}
+	case database.QuestionTypeMatrix:
+		config = &MatrixConfig{}
+	default:
+		return nil, fmt.Errorf("unknown question type: %s", questionType)
+	}
+
+	if err := decodeStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %v", questionType, err)
+	}
+
+	if err := jsonutil.Validate(config); err != nil {
+		return nil, err
+	}
+
+	switch c := config.(type) {
+	case *MultipleChoiceConfig:
+		if c.MinSelections > len(c.Options) {
+			return nil, fieldError("min_selections", fmt.Sprintf("min_selections must be less than or equal to %d", len(c.Options)))
+		}
+	case *LikertConfig:
+		if len(c.Labels) > 0 && len(c.Labels) != c.Points {
+			return nil, fieldError("labels", fmt.Sprintf("labels must be exactly %d items", c.Points))
+		}
+	}
+
+	return config, nil
+}
+
+// ValidateAnswer checks a raw answer against the question it answers.
+func ValidateAnswer(question database.Question, raw json.RawMessage) error {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	if err != nil {
+		return err
+	}
+
+	return config.ValidateAnswer(raw, question.Required)
+}
+
+// ValidateAnswers checks a full set of answers, keyed by question ID, against the questions of a
+// survey. Only the questions on the respondent's path (see Flow) must or may be answered. Every
+// problem is reported at once, each prefixed with the question it belongs to.
+func ValidateAnswers(questions []database.Question, answers map[int64]json.RawMessage) error {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return err
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	shown := make(map[int64]bool, len(questions))
+
+	for _, question := range flow.Path(answers) {
+		shown[question.ID] = true
+		path := fmt.Sprintf("answers[%d]", question.ID)
+
+		raw, answered := answers[question.ID]
+		if !answered {
+			if question.Required {
+				validationErrors = append(validationErrors, fmt.Sprintf("%s: question %d is required", path, question.ID))
+			}
+			continue
+		}
+
+		validationErrors = append(validationErrors, prefixErrors(path, ValidateAnswer(question, raw))...)
+	}
+
+	validationErrors = append(validationErrors, flow.unexpectedAnswers(answers, shown)...)
+
+	if len(validationErrors) > 0 {
+		sort.Strings(validationErrors)
+		return validationErrors
+	}
+
+	return nil
+}
+
+// unexpectedAnswers reports the answers to questions that were not shown to the respondent.
+func (f *Flow) unexpectedAnswers(answers map[int64]json.RawMessage, shown map[int64]bool) []string {
+	var problems []string
+
+	for questionID := range answers {
+		if shown[questionID] {
+			continue
+		}
+
+		if _, exists := f.index[questionID]; exists {
+			problems = append(problems, fmt.Sprintf("answers[%d]: question %d is skipped for this respondent and can't be answered", questionID, questionID))
+		} else {
+			problems = append(problems, fmt.Sprintf("answers[%d]: question %d is not part of this survey", questionID, questionID))
+		}
+	}
+
+	return problems
+}
+
+// prefixErrors turns the errors of a single answer into entries of a larger ValidationErrors.
+func prefixErrors(path string, err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{fmt.Sprintf("%s: %s", path, err.Error())}
+	}
+
+	prefixed := make([]string, 0, len(validationErrors))
+	for _, message := range validationErrors {
+		prefixed = append(prefixed, path+"."+message)
+	}
+
+	return prefixed
+}
+
+func (c *SingleChoiceConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer SingleChoiceAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	return jsonutil.ValidateField("option_id", answer.OptionID, "required,oneof="+optionIDs(c.Options))
+}
+
+func (c *MultipleChoiceConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer MultipleChoiceAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	var tags []string
+
+	minSelections := c.MinSelections
+	if required && minSelections == 0 {
+		minSelections = 1
+	}
+
+	if minSelections > 0 {
+		tags = append(tags, fmt.Sprintf("min=%d", minSelections))
+	}
+
+	if c.MaxSelections > 0 {
+		tags = append(tags, fmt.Sprintf("max=%d", c.MaxSelections))
+	}
+
+	tags = append(tags, "unique", "dive", "oneof="+optionIDs(c.Options))
+
+	return jsonutil.ValidateField("option_ids", answer.OptionIDs, strings.Join(tags, ","))
+}
+
+func (c *LikertConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer LikertAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	return jsonutil.ValidateField("value", answer.Value, fmt.Sprintf("gte=1,lte=%d", c.Points))
+}
+
+func (c *NumericConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer NumericAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	if answer.Value == nil {
+		return fieldError("value", "value is required")
+	}
+
+	if c.Integer && *answer.Value != math.Trunc(*answer.Value) {
+		return fieldError("value", "value must be a whole number")
+	}
+
+	return jsonutil.ValidateField("value", *answer.Value, fmt.Sprintf("gte=%s,lte=%s", formatFloat(c.Min), formatFloat(c.Max)))
+}
+
+func (c *TextConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer TextAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	tag := fmt.Sprintf("min=%d,max=%d", c.MinLength, c.MaxLength)
+	if required {
+		tag = "required," + tag
+	} else {
+		tag = "omitempty," + tag
+	}
+
+	return jsonutil.ValidateField("text", strings.TrimSpace(answer.Text), tag)
+}
+
+func (c *RankingConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer RankingAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	tag := fmt.Sprintf("len=%d,unique,dive,oneof=%s", len(c.Options), optionIDs(c.Options))
+
+	return jsonutil.ValidateField("ranking", answer.Ranking, tag)
+}
+
+func (c *MatrixConfig) ValidateAnswer(raw json.RawMessage, required bool) error {
+	var answer MatrixAnswer
+	if err := decodeStrict(raw, &answer); err != nil {
+		return fmt.Errorf("invalid answer: %v", err)
+	}
+
+	tag := fmt.Sprintf("dive,keys,oneof=%s,endkeys,oneof=%s", optionIDs(c.Rows), optionIDs(c.Columns))
+	if required {
+		// keys are unique and limited to the rows, so the right count means every row is answered
+		tag = fmt.Sprintf("len=%d,", len(c.Rows)) + tag
+	}
+
+	return jsonutil.ValidateField("rows", answer.Rows, tag)
+}
+
+func decodeStrict(raw json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+func optionIDs(options []Option) string {
+	ids := make([]string, 0, len(options))
+	for _, option := range options {
+		ids = append(ids, option.ID)
+	}
+
+	return strings.Join(ids, " ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// fieldError builds an error in the same shape jsonutil.Validate uses for checks that can't
+// be expressed as a validator tag.
+func fieldError(field, message string) error {
+	return jsonutil.ValidationErrors{fmt.Sprintf("%s: %s", field, message)}
+}
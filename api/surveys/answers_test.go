@@ -0,0 +1,99 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"strings"
+	"testing"
+)
+
+func TestParseQuestionConfig(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		questionType database.QuestionType
+		config       string
+		wantErr      string
+	}{
+		{"valid single choice", database.QuestionTypeSingleChoice, `{"options": [{"id": "a", "label": "A"}, {"id": "b", "label": "B"}]}`, ""},
+		{"single choice needs two options", database.QuestionTypeSingleChoice, `{"options": [{"id": "a", "label": "A"}]}`, "options must be at least 2 items"},
+		{"option ids must be unique", database.QuestionTypeRanking, `{"options": [{"id": "a", "label": "A"}, {"id": "a", "label": "B"}]}`, "options must not contain duplicates"},
+		{"option ids must not contain spaces", database.QuestionTypeSingleChoice, `{"options": [{"id": "a b", "label": "A"}, {"id": "c", "label": "C"}]}`, "id must only contain"},
+		{"unknown fields are rejected", database.QuestionTypeText, `{"max_length": 10, "options": []}`, "unknown field"},
+		{"likert labels must match points", database.QuestionTypeLikert, `{"points": 5, "labels": ["bad", "good"]}`, "labels must be exactly 5 items"},
+		{"numeric max must exceed min", database.QuestionTypeNumeric, `{"min": 10, "max": 1}`, "max must be greater than"},
+		{"text needs a max length", database.QuestionTypeText, `{"min_length": 3}`, "max_length is required"},
+		{"valid matrix", database.QuestionTypeMatrix, `{"rows": [{"id": "r1", "label": "Food"}], "columns": [{"id": "c1", "label": "Good"}, {"id": "c2", "label": "Bad"}]}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := surveys.ParseQuestionConfig(tt.questionType, json.RawMessage(tt.config))
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAnswer(t *testing.T) {
+
+	choices := `{"options": [{"id": "a", "label": "A"}, {"id": "b", "label": "B"}, {"id": "c", "label": "C"}]}`
+
+	tests := []struct {
+		name         string
+		questionType database.QuestionType
+		config       string
+		answer       string
+		wantErr      string
+	}{
+		{"single choice accepts a listed option", database.QuestionTypeSingleChoice, choices, `{"option_id": "b"}`, ""},
+		{"single choice rejects an unlisted option", database.QuestionTypeSingleChoice, choices, `{"option_id": "z"}`, "option_id: option_id must be one of [a b c]"},
+		{"multiple choice rejects an unlisted option", database.QuestionTypeMultipleChoice, choices, `{"option_ids": ["a", "z"]}`, "option_ids[1]: option_ids[1] must be one of [a b c]"},
+		{"multiple choice rejects repeated options", database.QuestionTypeMultipleChoice, choices, `{"option_ids": ["a", "a"]}`, "option_ids must not contain duplicates"},
+		{"multiple choice enforces max selections", database.QuestionTypeMultipleChoice, `{"options": [{"id": "a", "label": "A"}, {"id": "b", "label": "B"}], "max_selections": 1}`, `{"option_ids": ["a", "b"]}`, "option_ids must be at most 1 items"},
+		{"likert accepts a value on the scale", database.QuestionTypeLikert, `{"points": 5}`, `{"value": 5}`, ""},
+		{"likert rejects a value off the scale", database.QuestionTypeLikert, `{"points": 5}`, `{"value": 6}`, "value: value must be less than or equal to 5"},
+		{"numeric rejects a value below the range", database.QuestionTypeNumeric, `{"min": 0, "max": 120}`, `{"value": -1}`, "value must be greater than or equal to 0"},
+		{"numeric rejects a fraction for integer questions", database.QuestionTypeNumeric, `{"min": 0, "max": 120, "integer": true}`, `{"value": 2.5}`, "value must be a whole number"},
+		{"text enforces the max length", database.QuestionTypeText, `{"max_length": 5}`, `{"text": "too long"}`, "text must be at most 5 characters"},
+		{"ranking rejects a duplicate rank", database.QuestionTypeRanking, choices, `{"ranking": ["a", "b", "a"]}`, "ranking must not contain duplicates"},
+		{"ranking needs every option", database.QuestionTypeRanking, choices, `{"ranking": ["a", "b"]}`, "ranking must be exactly 3 items"},
+		{"ranking accepts a full order", database.QuestionTypeRanking, choices, `{"ranking": ["c", "a", "b"]}`, ""},
+		{"matrix rejects an unknown column", database.QuestionTypeMatrix, `{"rows": [{"id": "r1", "label": "Food"}], "columns": [{"id": "c1", "label": "Good"}, {"id": "c2", "label": "Bad"}]}`, `{"rows": {"r1": "c9"}}`, "rows[r1]: rows[r1] must be one of [c1 c2]"},
+		{"matrix needs every row when required", database.QuestionTypeMatrix, `{"rows": [{"id": "r1", "label": "Food"}, {"id": "r2", "label": "Rooms"}], "columns": [{"id": "c1", "label": "Good"}, {"id": "c2", "label": "Bad"}]}`, `{"rows": {"r1": "c1"}}`, "rows must be exactly 2 items"},
+		{"answers must match the question type", database.QuestionTypeSingleChoice, choices, `{"value": 3}`, "invalid answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := database.Question{
+				Type:     tt.questionType,
+				Required: true,
+				Config:   []byte(tt.config),
+			}
+
+			err := surveys.ValidateAnswer(question, json.RawMessage(tt.answer))
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
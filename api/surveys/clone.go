@@ -0,0 +1,343 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultTemplateLimit = 20
+	MaxTemplateLimit     = 100
+)
+
+// RemapLogic points the logic of a copied question at the copies of the questions it refers to.
+// ids maps the ID of every copied question to the ID of its copy.
+func RemapLogic(raw json.RawMessage, ids map[int64]int64) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	logic, err := ParseQuestionLogic(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, condition := range []*Condition{logic.DisplayIf, logic.ScreenOutIf, logic.AttentionCheck} {
+		if condition == nil {
+			continue
+		}
+		if err := remapCondition(condition, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range logic.Skip {
+		rule := &logic.Skip[i]
+
+		if err := remapCondition(&rule.When, ids); err != nil {
+			return nil, err
+		}
+
+		if rule.SkipTo != 0 {
+			id, ok := ids[rule.SkipTo]
+			if !ok {
+				return nil, fmt.Errorf("skip to unknown question %d", rule.SkipTo)
+			}
+			rule.SkipTo = id
+		}
+	}
+
+	return json.Marshal(logic)
+}
+
+func remapCondition(condition *Condition, ids map[int64]int64) error {
+	if condition.QuestionID != 0 {
+		id, ok := ids[condition.QuestionID]
+		if !ok {
+			return fmt.Errorf("condition on unknown question %d", condition.QuestionID)
+		}
+		condition.QuestionID = id
+	}
+
+	for _, group := range [][]Condition{condition.All, condition.Any} {
+		for i := range group {
+			if err := remapCondition(&group[i], ids); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// TemplateFilter narrows down the templates listed. Fields left empty don't filter.
+type TemplateFilter struct {
+	// FieldIDs matches templates in any of the fields.
+	FieldIDs []int64
+	// Search matches templates whose title or description has it, ignoring case.
+	Search string
+	// After is the ID of the last template of the previous page.
+	After int64
+	Limit int32
+}
+
+// ParseTemplateFilter reads which templates to list from the query string: field_id, which may be
+// given more than once, search, after and limit.
+func ParseTemplateFilter(query url.Values) (TemplateFilter, error) {
+	filter := TemplateFilter{
+		Search: strings.TrimSpace(query.Get("search")),
+		Limit:  DefaultTemplateLimit,
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	for _, value := range query["field_id"] {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			validationErrors = append(validationErrors, "field_id: field_id must be a field id")
+			continue
+		}
+		filter.FieldIDs = append(filter.FieldIDs, id)
+	}
+
+	if len(filter.Search) > 255 {
+		validationErrors = append(validationErrors, "search: search must be at most 255 characters")
+	}
+
+	if after := query.Get("after"); after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || id < 0 {
+			validationErrors = append(validationErrors, "after: after must be a template id")
+		}
+		filter.After = id
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxTemplateLimit {
+			validationErrors = append(validationErrors, fmt.Sprintf("limit: limit must be a number between 1 and %d", MaxTemplateLimit))
+		}
+		filter.Limit = int32(n)
+	}
+
+	if len(validationErrors) > 0 {
+		return TemplateFilter{}, validationErrors
+	}
+
+	return filter, nil
+}
+
+// cloneSurvey copies a survey into a new draft of the researcher and writes the copy as the
+// response. The title of the copy may be given in the body, which is optional.
+func (h *Handler) cloneSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request, sourceID, researcherID int64) {
+	var data CloneSurveyBody
+
+	if request.ContentLength != 0 {
+		var err error
+		data, err = jsonutil.UnmarshalJsonResponse[CloneSurveyBody](request)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+	}
+
+	survey, err := h.Store.CloneSurvey(ctx, sourceID, researcherID, data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "survey cloned successfully",
+		Data:    NewSurvey(survey),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+// CloneSurveyHandler copies one of the researcher's surveys, in whatever status, into a new draft of
+// theirs. A survey of an organization can be copied by any member who can edit its surveys, and the
+// copy stays in the organization.
+func (h *Handler) CloneSurveyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.managedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	h.cloneSurvey(ctx, responseWriter, request, survey.ID, int64(claims.UserID))
+	return
+}
+
+// UpdateTemplateHandler shares a survey as a template other researchers can copy, or stops
+// sharing it. Copies already made are not affected.
+func (h *Handler) UpdateTemplateHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.managedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[UpdateTemplateBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err = h.Store.SetTemplate(ctx, survey.ID, survey.ResearcherID, *data.Template)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "survey template updated successfully",
+		Data:    NewSurvey(survey),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) ListTemplatesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	filter, err := ParseTemplateFilter(request.URL.Query())
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.Store.ListTemplates(ctx, filter)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved templates successfully",
+		Data:    NewTemplatePage(rows, filter.Limit),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// GetTemplateHandler shows a template with the questions a copy of it starts out with.
+func (h *Handler) GetTemplateHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	surveyID, err := surveyIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.GetTemplate(ctx, surveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved template successfully",
+		Data:    TemplatePreview{Survey: NewSurvey(survey), Questions: NewQuestions(questions)},
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// UseTemplateHandler copies a template into a new draft of the researcher.
+func (h *Handler) UseTemplateHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	surveyID, err := surveyIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.Store.GetTemplate(ctx, surveyID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	h.cloneSurvey(ctx, responseWriter, request, template.ID, int64(userID))
+	return
+}
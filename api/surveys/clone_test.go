@@ -0,0 +1,398 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newCloneStore has a published survey of researcher 1 that skips walkers past the question on bus
+// fares, which only bus riders are shown, and a quota cell for women.
+func newCloneStore() *StubSurveyStore {
+	store := NewStubSurveyStore()
+	store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Title: "Commuting", Status: database.SurveyStatusPublished, Version: 2,
+		FieldIds: []int64{3}, Targeting: []byte(`{"genders": ["female"]}`), PublishedAt: pgtype.Timestamp{Valid: true}}
+
+	store.Questions[1] = database.Question{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Title: "How do you commute?",
+		Config: []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}]}`),
+		Logic:  []byte(`{"skip": [{"when": {"question_id": 1, "operator": "equals", "value": "walk"}, "skip_to": 3}]}`)}
+	store.Questions[2] = database.Question{ID: 2, SurveyID: 1, Position: 2, Type: database.QuestionTypeNumeric, Title: "Bus fare?",
+		Config: []byte(`{"min": 0, "max": 100}`),
+		Logic:  []byte(`{"display_if": {"any": [{"question_id": 1, "operator": "equals", "value": "bus"}]}}`)}
+	store.Questions[3] = database.Question{ID: 3, SurveyID: 1, Position: 3, Type: database.QuestionTypeText, Title: "Anything else?",
+		Config: []byte(`{"max_length": 500}`)}
+
+	store.Cells[1] = database.QuotaCell{ID: 1, SurveyID: 1, Name: "women", Criteria: []byte(`{"genders": ["female"]}`), Capacity: 50, Filled: 20}
+
+	return store
+}
+
+func clonedSurvey(t *testing.T, rec *httptest.ResponseRecorder) surveys.Survey {
+	t.Helper()
+
+	var got struct {
+		Data surveys.Survey `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+	}
+
+	return got.Data
+}
+
+// ============================================================================
+// RemapLogic Tests
+// ============================================================================
+
+func TestRemapLogic(t *testing.T) {
+
+	ids := map[int64]int64{1: 11, 2: 12, 3: 13}
+
+	t.Run("points every reference at the copies", func(t *testing.T) {
+		raw := []byte(`{
+			"display_if": {"all": [{"question_id": 1, "operator": "answered"}, {"any": [{"question_id": 2, "operator": "equals", "value": "x"}]}]},
+			"skip": [{"when": {"question_id": 2, "operator": "not_answered"}, "skip_to": 3}, {"when": {"question_id": 1, "operator": "answered"}, "end_survey": true}],
+			"attention_check": {"question_id": 2, "operator": "equals", "value": "x"}
+		}`)
+
+		remapped, err := surveys.RemapLogic(raw, ids)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		logic, _ := surveys.ParseQuestionLogic(remapped)
+
+		if logic.DisplayIf.All[0].QuestionID != 11 || logic.DisplayIf.All[1].Any[0].QuestionID != 12 {
+			t.Errorf("display_if = %+v, want questions 11 and 12", logic.DisplayIf)
+		}
+
+		if logic.Skip[0].When.QuestionID != 12 || logic.Skip[0].SkipTo != 13 || logic.Skip[1].SkipTo != 0 || !logic.Skip[1].EndSurvey {
+			t.Errorf("skip = %+v, want a skip from 12 to 13 then the end of the survey", logic.Skip)
+		}
+
+		if logic.AttentionCheck.QuestionID != 12 {
+			t.Errorf("attention_check = %+v, want question 12", logic.AttentionCheck)
+		}
+	})
+
+	t.Run("leaves no logic alone", func(t *testing.T) {
+		remapped, err := surveys.RemapLogic(nil, ids)
+		if err != nil || remapped != nil {
+			t.Errorf("got %s, %v, want no logic", remapped, err)
+		}
+	})
+
+	t.Run("rejects a reference to a question not copied", func(t *testing.T) {
+		_, err := surveys.RemapLogic([]byte(`{"display_if": {"question_id": 9, "operator": "answered"}}`), ids)
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+// ============================================================================
+// CloneSurveyHandler Tests
+// ============================================================================
+
+func TestCloneSurveyHandler(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1"}
+
+	t.Run("copies a published survey into a new draft", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		got := clonedSurvey(t, rec)
+		if got.ID == 1 || got.Status != "draft" || got.Version != 0 || got.PublishedAt != nil {
+			t.Errorf("survey = %+v, want a new unpublished draft", got)
+		}
+
+		if got.Title != "Commuting" || string(got.Targeting) != `{"genders":["female"]}` || got.ClonedFrom == nil || *got.ClonedFrom != 1 {
+			t.Errorf("survey = %+v, want the title and targeting of survey 1", got)
+		}
+	})
+
+	t.Run("points the logic of the copies at each other", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		got := clonedSurvey(t, rec)
+		questions, _ := store.ListQuestions(t.Context(), got.ID)
+		if len(questions) != 3 {
+			t.Fatalf("copied %d questions, want 3", len(questions))
+		}
+
+		copies := make(map[string]database.Question)
+		for _, question := range questions {
+			copies[question.Title] = question
+		}
+
+		first, fare, last := copies["How do you commute?"], copies["Bus fare?"], copies["Anything else?"]
+
+		skip, _ := surveys.ParseQuestionLogic(first.Logic)
+		if skip.Skip[0].When.QuestionID != first.ID || skip.Skip[0].SkipTo != last.ID {
+			t.Errorf("skip = %+v, want from %d to %d", skip.Skip, first.ID, last.ID)
+		}
+
+		display, _ := surveys.ParseQuestionLogic(fare.Logic)
+		if display.DisplayIf.Any[0].QuestionID != first.ID {
+			t.Errorf("display_if = %+v, want question %d", display.DisplayIf, first.ID)
+		}
+
+		if err := surveys.ValidateLogic(questions); err != nil {
+			t.Errorf("copied logic is invalid: %v", err)
+		}
+	})
+
+	t.Run("copies the quota cells empty", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		cells, _ := store.ListQuotaCells(t.Context(), clonedSurvey(t, rec).ID)
+		if len(cells) != 1 || cells[0].Name != "women" || cells[0].Filled != 0 {
+			t.Errorf("cells = %+v, want an empty copy of the women cell", cells)
+		}
+	})
+
+	t.Run("names the copy", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", []byte(`{"title": "Commuting 2027"}`), 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if got := clonedSurvey(t, rec); got.Title != "Commuting 2027" {
+			t.Errorf("title = %q, want Commuting 2027", got.Title)
+		}
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 2, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	// survey 1 belongs to organization 7, where researcher 2 is an editor and researcher 3 a viewer
+	organizationStore := func() *StubSurveyStore {
+		store := newCloneStore()
+		store.Members[7] = map[int64]database.OrganizationRole{
+			1: database.OrganizationRoleOwner,
+			2: database.OrganizationRoleEditor,
+			3: database.OrganizationRoleViewer,
+		}
+		survey := store.Surveys[1]
+		survey.OrganizationID = pgtype.Int8{Int64: 7, Valid: true}
+		store.Surveys[1] = survey
+
+		return store
+	}
+
+	t.Run("gives the copy to the member who made it", func(t *testing.T) {
+		store := organizationStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 2, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		copied := store.Surveys[clonedSurvey(t, rec).ID]
+		if copied.ResearcherID != 2 || copied.OrganizationID.Int64 != 7 {
+			t.Errorf("survey = %+v, want researcher 2's draft in organization 7", copied)
+		}
+	})
+
+	t.Run("returns 403 when a viewer copies a survey", func(t *testing.T) {
+		store := organizationStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/1/clone", nil, 3, params)
+		rec := httptest.NewRecorder()
+
+		handler.CloneSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+
+		if len(store.Surveys) != 1 {
+			t.Errorf("surveys = %d, want no copy made", len(store.Surveys))
+		}
+	})
+}
+
+// ============================================================================
+// Template Tests
+// ============================================================================
+
+func TestUpdateTemplateHandler(t *testing.T) {
+
+	t.Run("shares a survey as a template", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPut, "/surveys/1/template", []byte(`{"template": true}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateTemplateHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !store.Surveys[1].IsTemplate {
+			t.Errorf("expected survey 1 to be a template")
+		}
+	})
+
+	t.Run("returns 400 without a template flag", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPut, "/surveys/1/template", []byte(`{}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateTemplateHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+func TestListTemplatesHandler(t *testing.T) {
+
+	newTemplateStore := func() *StubSurveyStore {
+		store := newCloneStore()
+		store.Surveys[1] = func(survey database.Survey) database.Survey { survey.IsTemplate = true; return survey }(store.Surveys[1])
+		store.Surveys[2] = database.Survey{ID: 2, ResearcherID: 2, Title: "Campus food", IsTemplate: true, FieldIds: []int64{5},
+			Description: pgtype.Text{String: "What students eat between lectures", Valid: true}}
+		store.Surveys[3] = database.Survey{ID: 3, ResearcherID: 2, Title: "Private draft", FieldIds: []int64{3}}
+
+		return store
+	}
+
+	list := func(store *StubSurveyStore, query string) (*httptest.ResponseRecorder, surveys.TemplatePage) {
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, "/surveys/templates?"+query, nil, 2, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ListTemplatesHandler(rec, req)
+
+		var got struct {
+			Data surveys.TemplatePage `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		return rec, got.Data
+	}
+
+	t.Run("lists only surveys shared as templates", func(t *testing.T) {
+		rec, page := list(newTemplateStore(), "")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if len(page.Templates) != 2 || page.Templates[0].Questions != 3 || page.NextAfter != 0 {
+			t.Errorf("page = %+v, want templates 1 and 2 on one page", page)
+		}
+	})
+
+	t.Run("filters by field", func(t *testing.T) {
+		_, page := list(newTemplateStore(), "field_id=3&field_id=4")
+
+		if len(page.Templates) != 1 || page.Templates[0].ID != 1 {
+			t.Errorf("page = %+v, want template 1", page)
+		}
+	})
+
+	t.Run("searches titles and descriptions", func(t *testing.T) {
+		_, page := list(newTemplateStore(), "search=LECTURES")
+
+		if len(page.Templates) != 1 || page.Templates[0].ID != 2 {
+			t.Errorf("page = %+v, want template 2", page)
+		}
+	})
+
+	t.Run("pages through the templates", func(t *testing.T) {
+		store := newTemplateStore()
+
+		_, page := list(store, "limit=1")
+		if len(page.Templates) != 1 || page.NextAfter != 1 {
+			t.Fatalf("page = %+v, want template 1 and a next page", page)
+		}
+
+		_, page = list(store, "limit=1&after=1")
+		if len(page.Templates) != 1 || page.Templates[0].ID != 2 {
+			t.Errorf("page = %+v, want template 2", page)
+		}
+	})
+
+	t.Run("returns 400 for an invalid field", func(t *testing.T) {
+		rec, _ := list(newTemplateStore(), "field_id=commuting")
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+func TestUseTemplateHandler(t *testing.T) {
+
+	t.Run("copies a template into the researcher's drafts", func(t *testing.T) {
+		store := newCloneStore()
+		survey := store.Surveys[1]
+		survey.IsTemplate = true
+		store.Surveys[1] = survey
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/templates/1/use", nil, 2, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UseTemplateHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		got := clonedSurvey(t, rec)
+		if got.ResearcherID != 2 || got.Status != "draft" || got.Template {
+			t.Errorf("survey = %+v, want a draft of researcher 2 that isn't itself a template", got)
+		}
+	})
+
+	t.Run("returns 404 for a survey not shared as a template", func(t *testing.T) {
+		store := newCloneStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys/templates/1/use", nil, 2, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UseTemplateHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,691 @@
+package surveys
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefinitionFormat names the format of survey definition documents and DefinitionVersion is the
+// latest version of it. The version goes up whenever the format changes in a way a reader of the
+// older version would get wrong, documents of older versions can still be imported.
+const (
+	DefinitionFormat  = "answerly.survey"
+	DefinitionVersion = 1
+)
+
+// ruleFieldKeys are the targeting rules that refer to fields.
+var ruleFieldKeys = []string{"required_fields", "excluded_fields"}
+
+// Definition is everything a survey is set up with, as one document that can be kept apart from
+// the survey and imported into this instance or another one. IDs differ from one instance to the
+// next, so questions refer to each other by their ID within the document, and fields are named.
+type Definition struct {
+	Format    string               `json:"format"`
+	Version   int                  `json:"version"`
+	Survey    SurveyDefinition     `json:"survey"`
+	Questions []QuestionDefinition `json:"questions"`
+	Quotas    []QuotaDefinition    `json:"quotas"`
+}
+
+// SurveyDefinition is the settings part of a definition. Targeting rules are written as they are
+// stored, except that required_fields and excluded_fields name the fields.
+type SurveyDefinition struct {
+	Title             string           `json:"title" validate:"required,max=255"`
+	Description       string           `json:"description,omitempty"`
+	RewardPerResponse *decimal.Decimal `json:"reward_per_response,omitempty"`
+	TargetResponses   *int32           `json:"target_responses,omitempty" validate:"omitempty,gte=1"`
+	Targeting         json.RawMessage  `json:"targeting,omitempty"`
+	Fields            []string         `json:"fields,omitempty" validate:"omitempty,unique,dive,required"`
+	EstimatedMinutes  *int32           `json:"estimated_minutes,omitempty" validate:"omitempty,gte=1,lte=600"`
+	ScreenOutFee      *decimal.Decimal `json:"screen_out_fee,omitempty"`
+	ShareDemographics bool             `json:"share_demographics"`
+	Anonymous         bool             `json:"anonymous,omitempty"`
+	QualityChecks     json.RawMessage  `json:"quality_checks,omitempty"`
+	ResumeWindowHours *int32           `json:"resume_window_hours,omitempty" validate:"omitempty,gte=1,lte=720"`
+}
+
+// QuestionDefinition is a question of a definition. Questions are asked in the order the document
+// lists them.
+type QuestionDefinition struct {
+	// ID is what the logic of the document's questions refers to the question by.
+	ID          int64           `json:"id" validate:"required,gt=0"`
+	Type        string          `json:"type" validate:"required,oneof=single_choice multiple_choice likert numeric text ranking matrix"`
+	Title       string          `json:"title" validate:"required"`
+	Description string          `json:"description,omitempty"`
+	Required    *bool           `json:"required"`
+	Screener    bool            `json:"screener,omitempty"`
+	Block       string          `json:"block,omitempty" validate:"omitempty,max=64,key"`
+	Config      json.RawMessage `json:"config" validate:"required"`
+	Logic       json.RawMessage `json:"logic,omitempty"`
+}
+
+// QuotaDefinition is a quota cell of a definition, with its criteria written like targeting rules.
+type QuotaDefinition struct {
+	Name     string          `json:"name" validate:"required,max=255"`
+	Criteria json.RawMessage `json:"criteria" validate:"required"`
+	Capacity int32           `json:"capacity" validate:"required,gte=1"`
+}
+
+// SurveyImport is a checked definition, ready to be stored as a new draft.
+type SurveyImport struct {
+	Survey CreateSurveyBody
+	// Questions are in order and keep the IDs of the document, which their logic refers to.
+	Questions []database.Question
+	Quotas    []CreateQuotaCellBody
+}
+
+// NewDefinition writes the definition of a survey from the questions it asks now and its quota
+// cells. fields names every field the survey refers to.
+func NewDefinition(survey database.Survey, questions []database.Question, cells []database.QuotaCell, fields map[int64]string) (Definition, error) {
+	definition := Definition{
+		Format:  DefinitionFormat,
+		Version: DefinitionVersion,
+		Survey: SurveyDefinition{
+			Title:             survey.Title,
+			Description:       survey.Description.String,
+			ShareDemographics: survey.ShareDemographics,
+			Anonymous:         survey.Anonymous,
+		},
+		Questions: make([]QuestionDefinition, 0, len(questions)),
+		Quotas:    make([]QuotaDefinition, 0, len(cells)),
+	}
+
+	if survey.RewardPerResponse.Valid {
+		reward := database.DecimalFromNumeric(survey.RewardPerResponse)
+		definition.Survey.RewardPerResponse = &reward
+	}
+
+	if survey.ResumeWindowHours > 0 {
+		definition.Survey.ResumeWindowHours = &survey.ResumeWindowHours
+	}
+
+	if survey.TargetResponses.Valid {
+		definition.Survey.TargetResponses = &survey.TargetResponses.Int32
+	}
+
+	if survey.EstimatedMinutes.Valid {
+		definition.Survey.EstimatedMinutes = &survey.EstimatedMinutes.Int32
+	}
+
+	if survey.ScreenOutFee.Valid {
+		screenOutFee := database.DecimalFromNumeric(survey.ScreenOutFee)
+		definition.Survey.ScreenOutFee = &screenOutFee
+	}
+
+	if len(survey.QualityChecks) > 0 && string(survey.QualityChecks) != "null" {
+		definition.Survey.QualityChecks = survey.QualityChecks
+	}
+
+	for _, id := range survey.FieldIds {
+		name, ok := fields[id]
+		if !ok {
+			return Definition{}, fmt.Errorf("field %d does not exist", id)
+		}
+		definition.Survey.Fields = append(definition.Survey.Fields, name)
+	}
+
+	var err error
+	definition.Survey.Targeting, err = exportRules(survey.Targeting, fields)
+	if err != nil {
+		return Definition{}, fmt.Errorf("targeting: %v", err)
+	}
+
+	questions = slices.Clone(questions)
+	slices.SortFunc(questions, func(a, b database.Question) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+
+	// number the questions from 1 in the order they are asked
+	ids := make(map[int64]int64, len(questions))
+	for i, question := range questions {
+		ids[question.ID] = int64(i + 1)
+	}
+
+	for _, question := range questions {
+		logic, err := RemapLogic(question.Logic, ids)
+		if err != nil {
+			return Definition{}, fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		definition.Questions = append(definition.Questions, QuestionDefinition{
+			ID:          ids[question.ID],
+			Type:        string(question.Type),
+			Title:       question.Title,
+			Description: question.Description.String,
+			Required:    &question.Required,
+			Screener:    question.Screener,
+			Block:       question.Block.String,
+			Config:      question.Config,
+			Logic:       logic,
+		})
+	}
+
+	for _, cell := range cells {
+		criteria, err := exportRules(cell.Criteria, fields)
+		if err != nil {
+			return Definition{}, fmt.Errorf("quota cell %d: %v", cell.ID, err)
+		}
+
+		definition.Quotas = append(definition.Quotas, QuotaDefinition{
+			Name:     cell.Name,
+			Criteria: criteria,
+			Capacity: cell.Capacity,
+		})
+	}
+
+	return definition, nil
+}
+
+// DefinitionFieldIDs lists the fields a survey and its quota cells refer to, for them to be named
+// in its definition.
+func DefinitionFieldIDs(survey database.Survey, cells []database.QuotaCell) []int64 {
+	ids := slices.Clone(survey.FieldIds)
+	ids = append(ids, ruleFieldValues[int64](survey.Targeting)...)
+
+	for _, cell := range cells {
+		ids = append(ids, ruleFieldValues[int64](cell.Criteria)...)
+	}
+
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// FieldNames lists the fields a definition names, for them to be looked up before it is checked.
+func (d Definition) FieldNames() []string {
+	names := slices.Clone(d.Survey.Fields)
+	names = append(names, ruleFieldValues[string](d.Survey.Targeting)...)
+
+	for _, quota := range d.Quotas {
+		names = append(names, ruleFieldValues[string](quota.Criteria)...)
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// CheckDefinition checks a whole definition and turns it into a SurveyImport. Every problem is
+// reported at once, each under the JSON path of the part of the document it is found in. fields
+// maps the names of the fields that exist to their IDs.
+func CheckDefinition(definition Definition, fields map[string]int64) (SurveyImport, error) {
+	if definition.Format != DefinitionFormat {
+		return SurveyImport{}, fieldError("format", fmt.Sprintf("format must be %s", DefinitionFormat))
+	}
+
+	if definition.Version < 1 || definition.Version > DefinitionVersion {
+		return SurveyImport{}, fieldError("version", fmt.Sprintf("version %d is not supported, versions 1 to %d are", definition.Version, DefinitionVersion))
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	survey, problems := checkSurveyDefinition(definition.Survey, fields)
+	validationErrors = append(validationErrors, problems...)
+
+	questions, problems := checkQuestionDefinitions(definition.Questions)
+	validationErrors = append(validationErrors, problems...)
+
+	quotas, problems := checkQuotaDefinitions(definition.Quotas, fields)
+	validationErrors = append(validationErrors, problems...)
+
+	if len(validationErrors) > 0 {
+		return SurveyImport{}, validationErrors
+	}
+
+	return SurveyImport{Survey: survey, Questions: questions, Quotas: quotas}, nil
+}
+
+func checkSurveyDefinition(definition SurveyDefinition, fields map[string]int64) (CreateSurveyBody, []string) {
+	var problems []string
+
+	problems = append(problems, prefixErrors("survey", jsonutil.Validate(definition))...)
+	problems = append(problems, prefixErrors("survey", validateAmounts(definition.RewardPerResponse, definition.ScreenOutFee))...)
+
+	body := CreateSurveyBody{
+		Title:             definition.Title,
+		Description:       definition.Description,
+		RewardPerResponse: definition.RewardPerResponse,
+		TargetResponses:   definition.TargetResponses,
+		EstimatedMinutes:  definition.EstimatedMinutes,
+		ScreenOutFee:      definition.ScreenOutFee,
+		ShareDemographics: &definition.ShareDemographics,
+		ResumeWindowHours: definition.ResumeWindowHours,
+		Anonymous:         &definition.Anonymous,
+	}
+
+	for i, name := range definition.Fields {
+		id, ok := fields[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("survey.fields[%d]: field %q does not exist", i, name))
+			continue
+		}
+		body.FieldIDs = append(body.FieldIDs, id)
+	}
+
+	targeting, rulesProblems := importRules("survey.targeting", definition.Targeting, fields)
+	problems = append(problems, rulesProblems...)
+
+	if len(rulesProblems) == 0 && len(targeting) > 0 {
+		parsed, err := ParseTargeting(targeting)
+		if err != nil {
+			problems = append(problems, prefixErrors("survey", err)...)
+		} else {
+			body.Targeting, _ = json.Marshal(parsed)
+		}
+	}
+
+	if len(definition.QualityChecks) > 0 {
+		settings, err := ParseQualitySettings(definition.QualityChecks)
+		if err != nil {
+			problems = append(problems, prefixErrors("survey", err)...)
+		} else {
+			body.QualityChecks, _ = json.Marshal(settings)
+		}
+	}
+
+	return body, problems
+}
+
+func checkQuestionDefinitions(definitions []QuestionDefinition) ([]database.Question, []string) {
+	var problems []string
+
+	questions := make([]database.Question, 0, len(definitions))
+	index := make(map[int64]int, len(definitions))
+
+	// references between questions are only checked once every question is known by a unique ID
+	// and has a valid config and logic of the right shape, they would only add noise otherwise
+	references := true
+
+	for i, definition := range definitions {
+		path := fmt.Sprintf("questions[%d]", i)
+
+		if err := jsonutil.Validate(definition); err != nil {
+			problems = append(problems, prefixErrors(path, err)...)
+		}
+
+		if definition.ID > 0 {
+			if j, used := index[definition.ID]; used {
+				problems = append(problems, fmt.Sprintf("%s.id: id %d is already used by questions[%d]", path, definition.ID, j))
+				references = false
+			} else {
+				index[definition.ID] = i
+			}
+		} else {
+			references = false
+		}
+
+		question := database.Question{
+			ID:       definition.ID,
+			Position: int32(i + 1),
+			Type:     database.QuestionType(definition.Type),
+			Title:    definition.Title,
+			Required: definition.Required == nil || *definition.Required,
+			Screener: definition.Screener,
+		}
+		question.Description.String, question.Description.Valid = definition.Description, len(definition.Description) > 0
+		question.Block.String, question.Block.Valid = definition.Block, len(definition.Block) > 0
+
+		if _, known := conditionOperators[question.Type]; known && len(definition.Config) > 0 {
+			config, err := ParseQuestionConfig(question.Type, definition.Config)
+			if err != nil {
+				problems = append(problems, prefixErrors(path+".config", err)...)
+				references = false
+			} else {
+				question.Config, _ = json.Marshal(config)
+			}
+		}
+
+		if len(definition.Logic) > 0 && string(definition.Logic) != "null" {
+			logic, err := ParseQuestionLogic(definition.Logic)
+			if err != nil {
+				problems = append(problems, prefixErrors(path, err)...)
+				references = false
+			} else {
+				question.Logic, _ = json.Marshal(logic)
+			}
+		}
+
+		questions = append(questions, question)
+	}
+
+	if references {
+		problems = append(problems, documentPaths(ValidateLogic(questions), index)...)
+	}
+
+	return questions, problems
+}
+
+func checkQuotaDefinitions(definitions []QuotaDefinition, fields map[string]int64) ([]CreateQuotaCellBody, []string) {
+	var problems []string
+
+	quotas := make([]CreateQuotaCellBody, 0, len(definitions))
+	names := make(map[string]int, len(definitions))
+
+	for i, definition := range definitions {
+		path := fmt.Sprintf("quotas[%d]", i)
+
+		if err := jsonutil.Validate(definition); err != nil {
+			problems = append(problems, prefixErrors(path, err)...)
+		}
+
+		if j, used := names[definition.Name]; used {
+			problems = append(problems, fmt.Sprintf("%s.name: name %q is already used by quotas[%d]", path, definition.Name, j))
+		} else {
+			names[definition.Name] = i
+		}
+
+		quota := CreateQuotaCellBody{Name: definition.Name, Capacity: definition.Capacity}
+
+		criteria, rulesProblems := importRules(path+".criteria", definition.Criteria, fields)
+		problems = append(problems, rulesProblems...)
+
+		if len(rulesProblems) == 0 && len(criteria) > 0 {
+			parsed, err := ParseQuotaCriteria(criteria)
+			if err != nil {
+				problems = append(problems, prefixErrors(path, err)...)
+			} else {
+				quota.Criteria, _ = json.Marshal(parsed)
+			}
+		}
+
+		quotas = append(quotas, quota)
+	}
+
+	return quotas, problems
+}
+
+// documentPaths turns the error ValidateLogic returns into problems pointing at the place of the
+// questions in the document, where ValidateLogic names them by their ID.
+func documentPaths(err error, index map[int64]int) []string {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{fmt.Sprintf("questions: %v", err)}
+	}
+
+	problems := make([]string, 0, len(validationErrors))
+	for _, problem := range validationErrors {
+		if rest, ok := strings.CutPrefix(problem, "questions["); ok {
+			end := strings.Index(rest, "]")
+			if end > 0 {
+				id, err := strconv.ParseInt(rest[:end], 10, 64)
+				if position, ok := index[id]; err == nil && ok {
+					problem = fmt.Sprintf("questions[%d]%s", position, rest[end+1:])
+				}
+			}
+		}
+
+		problems = append(problems, problem)
+	}
+
+	return problems
+}
+
+// exportRules writes stored targeting rules as a definition carries them, naming the fields they
+// refer to.
+func exportRules(raw json.RawMessage, fields map[int64]string) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var rules map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+
+	for _, key := range ruleFieldKeys {
+		value, ok := rules[key]
+		if !ok {
+			continue
+		}
+
+		var ids []int64
+		if err := json.Unmarshal(value, &ids); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+
+		names := make([]string, 0, len(ids))
+		for _, id := range ids {
+			name, ok := fields[id]
+			if !ok {
+				return nil, fmt.Errorf("%s: field %d does not exist", key, id)
+			}
+			names = append(names, name)
+		}
+
+		rules[key], _ = json.Marshal(names)
+	}
+
+	return json.Marshal(rules)
+}
+
+// importRules turns the targeting rules of a definition back into stored ones, replacing the
+// names of fields with their IDs. Rules that don't decode are returned as they are, for parsing
+// them to report why.
+func importRules(path string, raw json.RawMessage, fields map[string]int64) (json.RawMessage, []string) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var rules map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return raw, nil
+	}
+
+	var problems []string
+
+	for _, key := range ruleFieldKeys {
+		value, ok := rules[key]
+		if !ok {
+			continue
+		}
+
+		var names []string
+		if err := json.Unmarshal(value, &names); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s: %s must be a list of field names", path, key, key))
+			continue
+		}
+
+		ids := make([]int64, 0, len(names))
+		for i, name := range names {
+			id, ok := fields[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s[%d]: field %q does not exist", path, key, i, name))
+				continue
+			}
+			ids = append(ids, id)
+		}
+
+		rules[key], _ = json.Marshal(ids)
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	converted, err := json.Marshal(rules)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+
+	return converted, nil
+}
+
+// ruleFieldValues returns what targeting rules hold under the keys that refer to fields: IDs in
+// stored rules, names in a definition. Rules that don't decode hold none.
+func ruleFieldValues[T any](raw json.RawMessage) []T {
+	var rules map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil
+	}
+
+	var values []T
+	for _, key := range ruleFieldKeys {
+		var value []T
+		if err := json.Unmarshal(rules[key], &value); err == nil {
+			values = append(values, value...)
+		}
+	}
+
+	return values
+}
+
+// ExportDefinitionHandler downloads the definition of a survey as a document ImportDefinitionHandler
+// takes back.
+func (h *Handler) ExportDefinitionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	cells, err := h.Store.ListQuotaCells(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	fields, err := h.Store.ListFields(ctx, DefinitionFieldIDs(survey, cells))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	names := make(map[int64]string, len(fields))
+	for _, field := range fields {
+		names[field.ID] = field.Name
+	}
+
+	definition, err := NewDefinition(survey, questions, cells, names)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	// indented, so the document reads well and diffs well once it is kept in version control
+	document, err := json.MarshalIndent(definition, "", "  ")
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="survey-%d.json"`, survey.ID))
+	responseWriter.WriteHeader(http.StatusOK)
+	_, _ = responseWriter.Write(append(document, '\n'))
+	return
+}
+
+// ImportDefinitionHandler creates a draft from a survey definition. Nothing is created unless the
+// whole document is valid.
+func (h *Handler) ImportDefinitionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+
+	var definition Definition
+	if err := decoder.Decode(&definition); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: fmt.Sprintf("invalid survey definition: %v", err),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	fields, err := h.Store.FindFields(ctx, definition.FieldNames())
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	ids := make(map[string]int64, len(fields))
+	for _, field := range fields {
+		ids[field.Name] = field.ID
+	}
+
+	imported, err := CheckDefinition(definition, ids)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.ImportSurvey(ctx, int64(userID), imported)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "survey imported successfully",
+		Data:    NewSurvey(survey),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
@@ -0,0 +1,251 @@
+package surveys_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newDefinitionStore is newCloneStore with the survey's targeting and its quota cell requiring the
+// transport field.
+func newDefinitionStore() *StubSurveyStore {
+	store := newCloneStore()
+	store.Fields = map[int64]string{3: "Transport", 4: "Economics"}
+
+	survey := store.Surveys[1]
+	survey.Targeting = []byte(`{"genders": ["female"], "required_fields": [3]}`)
+	store.Surveys[1] = survey
+
+	cell := store.Cells[1]
+	cell.Criteria = []byte(`{"excluded_fields": [4]}`)
+	store.Cells[1] = cell
+
+	return store
+}
+
+func compact(raw json.RawMessage) string {
+	var buffer bytes.Buffer
+	_ = json.Compact(&buffer, raw)
+	return buffer.String()
+}
+
+func exportDefinition(t *testing.T, store *StubSurveyStore) (*httptest.ResponseRecorder, surveys.Definition) {
+	t.Helper()
+
+	handler := &surveys.Handler{Store: store}
+
+	req := newRequest(http.MethodGet, "/surveys/1/definition", nil, 1, map[string]string{"surveyID": "1"})
+	rec := httptest.NewRecorder()
+
+	handler.ExportDefinitionHandler(rec, req)
+
+	var definition surveys.Definition
+	_ = json.Unmarshal(rec.Body.Bytes(), &definition)
+
+	return rec, definition
+}
+
+func importDefinition(store *StubSurveyStore, document []byte) *httptest.ResponseRecorder {
+	handler := &surveys.Handler{Store: store}
+
+	req := newRequest(http.MethodPost, "/surveys/import", document, 2, nil)
+	rec := httptest.NewRecorder()
+
+	handler.ImportDefinitionHandler(rec, req)
+
+	return rec
+}
+
+// ============================================================================
+// ExportDefinitionHandler Tests
+// ============================================================================
+
+func TestExportDefinitionHandler(t *testing.T) {
+
+	t.Run("downloads the whole survey as one document", func(t *testing.T) {
+		rec, definition := exportDefinition(t, newDefinitionStore())
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="survey-1.json"` {
+			t.Errorf("disposition = %q", disposition)
+		}
+
+		if definition.Format != surveys.DefinitionFormat || definition.Version != surveys.DefinitionVersion {
+			t.Errorf("format = %q version %d", definition.Format, definition.Version)
+		}
+
+		if definition.Survey.Title != "Commuting" || len(definition.Questions) != 3 || len(definition.Quotas) != 1 {
+			t.Errorf("definition = %+v, want survey 1 with 3 questions and 1 quota", definition)
+		}
+	})
+
+	t.Run("names fields instead of giving their ids", func(t *testing.T) {
+		_, definition := exportDefinition(t, newDefinitionStore())
+
+		if !slices.Equal(definition.Survey.Fields, []string{"Transport"}) {
+			t.Errorf("fields = %v, want Transport", definition.Survey.Fields)
+		}
+
+		if compact(definition.Survey.Targeting) != `{"genders":["female"],"required_fields":["Transport"]}` {
+			t.Errorf("targeting = %s, want the field named", definition.Survey.Targeting)
+		}
+
+		if compact(definition.Quotas[0].Criteria) != `{"excluded_fields":["Economics"]}` {
+			t.Errorf("criteria = %s, want the field named", definition.Quotas[0].Criteria)
+		}
+	})
+
+	t.Run("numbers questions by their place in the document", func(t *testing.T) {
+		store := newDefinitionStore()
+		// ids no longer follow the order the questions are asked in
+		question := store.Questions[3]
+		question.ID = 7
+		store.Questions[7] = question
+		delete(store.Questions, 3)
+		first := store.Questions[1]
+		first.Logic = []byte(`{"skip": [{"when": {"question_id": 1, "operator": "equals", "value": "walk"}, "skip_to": 7}]}`)
+		store.Questions[1] = first
+
+		_, definition := exportDefinition(t, store)
+
+		for i, question := range definition.Questions {
+			if question.ID != int64(i+1) {
+				t.Errorf("questions[%d].id = %d, want %d", i, question.ID, i+1)
+			}
+		}
+
+		logic, _ := surveys.ParseQuestionLogic(definition.Questions[0].Logic)
+		if logic.Skip[0].SkipTo != 3 {
+			t.Errorf("skip_to = %d, want the third question of the document", logic.Skip[0].SkipTo)
+		}
+	})
+}
+
+// ============================================================================
+// ImportDefinitionHandler Tests
+// ============================================================================
+
+func TestImportDefinitionHandler(t *testing.T) {
+
+	t.Run("imports an exported survey as a new draft", func(t *testing.T) {
+		store := newDefinitionStore()
+		rec, _ := exportDefinition(t, store)
+
+		rec = importDefinition(store, rec.Body.Bytes())
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		got := clonedSurvey(t, rec)
+		if got.ID == 1 || got.ResearcherID != 2 || got.Status != "draft" || got.Title != "Commuting" {
+			t.Fatalf("survey = %+v, want a draft copy of survey 1 for researcher 2", got)
+		}
+
+		if string(got.Targeting) != `{"genders":["female"],"required_fields":[3]}` {
+			t.Errorf("targeting = %s, want the field id back", got.Targeting)
+		}
+
+		questions, _ := store.ListQuestions(t.Context(), got.ID)
+		if len(questions) != 3 {
+			t.Fatalf("imported %d questions, want 3", len(questions))
+		}
+
+		if err := surveys.ValidateLogic(questions); err != nil {
+			t.Errorf("imported logic is invalid: %v", err)
+		}
+
+		cells, _ := store.ListQuotaCells(t.Context(), got.ID)
+		if len(cells) != 1 || string(cells[0].Criteria) != `{"excluded_fields":[4]}` {
+			t.Errorf("cells = %+v, want the quota with its field id back", cells)
+		}
+	})
+
+	t.Run("reports every problem with its path at once", func(t *testing.T) {
+		store := newDefinitionStore()
+
+		document := []byte(`{
+			"format": "answerly.survey",
+			"version": 1,
+			"survey": {"fields": ["Transport", "Astrology"], "targeting": {"min_age": 8}, "reward_per_response": "-1"},
+			"questions": [
+				{"id": 1, "type": "single_choice", "title": "How do you commute?", "config": {"options": []}},
+				{"id": 1, "type": "essay", "title": "Why?", "config": {}}
+			],
+			"quotas": [{"name": "astrologers", "criteria": {"required_fields": ["Astrology"]}, "capacity": 0}]
+		}`)
+
+		rec := importDefinition(store, document)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		body := rec.Body.String()
+		for _, path := range []string{
+			"survey.title:",
+			"survey.reward_per_response:",
+			`survey.fields[1]: field \"Astrology\" does not exist`,
+			"survey.targeting.min_age:",
+			"questions[0].config.options:",
+			"questions[1].id: id 1 is already used by questions[0]",
+			"questions[1].type:",
+			"quotas[0].capacity:",
+			`quotas[0].criteria.required_fields[0]: field \"Astrology\" does not exist`,
+		} {
+			if !strings.Contains(body, path) {
+				t.Errorf("expected a problem at %s in %s", path, body)
+			}
+		}
+
+		if len(store.Surveys) != 1 {
+			t.Errorf("expected nothing to be imported")
+		}
+	})
+
+	t.Run("reports logic problems at the place of the question in the document", func(t *testing.T) {
+		store := newDefinitionStore()
+
+		document := []byte(`{
+			"format": "answerly.survey",
+			"version": 1,
+			"survey": {"title": "Commuting"},
+			"questions": [
+				{"id": 10, "type": "text", "title": "Why?", "config": {"max_length": 500}, "logic": {"display_if": {"question_id": 20, "operator": "answered"}}},
+				{"id": 20, "type": "text", "title": "Why not?", "config": {"max_length": 500}, "logic": {"skip": [{"when": {"question_id": 20, "operator": "answered"}, "skip_to": 30}]}}
+			]
+		}`)
+
+		rec := importDefinition(store, document)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		body := rec.Body.String()
+		for _, path := range []string{
+			"questions[0].logic.display_if.question_id: question 20 is not answered yet",
+			"questions[1].logic.skip[0].skip_to: question 30 does not exist",
+		} {
+			if !strings.Contains(body, path) {
+				t.Errorf("expected %q in %s", path, body)
+			}
+		}
+	})
+
+	t.Run("returns 400 for a version it doesn't read", func(t *testing.T) {
+		rec := importDefinition(newDefinitionStore(), []byte(`{"format": "answerly.survey", "version": 2, "survey": {"title": "Commuting"}}`))
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		if !strings.Contains(rec.Body.String(), "version 2 is not supported") {
+			t.Errorf("body = %s", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 400 for unknown keys", func(t *testing.T) {
+		rec := importDefinition(newDefinitionStore(), []byte(`{"format": "answerly.survey", "version": 1, "survey": {"title": "Commuting"}, "pages": []}`))
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,595 @@
+package surveys
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CreateSurveyBody struct {
+	Title             string           `json:"title" validate:"required,max=255"`
+	Description       string           `json:"description"`
+	RewardPerResponse *decimal.Decimal `json:"reward_per_response"`
+	TargetResponses   *int32           `json:"target_responses" validate:"omitempty,gte=1"`
+	Targeting         json.RawMessage  `json:"targeting"`
+	FieldIDs          []int64          `json:"field_ids" validate:"omitempty,unique,dive,gt=0"`
+	EstimatedMinutes  *int32           `json:"estimated_minutes" validate:"omitempty,gte=1,lte=600"`
+	ScreenOutFee      *decimal.Decimal `json:"screen_out_fee"`
+	ShareDemographics *bool            `json:"share_demographics"`
+	QualityChecks     json.RawMessage  `json:"quality_checks"`
+	ResumeWindowHours *int32           `json:"resume_window_hours" validate:"omitempty,gte=1,lte=720"`
+	Anonymous         *bool            `json:"anonymous"`
+	// OrganizationID creates the survey in an organization the researcher can edit the surveys of,
+	// whose wallet then pays for it.
+	OrganizationID *int64 `json:"organization_id" validate:"omitempty,gt=0"`
+}
+
+type UpdateSurveyBody struct {
+	Title             string           `json:"title" validate:"omitempty,max=255"`
+	Description       string           `json:"description"`
+	RewardPerResponse *decimal.Decimal `json:"reward_per_response"`
+	TargetResponses   *int32           `json:"target_responses" validate:"omitempty,gte=1"`
+	Targeting         json.RawMessage  `json:"targeting"`
+	FieldIDs          []int64          `json:"field_ids" validate:"omitempty,unique,dive,gt=0"`
+	EstimatedMinutes  *int32           `json:"estimated_minutes" validate:"omitempty,gte=1,lte=600"`
+	ScreenOutFee      *decimal.Decimal `json:"screen_out_fee"`
+	ShareDemographics *bool            `json:"share_demographics"`
+	QualityChecks     json.RawMessage  `json:"quality_checks"`
+	ResumeWindowHours *int32           `json:"resume_window_hours" validate:"omitempty,gte=1,lte=720"`
+	Anonymous         *bool            `json:"anonymous"`
+}
+
+type UpdateSurveyStatusBody struct {
+	Status string `json:"status" validate:"required,oneof=draft published closed cancelled archived"`
+}
+
+// UpdateLocalesBody sets the locale a survey is written in, which respondents fall back to, and the
+// other locales it is translated into.
+type UpdateLocalesBody struct {
+	DefaultLocale string   `json:"default_locale" validate:"required"`
+	Locales       []string `json:"locales" validate:"omitempty,max=20"`
+}
+
+// TranslationBody holds the strings of a survey in one locale, those of its questions keyed by
+// question ID.
+type TranslationBody struct {
+	Survey    SurveyTranslation             `json:"survey"`
+	Questions map[int64]QuestionTranslation `json:"questions" validate:"dive"`
+}
+
+// Translations is every string of a survey in the locales it is translated into, keyed by locale,
+// and the strings that are still missing.
+type Translations struct {
+	DefaultLocale string                                   `json:"default_locale"`
+	Locales       []string                                 `json:"locales"`
+	Survey        map[string]SurveyTranslation             `json:"survey"`
+	Questions     map[int64]map[string]QuestionTranslation `json:"questions"`
+	Missing       []string                                 `json:"missing"`
+}
+
+// ScheduleSurveyBody sets when a survey opens and closes. A time left out is cleared, so the
+// survey no longer opens or closes by itself.
+type ScheduleSurveyBody struct {
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+type Survey struct {
+	ID                int64            `json:"id"`
+	ResearcherID      int64            `json:"researcher_id"`
+	Title             string           `json:"title"`
+	Description       string           `json:"description"`
+	Status            string           `json:"status"`
+	RewardPerResponse *decimal.Decimal `json:"reward_per_response"`
+	TargetResponses   *int32           `json:"target_responses"`
+	Targeting         json.RawMessage  `json:"targeting,omitempty"`
+	FieldIDs          []int64          `json:"field_ids"`
+	EstimatedMinutes  *int32           `json:"estimated_minutes"`
+	ScreenOutFee      *decimal.Decimal `json:"screen_out_fee"`
+	// ShareDemographics tells respondents whether the researcher gets to see their demographics.
+	ShareDemographics bool `json:"share_demographics"`
+	// Anonymous tells respondents that the researcher sees a pseudonym in place of who they are.
+	Anonymous     bool            `json:"anonymous"`
+	QualityChecks json.RawMessage `json:"quality_checks,omitempty"`
+	// ResumeWindowHours is how long a respondent may leave a response unfinished before it
+	// expires, counted from the last time they saved it.
+	ResumeWindowHours int32 `json:"resume_window_hours"`
+	// DefaultLocale is the locale the survey is written in and respondents fall back to, Locales
+	// the other locales it is translated into. Locale is the one it is shown in.
+	DefaultLocale string   `json:"default_locale"`
+	Locales       []string `json:"locales"`
+	Locale        string   `json:"locale,omitempty"`
+	// Version counts the versions of the survey's questions, 0 until it is published.
+	Version int32 `json:"version"`
+	// Template tells whether other researchers can find the survey among the templates and copy it.
+	Template bool `json:"template"`
+	// ClonedFrom is the survey this one was copied from, if any.
+	ClonedFrom *int64 `json:"cloned_from"`
+	// OrganizationID is the organization the survey belongs to, if any. Its members share it and
+	// its wallet pays for it.
+	OrganizationID *int64 `json:"organization_id"`
+	// OpensAt and ClosesAt are when the survey is scheduled to be published and closed.
+	OpensAt     *time.Time `json:"opens_at"`
+	ClosesAt    *time.Time `json:"closes_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func NewSurvey(survey database.Survey) Survey {
+	data := Survey{
+		ID:                survey.ID,
+		ResearcherID:      survey.ResearcherID,
+		Title:             survey.Title,
+		Description:       survey.Description.String,
+		Status:            string(survey.Status),
+		Targeting:         survey.Targeting,
+		FieldIDs:          survey.FieldIds,
+		ShareDemographics: survey.ShareDemographics,
+		Anonymous:         survey.Anonymous,
+		QualityChecks:     survey.QualityChecks,
+		ResumeWindowHours: survey.ResumeWindowHours,
+		DefaultLocale:     survey.DefaultLocale,
+		Locales:           survey.Locales,
+		Version:           survey.Version,
+		Template:          survey.IsTemplate,
+		CreatedAt:         survey.CreatedAt.Time,
+		UpdatedAt:         survey.UpdatedAt.Time,
+	}
+
+	if survey.PublishedAt.Valid {
+		data.PublishedAt = &survey.PublishedAt.Time
+	}
+
+	if survey.ClosedAt.Valid {
+		data.ClosedAt = &survey.ClosedAt.Time
+	}
+
+	if survey.ClonedFrom.Valid {
+		data.ClonedFrom = &survey.ClonedFrom.Int64
+	}
+
+	if survey.OrganizationID.Valid {
+		data.OrganizationID = &survey.OrganizationID.Int64
+	}
+
+	if survey.OpensAt.Valid {
+		data.OpensAt = &survey.OpensAt.Time
+	}
+
+	if survey.ClosesAt.Valid {
+		data.ClosesAt = &survey.ClosesAt.Time
+	}
+
+	if survey.RewardPerResponse.Valid {
+		reward := database.DecimalFromNumeric(survey.RewardPerResponse)
+		data.RewardPerResponse = &reward
+	}
+
+	if survey.TargetResponses.Valid {
+		data.TargetResponses = &survey.TargetResponses.Int32
+	}
+
+	if survey.ScreenOutFee.Valid {
+		screenOutFee := database.DecimalFromNumeric(survey.ScreenOutFee)
+		data.ScreenOutFee = &screenOutFee
+	}
+
+	if survey.EstimatedMinutes.Valid {
+		data.EstimatedMinutes = &survey.EstimatedMinutes.Int32
+	}
+
+	if data.FieldIDs == nil {
+		data.FieldIDs = []int64{}
+	}
+
+	if data.Locales == nil {
+		data.Locales = []string{}
+	}
+
+	return data
+}
+
+func NewSurveys(surveys []database.Survey) []Survey {
+	data := make([]Survey, 0, len(surveys))
+	for _, survey := range surveys {
+		data = append(data, NewSurvey(survey))
+	}
+
+	return data
+}
+
+type CreateQuestionBody struct {
+	Type        string          `json:"type" validate:"required,oneof=single_choice multiple_choice likert numeric text ranking matrix"`
+	Title       string          `json:"title" validate:"required"`
+	Description string          `json:"description"`
+	Required    *bool           `json:"required"`
+	Position    *int32          `json:"position" validate:"omitempty,gte=1"`
+	Config      json.RawMessage `json:"config" validate:"required"`
+	Logic       json.RawMessage `json:"logic"`
+	Screener    *bool           `json:"screener"`
+	// Block puts the question in a block, whose questions are shown one after another in a
+	// different order to every respondent.
+	Block string `json:"block" validate:"omitempty,max=64,key"`
+}
+
+type UpdateQuestionBody struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Required    *bool           `json:"required"`
+	Position    *int32          `json:"position" validate:"omitempty,gte=1"`
+	Config      json.RawMessage `json:"config"`
+	Logic       json.RawMessage `json:"logic"`
+	Screener    *bool           `json:"screener"`
+	// Block moves the question to another block, an empty one takes it out of its block.
+	Block *string `json:"block" validate:"omitempty,max=64,key"`
+}
+
+type Question struct {
+	ID          int64           `json:"id"`
+	SurveyID    int64           `json:"survey_id"`
+	Position    int32           `json:"position"`
+	Type        string          `json:"type"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Required    bool            `json:"required"`
+	Config      json.RawMessage `json:"config"`
+	Logic       json.RawMessage `json:"logic,omitempty"`
+	Screener    bool            `json:"screener"`
+	Block       string          `json:"block,omitempty"`
+}
+
+func NewQuestion(question database.Question) Question {
+	return Question{
+		ID:          question.ID,
+		SurveyID:    question.SurveyID,
+		Position:    question.Position,
+		Type:        string(question.Type),
+		Title:       question.Title,
+		Description: question.Description.String,
+		Required:    question.Required,
+		Config:      question.Config,
+		Logic:       question.Logic,
+		Screener:    question.Screener,
+		Block:       question.Block.String,
+	}
+}
+
+func NewQuestions(questions []database.Question) []Question {
+	data := make([]Question, 0, len(questions))
+	for _, question := range questions {
+		data = append(data, NewQuestion(question))
+	}
+
+	return data
+}
+
+// SurveyVersion is the questions of a published survey as they stood at one version.
+type SurveyVersion struct {
+	Version   int32      `json:"version"`
+	Questions []Question `json:"questions"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AnswerImpact tells how many answers already given to a question a change to it would make
+// invalid.
+type AnswerImpact struct {
+	QuestionID         int64 `json:"question_id"`
+	InvalidatedAnswers int32 `json:"invalidated_answers"`
+}
+
+type CloneSurveyBody struct {
+	// Title names the copy, which keeps the title of the original when it is left out.
+	Title string `json:"title" validate:"omitempty,max=255"`
+}
+
+type UpdateTemplateBody struct {
+	Template *bool `json:"template" validate:"required"`
+}
+
+// Template is a survey shared for other researchers to copy.
+type Template struct {
+	ID               int64     `json:"id"`
+	ResearcherID     int64     `json:"researcher_id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	FieldIDs         []int64   `json:"field_ids"`
+	EstimatedMinutes *int32    `json:"estimated_minutes"`
+	Questions        int32     `json:"questions"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func NewTemplate(row database.ListTemplatesRow) Template {
+	data := Template{
+		ID:           row.ID,
+		ResearcherID: row.ResearcherID,
+		Title:        row.Title,
+		Description:  row.Description.String,
+		FieldIDs:     row.FieldIds,
+		Questions:    row.Questions,
+		UpdatedAt:    row.UpdatedAt.Time,
+	}
+
+	if row.EstimatedMinutes.Valid {
+		data.EstimatedMinutes = &row.EstimatedMinutes.Int32
+	}
+
+	return data
+}
+
+// TemplatePage is a page of templates. NextAfter is passed back as after to get the next page, it
+// is left out on the last one.
+type TemplatePage struct {
+	Templates []Template `json:"templates"`
+	NextAfter int64      `json:"next_after,omitempty"`
+}
+
+func NewTemplatePage(rows []database.ListTemplatesRow, limit int32) TemplatePage {
+	page := TemplatePage{Templates: make([]Template, 0, len(rows))}
+
+	for _, row := range rows {
+		page.Templates = append(page.Templates, NewTemplate(row))
+	}
+
+	if len(rows) > 0 && int32(len(rows)) == limit {
+		page.NextAfter = rows[len(rows)-1].ID
+	}
+
+	return page
+}
+
+// TemplatePreview is a template with the questions a copy of it starts out with.
+type TemplatePreview struct {
+	Survey    Survey     `json:"survey"`
+	Questions []Question `json:"questions"`
+}
+
+type CreateQuotaCellBody struct {
+	Name     string          `json:"name" validate:"required,max=255"`
+	Criteria json.RawMessage `json:"criteria" validate:"required"`
+	Capacity int32           `json:"capacity" validate:"required,gte=1"`
+}
+
+type QuotaCell struct {
+	ID       int64           `json:"id"`
+	SurveyID int64           `json:"survey_id"`
+	Name     string          `json:"name"`
+	Criteria json.RawMessage `json:"criteria"`
+	Capacity int32           `json:"capacity"`
+	Filled   int32           `json:"filled"`
+	// Held counts the responses in progress holding a slot in the cell until they are submitted
+	// or expire.
+	Held int32 `json:"held"`
+	// Full cells take no more responses, they close on their own once filled and held slots
+	// reach capacity.
+	Full bool `json:"full"`
+}
+
+func NewQuotaCell(cell database.QuotaCell) QuotaCell {
+	return QuotaCell{
+		ID:       cell.ID,
+		SurveyID: cell.SurveyID,
+		Name:     cell.Name,
+		Criteria: cell.Criteria,
+		Capacity: cell.Capacity,
+		Filled:   cell.Filled,
+		Held:     cell.Held,
+		Full:     cell.Filled+cell.Held >= cell.Capacity,
+	}
+}
+
+func NewQuotaCells(cells []database.QuotaCell) []QuotaCell {
+	data := make([]QuotaCell, 0, len(cells))
+	for _, cell := range cells {
+		data = append(data, NewQuotaCell(cell))
+	}
+
+	return data
+}
+
+type Escrow struct {
+	SurveyID          int64           `json:"survey_id"`
+	RewardPerResponse decimal.Decimal `json:"reward_per_response"`
+	FeePerResponse    decimal.Decimal `json:"fee_per_response"`
+	ScreenOutFee      decimal.Decimal `json:"screen_out_fee"`
+	Amount            decimal.Decimal `json:"amount"`
+	Spent             decimal.Decimal `json:"spent"`
+	// Reserved is set aside for submitted responses awaiting review.
+	Reserved decimal.Decimal `json:"reserved"`
+	Refunded decimal.Decimal `json:"refunded"`
+	Status   string          `json:"status"`
+}
+
+func NewEscrow(escrow database.Escrow) Escrow {
+	return Escrow{
+		SurveyID:          escrow.SurveyID,
+		RewardPerResponse: database.DecimalFromNumeric(escrow.RewardPerResponse),
+		FeePerResponse:    database.DecimalFromNumeric(escrow.FeePerResponse),
+		ScreenOutFee:      database.DecimalFromNumeric(escrow.ScreenOutFee),
+		Amount:            database.DecimalFromNumeric(escrow.Amount),
+		Spent:             database.DecimalFromNumeric(escrow.Spent),
+		Reserved:          database.DecimalFromNumeric(escrow.Reserved),
+		Refunded:          database.DecimalFromNumeric(escrow.Refunded),
+		Status:            string(escrow.Status),
+	}
+}
+
+type SurveyStats struct {
+	SurveyID    int64 `json:"survey_id"`
+	Started     int32 `json:"started"`
+	InProgress  int32 `json:"in_progress"`
+	Submitted   int32 `json:"submitted"`
+	ScreenedOut int32 `json:"screened_out"`
+	// Expired counts the responses left unfinished past the survey's resume window.
+	Expired int32 `json:"expired"`
+	// ScreenOutRate is the share of finished attempts that were screened out.
+	ScreenOutRate     decimal.Decimal `json:"screen_out_rate"`
+	RewardsPaid       decimal.Decimal `json:"rewards_paid"`
+	ScreenOutFeesPaid decimal.Decimal `json:"screen_out_fees_paid"`
+	// AwaitingReview counts the submitted responses that are neither approved nor rejected yet.
+	AwaitingReview int32 `json:"awaiting_review"`
+	Rejected       int32 `json:"rejected"`
+}
+
+func NewSurveyStats(surveyID int64, stats database.GetSurveyStatsRow) SurveyStats {
+	data := SurveyStats{
+		SurveyID:          surveyID,
+		Started:           stats.Started,
+		InProgress:        stats.InProgress,
+		Submitted:         stats.Submitted,
+		ScreenedOut:       stats.ScreenedOut,
+		Expired:           stats.Expired,
+		ScreenOutRate:     decimal.Zero,
+		RewardsPaid:       database.DecimalFromNumeric(stats.RewardsPaid),
+		ScreenOutFeesPaid: database.DecimalFromNumeric(stats.ScreenOutFeesPaid),
+		AwaitingReview:    stats.AwaitingReview,
+		Rejected:          stats.Rejected,
+	}
+
+	if finished := stats.Submitted + stats.ScreenedOut; finished > 0 {
+		data.ScreenOutRate = decimal.NewFromInt32(stats.ScreenedOut).DivRound(decimal.NewFromInt32(finished), 4)
+	}
+
+	return data
+}
+
+type FeedSurvey struct {
+	ID                int64           `json:"id"`
+	Title             string          `json:"title"`
+	Description       string          `json:"description"`
+	RewardPerResponse decimal.Decimal `json:"reward_per_response"`
+	EstimatedMinutes  int32           `json:"estimated_minutes"`
+	RewardPerMinute   decimal.Decimal `json:"reward_per_minute"`
+	MatchingFields    int32           `json:"matching_fields"`
+	FieldIDs          []int64         `json:"field_ids"`
+	ShareDemographics bool            `json:"share_demographics"`
+	Anonymous         bool            `json:"anonymous"`
+	// Locale is the locale the title and description are shown in.
+	Locale      string     `json:"locale"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+func NewFeedSurvey(survey database.ListFeedSurveysRow) FeedSurvey {
+	data := FeedSurvey{
+		ID:                survey.ID,
+		Title:             survey.Title,
+		Description:       survey.Description.String,
+		RewardPerResponse: database.DecimalFromNumeric(survey.RewardPerResponse),
+		EstimatedMinutes:  survey.Minutes,
+		RewardPerMinute:   database.DecimalFromNumeric(survey.RewardPerMinute),
+		MatchingFields:    survey.Overlap,
+		FieldIDs:          survey.FieldIds,
+		ShareDemographics: survey.ShareDemographics,
+		Anonymous:         survey.Anonymous,
+		Locale:            survey.DefaultLocale,
+	}
+
+	if survey.PublishedAt.Valid {
+		data.PublishedAt = &survey.PublishedAt.Time
+	}
+
+	if data.FieldIDs == nil {
+		data.FieldIDs = []int64{}
+	}
+
+	return data
+}
+
+type Feed struct {
+	Surveys    []FeedSurvey `json:"surveys"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// ExportLine is one response in a JSONL export. Answers are keyed like the CSV columns, q1, q2 and
+// so on, and hold the answer as it was given. OrderSeed and Display are only set for surveys that
+// randomize the order of their questions or options; Display is keyed like Answers. Test is only
+// set when the export includes test responses.
+type ExportLine struct {
+	ResponseID    int64                      `json:"response_id"`
+	Status        string                     `json:"status"`
+	StartedAt     time.Time                  `json:"started_at"`
+	SubmittedAt   *time.Time                 `json:"submitted_at"`
+	SurveyVersion int32                      `json:"survey_version"`
+	Test          *bool                      `json:"test,omitempty"`
+	Pseudonym     string                     `json:"pseudonym,omitempty"`
+	OrderSeed     *int64                     `json:"order_seed,omitempty"`
+	Demographics  *ExportDemographics        `json:"demographics,omitempty"`
+	Answers       map[string]json.RawMessage `json:"answers"`
+	Display       map[string]Display         `json:"display,omitempty"`
+}
+
+// ExportDemographics is what an export tells about a respondent, when the survey shares it.
+type ExportDemographics struct {
+	Age        *int   `json:"age"`
+	Gender     string `json:"gender"`
+	University string `json:"university"`
+	Faculty    string `json:"faculty"`
+	Location   string `json:"location"`
+}
+
+type Results struct {
+	SurveyID int64 `json:"survey_id"`
+	// Responses is the number of submitted responses the results are worked out from.
+	Responses int32            `json:"responses"`
+	Questions []QuestionResult `json:"questions"`
+	CrossTab  *CrossTab        `json:"crosstab,omitempty"`
+}
+
+// QuestionResult is the distribution of the answers to a question. Choice and likert questions
+// have Choices, matrix and ranking questions have Rows, numeric and likert questions have Stats.
+type QuestionResult struct {
+	QuestionID int64          `json:"question_id"`
+	Position   int32          `json:"position"`
+	Title      string         `json:"title"`
+	Type       string         `json:"type"`
+	Answered   int32          `json:"answered"`
+	Removed    bool           `json:"removed,omitempty"`
+	Choices    []ChoiceResult `json:"choices,omitempty"`
+	Rows       []RowResult    `json:"rows,omitempty"`
+	Stats      *NumericStats  `json:"stats,omitempty"`
+}
+
+type ChoiceResult struct {
+	ID         string          `json:"id"`
+	Label      string          `json:"label"`
+	Count      int32           `json:"count"`
+	Percentage decimal.Decimal `json:"percentage"`
+	// Suppressed is set when the count was withheld, leaving it and the percentage at 0, because it
+	// could single out respondents to an anonymous survey.
+	Suppressed bool `json:"suppressed,omitempty"`
+	// Positions breaks Count down by where the option was shown, for questions that shuffle their
+	// options.
+	Positions []PositionResult `json:"positions,omitempty"`
+}
+
+// PositionResult is how many times an option was picked when shown at Position, counted from 1.
+type PositionResult struct {
+	Position int   `json:"position"`
+	Count    int32 `json:"count"`
+}
+
+type RowResult struct {
+	ID      string         `json:"id"`
+	Label   string         `json:"label"`
+	Total   int32          `json:"total"`
+	Choices []ChoiceResult `json:"choices"`
+}
+
+type NumericStats struct {
+	Count  int32           `json:"count"`
+	Mean   decimal.Decimal `json:"mean"`
+	Median decimal.Decimal `json:"median"`
+	StdDev decimal.Decimal `json:"stddev"`
+	Min    decimal.Decimal `json:"min"`
+	Max    decimal.Decimal `json:"max"`
+}
+
+// CrossTab counts the answers to a question (the rows) against the answers to another question or
+// a profile attribute (the columns), By holds the other question's ID or the attribute.
+type CrossTab struct {
+	QuestionID int64       `json:"question_id"`
+	By         string      `json:"by"`
+	Rows       []RowResult `json:"rows"`
+}
@@ -0,0 +1,94 @@
+package surveys
+
+import (
+	"fmt"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+)
+
+// FeeRate is the platform fee charged on top of every reward, as a fraction of the reward.
+var FeeRate = decimal.RequireFromString("0.10")
+
+// Budget is what publishing a survey moves from the researcher's wallet into escrow: the reward
+// and the fee for every response up to the target.
+//
+// Screen-out fees are paid from the same escrow, so every respondent screened out leaves a little
+// less for completed responses.
+type Budget struct {
+	RewardPerResponse decimal.Decimal
+	FeePerResponse    decimal.Decimal
+	ScreenOutFee      decimal.Decimal
+	TargetResponses   int32
+	Total             decimal.Decimal
+}
+
+// NewBudget works out the budget of a survey. It fails when the survey has no reward or target
+// response count yet, since neither can change once the money is in escrow.
+func NewBudget(survey database.Survey) (Budget, error) {
+	var validationErrors jsonutil.ValidationErrors
+
+	reward := database.DecimalFromNumeric(survey.RewardPerResponse)
+	if !reward.IsPositive() {
+		validationErrors = append(validationErrors, "reward_per_response: reward_per_response must be set before publishing")
+	}
+
+	if !survey.TargetResponses.Valid || survey.TargetResponses.Int32 < 1 {
+		validationErrors = append(validationErrors, "target_responses: target_responses must be set before publishing")
+	}
+
+	screenOutFee := decimal.Zero
+	if survey.ScreenOutFee.Valid {
+		screenOutFee = database.DecimalFromNumeric(survey.ScreenOutFee)
+	}
+
+	if reward.IsPositive() && screenOutFee.GreaterThanOrEqual(reward) {
+		validationErrors = append(validationErrors, "screen_out_fee: screen_out_fee must be less than reward_per_response")
+	}
+
+	if len(validationErrors) > 0 {
+		return Budget{}, validationErrors
+	}
+
+	// the fee is rounded up to the cent so the platform never takes less than its rate
+	fee := reward.Mul(FeeRate).RoundCeil(2)
+	target := survey.TargetResponses.Int32
+
+	return Budget{
+		RewardPerResponse: reward,
+		FeePerResponse:    fee,
+		ScreenOutFee:      screenOutFee,
+		TargetResponses:   target,
+		Total:             reward.Add(fee).Mul(decimal.NewFromInt32(target)),
+	}, nil
+}
+
+// validateAmounts checks the amounts sent by a researcher, which the validator can't do for decimals.
+func validateAmounts(reward, screenOutFee *decimal.Decimal) error {
+	var validationErrors jsonutil.ValidationErrors
+
+	validationErrors = append(validationErrors, checkAmount("reward_per_response", reward)...)
+	validationErrors = append(validationErrors, checkAmount("screen_out_fee", screenOutFee)...)
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return nil
+}
+
+func checkAmount(field string, amount *decimal.Decimal) []string {
+	if amount == nil {
+		return nil
+	}
+
+	if !amount.IsPositive() {
+		return []string{fmt.Sprintf("%s: %s must be greater than 0", field, field)}
+	}
+
+	if !amount.Equal(amount.Round(2)) {
+		return []string{fmt.Sprintf("%s: %s must have at most 2 decimal places", field, field)}
+	}
+
+	return nil
+}
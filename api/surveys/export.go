@@ -0,0 +1,686 @@
+package surveys
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+)
+
+// ParseExportFormat reads the format of an export request, defaulting to CSV.
+func ParseExportFormat(value string) (string, error) {
+	switch value {
+	case "", ExportCSV:
+		return ExportCSV, nil
+	case ExportJSONL:
+		return ExportJSONL, nil
+	default:
+		return "", fieldError("format", "format must be one of csv jsonl")
+	}
+}
+
+// ExportLayout is how the responses to a survey are laid out in an export. Every question gets a
+// key made from its position, q1, q2 and so on, which names its CSV column and its JSONL answer.
+// Questions taken off the survey after it was published get one made from their ID instead,
+// removed_7 and so on, as their position may since have gone to another question. Questions with
+// several parts get one CSV column per part: a column per option for multiple choice (1 when
+// picked, 0 when not) and ranking (the rank given), and a column per row for matrix. Since answers
+// are matched to questions and options by ID, answers given to any version of the survey land in
+// the right column.
+//
+// When the survey shows its questions or options in a different order to every respondent, the
+// order each respondent saw is rebuilt from the seed of their response and the version they
+// answered: the seed gets an order_seed column, every question a _position column with the place
+// it was shown at, and every question that shuffles its options an _order column listing the
+// option IDs as they were shown.
+//
+// Responses to an anonymous survey are exported under their pseudonym, in a pseudonym column, and
+// without the demographics of their respondents, whose answers next to their profile attributes
+// could single them out.
+//
+// Test responses are only exported when asked for, and then get a test column telling them apart.
+//
+// A survey translated into other locales exports the IDs of the options picked instead of their
+// labels, so the same answer reads the same whatever language the respondent saw it in.
+type ExportLayout struct {
+	questions []exportQuestion
+	// demographics is set when the survey shares its respondents' demographics with the researcher
+	demographics bool
+	anonymous    bool
+	// tests is set when the export includes test responses
+	tests bool
+	// optionIDs is set when the survey is translated, see exportQuestion.optionIDs
+	optionIDs bool
+	// versions holds the questions each version asked when the survey randomizes any order
+	versions map[int32][]database.Question
+	now      time.Time
+}
+
+type exportQuestion struct {
+	id     int64
+	key    string
+	config QuestionConfig
+	// shuffled is set when some version of the question shuffles its options
+	shuffled bool
+	// optionIDs writes the options picked as their ID rather than their label
+	optionIDs bool
+}
+
+// NewExportLayout lays out the export of a survey with the given question history, with or without
+// its test responses. Ages are worked out as of now.
+func NewExportLayout(survey database.Survey, history QuestionHistory, includeTests bool, now time.Time) (ExportLayout, error) {
+	layout := ExportLayout{
+		demographics: survey.ShareDemographics && !survey.Anonymous,
+		anonymous:    survey.Anonymous,
+		tests:        includeTests,
+		optionIDs:    len(survey.Locales) > 0,
+		now:          now,
+	}
+
+	questions := slices.Clone(history.Current)
+	slices.SortFunc(questions, func(a, b database.Question) int {
+		return int(a.Position - b.Position)
+	})
+
+	for _, question := range questions {
+		if err := layout.add(question, fmt.Sprintf("q%d", question.Position)); err != nil {
+			return ExportLayout{}, err
+		}
+	}
+
+	for _, question := range history.Removed {
+		if err := layout.add(question, fmt.Sprintf("removed_%d", question.ID)); err != nil {
+			return ExportLayout{}, err
+		}
+	}
+
+	index := make(map[int64]int, len(layout.questions))
+	for i, question := range layout.questions {
+		index[question.id] = i
+	}
+
+	randomized := Randomized(history.Current)
+	for _, questions := range history.Versions {
+		randomized = randomized || Randomized(questions)
+
+		for _, question := range questions {
+			if i, ok := index[question.ID]; ok && shufflesOptions(question) {
+				layout.questions[i].shuffled = true
+			}
+		}
+	}
+
+	if randomized {
+		layout.versions = history.Versions
+	}
+
+	return layout, nil
+}
+
+func (l *ExportLayout) add(question database.Question, key string) error {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	if err != nil {
+		return fmt.Errorf("question %d: %v", question.ID, err)
+	}
+
+	l.questions = append(l.questions, exportQuestion{
+		id:        question.ID,
+		key:       key,
+		config:    config,
+		shuffled:  shuffledOptions(config) != nil,
+		optionIDs: l.optionIDs,
+	})
+
+	return nil
+}
+
+// Header returns the CSV header row.
+func (l ExportLayout) Header() []string {
+	header := []string{"response_id", "status", "started_at", "submitted_at", "survey_version"}
+
+	if l.tests {
+		header = append(header, "test")
+	}
+
+	if l.anonymous {
+		header = append(header, "pseudonym")
+	}
+
+	if l.randomized() {
+		header = append(header, "order_seed")
+	}
+
+	if l.demographics {
+		header = append(header, "age", "gender", "university", "faculty", "location")
+	}
+
+	for _, question := range l.questions {
+		switch config := question.config.(type) {
+		case *MultipleChoiceConfig:
+			header = append(header, optionColumns(question.key, config.Options)...)
+		case *RankingConfig:
+			header = append(header, optionColumns(question.key, config.Options)...)
+		case *MatrixConfig:
+			header = append(header, optionColumns(question.key, config.Rows)...)
+		default:
+			header = append(header, question.key)
+		}
+
+		if l.randomized() {
+			header = append(header, question.key+"_position")
+			if question.shuffled {
+				header = append(header, question.key+"_order")
+			}
+		}
+	}
+
+	return header
+}
+
+// Record returns the CSV row of a response, matching Header.
+func (l ExportLayout) Record(row database.ListSurveyExportRowsRow) ([]string, error) {
+	answers, err := decodeExportAnswers(row)
+	if err != nil {
+		return nil, err
+	}
+
+	displays := l.displays(row)
+
+	record := []string{
+		strconv.FormatInt(row.ID, 10), string(row.Status), formatTimestamp(row.StartedAt), formatTimestamp(row.SubmittedAt),
+		strconv.Itoa(int(row.SurveyVersion)),
+	}
+
+	if l.tests {
+		record = append(record, strconv.FormatBool(row.IsTest))
+	}
+
+	if l.anonymous {
+		record = append(record, row.Pseudonym.String)
+	}
+
+	if l.randomized() {
+		record = append(record, strconv.FormatInt(row.OrderSeed, 10))
+	}
+
+	if l.demographics {
+		demographics := l.demographicsOf(row)
+
+		age := ""
+		if demographics.Age != nil {
+			age = strconv.Itoa(*demographics.Age)
+		}
+
+		record = append(record, age, demographics.Gender, csvText(demographics.University), csvText(demographics.Faculty), csvText(demographics.Location))
+	}
+
+	for _, question := range l.questions {
+		cells, err := question.cells(answers[strconv.FormatInt(question.id, 10)])
+		if err != nil {
+			return nil, fmt.Errorf("response %d, %s: %v", row.ID, question.key, err)
+		}
+
+		record = append(record, cells...)
+
+		if l.randomized() {
+			// a question the response's version didn't ask has neither
+			display, shown := displays[question.id]
+
+			position := ""
+			if shown {
+				position = strconv.Itoa(int(display.Position))
+			}
+			record = append(record, position)
+
+			if question.shuffled {
+				record = append(record, strings.Join(display.Options, " "))
+			}
+		}
+	}
+
+	return record, nil
+}
+
+// Line returns the JSONL line of a response, with the answers as they were given.
+func (l ExportLayout) Line(row database.ListSurveyExportRowsRow) (ExportLine, error) {
+	answers, err := decodeExportAnswers(row)
+	if err != nil {
+		return ExportLine{}, err
+	}
+
+	line := ExportLine{
+		ResponseID:    row.ID,
+		Status:        string(row.Status),
+		StartedAt:     row.StartedAt.Time,
+		SurveyVersion: row.SurveyVersion,
+		Answers:       make(map[string]json.RawMessage, len(answers)),
+	}
+
+	if row.SubmittedAt.Valid {
+		line.SubmittedAt = &row.SubmittedAt.Time
+	}
+
+	if l.tests {
+		line.Test = &row.IsTest
+	}
+
+	if l.anonymous {
+		line.Pseudonym = row.Pseudonym.String
+	}
+
+	if l.demographics {
+		demographics := l.demographicsOf(row)
+		line.Demographics = &demographics
+	}
+
+	if l.randomized() {
+		line.OrderSeed = &row.OrderSeed
+		line.Display = make(map[string]Display)
+	}
+
+	displays := l.displays(row)
+
+	for _, question := range l.questions {
+		if answer, ok := answers[strconv.FormatInt(question.id, 10)]; ok {
+			line.Answers[question.key] = answer
+		}
+
+		if display, shown := displays[question.id]; shown {
+			line.Display[question.key] = display
+		}
+	}
+
+	return line, nil
+}
+
+func (l ExportLayout) randomized() bool {
+	return l.versions != nil
+}
+
+// displays rebuilds where the questions of the version a response answered, and their options,
+// were shown to its respondent. It is empty when the survey shows everyone the same order.
+func (l ExportLayout) displays(row database.ListSurveyExportRowsRow) map[int64]Display {
+	if !l.randomized() {
+		return nil
+	}
+
+	return Displays(Arrange(l.versions[row.SurveyVersion], row.OrderSeed))
+}
+
+func (l ExportLayout) demographicsOf(row database.ListSurveyExportRowsRow) ExportDemographics {
+	demographics := ExportDemographics{
+		University: row.University.String,
+		Faculty:    row.Faculty.String,
+		Location:   row.Location.String,
+	}
+
+	// the age rather than the date of birth, which would say more about the respondent than needed
+	if row.DateOfBirth.Valid {
+		age := ageOn(row.DateOfBirth.Time, l.now)
+		demographics.Age = &age
+	}
+
+	if row.Gender.Valid {
+		demographics.Gender = string(row.Gender.Gender)
+	}
+
+	return demographics
+}
+
+// cells turns an answer into the CSV cells of its question, all empty when it wasn't answered.
+func (q exportQuestion) cells(raw json.RawMessage) ([]string, error) {
+	switch config := q.config.(type) {
+	case *SingleChoiceConfig:
+		if raw == nil {
+			return []string{""}, nil
+		}
+
+		var answer SingleChoiceAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+		return []string{q.optionCell(config.Options, answer.OptionID)}, nil
+
+	case *MultipleChoiceConfig:
+		cells := make([]string, len(config.Options))
+		if raw == nil {
+			return cells, nil
+		}
+
+		var answer MultipleChoiceAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+
+		for i, option := range config.Options {
+			cells[i] = "0"
+			if slices.Contains(answer.OptionIDs, option.ID) {
+				cells[i] = "1"
+			}
+		}
+		return cells, nil
+
+	case *LikertConfig:
+		if raw == nil {
+			return []string{""}, nil
+		}
+
+		var answer LikertAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+		return []string{strconv.Itoa(answer.Value)}, nil
+
+	case *NumericConfig:
+		if raw == nil {
+			return []string{""}, nil
+		}
+
+		var answer NumericAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+
+		if answer.Value == nil {
+			return []string{""}, nil
+		}
+		return []string{strconv.FormatFloat(*answer.Value, 'f', -1, 64)}, nil
+
+	case *TextConfig:
+		if raw == nil {
+			return []string{""}, nil
+		}
+
+		var answer TextAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+		return []string{csvText(answer.Text)}, nil
+
+	case *RankingConfig:
+		cells := make([]string, len(config.Options))
+		if raw == nil {
+			return cells, nil
+		}
+
+		var answer RankingAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+
+		for i, option := range config.Options {
+			if rank := slices.Index(answer.Ranking, option.ID); rank >= 0 {
+				cells[i] = strconv.Itoa(rank + 1)
+			}
+		}
+		return cells, nil
+
+	case *MatrixConfig:
+		cells := make([]string, len(config.Rows))
+		if raw == nil {
+			return cells, nil
+		}
+
+		var answer MatrixAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil {
+			return nil, err
+		}
+
+		for i, row := range config.Rows {
+			if columnID, ok := answer.Rows[row.ID]; ok {
+				cells[i] = q.optionCell(config.Columns, columnID)
+			}
+		}
+		return cells, nil
+
+	default:
+		return nil, fmt.Errorf("unknown question config: %T", config)
+	}
+}
+
+// optionCell is the cell of an option picked among the given ones.
+func (q exportQuestion) optionCell(options []Option, id string) string {
+	if q.optionIDs {
+		return id
+	}
+
+	return optionLabel(options, id)
+}
+
+func decodeExportAnswers(row database.ListSurveyExportRowsRow) (map[string]json.RawMessage, error) {
+	var answers map[string]json.RawMessage
+	if err := json.Unmarshal(row.Answers, &answers); err != nil {
+		return nil, fmt.Errorf("response %d: invalid answers: %v", row.ID, err)
+	}
+
+	return answers, nil
+}
+
+func optionColumns(key string, options []Option) []string {
+	columns := make([]string, 0, len(options))
+	for _, option := range options {
+		columns = append(columns, key+"_"+option.ID)
+	}
+
+	return columns
+}
+
+// optionLabel returns the label of the option with the given ID, or the ID itself if there is no
+// such option.
+func optionLabel(options []Option, id string) string {
+	for _, option := range options {
+		if option.ID == id {
+			return option.Label
+		}
+	}
+
+	return id
+}
+
+// csvText guards free text typed in by respondents against being run as a formula when the export
+// is opened in a spreadsheet.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+
+	return text
+}
+
+func formatTimestamp(timestamp pgtype.Timestamp) string {
+	if !timestamp.Valid {
+		return ""
+	}
+
+	return timestamp.Time.UTC().Format(time.RFC3339)
+}
+
+// exportWriter writes the responses to a survey in one export format.
+type exportWriter interface {
+	ContentType() string
+	WriteHeader() error
+	WriteRow(row database.ListSurveyExportRowsRow) error
+	Flush() error
+}
+
+func newExportWriter(format string, w io.Writer, layout ExportLayout) exportWriter {
+	if format == ExportJSONL {
+		return &jsonlExportWriter{encoder: json.NewEncoder(w), layout: layout}
+	}
+
+	return &csvExportWriter{writer: csv.NewWriter(w), layout: layout}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+	layout ExportLayout
+}
+
+func (w *csvExportWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (w *csvExportWriter) WriteHeader() error {
+	return w.writer.Write(w.layout.Header())
+}
+
+func (w *csvExportWriter) WriteRow(row database.ListSurveyExportRowsRow) error {
+	record, err := w.layout.Record(row)
+	if err != nil {
+		return err
+	}
+
+	return w.writer.Write(record)
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonlExportWriter struct {
+	encoder *json.Encoder
+	layout  ExportLayout
+}
+
+func (w *jsonlExportWriter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (w *jsonlExportWriter) WriteHeader() error {
+	return nil
+}
+
+func (w *jsonlExportWriter) WriteRow(row database.ListSurveyExportRowsRow) error {
+	line, err := w.layout.Line(row)
+	if err != nil {
+		return err
+	}
+
+	// Encode ends every value with a newline, which is all JSONL asks for
+	return w.encoder.Encode(line)
+}
+
+func (w *jsonlExportWriter) Flush() error {
+	return nil
+}
+
+// ExportResponsesHandler streams the responses to a survey as CSV or JSONL, straight from the
+// database to the client, with its test responses only when include_tests is set. The status and
+// headers go out with the first row, so an error before that is still reported as JSON; one after
+// it can only cut the download short.
+func (h *Handler) ExportResponsesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	format, err := ParseExportFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	includeTests := false
+	if value := request.URL.Query().Get("include_tests"); value != "" {
+		includeTests, err = strconv.ParseBool(value)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: "include_tests must be true or false",
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := h.questionHistory(ctx, survey)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	layout, err := NewExportLayout(survey, history, includeTests, time.Now())
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	writer := newExportWriter(format, responseWriter, layout)
+	started := false
+
+	start := func() error {
+		started = true
+
+		responseWriter.Header().Set("Content-Type", writer.ContentType())
+		responseWriter.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="survey-%d-responses.%s"`, survey.ID, format))
+		responseWriter.WriteHeader(http.StatusOK)
+
+		return writer.WriteHeader()
+	}
+
+	// the request's context, so the query stops when the client goes away mid download
+	err = h.Store.ExportResponses(request.Context(), survey.ID, includeTests, func(row database.ListSurveyExportRowsRow) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		return writer.WriteRow(row)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err != nil {
+		if !started {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		// part of the file is already out, abort the connection so the client doesn't take it
+		// for a complete export
+		log.Printf("error exporting responses to survey %d: %s", survey.ID, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	return
+}
@@ -0,0 +1,259 @@
+package surveys_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportStore(shareDemographics bool) *StubSurveyStore {
+	store := NewStubSurveyStore()
+
+	survey := fundedSurvey(database.SurveyStatusClosed)
+	survey.ShareDemographics = shareDemographics
+	store.Surveys[1] = survey
+
+	store.Questions[1] = database.Question{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice,
+		Config: []byte(`{"options": [{"id": "yes", "label": "Yes"}, {"id": "no", "label": "No"}]}`)}
+	store.Questions[2] = database.Question{ID: 2, SurveyID: 1, Position: 2, Type: database.QuestionTypeMultipleChoice,
+		Config: []byte(`{"options": [{"id": "go", "label": "Go"}, {"id": "rust", "label": "Rust"}, {"id": "zig", "label": "Zig"}]}`)}
+	store.Questions[3] = database.Question{ID: 3, SurveyID: 1, Position: 3, Type: database.QuestionTypeText,
+		Config: []byte(`{"max_length": 200}`)}
+
+	store.Exports = map[int64][]database.ListSurveyExportRowsRow{
+		1: {
+			{
+				ID:            10,
+				Status:        database.ResponseStatusSubmitted,
+				StartedAt:     pgtype.Timestamp{Time: time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC), Valid: true},
+				SubmittedAt:   pgtype.Timestamp{Time: time.Date(2026, time.October, 1, 9, 5, 0, 0, time.UTC), Valid: true},
+				SurveyVersion: 1,
+				Answers:       []byte(`{"1": {"option_id": "yes"}, "2": {"option_ids": ["go", "zig"]}, "3": {"text": "=HYPERLINK(\"x\")"}}`),
+				DateOfBirth:   pgtype.Date{Time: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+				Gender:        database.NullGender{Gender: database.GenderFemale, Valid: true},
+				University:    pgtype.Text{String: "Unilag", Valid: true},
+			},
+			{
+				ID:            11,
+				Status:        database.ResponseStatusInProgress,
+				StartedAt:     pgtype.Timestamp{Time: time.Date(2026, time.October, 2, 9, 0, 0, 0, time.UTC), Valid: true},
+				SurveyVersion: 1,
+				Answers:       []byte(`{"1": {"option_id": "no"}}`),
+			},
+		},
+	}
+
+	return store
+}
+
+func export(t *testing.T, store *StubSurveyStore, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := &surveys.Handler{Store: store}
+
+	req := newRequest(http.MethodGet, target, nil, 1, map[string]string{"surveyID": "1"})
+	rec := httptest.NewRecorder()
+
+	handler.ExportResponsesHandler(rec, req)
+	return rec
+}
+
+func TestExportResponsesHandler(t *testing.T) {
+
+	t.Run("exports CSV with a column per multiple choice option", func(t *testing.T) {
+		rec := export(t, newExportStore(false), "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+			t.Errorf("content type = %q, want text/csv", got)
+		}
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		want := [][]string{
+			{"response_id", "status", "started_at", "submitted_at", "survey_version", "q1", "q2_go", "q2_rust", "q2_zig", "q3"},
+			{"10", "submitted", "2026-10-01T09:00:00Z", "2026-10-01T09:05:00Z", "1", "Yes", "1", "0", "1", `'=HYPERLINK("x")`},
+			{"11", "in_progress", "2026-10-02T09:00:00Z", "", "1", "No", "", "", "", ""},
+		}
+
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("records = %q, want %q", records, want)
+		}
+	})
+
+	t.Run("exports option IDs for a survey translated into other locales", func(t *testing.T) {
+		store := newExportStore(false)
+		survey := store.Surveys[1]
+		survey.DefaultLocale, survey.Locales = "en", []string{"fr"}
+		store.Surveys[1] = survey
+
+		rec := export(t, store, "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		if records[1][5] != "yes" || records[2][5] != "no" {
+			t.Errorf("q1 = %q and %q, want the option IDs yes and no", records[1][5], records[2][5])
+		}
+	})
+
+	t.Run("includes demographics when the survey shares them", func(t *testing.T) {
+		rec := export(t, newExportStore(true), "/surveys/1/export?format=csv")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		if got := records[0][5:10]; !reflect.DeepEqual(got, []string{"age", "gender", "university", "faculty", "location"}) {
+			t.Errorf("demographic columns = %q", got)
+		}
+
+		if got := records[1][6:8]; !reflect.DeepEqual(got, []string{"female", "Unilag"}) {
+			t.Errorf("demographics = %q, want female from Unilag", got)
+		}
+	})
+
+	t.Run("exports one JSON object per line", func(t *testing.T) {
+		rec := export(t, newExportStore(false), "/surveys/1/export?format=jsonl")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var lines []surveys.ExportLine
+
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line surveys.ExportLine
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines, want 2", len(lines))
+		}
+
+		if lines[0].Demographics != nil {
+			t.Errorf("demographics = %+v, want none", lines[0].Demographics)
+		}
+
+		if len(lines[0].Answers) != 3 || lines[1].SubmittedAt != nil {
+			t.Errorf("lines = %+v", lines)
+		}
+	})
+
+	t.Run("leaves test responses out unless asked for", func(t *testing.T) {
+		store := newExportStore(false)
+		store.Exports[1] = append(store.Exports[1], database.ListSurveyExportRowsRow{
+			ID:            12,
+			Status:        database.ResponseStatusSubmitted,
+			StartedAt:     pgtype.Timestamp{Time: time.Date(2026, time.October, 3, 9, 0, 0, 0, time.UTC), Valid: true},
+			SurveyVersion: 1,
+			IsTest:        true,
+			Answers:       []byte(`{"1": {"option_id": "yes"}}`),
+		})
+
+		records, err := csv.NewReader(export(t, store, "/surveys/1/export").Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		if len(records) != 3 || slices.Contains(records[0], "test") {
+			t.Errorf("records = %q, want the two real responses without a test column", records)
+		}
+
+		records, err = csv.NewReader(export(t, store, "/surveys/1/export?include_tests=true").Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		if len(records) != 4 || records[0][5] != "test" || records[1][5] != "false" || records[3][5] != "true" {
+			t.Errorf("records = %q, want every response with a test column", records)
+		}
+	})
+
+	t.Run("writes the header of a survey without responses", func(t *testing.T) {
+		store := newExportStore(false)
+		store.Exports = nil
+
+		rec := export(t, store, "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got := strings.TrimSpace(rec.Body.String()); got != "response_id,status,started_at,submitted_at,survey_version,q1,q2_go,q2_rust,q2_zig,q3" {
+			t.Errorf("body = %q", got)
+		}
+	})
+
+	t.Run("maps answers to earlier versions of the survey", func(t *testing.T) {
+		store := newExportStore(false)
+		// version 1 had a third option for question 2 and a fourth question, both taken out since
+		store.Versions[1] = []database.SurveyVersion{{SurveyID: 1, Version: 1, Questions: []byte(`[
+			{"id": 1, "position": 1, "type": "single_choice", "title": "Ok?", "config": {"options": [{"id": "yes", "label": "Yes"}, {"id": "no", "label": "No"}]}},
+			{"id": 2, "position": 2, "type": "multiple_choice", "title": "Languages", "config": {"options": [{"id": "go", "label": "Go"}, {"id": "c", "label": "C"}]}},
+			{"id": 4, "position": 3, "type": "text", "title": "Why?", "config": {"max_length": 200}}
+		]`)}}
+		store.Exports[1] = append(store.Exports[1], database.ListSurveyExportRowsRow{
+			ID:            12,
+			Status:        database.ResponseStatusSubmitted,
+			SurveyVersion: 1,
+			Answers:       []byte(`{"1": {"option_id": "yes"}, "2": {"option_ids": ["c"]}, "4": {"text": "because"}}`),
+		})
+
+		rec := export(t, store, "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+
+		wantHeader := []string{"response_id", "status", "started_at", "submitted_at", "survey_version", "q1", "q2_go", "q2_rust", "q2_zig", "q2_c", "q3", "removed_4"}
+		if !reflect.DeepEqual(records[0], wantHeader) {
+			t.Errorf("header = %q, want %q", records[0], wantHeader)
+		}
+
+		if got := records[3][5:]; !reflect.DeepEqual(got, []string{"Yes", "0", "0", "0", "1", "", "because"}) {
+			t.Errorf("answers to version 1 = %q", got)
+		}
+	})
+
+	t.Run("returns 400 for an unknown format", func(t *testing.T) {
+		rec := export(t, newExportStore(false), "/surveys/1/export?format=xlsx")
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := newExportStore(false)
+		survey := store.Surveys[1]
+		survey.ResearcherID = 2
+		store.Surveys[1] = survey
+
+		rec := export(t, store, "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,172 @@
+package surveys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/language"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultFeedLimit = 20
+	MaxFeedLimit     = 50
+	// maxFeedScans caps how many pages one request reads from the store while skipping surveys
+	// the user isn't targeted by, so a narrow audience can't turn into a scan of every survey.
+	maxFeedScans = 5
+)
+
+// FeedCursor is the position of the last survey a feed page looked at, in ranking order.
+type FeedCursor struct {
+	Overlap         int32           `json:"overlap"`
+	RewardPerMinute decimal.Decimal `json:"reward_per_minute"`
+	ID              int64           `json:"id"`
+}
+
+// Encode turns the cursor into the opaque string handed to clients.
+func (c FeedCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseFeedCursor decodes a cursor sent back by a client. An empty string is the first page.
+func ParseFeedCursor(encoded string) (*FeedCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fieldError("cursor", "cursor is invalid")
+	}
+
+	var cursor FeedCursor
+	if err := decodeStrict(raw, &cursor); err != nil || cursor.ID < 1 {
+		return nil, fieldError("cursor", "cursor is invalid")
+	}
+
+	return &cursor, nil
+}
+
+// ParseFeedLimit reads the page size of a feed request, defaulting to DefaultFeedLimit.
+func ParseFeedLimit(value string) (int32, error) {
+	if value == "" {
+		return DefaultFeedLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > MaxFeedLimit {
+		return 0, fieldError("limit", fmt.Sprintf("limit must be a number between 1 and %d", MaxFeedLimit))
+	}
+
+	return int32(limit), nil
+}
+
+// BuildFeed pages through the surveys the store ranks for a user and keeps the ones the user is
+// eligible for, in the languages they want, until the page is full or the surveys run out. Targeting and quota cells live in
+// JSON, so they are checked here rather than in the query; the cursor always points at the last survey looked at,
+// eligible or not, so the next page carries on from there.
+func BuildFeed(ctx context.Context, store Store, userID int64, audience Audience, wants []language.Tag, cursor *FeedCursor, limit int32, now time.Time) (Feed, error) {
+	feed := Feed{Surveys: []FeedSurvey{}}
+
+	for scan := 0; scan < maxFeedScans; scan++ {
+		surveys, err := store.ListFeed(ctx, userID, cursor, limit)
+		if err != nil {
+			return Feed{}, err
+		}
+
+		cells, err := quotaCellsBySurvey(ctx, store, surveys)
+		if err != nil {
+			return Feed{}, err
+		}
+
+		for _, survey := range surveys {
+			cursor = &FeedCursor{
+				Overlap:         survey.Overlap,
+				RewardPerMinute: database.DecimalFromNumeric(survey.RewardPerMinute),
+				ID:              survey.ID,
+			}
+
+			if eligibleForFeed(survey, cells[survey.ID], audience, now) {
+				localized, err := localizeFeedSurvey(survey, wants)
+				if err != nil {
+					return Feed{}, err
+				}
+				feed.Surveys = append(feed.Surveys, localized)
+			}
+
+			if len(feed.Surveys) == int(limit) {
+				feed.NextCursor = cursor.Encode()
+				return feed, nil
+			}
+		}
+
+		if len(surveys) < int(limit) {
+			return feed, nil
+		}
+	}
+
+	// the scan budget ran out before the page filled up, let the client carry on from here
+	feed.NextCursor = cursor.Encode()
+	return feed, nil
+}
+
+func eligibleForFeed(survey database.ListFeedSurveysRow, cells []database.QuotaCell, audience Audience, now time.Time) bool {
+	targeting, err := ParseTargeting(survey.Targeting)
+	if err != nil || targeting.Eligible(audience, now) != nil {
+		return false
+	}
+
+	_, err = MatchQuotaCells(cells, audience, now)
+	return err == nil
+}
+
+// localizeFeedSurvey shows a survey of the feed in the locale of it that best matches the languages
+// the user wants.
+func localizeFeedSurvey(survey database.ListFeedSurveysRow, wants []language.Tag) (FeedSurvey, error) {
+	locale := negotiate(survey.DefaultLocale, survey.Locales, wants)
+
+	localized, err := LocalizeSurvey(database.Survey{
+		Title:        survey.Title,
+		Description:  survey.Description,
+		Translations: survey.Translations,
+	}, locale)
+	if err != nil {
+		return FeedSurvey{}, fmt.Errorf("survey %d: %v", survey.ID, err)
+	}
+
+	data := NewFeedSurvey(survey)
+	data.Title = localized.Title
+	data.Description = localized.Description.String
+	data.Locale = locale
+
+	return data, nil
+}
+
+// quotaCellsBySurvey loads the quota cells of a page of surveys in one go, keyed by survey.
+func quotaCellsBySurvey(ctx context.Context, store Store, surveys []database.ListFeedSurveysRow) (map[int64][]database.QuotaCell, error) {
+	grouped := make(map[int64][]database.QuotaCell, len(surveys))
+	if len(surveys) == 0 {
+		return grouped, nil
+	}
+
+	surveyIDs := make([]int64, 0, len(surveys))
+	for _, survey := range surveys {
+		surveyIDs = append(surveyIDs, survey.ID)
+	}
+
+	cells, err := store.ListQuotaCellsForSurveys(ctx, surveyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cell := range cells {
+		grouped[cell.SurveyID] = append(grouped[cell.SurveyID], cell)
+	}
+
+	return grouped, nil
+}
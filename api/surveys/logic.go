@@ -0,0 +1,730 @@
+package surveys
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Operators a condition can compare an answer with. Which of them apply depends on the type of
+// the question the condition looks at, see conditionOperators.
+const (
+	OperatorEquals             = "equals"
+	OperatorNotEquals          = "not_equals"
+	OperatorIncludes           = "includes"
+	OperatorNotIncludes        = "not_includes"
+	OperatorGreaterThan        = "gt"
+	OperatorGreaterThanOrEqual = "gte"
+	OperatorLessThan           = "lt"
+	OperatorLessThanOrEqual    = "lte"
+	OperatorAnswered           = "answered"
+	OperatorNotAnswered        = "not_answered"
+)
+
+var conditionOperators = map[database.QuestionType][]string{
+	database.QuestionTypeSingleChoice:   {OperatorEquals, OperatorNotEquals, OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeMultipleChoice: {OperatorIncludes, OperatorNotIncludes, OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeLikert:         {OperatorEquals, OperatorNotEquals, OperatorGreaterThan, OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual, OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeNumeric:        {OperatorEquals, OperatorNotEquals, OperatorGreaterThan, OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual, OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeText:           {OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeRanking:        {OperatorAnswered, OperatorNotAnswered},
+	database.QuestionTypeMatrix:         {OperatorAnswered, OperatorNotAnswered},
+}
+
+// Condition is either a comparison against the answer to one question, or a group of conditions
+// joined by all (every one must hold) or any (at least one must hold).
+type Condition struct {
+	QuestionID int64           `json:"question_id,omitempty"`
+	Operator   string          `json:"operator,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	All        []Condition     `json:"all,omitempty"`
+	Any        []Condition     `json:"any,omitempty"`
+}
+
+// SkipRule moves the respondent past the questions in between once its condition holds, either
+// to a later question or straight to the end of the survey.
+type SkipRule struct {
+	When      Condition `json:"when"`
+	SkipTo    int64     `json:"skip_to,omitempty"`
+	EndSurvey bool      `json:"end_survey,omitempty"`
+}
+
+// QuestionLogic is the branching part of a question, stored in questions.logic.
+//
+// Conditions may only look at questions that come earlier in the survey (a skip rule may also look
+// at its own question) and skips may only move forward, which is what keeps the rules of a survey
+// free of cycles.
+type QuestionLogic struct {
+	// DisplayIf hides the question unless it holds.
+	DisplayIf *Condition `json:"display_if,omitempty"`
+	// Skip rules are checked in order after the question is shown, the first one that holds wins.
+	Skip []SkipRule `json:"skip,omitempty"`
+	// ScreenOutIf ends the attempt of a respondent it holds for. Only screener questions have it.
+	ScreenOutIf *Condition `json:"screen_out_if,omitempty"`
+	// AttentionCheck makes the question an attention check: it holds for respondents who read the
+	// question, and a response whose answers break it fails the check.
+	AttentionCheck *Condition `json:"attention_check,omitempty"`
+}
+
+// ParseQuestionLogic decodes the logic of a question and checks its shape. References to other
+// questions are checked by ValidateLogic, which sees the whole survey.
+func ParseQuestionLogic(raw json.RawMessage) (QuestionLogic, error) {
+	var logic QuestionLogic
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return logic, nil
+	}
+
+	if err := decodeStrict(raw, &logic); err != nil {
+		return QuestionLogic{}, fmt.Errorf("invalid logic: %v", err)
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	if logic.DisplayIf != nil {
+		validationErrors = append(validationErrors, logic.DisplayIf.check("logic.display_if")...)
+	}
+
+	if logic.ScreenOutIf != nil {
+		validationErrors = append(validationErrors, logic.ScreenOutIf.check("logic.screen_out_if")...)
+	}
+
+	if logic.AttentionCheck != nil {
+		validationErrors = append(validationErrors, logic.AttentionCheck.check("logic.attention_check")...)
+	}
+
+	for i, rule := range logic.Skip {
+		path := fmt.Sprintf("logic.skip[%d]", i)
+
+		validationErrors = append(validationErrors, rule.When.check(path+".when")...)
+
+		if (rule.SkipTo == 0) == !rule.EndSurvey {
+			validationErrors = append(validationErrors, path+": exactly one of skip_to and end_survey must be set")
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return QuestionLogic{}, validationErrors
+	}
+
+	return logic, nil
+}
+
+// check reports the shape problems of a condition and of the conditions nested in it.
+func (c Condition) check(path string) []string {
+	var problems []string
+
+	forms := 0
+	if c.QuestionID != 0 || c.Operator != "" || len(c.Value) > 0 {
+		forms++
+	}
+	if len(c.All) > 0 {
+		forms++
+	}
+	if len(c.Any) > 0 {
+		forms++
+	}
+
+	if forms != 1 {
+		return []string{path + ": a condition must be exactly one of a comparison, all or any"}
+	}
+
+	for i, condition := range c.All {
+		problems = append(problems, condition.check(fmt.Sprintf("%s.all[%d]", path, i))...)
+	}
+
+	for i, condition := range c.Any {
+		problems = append(problems, condition.check(fmt.Sprintf("%s.any[%d]", path, i))...)
+	}
+
+	if len(c.All) > 0 || len(c.Any) > 0 {
+		return problems
+	}
+
+	if c.QuestionID <= 0 {
+		problems = append(problems, path+".question_id: question_id is required")
+	}
+
+	switch c.Operator {
+	case "":
+		problems = append(problems, path+".operator: operator is required")
+	case OperatorAnswered, OperatorNotAnswered:
+		if len(c.Value) > 0 {
+			problems = append(problems, fmt.Sprintf("%s.value: %s takes no value", path, c.Operator))
+		}
+	default:
+		if len(c.Value) == 0 {
+			problems = append(problems, path+".value: value is required")
+		}
+	}
+
+	return problems
+}
+
+// ValidateLogic checks the logic of every question of a survey against the survey as a whole:
+// every question a rule names must exist, conditions must fit the question they look at, and no
+// rule may look or skip backwards in a way that would form a cycle. Screener questions must all
+// come before the rest of the survey, and only they may screen respondents out.
+//
+// The questions of a block must come one after another. Since they are shuffled (see Arrange), no
+// rule may depend on the order they are shown in: conditions can't look at the other questions of
+// their block and skips can't land inside their own block.
+func ValidateLogic(questions []database.Question) error {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return err
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	screening := true
+	blocks := make(map[string]bool)
+
+	for i, question := range flow.questions {
+		logic := flow.logic[i]
+		path := fmt.Sprintf("questions[%d].logic", question.ID)
+
+		if question.Screener && !screening {
+			validationErrors = append(validationErrors, fmt.Sprintf("questions[%d].screener: screener questions must come before the other questions", question.ID))
+		}
+		screening = screening && question.Screener
+
+		if question.Block.Valid && (i == 0 || !sameBlock(flow.questions[i-1], question)) {
+			if blocks[question.Block.String] {
+				validationErrors = append(validationErrors, fmt.Sprintf("questions[%d].block: the questions of block %s must come one after another", question.ID, question.Block.String))
+			}
+			blocks[question.Block.String] = true
+		}
+
+		if i > 0 && sameBlock(flow.questions[i-1], question) && flow.questions[i-1].Screener != question.Screener {
+			validationErrors = append(validationErrors, fmt.Sprintf("questions[%d].block: block %s can't mix screener and other questions", question.ID, question.Block.String))
+		}
+
+		if logic.ScreenOutIf != nil {
+			if question.Screener {
+				validationErrors = append(validationErrors, flow.checkCondition(*logic.ScreenOutIf, path+".screen_out_if", i, i)...)
+			} else {
+				validationErrors = append(validationErrors, path+".screen_out_if: only screener questions can screen respondents out")
+			}
+		}
+
+		if logic.AttentionCheck != nil {
+			validationErrors = append(validationErrors, flow.checkCondition(*logic.AttentionCheck, path+".attention_check", i, i)...)
+		}
+
+		if logic.DisplayIf != nil {
+			// a question can't decide whether it is shown by its own answer
+			validationErrors = append(validationErrors, flow.checkCondition(*logic.DisplayIf, path+".display_if", i, i-1)...)
+		}
+
+		for j, rule := range logic.Skip {
+			rulePath := fmt.Sprintf("%s.skip[%d]", path, j)
+
+			validationErrors = append(validationErrors, flow.checkCondition(rule.When, rulePath+".when", i, i)...)
+
+			if rule.EndSurvey {
+				continue
+			}
+
+			target, exists := flow.index[rule.SkipTo]
+			switch {
+			case !exists:
+				validationErrors = append(validationErrors, fmt.Sprintf("%s.skip_to: question %d does not exist in this survey", rulePath, rule.SkipTo))
+			case target <= i:
+				validationErrors = append(validationErrors, fmt.Sprintf("%s.skip_to: skipping back to question %d would form a cycle", rulePath, rule.SkipTo))
+			case sameBlock(question, flow.questions[target]):
+				validationErrors = append(validationErrors, fmt.Sprintf("%s.skip_to: question %d is in the same randomized block, where it lands would depend on the order shown", rulePath, rule.SkipTo))
+			}
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return nil
+}
+
+// checkCondition reports the problems of a condition that can only be seen with the whole survey
+// at hand. owner is the index of the question the condition belongs to and latest the index of the
+// last question the condition may look at.
+func (f *Flow) checkCondition(c Condition, path string, owner, latest int) []string {
+	var problems []string
+
+	for i, condition := range c.All {
+		problems = append(problems, f.checkCondition(condition, fmt.Sprintf("%s.all[%d]", path, i), owner, latest)...)
+	}
+
+	for i, condition := range c.Any {
+		problems = append(problems, f.checkCondition(condition, fmt.Sprintf("%s.any[%d]", path, i), owner, latest)...)
+	}
+
+	if len(c.All) > 0 || len(c.Any) > 0 {
+		return problems
+	}
+
+	i, exists := f.index[c.QuestionID]
+	if !exists {
+		return []string{fmt.Sprintf("%s.question_id: question %d does not exist in this survey", path, c.QuestionID)}
+	}
+
+	if i > latest {
+		return []string{fmt.Sprintf("%s.question_id: question %d is not answered yet at this point, depending on it would form a cycle", path, c.QuestionID)}
+	}
+
+	if i != owner && sameBlock(f.questions[i], f.questions[owner]) {
+		return []string{fmt.Sprintf("%s.question_id: question %d is in the same randomized block, it may not be answered yet at this point", path, c.QuestionID)}
+	}
+
+	question := f.questions[i]
+
+	if !slices.Contains(conditionOperators[question.Type], c.Operator) {
+		return []string{fmt.Sprintf("%s.operator: operator must be one of [%s] for a %s question", path, strings.Join(conditionOperators[question.Type], " "), question.Type)}
+	}
+
+	if c.Operator == OperatorAnswered || c.Operator == OperatorNotAnswered {
+		return nil
+	}
+
+	switch question.Type {
+	case database.QuestionTypeSingleChoice, database.QuestionTypeMultipleChoice:
+		var optionID string
+		if err := json.Unmarshal(c.Value, &optionID); err != nil {
+			return []string{path + ".value: value must be an option id"}
+		}
+
+		options := choiceOptions(question)
+		if !slices.ContainsFunc(options, func(option Option) bool { return option.ID == optionID }) {
+			return []string{fmt.Sprintf("%s.value: value must be one of [%s]", path, optionIDs(options))}
+		}
+	case database.QuestionTypeLikert, database.QuestionTypeNumeric:
+		var number float64
+		if err := json.Unmarshal(c.Value, &number); err != nil {
+			return []string{path + ".value: value must be a number"}
+		}
+	}
+
+	return nil
+}
+
+// choiceOptions returns the options of a single or multiple choice question. The config has been
+// validated when it was stored, so a config that no longer parses just has no options.
+func choiceOptions(question database.Question) []Option {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	if err != nil {
+		return nil
+	}
+
+	switch c := config.(type) {
+	case *SingleChoiceConfig:
+		return c.Options
+	case *MultipleChoiceConfig:
+		return c.Options
+	}
+
+	return nil
+}
+
+// Flow walks the questions of a survey in order, applying their logic to a set of answers.
+type Flow struct {
+	questions []database.Question
+	logic     []QuestionLogic
+	index     map[int64]int
+}
+
+func NewFlow(questions []database.Question) (*Flow, error) {
+	ordered := slices.Clone(questions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Position != ordered[j].Position {
+			return ordered[i].Position < ordered[j].Position
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	flow := &Flow{
+		questions: ordered,
+		logic:     make([]QuestionLogic, len(ordered)),
+		index:     make(map[int64]int, len(ordered)),
+	}
+
+	for i, question := range ordered {
+		logic, err := ParseQuestionLogic(question.Logic)
+		if err != nil {
+			return nil, jsonutil.ValidationErrors(prefixErrors(fmt.Sprintf("questions[%d]", question.ID), err))
+		}
+
+		flow.logic[i] = logic
+		flow.index[question.ID] = i
+	}
+
+	return flow, nil
+}
+
+// Path returns the questions a respondent with the given answers is shown, in order. Answers to
+// questions that are not on the path are ignored. A respondent who is screened out is shown
+// nothing after the screener that screened them out.
+func (f *Flow) Path(answers map[int64]json.RawMessage) []database.Question {
+	path, _ := f.walk(answers)
+	return path
+}
+
+// ScreenedOut reports whether the answers of a respondent screen them out of the survey.
+func (f *Flow) ScreenedOut(answers map[int64]json.RawMessage) bool {
+	_, screenedOut := f.walk(answers)
+	return screenedOut
+}
+
+// FailedAttentionChecks returns the IDs of the attention check questions a respondent with the
+// given answers was shown and whose check the answers break.
+func (f *Flow) FailedAttentionChecks(answers map[int64]json.RawMessage) []int64 {
+	path := f.Path(answers)
+
+	seen := make(map[int64]json.RawMessage, len(path))
+	for _, question := range path {
+		if raw, answered := answers[question.ID]; answered {
+			seen[question.ID] = raw
+		}
+	}
+
+	var failed []int64
+	for _, question := range path {
+		check := f.logic[f.index[question.ID]].AttentionCheck
+		if check != nil && !f.holds(*check, seen) {
+			failed = append(failed, question.ID)
+		}
+	}
+
+	return failed
+}
+
+func (f *Flow) walk(answers map[int64]json.RawMessage) (path []database.Question, screenedOut bool) {
+	// only answers to questions the respondent was shown may steer the rest of the path
+	seen := make(map[int64]json.RawMessage, len(answers))
+
+	i := 0
+	for i < len(f.questions) {
+		question := f.questions[i]
+		logic := f.logic[i]
+
+		if logic.DisplayIf != nil && !f.holds(*logic.DisplayIf, seen) {
+			i++
+			continue
+		}
+
+		path = append(path, question)
+		if raw, answered := answers[question.ID]; answered {
+			seen[question.ID] = raw
+		}
+
+		if logic.ScreenOutIf != nil && f.holds(*logic.ScreenOutIf, seen) {
+			return path, true
+		}
+
+		next := i + 1
+		for _, rule := range logic.Skip {
+			if !f.holds(rule.When, seen) {
+				continue
+			}
+
+			if rule.EndSurvey {
+				next = len(f.questions)
+			} else if target, exists := f.index[rule.SkipTo]; exists && target > i {
+				next = f.blockStart(target, i)
+			}
+			break
+		}
+
+		i = next
+	}
+
+	return path, false
+}
+
+// blockStart moves a skip target back to the first question of its block as the respondent is
+// shown it, so a skip to a shuffled block never passes over part of it. It never goes back as far
+// as from, the question skipping.
+func (f *Flow) blockStart(target, from int) int {
+	for target-1 > from && sameBlock(f.questions[target-1], f.questions[target]) {
+		target--
+	}
+
+	return target
+}
+
+// Next returns the question shown after the question with ID after, or the first question when
+// after is 0. ok is false once the respondent has reached the end of the survey.
+func (f *Flow) Next(answers map[int64]json.RawMessage, after int64) (question database.Question, ok bool, err error) {
+	path := f.Path(answers)
+
+	position := -1
+	if after != 0 {
+		position = slices.IndexFunc(path, func(question database.Question) bool { return question.ID == after })
+		if position == -1 {
+			return database.Question{}, false, fieldError("after", fmt.Sprintf("question %d is not shown to this respondent", after))
+		}
+	}
+
+	if position+1 >= len(path) {
+		return database.Question{}, false, nil
+	}
+
+	return path[position+1], true, nil
+}
+
+// NextQuestion works out the question to show after the question with ID after, or the first
+// question when after is 0, from the answers given so far. The answers up to after are validated
+// the same way ValidateAnswers does, without requiring the ones still to come.
+func NextQuestion(questions []database.Question, answers map[int64]json.RawMessage, after int64) (database.Question, bool, error) {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return database.Question{}, false, err
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	shown := make(map[int64]bool, len(questions))
+
+	// answers past after don't steer anything yet, so they are left for later
+	reached := after == 0
+
+	for _, question := range flow.Path(answers) {
+		shown[question.ID] = true
+
+		if raw, answered := answers[question.ID]; answered && !reached {
+			validationErrors = append(validationErrors, prefixErrors(fmt.Sprintf("answers[%d]", question.ID), ValidateAnswer(question, raw))...)
+		}
+
+		if question.ID == after {
+			reached = true
+		}
+	}
+
+	validationErrors = append(validationErrors, flow.unexpectedAnswers(answers, shown)...)
+
+	if len(validationErrors) > 0 {
+		sort.Strings(validationErrors)
+		return database.Question{}, false, validationErrors
+	}
+
+	return flow.Next(answers, after)
+}
+
+// ScreenedOut reports whether a respondent with the given answers is screened out of the survey.
+// The answers are expected to have been validated with NextQuestion or ValidateAnswers.
+func ScreenedOut(questions []database.Question, answers map[int64]json.RawMessage) (bool, error) {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return false, err
+	}
+
+	return flow.ScreenedOut(answers), nil
+}
+
+// PruneAnswers re-checks saved answers against the survey's current questions and logic. Answers
+// to questions that are gone or no longer on the respondent's path, and answers their question no
+// longer accepts, are dropped; since dropping an answer can change the path, this repeats until
+// nothing else is. It returns the answers that are left and the IDs of the dropped ones, in order.
+func PruneAnswers(questions []database.Question, answers map[int64]json.RawMessage) (map[int64]json.RawMessage, []int64, error) {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept, dropped := flow.prune(answers)
+	return kept, dropped, nil
+}
+
+func (f *Flow) prune(answers map[int64]json.RawMessage) (map[int64]json.RawMessage, []int64) {
+	kept := maps.Clone(answers)
+	if kept == nil {
+		kept = make(map[int64]json.RawMessage)
+	}
+
+	var dropped []int64
+
+	for {
+		shown := make(map[int64]database.Question, len(kept))
+		for _, question := range f.Path(kept) {
+			shown[question.ID] = question
+		}
+
+		changed := false
+		for questionID, raw := range kept {
+			question, onPath := shown[questionID]
+			if onPath && ValidateAnswer(question, raw) == nil {
+				continue
+			}
+
+			delete(kept, questionID)
+			dropped = append(dropped, questionID)
+			changed = true
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	slices.Sort(dropped)
+	return kept, dropped
+}
+
+// MergeAnswers adds a page of answers to the ones a respondent saved before. The page replaces
+// earlier answers to the same questions, and saved answers that no longer fit once the page is in,
+// like the answers to a branch the respondent no longer takes, are dropped. The page itself is
+// kept as it is, for the caller to validate with NextQuestion or ValidateAnswers, so its problems
+// are reported instead of dropped. It returns the merged answers and the IDs of the dropped ones,
+// in order.
+func MergeAnswers(questions []database.Question, saved, page map[int64]json.RawMessage) (map[int64]json.RawMessage, []int64, error) {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := maps.Clone(saved)
+	if merged == nil {
+		merged = make(map[int64]json.RawMessage, len(page))
+	}
+	maps.Copy(merged, page)
+
+	kept, pruned := flow.prune(merged)
+	maps.Copy(kept, page)
+
+	var dropped []int64
+	for _, questionID := range pruned {
+		if _, onPage := page[questionID]; !onPage {
+			dropped = append(dropped, questionID)
+		}
+	}
+
+	return kept, dropped, nil
+}
+
+// ResumeAfter works out which question a respondent picking a saved response back up continues
+// after: the last question they saved when it is still on their path, otherwise the last question
+// on their path they answered, or 0 to start from the first question. The answers are expected to
+// have been pruned with PruneAnswers.
+func ResumeAfter(questions []database.Question, answers map[int64]json.RawMessage, last int64) (int64, error) {
+	flow, err := NewFlow(questions)
+	if err != nil {
+		return 0, err
+	}
+
+	var after int64
+
+	for _, question := range flow.Path(answers) {
+		if question.ID == last {
+			return last, nil
+		}
+
+		if _, answered := answers[question.ID]; answered {
+			after = question.ID
+		}
+	}
+
+	return after, nil
+}
+
+// holds evaluates a condition against the answers seen so far. A comparison against a question
+// that has no answer never holds, only not_answered does.
+func (f *Flow) holds(c Condition, answers map[int64]json.RawMessage) bool {
+	if len(c.All) > 0 {
+		for _, condition := range c.All {
+			if !f.holds(condition, answers) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(c.Any) > 0 {
+		for _, condition := range c.Any {
+			if f.holds(condition, answers) {
+				return true
+			}
+		}
+		return false
+	}
+
+	raw, answered := answers[c.QuestionID]
+
+	switch c.Operator {
+	case OperatorAnswered:
+		return answered
+	case OperatorNotAnswered:
+		return !answered
+	}
+
+	i, exists := f.index[c.QuestionID]
+	if !answered || !exists {
+		return false
+	}
+
+	switch f.questions[i].Type {
+	case database.QuestionTypeSingleChoice:
+		var answer SingleChoiceAnswer
+		var optionID string
+		if json.Unmarshal(raw, &answer) != nil || json.Unmarshal(c.Value, &optionID) != nil {
+			return false
+		}
+
+		return compareEquality(c.Operator, answer.OptionID == optionID)
+	case database.QuestionTypeMultipleChoice:
+		var answer MultipleChoiceAnswer
+		var optionID string
+		if json.Unmarshal(raw, &answer) != nil || json.Unmarshal(c.Value, &optionID) != nil {
+			return false
+		}
+
+		included := slices.Contains(answer.OptionIDs, optionID)
+		if c.Operator == OperatorNotIncludes {
+			return !included
+		}
+		return c.Operator == OperatorIncludes && included
+	case database.QuestionTypeLikert, database.QuestionTypeNumeric:
+		var answer NumericAnswer
+		var number float64
+		if json.Unmarshal(raw, &answer) != nil || answer.Value == nil || json.Unmarshal(c.Value, &number) != nil {
+			return false
+		}
+
+		return compareNumbers(c.Operator, *answer.Value, number)
+	}
+
+	return false
+}
+
+func compareEquality(operator string, equal bool) bool {
+	switch operator {
+	case OperatorEquals:
+		return equal
+	case OperatorNotEquals:
+		return !equal
+	}
+
+	return false
+}
+
+func compareNumbers(operator string, answer, value float64) bool {
+	switch operator {
+	case OperatorEquals:
+		return answer == value
+	case OperatorNotEquals:
+		return answer != value
+	case OperatorGreaterThan:
+		return answer > value
+	case OperatorGreaterThanOrEqual:
+		return answer >= value
+	case OperatorLessThan:
+		return answer < value
+	case OperatorLessThanOrEqual:
+		return answer <= value
+	}
+
+	return false
+}
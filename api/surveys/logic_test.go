@@ -0,0 +1,445 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"strings"
+	"testing"
+)
+
+// commuteSurvey skips to question 4 when question 1 is answered "no" and only shows question 3
+// when "other" is picked in question 2.
+func commuteSurvey() []database.Question {
+	return []database.Question{
+		{
+			ID:       1,
+			Position: 1,
+			Type:     database.QuestionTypeSingleChoice,
+			Required: true,
+			Config:   []byte(`{"options": [{"id": "yes", "label": "Yes"}, {"id": "no", "label": "No"}]}`),
+			Logic:    []byte(`{"skip": [{"when": {"question_id": 1, "operator": "equals", "value": "no"}, "skip_to": 4}]}`),
+		},
+		{
+			ID:       2,
+			Position: 2,
+			Type:     database.QuestionTypeMultipleChoice,
+			Required: true,
+			Config:   []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "car", "label": "Car"}, {"id": "other", "label": "Other"}]}`),
+		},
+		{
+			ID:       3,
+			Position: 3,
+			Type:     database.QuestionTypeText,
+			Required: true,
+			Config:   []byte(`{"max_length": 100}`),
+			Logic:    []byte(`{"display_if": {"question_id": 2, "operator": "includes", "value": "other"}}`),
+		},
+		{
+			ID:       4,
+			Position: 4,
+			Type:     database.QuestionTypeLikert,
+			Required: true,
+			Config:   []byte(`{"points": 5}`),
+		},
+	}
+}
+
+func TestParseQuestionLogic(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		logic   string
+		wantErr string
+	}{
+		{"empty logic", ``, ""},
+		{"nested conditions", `{"display_if": {"any": [{"question_id": 1, "operator": "answered"}, {"all": [{"question_id": 2, "operator": "gt", "value": 3}]}]}}`, ""},
+		{"skip needs a target", `{"skip": [{"when": {"question_id": 1, "operator": "answered"}}]}`, "logic.skip[0]: exactly one of skip_to and end_survey must be set"},
+		{"condition mixes forms", `{"display_if": {"question_id": 1, "operator": "answered", "all": [{"question_id": 2, "operator": "answered"}]}}`, "a condition must be exactly one of"},
+		{"comparison needs a value", `{"display_if": {"question_id": 1, "operator": "equals"}}`, "logic.display_if.value: value is required"},
+		{"answered takes no value", `{"display_if": {"question_id": 1, "operator": "answered", "value": "a"}}`, "answered takes no value"},
+		{"unknown fields are rejected", `{"show_if": {}}`, "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := surveys.ParseQuestionLogic(json.RawMessage(tt.logic))
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLogic(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		question int
+		logic    string
+		wantErr  string
+	}{
+		{"valid survey", 0, "", ""},
+		{"skip to a missing question", 0, `{"skip": [{"when": {"question_id": 1, "operator": "answered"}, "skip_to": 9}]}`, "questions[1].logic.skip[0].skip_to: question 9 does not exist in this survey"},
+		{"skip backwards", 3, `{"skip": [{"when": {"question_id": 4, "operator": "equals", "value": 1}, "skip_to": 2}]}`, "skipping back to question 2 would form a cycle"},
+		{"display depends on itself", 2, `{"display_if": {"question_id": 3, "operator": "answered"}}`, "question 3 is not answered yet at this point"},
+		{"display depends on a later question", 1, `{"display_if": {"question_id": 4, "operator": "gte", "value": 3}}`, "question 4 is not answered yet at this point"},
+		{"condition on a missing question", 3, `{"display_if": {"question_id": 7, "operator": "answered"}}`, "question 7 does not exist in this survey"},
+		{"operator must fit the question type", 1, `{"display_if": {"question_id": 1, "operator": "includes", "value": "no"}}`, "operator must be one of [equals not_equals answered not_answered] for a single_choice question"},
+		{"screen out on a regular question", 1, `{"screen_out_if": {"question_id": 2, "operator": "includes", "value": "car"}}`, "only screener questions can screen respondents out"},
+		{"attention check on a later question", 1, `{"attention_check": {"question_id": 4, "operator": "equals", "value": 2}}`, "question 4 is not answered yet at this point"},
+		{"value must be an option", 2, `{"display_if": {"question_id": 2, "operator": "includes", "value": "train"}}`, "value must be one of [bus car other]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions := commuteSurvey()
+			if tt.logic != "" {
+				questions[tt.question].Logic = []byte(tt.logic)
+			}
+
+			err := surveys.ValidateLogic(questions)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlowPath(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		answers map[int64]string
+		want    []int64
+	}{
+		{"no answers yet", map[int64]string{}, []int64{1, 2, 4}},
+		{"skip rule jumps ahead", map[int64]string{1: `{"option_id": "no"}`}, []int64{1, 4}},
+		{"display condition shows a question", map[int64]string{1: `{"option_id": "yes"}`, 2: `{"option_ids": ["bus", "other"]}`}, []int64{1, 2, 3, 4}},
+		{"answers to skipped questions don't steer", map[int64]string{1: `{"option_id": "no"}`, 2: `{"option_ids": ["other"]}`}, []int64{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow, err := surveys.NewFlow(commuteSurvey())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			answers := make(map[int64]json.RawMessage, len(tt.answers))
+			for questionID, answer := range tt.answers {
+				answers[questionID] = json.RawMessage(answer)
+			}
+
+			var got []int64
+			for _, question := range flow.Path(answers) {
+				got = append(got, question.ID)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("path = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("path = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAnswersWithLogic(t *testing.T) {
+
+	t.Run("skipped questions are not required", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "no"}`),
+			4: json.RawMessage(`{"value": 2}`),
+		}
+
+		if err := surveys.ValidateAnswers(commuteSurvey(), answers); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("answers to skipped questions are rejected", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "no"}`),
+			2: json.RawMessage(`{"option_ids": ["bus"]}`),
+			4: json.RawMessage(`{"value": 2}`),
+		}
+
+		err := surveys.ValidateAnswers(commuteSurvey(), answers)
+		if err == nil || !strings.Contains(err.Error(), "answers[2]: question 2 is skipped for this respondent") {
+			t.Errorf("error = %v, want a skipped question error", err)
+		}
+	})
+
+	t.Run("shown questions are required", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "yes"}`),
+			2: json.RawMessage(`{"option_ids": ["other"]}`),
+			4: json.RawMessage(`{"value": 2}`),
+		}
+
+		err := surveys.ValidateAnswers(commuteSurvey(), answers)
+		if err == nil || !strings.Contains(err.Error(), "answers[3]: question 3 is required") {
+			t.Errorf("error = %v, want a required question error", err)
+		}
+	})
+}
+
+func TestNextQuestion(t *testing.T) {
+
+	t.Run("starts at the first question", func(t *testing.T) {
+		question, ok, err := surveys.NextQuestion(commuteSurvey(), nil, 0)
+		if err != nil || !ok || question.ID != 1 {
+			t.Fatalf("got question %d (ok %v, err %v), want question 1", question.ID, ok, err)
+		}
+	})
+
+	t.Run("follows a skip rule", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "no"}`)}
+
+		question, ok, err := surveys.NextQuestion(commuteSurvey(), answers, 1)
+		if err != nil || !ok || question.ID != 4 {
+			t.Fatalf("got question %d (ok %v, err %v), want question 4", question.ID, ok, err)
+		}
+	})
+
+	t.Run("ends after the last question", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "no"}`),
+			4: json.RawMessage(`{"value": 3}`),
+		}
+
+		_, ok, err := surveys.NextQuestion(commuteSurvey(), answers, 4)
+		if err != nil || ok {
+			t.Fatalf("got ok %v (err %v), want the end of the survey", ok, err)
+		}
+	})
+
+	t.Run("rejects an invalid answer so far", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "maybe"}`)}
+
+		_, _, err := surveys.NextQuestion(commuteSurvey(), answers, 1)
+		if err == nil || !strings.Contains(err.Error(), "answers[1].option_id") {
+			t.Errorf("error = %v, want an option_id error", err)
+		}
+	})
+
+	t.Run("rejects a question that was skipped", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "no"}`)}
+
+		_, _, err := surveys.NextQuestion(commuteSurvey(), answers, 2)
+		if err == nil || !strings.Contains(err.Error(), "question 2 is not shown to this respondent") {
+			t.Errorf("error = %v, want a not shown error", err)
+		}
+	})
+}
+
+func TestPruneAnswers(t *testing.T) {
+
+	t.Run("keeps answers that still fit", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "yes"}`),
+			2: json.RawMessage(`{"option_ids": ["bus"]}`),
+		}
+
+		kept, dropped, err := surveys.PruneAnswers(commuteSurvey(), answers)
+		if err != nil || len(kept) != 2 || len(dropped) != 0 {
+			t.Fatalf("kept %v, dropped %v (err %v), want both kept", kept, dropped, err)
+		}
+	})
+
+	t.Run("drops answers the current branching skips", func(t *testing.T) {
+		// saved before question 1 got its skip rule, so questions 2 and 3 were answered
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "no"}`),
+			2: json.RawMessage(`{"option_ids": ["other"]}`),
+			3: json.RawMessage(`{"text": "cycle"}`),
+		}
+
+		kept, dropped, err := surveys.PruneAnswers(commuteSurvey(), answers)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(kept) != 1 || len(dropped) != 2 || dropped[0] != 2 || dropped[1] != 3 {
+			t.Errorf("kept %v, dropped %v, want only question 1 kept", kept, dropped)
+		}
+	})
+
+	t.Run("drops answers that no longer validate and the ones hanging off them", func(t *testing.T) {
+		// the "other" option was removed from question 2 since, which also hides question 3
+		questions := commuteSurvey()
+		questions[1].Config = []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "car", "label": "Car"}]}`)
+		questions[2].Logic = []byte(`{"display_if": {"question_id": 2, "operator": "answered"}}`)
+
+		answers := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "yes"}`),
+			2: json.RawMessage(`{"option_ids": ["other"]}`),
+			3: json.RawMessage(`{"text": "cycle"}`),
+		}
+
+		kept, dropped, err := surveys.PruneAnswers(questions, answers)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := kept[1]; !ok || len(kept) != 1 || len(dropped) != 2 {
+			t.Errorf("kept %v, dropped %v, want only question 1 kept", kept, dropped)
+		}
+	})
+
+	t.Run("drops answers to questions that are gone", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{
+			1:  json.RawMessage(`{"option_id": "yes"}`),
+			99: json.RawMessage(`{"text": "gone"}`),
+		}
+
+		_, dropped, err := surveys.PruneAnswers(commuteSurvey(), answers)
+		if err != nil || len(dropped) != 1 || dropped[0] != 99 {
+			t.Errorf("dropped %v (err %v), want question 99 dropped", dropped, err)
+		}
+	})
+}
+
+func TestMergeAnswers(t *testing.T) {
+
+	t.Run("drops saved answers on a branch the page leaves", func(t *testing.T) {
+		saved := map[int64]json.RawMessage{
+			1: json.RawMessage(`{"option_id": "yes"}`),
+			2: json.RawMessage(`{"option_ids": ["bus"]}`),
+		}
+		page := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "no"}`)}
+
+		merged, dropped, err := surveys.MergeAnswers(commuteSurvey(), saved, page)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(merged[1]) != `{"option_id": "no"}` || len(merged) != 1 {
+			t.Errorf("merged = %v, want only the new answer to question 1", merged)
+		}
+
+		if len(dropped) != 1 || dropped[0] != 2 {
+			t.Errorf("dropped = %v, want question 2", dropped)
+		}
+	})
+
+	t.Run("keeps a bad page answer for the caller to report", func(t *testing.T) {
+		saved := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "yes"}`)}
+		page := map[int64]json.RawMessage{2: json.RawMessage(`{"option_ids": ["plane"]}`)}
+
+		merged, dropped, err := surveys.MergeAnswers(commuteSurvey(), saved, page)
+		if err != nil || len(merged) != 2 || len(dropped) != 0 {
+			t.Fatalf("merged %v, dropped %v (err %v), want the page kept", merged, dropped, err)
+		}
+
+		if _, _, err := surveys.NextQuestion(commuteSurvey(), merged, 2); err == nil {
+			t.Errorf("expected the bad answer to be reported")
+		}
+	})
+}
+
+func TestResumeAfter(t *testing.T) {
+
+	answers := map[int64]json.RawMessage{
+		1: json.RawMessage(`{"option_id": "yes"}`),
+		2: json.RawMessage(`{"option_ids": ["bus"]}`),
+	}
+
+	tests := []struct {
+		name string
+		last int64
+		want int64
+	}{
+		{"continues after the last saved question", 2, 2},
+		{"falls back to the last answer when the saved question is off the path", 3, 2},
+		{"falls back when nothing was saved", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := surveys.ResumeAfter(commuteSurvey(), answers, tt.last)
+			if err != nil || after != tt.want {
+				t.Errorf("after = %d (err %v), want %d", after, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("starts over without answers", func(t *testing.T) {
+		after, err := surveys.ResumeAfter(commuteSurvey(), nil, 0)
+		if err != nil || after != 0 {
+			t.Errorf("after = %d (err %v), want 0", after, err)
+		}
+	})
+}
+
+func TestScreenedOut(t *testing.T) {
+
+	screened := func() []database.Question {
+		questions := commuteSurvey()
+		questions[0].Screener = true
+		questions[0].Logic = []byte(`{"screen_out_if": {"question_id": 1, "operator": "equals", "value": "no"}}`)
+		return questions
+	}
+
+	t.Run("screeners must come first", func(t *testing.T) {
+		questions := screened()
+		questions[2].Screener = true
+
+		err := surveys.ValidateLogic(questions)
+		if err == nil || !strings.Contains(err.Error(), "questions[3].screener: screener questions must come before the other questions") {
+			t.Errorf("error = %v, want a screener order error", err)
+		}
+	})
+
+	t.Run("a disqualifying answer ends the path", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "no"}`)}
+
+		screenedOut, err := surveys.ScreenedOut(screened(), answers)
+		if err != nil || !screenedOut {
+			t.Fatalf("got screened out %v (err %v), want true", screenedOut, err)
+		}
+
+		_, ok, err := surveys.NextQuestion(screened(), answers, 1)
+		if err != nil || ok {
+			t.Errorf("got ok %v (err %v), want the end of the survey", ok, err)
+		}
+
+		if err := surveys.ValidateAnswers(screened(), answers); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("a qualifying answer carries on", func(t *testing.T) {
+		answers := map[int64]json.RawMessage{1: json.RawMessage(`{"option_id": "yes"}`)}
+
+		screenedOut, err := surveys.ScreenedOut(screened(), answers)
+		if err != nil || screenedOut {
+			t.Fatalf("got screened out %v (err %v), want false", screenedOut, err)
+		}
+
+		question, ok, err := surveys.NextQuestion(screened(), answers, 1)
+		if err != nil || !ok || question.ID != 2 {
+			t.Errorf("got question %d (ok %v, err %v), want question 2", question.ID, ok, err)
+		}
+	})
+}
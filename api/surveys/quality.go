@@ -0,0 +1,359 @@
+package surveys
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Names of the quality checks, as used in QualitySettings.Disabled and in quality reports.
+const (
+	CheckSpeeding        = "speeding"
+	CheckStraightLining  = "straight_lining"
+	CheckAttentionChecks = "attention_checks"
+	CheckGibberish       = "gibberish"
+)
+
+// Defaults of the quality thresholds, used for the ones a survey leaves unset.
+const (
+	DefaultSpeedingRatio      = 0.3
+	DefaultStraightLiningRows = 3
+	DefaultGibberishRatio     = 0.5
+	// MinSpeedingSample is how many other responses a survey needs before a response is compared
+	// with their median time, below that the median says too little.
+	MinSpeedingSample = 10
+)
+
+// QualityChecks are the checks every submitted response goes through, unless its survey disables
+// some of them.
+var QualityChecks = []QualityCheck{SpeedingCheck{}, StraightLiningCheck{}, AttentionCheck{}, GibberishCheck{}}
+
+// QualitySettings are the thresholds of a survey's quality checks, stored in surveys.quality_checks.
+// A response that fails any check that runs is flagged for review instead of being paid.
+type QualitySettings struct {
+	// Disabled lists the checks that don't run for the survey.
+	Disabled []string `json:"disabled,omitempty" validate:"omitempty,unique,dive,oneof=speeding straight_lining attention_checks gibberish"`
+	// SpeedingRatio fails responses completed in less than this share of the median time.
+	SpeedingRatio float64 `json:"speeding_ratio,omitempty" validate:"omitempty,gt=0,lt=1"`
+	// StraightLiningRows is how many rows a matrix needs before picking the same column in every
+	// one of them counts as straight-lining.
+	StraightLiningRows int `json:"straight_lining_rows,omitempty" validate:"omitempty,gte=2"`
+	// MaxFailedAttentionChecks is how many attention checks a response may fail and still pass.
+	MaxFailedAttentionChecks int `json:"max_failed_attention_checks,omitempty" validate:"gte=0"`
+	// GibberishRatio fails responses where at least this share of the free text answers looks
+	// like gibberish.
+	GibberishRatio float64 `json:"gibberish_ratio,omitempty" validate:"omitempty,gt=0,lte=1"`
+}
+
+// ParseQualitySettings decodes and validates the quality settings of a survey.
+func ParseQualitySettings(raw json.RawMessage) (QualitySettings, error) {
+	var settings QualitySettings
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return settings, nil
+	}
+
+	if err := decodeStrict(raw, &settings); err != nil {
+		return QualitySettings{}, fmt.Errorf("invalid quality_checks: %v", err)
+	}
+
+	if err := jsonutil.Validate(settings); err != nil {
+		return QualitySettings{}, jsonutil.ValidationErrors(prefixErrors("quality_checks", err))
+	}
+
+	return settings, nil
+}
+
+// withDefaults fills in the thresholds the survey left unset.
+func (s QualitySettings) withDefaults() QualitySettings {
+	if s.SpeedingRatio == 0 {
+		s.SpeedingRatio = DefaultSpeedingRatio
+	}
+
+	if s.StraightLiningRows == 0 {
+		s.StraightLiningRows = DefaultStraightLiningRows
+	}
+
+	if s.GibberishRatio == 0 {
+		s.GibberishRatio = DefaultGibberishRatio
+	}
+
+	return s
+}
+
+// Submission is a submitted response as the quality checks see it.
+type Submission struct {
+	Questions []database.Question
+	Answers   map[int64]json.RawMessage
+	// Duration is how long the respondent took, from starting to submitting.
+	Duration time.Duration
+	// MedianDuration is the median time the survey's other submitted responses took, and Sample
+	// how many of them there are.
+	MedianDuration time.Duration
+	Sample         int32
+	Settings       QualitySettings
+}
+
+// QualityCheck scores one aspect of a submitted response.
+type QualityCheck interface {
+	Name() string
+	Run(submission Submission) CheckResult
+}
+
+// CheckResult is the outcome of one quality check. A check with nothing to look at, like the
+// gibberish check on a survey without text questions, passes and is marked Skipped.
+type CheckResult struct {
+	Check   string `json:"check"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// QualityReport is what the quality checks made of a response, stored in responses.quality_report.
+// Score is the share of the checks that ran and passed, from 0 to 1.
+type QualityReport struct {
+	Score   float64       `json:"score"`
+	Flagged bool          `json:"flagged"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// ScoreResponse runs the checks the submission's survey hasn't disabled. The response is flagged
+// when any of them fails.
+func ScoreResponse(submission Submission, checks []QualityCheck) QualityReport {
+	submission.Settings = submission.Settings.withDefaults()
+
+	report := QualityReport{Score: 1, Checks: []CheckResult{}}
+
+	ran, passed := 0, 0
+	for _, check := range checks {
+		if slices.Contains(submission.Settings.Disabled, check.Name()) {
+			continue
+		}
+
+		result := check.Run(submission)
+		result.Check = check.Name()
+		report.Checks = append(report.Checks, result)
+
+		if result.Skipped {
+			continue
+		}
+
+		ran++
+		if result.Passed {
+			passed++
+		} else {
+			report.Flagged = true
+		}
+	}
+
+	if ran > 0 {
+		report.Score = float64(passed) / float64(ran)
+	}
+
+	return report
+}
+
+// SpeedingCheck fails responses completed much faster than the survey's other responses.
+type SpeedingCheck struct{}
+
+func (SpeedingCheck) Name() string {
+	return CheckSpeeding
+}
+
+func (SpeedingCheck) Run(submission Submission) CheckResult {
+	if submission.Sample < MinSpeedingSample || submission.MedianDuration <= 0 {
+		return CheckResult{Passed: true, Skipped: true, Detail: "not enough responses to compare with yet"}
+	}
+
+	ratio := submission.Duration.Seconds() / submission.MedianDuration.Seconds()
+
+	return CheckResult{
+		Passed: ratio >= submission.Settings.SpeedingRatio,
+		Detail: fmt.Sprintf("completed in %s against a median of %s", submission.Duration.Round(time.Second), submission.MedianDuration.Round(time.Second)),
+	}
+}
+
+// StraightLiningCheck fails responses that picked the same column in every row of a matrix.
+type StraightLiningCheck struct{}
+
+func (StraightLiningCheck) Name() string {
+	return CheckStraightLining
+}
+
+func (StraightLiningCheck) Run(submission Submission) CheckResult {
+	checked := 0
+	var straightLined []string
+
+	for _, question := range submission.Questions {
+		raw, answered := submission.Answers[question.ID]
+		if question.Type != database.QuestionTypeMatrix || !answered {
+			continue
+		}
+
+		var answer MatrixAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil || len(answer.Rows) < submission.Settings.StraightLiningRows {
+			continue
+		}
+
+		checked++
+
+		columns := make(map[string]bool)
+		for _, column := range answer.Rows {
+			columns[column] = true
+		}
+
+		if len(columns) == 1 {
+			straightLined = append(straightLined, fmt.Sprintf("%d", question.ID))
+		}
+	}
+
+	if checked == 0 {
+		return CheckResult{Passed: true, Skipped: true}
+	}
+
+	if len(straightLined) > 0 {
+		return CheckResult{Detail: fmt.Sprintf("the same answer in every row of question %s", strings.Join(straightLined, ", "))}
+	}
+
+	return CheckResult{Passed: true}
+}
+
+// AttentionCheck fails responses that broke more attention checks than the survey allows.
+type AttentionCheck struct{}
+
+func (AttentionCheck) Name() string {
+	return CheckAttentionChecks
+}
+
+func (AttentionCheck) Run(submission Submission) CheckResult {
+	flow, err := NewFlow(submission.Questions)
+	if err != nil {
+		return CheckResult{Passed: true, Skipped: true, Detail: err.Error()}
+	}
+
+	hasChecks := slices.ContainsFunc(flow.logic, func(logic QuestionLogic) bool { return logic.AttentionCheck != nil })
+	if !hasChecks {
+		return CheckResult{Passed: true, Skipped: true}
+	}
+
+	failed := flow.FailedAttentionChecks(submission.Answers)
+
+	return CheckResult{
+		Passed: len(failed) <= submission.Settings.MaxFailedAttentionChecks,
+		Detail: fmt.Sprintf("failed %d attention checks", len(failed)),
+	}
+}
+
+// GibberishCheck fails responses whose free text answers are mostly keyboard mashing.
+type GibberishCheck struct{}
+
+func (GibberishCheck) Name() string {
+	return CheckGibberish
+}
+
+func (GibberishCheck) Run(submission Submission) CheckResult {
+	texts, gibberish := 0, 0
+
+	for _, question := range submission.Questions {
+		raw, answered := submission.Answers[question.ID]
+		if question.Type != database.QuestionTypeText || !answered {
+			continue
+		}
+
+		var answer TextAnswer
+		if err := json.Unmarshal(raw, &answer); err != nil || strings.TrimSpace(answer.Text) == "" {
+			continue
+		}
+
+		texts++
+		if LooksLikeGibberish(answer.Text) {
+			gibberish++
+		}
+	}
+
+	if texts == 0 {
+		return CheckResult{Passed: true, Skipped: true}
+	}
+
+	return CheckResult{
+		Passed: float64(gibberish)/float64(texts) < submission.Settings.GibberishRatio,
+		Detail: fmt.Sprintf("%d of %d text answers look like gibberish", gibberish, texts),
+	}
+}
+
+// keyboardRows are runs of keys that keyboard mashing tends to produce.
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// LooksLikeGibberish guesses whether a piece of latin script text was typed without meaning to say
+// anything: a word running along a keyboard row, one letter held down or a long run of consonants,
+// or almost no vowels in the whole answer. Short answers and text in other scripts are given the
+// benefit of the doubt.
+func LooksLikeGibberish(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+
+	letters, latin, vowels := 0, 0, 0
+	for _, word := range words {
+		for _, r := range word {
+			letters++
+			if r >= 'a' && r <= 'z' {
+				latin++
+			}
+			if strings.ContainsRune("aeiouy", r) {
+				vowels++
+			}
+		}
+	}
+
+	// accented letters say nothing about vowels, so only text that is nearly all plain latin is judged
+	if letters < 5 || latin*10 < letters*9 {
+		return false
+	}
+
+	for _, word := range words {
+		if gibberishWord(word) {
+			return true
+		}
+	}
+
+	return float64(vowels)/float64(letters) < 0.1
+}
+
+// gibberishWord checks a single word, so runs of keys or consonants never span two words.
+func gibberishWord(word string) bool {
+	for _, row := range keyboardRows {
+		for i := 0; i+5 <= len(row); i++ {
+			if strings.Contains(word, row[i:i+5]) {
+				return true
+			}
+		}
+	}
+
+	letters := []rune(word)
+	run, repeated := 0, 1
+	for i, r := range letters {
+		if strings.ContainsRune("aeiouy", r) {
+			run = 0
+		} else {
+			run++
+			if run >= 6 {
+				return true
+			}
+		}
+
+		if i > 0 && r == letters[i-1] {
+			repeated++
+			if repeated >= 5 {
+				return true
+			}
+		} else {
+			repeated = 1
+		}
+	}
+
+	return false
+}
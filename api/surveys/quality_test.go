@@ -0,0 +1,206 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"testing"
+	"time"
+)
+
+// qualitySurvey has a matrix, an attention check asking for "Agree" on a likert scale and a text
+// question.
+func qualitySurvey() []database.Question {
+	return []database.Question{
+		{
+			ID:       1,
+			Position: 1,
+			Type:     database.QuestionTypeMatrix,
+			Config:   []byte(`{"rows": [{"id": "price", "label": "Price"}, {"id": "speed", "label": "Speed"}, {"id": "support", "label": "Support"}], "columns": [{"id": "bad", "label": "Bad"}, {"id": "good", "label": "Good"}]}`),
+		},
+		{
+			ID:       2,
+			Position: 2,
+			Type:     database.QuestionTypeLikert,
+			Config:   []byte(`{"points": 5}`),
+			Logic:    []byte(`{"attention_check": {"question_id": 2, "operator": "equals", "value": 4}}`),
+		},
+		{
+			ID:       3,
+			Position: 3,
+			Type:     database.QuestionTypeText,
+			Config:   []byte(`{"max_length": 200}`),
+		},
+	}
+}
+
+func qualitySubmission(answers map[int64]string) surveys.Submission {
+	raw := make(map[int64]json.RawMessage, len(answers))
+	for id, answer := range answers {
+		raw[id] = json.RawMessage(answer)
+	}
+
+	return surveys.Submission{
+		Questions:      qualitySurvey(),
+		Answers:        raw,
+		Duration:       5 * time.Minute,
+		MedianDuration: 6 * time.Minute,
+		Sample:         20,
+	}
+}
+
+func TestScoreResponse(t *testing.T) {
+
+	careful := map[int64]string{
+		1: `{"rows": {"price": "bad", "speed": "good", "support": "good"}}`,
+		2: `{"value": 4}`,
+		3: `{"text": "The app is quick but support takes days to reply."}`,
+	}
+
+	t.Run("passes a careful response", func(t *testing.T) {
+		report := surveys.ScoreResponse(qualitySubmission(careful), surveys.QualityChecks)
+
+		if report.Flagged || report.Score != 1 || len(report.Checks) != 4 {
+			t.Errorf("report = %+v, want all 4 checks passed", report)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(submission *surveys.Submission)
+		failed string
+	}{
+		{"speeding", func(s *surveys.Submission) { s.Duration = time.Minute }, surveys.CheckSpeeding},
+		{"straight-lining", func(s *surveys.Submission) {
+			s.Answers[1] = json.RawMessage(`{"rows": {"price": "good", "speed": "good", "support": "good"}}`)
+		}, surveys.CheckStraightLining},
+		{"failed attention check", func(s *surveys.Submission) { s.Answers[2] = json.RawMessage(`{"value": 1}`) }, surveys.CheckAttentionChecks},
+		{"gibberish", func(s *surveys.Submission) { s.Answers[3] = json.RawMessage(`{"text": "asdfgh jkl"}`) }, surveys.CheckGibberish},
+	}
+
+	for _, tt := range tests {
+		t.Run("flags "+tt.name, func(t *testing.T) {
+			submission := qualitySubmission(careful)
+			tt.modify(&submission)
+
+			report := surveys.ScoreResponse(submission, surveys.QualityChecks)
+
+			if !report.Flagged || report.Score != 0.75 {
+				t.Fatalf("report = %+v, want it flagged with 3 of 4 checks passed", report)
+			}
+
+			for _, result := range report.Checks {
+				if result.Passed == (result.Check == tt.failed) {
+					t.Errorf("%s passed = %v", result.Check, result.Passed)
+				}
+			}
+		})
+	}
+
+	t.Run("passes an ordinary free-text answer", func(t *testing.T) {
+		submission := qualitySubmission(careful)
+		submission.Answers[3] = json.RawMessage(`{"text": "Lunch schedule is fine, but the first street past campus floods when it rains."}`)
+
+		report := surveys.ScoreResponse(submission, surveys.QualityChecks)
+
+		if report.Flagged || !report.Checks[3].Passed {
+			t.Errorf("report = %+v, want the gibberish check passed", report)
+		}
+	})
+
+	t.Run("skips speeding without enough responses to compare with", func(t *testing.T) {
+		submission := qualitySubmission(careful)
+		submission.Duration = time.Second
+		submission.Sample = 3
+
+		report := surveys.ScoreResponse(submission, surveys.QualityChecks)
+
+		if report.Flagged || !report.Checks[0].Skipped {
+			t.Errorf("report = %+v, want speeding skipped", report)
+		}
+	})
+
+	t.Run("leaves out disabled checks", func(t *testing.T) {
+		submission := qualitySubmission(careful)
+		submission.Duration = time.Second
+		submission.Settings = surveys.QualitySettings{Disabled: []string{surveys.CheckSpeeding}}
+
+		report := surveys.ScoreResponse(submission, surveys.QualityChecks)
+
+		if report.Flagged || len(report.Checks) != 3 {
+			t.Errorf("report = %+v, want 3 checks passed", report)
+		}
+	})
+
+	t.Run("allows the configured number of failed attention checks", func(t *testing.T) {
+		submission := qualitySubmission(careful)
+		submission.Answers[2] = json.RawMessage(`{"value": 1}`)
+		submission.Settings = surveys.QualitySettings{MaxFailedAttentionChecks: 1}
+
+		report := surveys.ScoreResponse(submission, surveys.QualityChecks)
+
+		if report.Flagged {
+			t.Errorf("report = %+v, want it passed", report)
+		}
+	})
+}
+
+func TestParseQualitySettings(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{"no settings", ``, false},
+		{"thresholds", `{"speeding_ratio": 0.5, "straight_lining_rows": 4, "max_failed_attention_checks": 1, "gibberish_ratio": 0.3}`, false},
+		{"disabled checks", `{"disabled": ["speeding", "gibberish"]}`, false},
+		{"unknown check", `{"disabled": ["typos"]}`, true},
+		{"speeding ratio of 1", `{"speeding_ratio": 1}`, true},
+		{"single row matrix", `{"straight_lining_rows": 1}`, true},
+		{"unknown field", `{"min_score": 0.5}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := surveys.ParseQualitySettings(json.RawMessage(tt.settings))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLooksLikeGibberish(t *testing.T) {
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"The checkout page kept timing out on my phone.", false},
+		{"Strengths", false},
+		{"I watch schools", false},
+		{"Lunch schedule is fine", false},
+		{"The first street", false},
+		{"Good strength training", false},
+		{"asdfgh jkl", true},
+		{"the food was ok asdfghjk", true},
+		{"ok", false},
+		{"qwerty", true},
+		{"sdfghjkl", true},
+		{"aaaaaaaa", true},
+		{"xkcdvbnrtp", true},
+		{"hjkhjkhjk", true},
+		{"Ọjà náà dára púpọ̀", false},
+		{"Очень удобное приложение", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := surveys.LooksLikeGibberish(tt.text); got != tt.want {
+				t.Errorf("LooksLikeGibberish(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
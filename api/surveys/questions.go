@@ -0,0 +1,405 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func questionIDParam(request *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(request, "questionID"), 10, 64)
+}
+
+// ownedSurvey loads the survey named in the URL for the researcher making the request.
+// When it can't, it writes the error response itself and returns false.
+func (h *Handler) ownedSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Survey, bool) {
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return database.Survey{}, false
+	}
+
+	surveyID, err := surveyIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return database.Survey{}, false
+	}
+
+	survey, err := h.Store.GetSurvey(ctx, surveyID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return database.Survey{}, false
+	}
+
+	return survey, true
+}
+
+// canManage tells whether a researcher who can see a survey may also change it, which the viewers of
+// the organization it belongs to may not. When they can't, it writes the error response itself.
+func (h *Handler) canManage(ctx context.Context, responseWriter http.ResponseWriter, survey database.Survey, userID int64) bool {
+	if !survey.OrganizationID.Valid {
+		return true
+	}
+
+	member, err := h.Store.GetMember(ctx, survey.OrganizationID.Int64, userID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return false
+	}
+
+	if member.Role == database.OrganizationRoleViewer {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "viewers can't change the surveys of their organization",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// managedSurvey is ownedSurvey for requests that change the survey.
+func (h *Handler) managedSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Survey, bool) {
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return database.Survey{}, false
+	}
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	if !h.canManage(ctx, responseWriter, survey, int64(claims.UserID)) {
+		return database.Survey{}, false
+	}
+
+	return survey, true
+}
+
+// editableSurvey is managedSurvey for requests that change how the survey is set up, which is only
+// allowed while the survey is a draft.
+func (h *Handler) editableSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Survey, bool) {
+	survey, ok := h.managedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return database.Survey{}, false
+	}
+
+	if survey.Status != database.SurveyStatusDraft {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "only draft surveys can be edited",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return database.Survey{}, false
+	}
+
+	return survey, true
+}
+
+// revisableSurvey is managedSurvey for requests that change the survey's questions. Unlike the rest
+// of a survey they can still change once it is published, each change making a new version of it
+// while the responses already given keep pointing at the version they answered.
+func (h *Handler) revisableSurvey(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request) (database.Survey, bool) {
+	survey, ok := h.managedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return database.Survey{}, false
+	}
+
+	if survey.Status != database.SurveyStatusDraft && survey.Status != database.SurveyStatusPublished {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "only draft and published surveys can be edited",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return database.Survey{}, false
+	}
+
+	return survey, true
+}
+
+func (h *Handler) CreateQuestionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.revisableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[CreateQuestionBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	config, err := ParseQuestionConfig(database.QuestionType(data.Type), data.Config)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	// store the config as parsed so it only ever holds the fields of its type
+	data.Config, err = json.Marshal(config)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	if len(data.Logic) > 0 {
+		logic, err := ParseQuestionLogic(data.Logic)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+
+		data.Logic, err = json.Marshal(logic)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	question, err := h.Store.CreateQuestion(ctx, survey.ID, data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "question created successfully",
+		Data:    NewQuestion(question),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+func (h *Handler) ListQuestionsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved questions successfully",
+		Data:    NewQuestions(questions),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) UpdateQuestionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.revisableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questionID, err := questionIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid question id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[UpdateQuestionBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	question, err := h.Store.GetQuestion(ctx, survey.ID, questionID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if len(data.Config) > 0 {
+		config, err := ParseQuestionConfig(question.Type, data.Config)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+
+		data.Config, err = json.Marshal(config)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if len(data.Logic) > 0 {
+		logic, err := ParseQuestionLogic(data.Logic)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+
+		data.Logic, err = json.Marshal(logic)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	changed := question
+	if len(data.Config) > 0 {
+		changed.Config = data.Config
+	}
+	if data.Required != nil {
+		changed.Required = *data.Required
+	}
+
+	if !h.checkInvalidated(ctx, responseWriter, request, survey, question.ID, &changed) {
+		return
+	}
+
+	question, err = h.Store.UpdateQuestion(ctx, survey.ID, questionID, data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "question updated successfully",
+		Data:    NewQuestion(question),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) DeleteQuestionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.revisableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questionID, err := questionIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid question id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkInvalidated(ctx, responseWriter, request, survey, questionID, nil) {
+		return
+	}
+
+	// a draft's questions can go for good, a published survey's still have answers pointing at them
+	if survey.Status == database.SurveyStatusDraft {
+		err = h.Store.DeleteQuestion(ctx, survey.ID, questionID)
+	} else {
+		err = h.Store.RemoveQuestion(ctx, survey.ID, questionID)
+	}
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "question deleted successfully",
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
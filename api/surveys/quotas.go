@@ -0,0 +1,178 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func quotaIDParam(request *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(request, "quotaID"), 10, 64)
+}
+
+// ParseQuotaCriteria decodes and validates the criteria of a quota cell. They are written like
+// targeting rules and a respondent falls in the cell when they would pass them, but unlike
+// targeting a cell must have at least one rule.
+func ParseQuotaCriteria(raw json.RawMessage) (Targeting, error) {
+	criteria, err := parseRules("criteria", raw)
+	if err != nil {
+		return Targeting{}, err
+	}
+
+	if criteria.IsEmpty() {
+		return Targeting{}, fieldError("criteria", "criteria must have at least one rule")
+	}
+
+	return criteria, nil
+}
+
+// MatchQuotaCells returns the quota cells a respondent counts toward. Every response counts toward
+// each cell it falls in, so when one of them is already full, counting the slots held by responses
+// still in progress, the respondent is turned away with an error wrapping ErrNotEligible. A respondent who falls in no cell is only held to the survey's
+// total target.
+func MatchQuotaCells(cells []database.QuotaCell, audience Audience, now time.Time) ([]database.QuotaCell, error) {
+	var matched []database.QuotaCell
+
+	for _, cell := range cells {
+		// criteria were validated when the cell was stored, one that no longer parses matches nobody
+		criteria, err := ParseQuotaCriteria(cell.Criteria)
+		if err != nil || criteria.Eligible(audience, now) != nil {
+			continue
+		}
+
+		if cell.Filled+cell.Held >= cell.Capacity {
+			return nil, notEligible("this survey already has all the responses it needs from people like you")
+		}
+
+		matched = append(matched, cell)
+	}
+
+	return matched, nil
+}
+
+func (h *Handler) CreateQuotaCellHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.editableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[CreateQuotaCellBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	criteria, err := ParseQuotaCriteria(data.Criteria)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	// store the criteria as parsed so they only ever hold known fields
+	data.Criteria, err = json.Marshal(criteria)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	cell, err := h.Store.CreateQuotaCell(ctx, survey.ID, data)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "quota cell created successfully",
+		Data:    NewQuotaCell(cell),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusCreated)
+	return
+}
+
+func (h *Handler) ListQuotaCellsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	cells, err := h.Store.ListQuotaCells(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved quota cells successfully",
+		Data:    NewQuotaCells(cells),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) DeleteQuotaCellHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.editableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	quotaID, err := quotaIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid quota id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	err = h.Store.DeleteQuotaCell(ctx, survey.ID, quotaID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "quota cell deleted successfully",
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
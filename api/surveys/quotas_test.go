@@ -0,0 +1,79 @@
+package surveys_test
+
+import (
+	"errors"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+	"time"
+)
+
+func TestMatchQuotaCells(t *testing.T) {
+
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	engineer := surveys.Audience{
+		Profile: database.Profile{
+			Gender:  database.NullGender{Gender: database.GenderFemale, Valid: true},
+			Faculty: pgtype.Text{String: "Engineering", Valid: true},
+		},
+	}
+
+	cell := func(id int64, criteria string, filled int32) database.QuotaCell {
+		return database.QuotaCell{ID: id, SurveyID: 1, Criteria: []byte(criteria), Capacity: 10, Filled: filled}
+	}
+
+	tests := []struct {
+		name     string
+		cells    []database.QuotaCell
+		want     []int64
+		wantFull bool
+	}{
+		{"no cells", nil, nil, false},
+		{"no matching cell", []database.QuotaCell{cell(1, `{"genders": ["male"]}`, 10)}, nil, false},
+		{"counts toward every matching cell", []database.QuotaCell{
+			cell(1, `{"genders": ["female"]}`, 3),
+			cell(2, `{"faculties": ["engineering"]}`, 9),
+			cell(3, `{"faculties": ["Law"]}`, 0),
+		}, []int64{1, 2}, false},
+		{"a full matching cell turns the user away", []database.QuotaCell{
+			cell(1, `{"genders": ["female"], "faculties": ["Engineering"]}`, 10),
+		}, nil, true},
+		{"full cells the user is not in don't matter", []database.QuotaCell{
+			cell(1, `{"genders": ["male"]}`, 10),
+			cell(2, `{"genders": ["female"]}`, 4),
+		}, []int64{2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := surveys.MatchQuotaCells(tt.cells, engineer, now)
+
+			if tt.wantFull {
+				if !errors.Is(err, surveys.ErrNotEligible) {
+					t.Fatalf("error = %v, want a not eligible error", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []int64
+			for _, cell := range matched {
+				got = append(got, cell.ID)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
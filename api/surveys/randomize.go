@@ -0,0 +1,186 @@
+package surveys
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/database"
+	"hash/fnv"
+	"slices"
+)
+
+// Display is where a question and its options were shown to a respondent. Options holds the IDs
+// of the options, or of the rows of a matrix, in the order they were shown, and is only set for
+// questions that shuffle them.
+type Display struct {
+	Position int32    `json:"position"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// Arrange puts the questions of a survey in the order one respondent is shown them. Consecutive
+// questions sharing a block are shuffled among themselves, and the options of questions that
+// randomize them are shuffled around the anchored ones, both from the respondent's seed, so the
+// same seed always gives the same order. The questions come back in that order with Position
+// renumbered to match and Config listing the options as shown. IDs are left alone, so answers
+// still refer to questions and options the same way whatever order they were shown in.
+func Arrange(questions []database.Question, seed int64) []database.Question {
+	arranged := slices.Clone(questions)
+	slices.SortStableFunc(arranged, func(a, b database.Question) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+
+	for start := 0; start < len(arranged); {
+		end := start + 1
+		for end < len(arranged) && sameBlock(arranged[start], arranged[end]) {
+			end++
+		}
+
+		// every block gets its own stream, so adding a question to one leaves the others alone
+		shuffle(newShuffler(seed, "block:"+arranged[start].Block.String), arranged[start:end])
+		start = end
+	}
+
+	for i := range arranged {
+		arranged[i].Position = int32(i + 1)
+		arranged[i].Config = arrangeOptions(arranged[i], seed)
+	}
+
+	return arranged
+}
+
+// Displays returns where each of the questions Arrange put in order was shown, keyed by ID.
+func Displays(arranged []database.Question) map[int64]Display {
+	displays := make(map[int64]Display, len(arranged))
+
+	for _, question := range arranged {
+		display := Display{Position: question.Position}
+
+		if config, err := ParseQuestionConfig(question.Type, question.Config); err == nil {
+			for _, option := range shuffledOptions(config) {
+				display.Options = append(display.Options, option.ID)
+			}
+		}
+
+		displays[question.ID] = display
+	}
+
+	return displays
+}
+
+// Randomized reports whether any of the questions is shown in a different order, or with its
+// options in a different order, from one respondent to the next.
+func Randomized(questions []database.Question) bool {
+	return slices.ContainsFunc(questions, func(question database.Question) bool {
+		return question.Block.Valid || shufflesOptions(question)
+	})
+}
+
+// shufflesOptions reports whether a question shows its options in a different order to every
+// respondent.
+func shufflesOptions(question database.Question) bool {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	return err == nil && shuffledOptions(config) != nil
+}
+
+// arrangeOptions returns the config of a question with its options shuffled for the seed when it
+// randomizes them, otherwise the config as it is.
+func arrangeOptions(question database.Question, seed int64) json.RawMessage {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	if err != nil {
+		return question.Config
+	}
+
+	options := shuffledOptions(config)
+	if options == nil {
+		return question.Config
+	}
+
+	// anchored options keep their slots, the others are shuffled through the slots left
+	var free []int
+	for i, option := range options {
+		if !option.Anchored {
+			free = append(free, i)
+		}
+	}
+
+	moving := make([]Option, len(free))
+	for i, slot := range free {
+		moving[i] = options[slot]
+	}
+
+	shuffle(newShuffler(seed, fmt.Sprintf("question:%d", question.ID)), moving)
+
+	for i, slot := range free {
+		options[slot] = moving[i]
+	}
+
+	arranged, err := json.Marshal(config)
+	if err != nil {
+		return question.Config
+	}
+
+	return arranged
+}
+
+// shuffledOptions returns the options of a config that are shuffled for every respondent, or nil
+// when it keeps them in order. The slice is the config's own.
+func shuffledOptions(config QuestionConfig) []Option {
+	switch c := config.(type) {
+	case *SingleChoiceConfig:
+		if c.Randomize {
+			return c.Options
+		}
+	case *MultipleChoiceConfig:
+		if c.Randomize {
+			return c.Options
+		}
+	case *RankingConfig:
+		if c.Randomize {
+			return c.Options
+		}
+	case *MatrixConfig:
+		if c.RandomizeRows {
+			return c.Rows
+		}
+	}
+
+	return nil
+}
+
+// sameBlock reports whether two questions belong to the same block.
+func sameBlock(a, b database.Question) bool {
+	return a.Block.Valid && a.Block == b.Block
+}
+
+// shuffler is a splitmix64 generator. It is written out instead of taken from math/rand so that a
+// seed gives the same order for as long as the order needs rebuilding, whatever the Go version.
+type shuffler struct {
+	state uint64
+}
+
+// newShuffler starts the stream of a seed named by stream, so separate lists shuffled from one
+// seed don't move in step.
+func newShuffler(seed int64, stream string) *shuffler {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(stream))
+
+	return &shuffler{state: uint64(seed) ^ hash.Sum64()}
+}
+
+func (s *shuffler) next() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// shuffle is a Fisher-Yates shuffle. The modulo bias is negligible for lists as short as these.
+func shuffle[T any](s *shuffler, items []T) {
+	for i := len(items) - 1; i > 0; i-- {
+		j := int(s.next() % uint64(i+1))
+		items[i], items[j] = items[j], items[i]
+	}
+}
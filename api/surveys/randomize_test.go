@@ -0,0 +1,323 @@
+package surveys_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randomizedQuestions asks how respondents commute, then rates three brands in a shuffled block,
+// then asks for any last thoughts. The commute options are shuffled with "none" anchored last.
+func randomizedQuestions() []database.Question {
+	brand := pgtype.Text{String: "brands", Valid: true}
+	rating := []byte(`{"points": 5}`)
+
+	return []database.Question{
+		{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Title: "How do you commute?",
+			Config: []byte(`{"randomize": true, "options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}, {"id": "bike", "label": "Bike"}, {"id": "car", "label": "Car"}, {"id": "none", "label": "None of the above", "anchored": true}]}`)},
+		{ID: 2, SurveyID: 1, Position: 2, Type: database.QuestionTypeLikert, Title: "Rate Uber", Config: rating, Block: brand},
+		{ID: 3, SurveyID: 1, Position: 3, Type: database.QuestionTypeLikert, Title: "Rate Bolt", Config: rating, Block: brand},
+		{ID: 4, SurveyID: 1, Position: 4, Type: database.QuestionTypeLikert, Title: "Rate inDrive", Config: rating, Block: brand},
+		{ID: 5, SurveyID: 1, Position: 5, Type: database.QuestionTypeText, Title: "Anything else?", Config: []byte(`{"max_length": 500}`)},
+	}
+}
+
+func questionIDs(questions []database.Question) []int64 {
+	ids := make([]int64, 0, len(questions))
+	for _, question := range questions {
+		ids = append(ids, question.ID)
+	}
+
+	return ids
+}
+
+func optionOrder(t *testing.T, question database.Question) []string {
+	t.Helper()
+
+	var config surveys.SingleChoiceConfig
+	if err := json.Unmarshal(question.Config, &config); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	ids := make([]string, 0, len(config.Options))
+	for _, option := range config.Options {
+		ids = append(ids, option.ID)
+	}
+
+	return ids
+}
+
+// ============================================================================
+// Arrange Tests
+// ============================================================================
+
+func TestArrange(t *testing.T) {
+
+	t.Run("gives the same order for the same seed", func(t *testing.T) {
+		first := surveys.Arrange(randomizedQuestions(), 42)
+		again := surveys.Arrange(randomizedQuestions(), 42)
+
+		if !slices.Equal(questionIDs(first), questionIDs(again)) || !slices.Equal(optionOrder(t, first[0]), optionOrder(t, again[0])) {
+			t.Errorf("seed 42 gave %v then %v", questionIDs(first), questionIDs(again))
+		}
+	})
+
+	t.Run("shuffles a block in its place", func(t *testing.T) {
+		orders := make(map[string]bool)
+
+		for seed := int64(1); seed <= 50; seed++ {
+			arranged := surveys.Arrange(randomizedQuestions(), seed)
+			ids := questionIDs(arranged)
+
+			if ids[0] != 1 || ids[4] != 5 {
+				t.Fatalf("seed %d: order = %v, want the questions outside the block left where they are", seed, ids)
+			}
+
+			block := slices.Clone(ids[1:4])
+			slices.Sort(block)
+			if !slices.Equal(block, []int64{2, 3, 4}) {
+				t.Fatalf("seed %d: order = %v, want the block kept together", seed, ids)
+			}
+
+			for i, question := range arranged {
+				if question.Position != int32(i+1) {
+					t.Fatalf("seed %d: question %d at position %d, want %d", seed, question.ID, question.Position, i+1)
+				}
+			}
+
+			orders[fmt.Sprint(ids[1:4])] = true
+		}
+
+		if len(orders) < 2 {
+			t.Errorf("every seed gave the same block order")
+		}
+	})
+
+	t.Run("keeps anchored options in their place", func(t *testing.T) {
+		orders := make(map[string]bool)
+
+		for seed := int64(1); seed <= 50; seed++ {
+			options := optionOrder(t, surveys.Arrange(randomizedQuestions(), seed)[0])
+
+			if len(options) != 5 || options[4] != "none" {
+				t.Fatalf("seed %d: options = %v, want none last", seed, options)
+			}
+
+			orders[strings.Join(options, " ")] = true
+		}
+
+		if len(orders) < 2 {
+			t.Errorf("every seed gave the same option order")
+		}
+	})
+
+	t.Run("records the canonical option ids in the order shown", func(t *testing.T) {
+		arranged := surveys.Arrange(randomizedQuestions(), 7)
+		displays := surveys.Displays(arranged)
+
+		if !slices.Equal(displays[1].Options, optionOrder(t, arranged[0])) {
+			t.Errorf("display = %v, want %v", displays[1].Options, optionOrder(t, arranged[0]))
+		}
+
+		if displays[5].Position != 5 || displays[5].Options != nil {
+			t.Errorf("display = %+v, want position 5 and no options", displays[5])
+		}
+	})
+}
+
+// ============================================================================
+// Randomized logic Tests
+// ============================================================================
+
+func TestRandomizedLogic(t *testing.T) {
+
+	t.Run("skipping to a block lands on the first question shown of it", func(t *testing.T) {
+		questions := randomizedQuestions()
+		questions[0].Logic = []byte(`{"skip": [{"when": {"question_id": 1, "operator": "equals", "value": "car"}, "skip_to": 3}]}`)
+
+		if err := surveys.ValidateLogic(questions); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for seed := int64(1); seed <= 20; seed++ {
+			arranged := surveys.Arrange(questions, seed)
+
+			next, found, err := surveys.NextQuestion(arranged, map[int64]json.RawMessage{1: []byte(`{"option_id": "car"}`)}, 1)
+			if err != nil || !found {
+				t.Fatalf("seed %d: unexpected error %v", seed, err)
+			}
+
+			if next.ID != arranged[1].ID {
+				t.Fatalf("seed %d: next = %d, want %d, the first of the block as shown", seed, next.ID, arranged[1].ID)
+			}
+		}
+	})
+
+	t.Run("rejects rules that depend on the order of a block", func(t *testing.T) {
+		questions := randomizedQuestions()
+		questions[2].Logic = []byte(`{"display_if": {"question_id": 2, "operator": "answered"}}`)
+		questions[1].Logic = []byte(`{"skip": [{"when": {"question_id": 2, "operator": "gte", "value": 4}, "skip_to": 4}]}`)
+
+		err := surveys.ValidateLogic(questions)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		for _, problem := range []string{
+			"questions[3].logic.display_if.question_id: question 2 is in the same randomized block",
+			"questions[2].logic.skip[0].skip_to: question 4 is in the same randomized block",
+		} {
+			if !strings.Contains(err.Error(), problem) {
+				t.Errorf("expected %q in %v", problem, err)
+			}
+		}
+	})
+
+	t.Run("rejects a block split by another question", func(t *testing.T) {
+		questions := randomizedQuestions()
+		questions[4].Block = pgtype.Text{String: "brands", Valid: true}
+		questions[4].Position = 6
+		questions = append(questions, database.Question{ID: 6, SurveyID: 1, Position: 5, Type: database.QuestionTypeText,
+			Title: "Why?", Config: []byte(`{"max_length": 500}`)})
+
+		err := surveys.ValidateLogic(questions)
+		if err == nil || !strings.Contains(err.Error(), "questions[5].block: the questions of block brands must come one after another") {
+			t.Errorf("err = %v, want the split block reported", err)
+		}
+	})
+}
+
+// ============================================================================
+// Randomized export Tests
+// ============================================================================
+
+func TestRandomizedExport(t *testing.T) {
+
+	newRandomizedExportStore := func() *StubSurveyStore {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusPublished}
+
+		for _, question := range randomizedQuestions() {
+			store.Questions[question.ID] = question
+		}
+		_ = store.createVersion(1)
+
+		store.Exports = map[int64][]database.ListSurveyExportRowsRow{
+			1: {{ID: 10, Status: database.ResponseStatusSubmitted, SurveyVersion: 1, OrderSeed: 7,
+				Answers: []byte(`{"1": {"option_id": "bike"}, "2": {"value": 4}}`)}},
+		}
+
+		return store
+	}
+
+	shown := surveys.Displays(surveys.Arrange(randomizedQuestions(), 7))
+
+	t.Run("rebuilds the order each respondent saw in CSV", func(t *testing.T) {
+		rec := export(t, newRandomizedExportStore(), "/surveys/1/export")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+		if err != nil || len(rows) != 2 {
+			t.Fatalf("rows = %v (%v), want a header and one response", rows, err)
+		}
+
+		cell := func(column string) string {
+			i := slices.Index(rows[0], column)
+			if i == -1 {
+				t.Fatalf("no %s column in %v", column, rows[0])
+			}
+			return rows[1][i]
+		}
+
+		if cell("order_seed") != "7" {
+			t.Errorf("order_seed = %q, want 7", cell("order_seed"))
+		}
+
+		if cell("q1") != "Bike" || cell("q1_order") != strings.Join(shown[1].Options, " ") {
+			t.Errorf("q1 = %q with order %q, want Bike with %v", cell("q1"), cell("q1_order"), shown[1].Options)
+		}
+
+		if got := cell("q3_position"); got != strconv.Itoa(int(shown[3].Position)) {
+			t.Errorf("q3_position = %q, want %d", got, shown[3].Position)
+		}
+
+		if slices.Contains(rows[0], "q2_order") {
+			t.Errorf("header %v has an order for a question that keeps its options in order", rows[0])
+		}
+	})
+
+	t.Run("rebuilds the order each respondent saw in JSONL", func(t *testing.T) {
+		rec := export(t, newRandomizedExportStore(), "/surveys/1/export?format=jsonl")
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var line surveys.ExportLine
+		if err := json.Unmarshal(rec.Body.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", rec.Body.String(), err)
+		}
+
+		if line.OrderSeed == nil || *line.OrderSeed != 7 {
+			t.Errorf("order_seed = %v, want 7", line.OrderSeed)
+		}
+
+		if !slices.Equal(line.Display["q1"].Options, shown[1].Options) || line.Display["q4"].Position != shown[4].Position {
+			t.Errorf("display = %+v, want %+v", line.Display, shown)
+		}
+	})
+
+	t.Run("leaves the order out for surveys that don't randomize", func(t *testing.T) {
+		rec := export(t, newExportStore(false), "/surveys/1/export")
+
+		if strings.Contains(rec.Body.String(), "order_seed") {
+			t.Errorf("body = %s, want no order columns", rec.Body.String())
+		}
+	})
+}
+
+// ============================================================================
+// Randomized results Tests
+// ============================================================================
+
+func TestRandomizedResults(t *testing.T) {
+
+	t.Run("breaks choices down by the position they were shown at", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusPublished}
+		for _, question := range randomizedQuestions() {
+			store.Questions[question.ID] = question
+		}
+
+		store.Results = map[int64][]database.ListSurveyResultsRow{
+			1: {
+				{Kind: "total", Count: 3},
+				{Kind: "answered", QuestionID: 1, Count: 3},
+				{Kind: "choice", QuestionID: 1, Choice: "bus", Count: 3},
+				{Kind: "position", QuestionID: 1, Part: "bus", Choice: "3", Count: 1},
+				{Kind: "position", QuestionID: 1, Part: "bus", Choice: "1", Count: 2},
+			},
+		}
+
+		code, results := getResults(t, store, "/surveys/1/results")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		bus := results.Questions[0].Choices[0]
+		want := []surveys.PositionResult{{Position: 1, Count: 2}, {Position: 3, Count: 1}}
+		if bus.ID != "bus" || !slices.Equal(bus.Positions, want) {
+			t.Errorf("bus = %+v, want positions %v", bus, want)
+		}
+
+		if results.Questions[0].Choices[1].Positions != nil {
+			t.Errorf("walk positions = %v, want none", results.Questions[0].Choices[1].Positions)
+		}
+	})
+}
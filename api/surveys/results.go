@@ -0,0 +1,604 @@
+package surveys
+
+import (
+	"context"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CrossTabAttributes are the profile attributes a question can be cross-tabulated against.
+var CrossTabAttributes = []string{"gender", "university", "faculty", "location", "age_band"}
+
+// ageBands are the values of the age_band attribute, youngest first.
+var ageBands = []string{"under 18", "18-24", "25-34", "35-44", "45-54", "55-64", "65+"}
+
+// ResultsFilter narrows down the submitted responses results are worked out from. Fields left
+// empty don't filter. Test responses are left out unless IncludeTests is set.
+type ResultsFilter struct {
+	// From and To bound the day a response was submitted on, both inclusive.
+	From       *time.Time
+	To         *time.Time
+	Gender     string
+	University string
+	Faculty    string
+	Location   string
+	MinAge     int
+	MaxAge     int
+
+	IncludeTests bool
+}
+
+// ParseResultsFilter reads a results filter from the query parameters from, to (as YYYY-MM-DD),
+// gender, university, faculty, location, min_age, max_age and include_tests.
+func ParseResultsFilter(query url.Values) (ResultsFilter, error) {
+	filter := ResultsFilter{
+		Gender:     query.Get("gender"),
+		University: query.Get("university"),
+		Faculty:    query.Get("faculty"),
+		Location:   query.Get("location"),
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+
+	for name, bound := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			validationErrors = append(validationErrors, fmt.Sprintf("%s: %s must be a date like 2026-01-31", name, name))
+			continue
+		}
+		*bound = &day
+	}
+
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		validationErrors = append(validationErrors, "to: to must be on or after from")
+	}
+
+	if filter.Gender != "" && !slices.Contains([]string{"male", "female", "prefer_not_to_say"}, filter.Gender) {
+		validationErrors = append(validationErrors, "gender: gender must be one of male female prefer_not_to_say")
+	}
+
+	for name, age := range map[string]*int{"min_age": &filter.MinAge, "max_age": &filter.MaxAge} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 13 || parsed > 120 {
+			validationErrors = append(validationErrors, fmt.Sprintf("%s: %s must be a number between 13 and 120", name, name))
+			continue
+		}
+		*age = parsed
+	}
+
+	if value := query.Get("include_tests"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			validationErrors = append(validationErrors, "include_tests: include_tests must be true or false")
+		}
+		filter.IncludeTests = include
+	}
+
+	if filter.MinAge > 0 && filter.MaxAge > 0 && filter.MaxAge < filter.MinAge {
+		validationErrors = append(validationErrors, fmt.Sprintf("max_age: max_age must be greater than or equal to %d", filter.MinAge))
+	}
+
+	if len(validationErrors) > 0 {
+		slices.Sort(validationErrors)
+		return ResultsFilter{}, validationErrors
+	}
+
+	return filter, nil
+}
+
+// Demographic reports whether the filter looks at the respondents' profiles.
+func (f ResultsFilter) Demographic() bool {
+	return f.Gender != "" || f.University != "" || f.Faculty != "" || f.Location != "" || f.MinAge > 0 || f.MaxAge > 0
+}
+
+// CrossTabRequest asks for the answers to a question to be counted against the answers to another
+// question or against a profile attribute. Exactly one of ByQuestionID and ByAttribute is set.
+type CrossTabRequest struct {
+	QuestionID   int64
+	ByQuestionID int64
+	ByAttribute  string
+}
+
+// ParseCrossTab reads a cross-tab request from the query parameters crosstab, the question ID, and
+// by, another question's ID or one of CrossTabAttributes. It returns nil when none was asked for.
+func ParseCrossTab(query url.Values) (*CrossTabRequest, error) {
+	if query.Get("crosstab") == "" && query.Get("by") == "" {
+		return nil, nil
+	}
+
+	questionID, err := strconv.ParseInt(query.Get("crosstab"), 10, 64)
+	if err != nil || questionID < 1 {
+		return nil, fieldError("crosstab", "crosstab must be a question id")
+	}
+
+	by := query.Get("by")
+	if slices.Contains(CrossTabAttributes, by) {
+		return &CrossTabRequest{QuestionID: questionID, ByAttribute: by}, nil
+	}
+
+	byQuestionID, err := strconv.ParseInt(by, 10, 64)
+	if err != nil || byQuestionID < 1 {
+		return nil, fieldError("by", "by must be a question id or one of gender university faculty location age_band")
+	}
+
+	if byQuestionID == questionID {
+		return nil, fieldError("by", "a question can't be cross-tabulated against itself")
+	}
+
+	return &CrossTabRequest{QuestionID: questionID, ByQuestionID: byQuestionID}, nil
+}
+
+// NewResults puts together the results of a survey's questions from the rows of the
+// ListSurveyResults query. Answers are counted by question and option ID, so those given to every
+// version of the survey are counted together; the questions it no longer asks come last.
+func NewResults(surveyID int64, history QuestionHistory, rows []database.ListSurveyResultsRow) (Results, error) {
+	results := Results{SurveyID: surveyID, Questions: []QuestionResult{}}
+
+	answered := make(map[int64]int32)
+	stats := make(map[int64]database.ListSurveyResultsRow)
+	// counts is keyed by question, then part, then choice
+	counts := make(map[int64]map[string]map[string]int32)
+	// positions is keyed by question, then option
+	positions := make(map[int64]map[string][]PositionResult)
+
+	for _, row := range rows {
+		switch row.Kind {
+		case "total":
+			results.Responses = row.Count
+		case "answered":
+			answered[row.QuestionID] = row.Count
+		case "stats":
+			stats[row.QuestionID] = row
+		case "choice":
+			if counts[row.QuestionID] == nil {
+				counts[row.QuestionID] = make(map[string]map[string]int32)
+			}
+			if counts[row.QuestionID][row.Part] == nil {
+				counts[row.QuestionID][row.Part] = make(map[string]int32)
+			}
+			counts[row.QuestionID][row.Part][row.Choice] = row.Count
+		case "position":
+			position, err := strconv.Atoi(row.Choice)
+			if err != nil {
+				return Results{}, fmt.Errorf("question %d: invalid position %q", row.QuestionID, row.Choice)
+			}
+			if positions[row.QuestionID] == nil {
+				positions[row.QuestionID] = make(map[string][]PositionResult)
+			}
+			positions[row.QuestionID][row.Part] = append(positions[row.QuestionID][row.Part], PositionResult{Position: position, Count: row.Count})
+		}
+	}
+
+	questions := slices.Clone(history.Current)
+	slices.SortFunc(questions, func(a, b database.Question) int {
+		return int(a.Position - b.Position)
+	})
+
+	removed := len(questions)
+	questions = append(questions, history.Removed...)
+
+	for i, question := range questions {
+		config, err := ParseQuestionConfig(question.Type, question.Config)
+		if err != nil {
+			return Results{}, fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		result := QuestionResult{
+			QuestionID: question.ID,
+			Position:   question.Position,
+			Title:      question.Title,
+			Type:       string(question.Type),
+			Answered:   answered[question.ID],
+			Removed:    i >= removed,
+		}
+
+		partCounts := counts[question.ID]
+
+		switch config := config.(type) {
+		case *SingleChoiceConfig:
+			result.Choices = choiceResults(config.Options, partCounts[""], result.Answered)
+			addPositions(result.Choices, positions[question.ID])
+		case *MultipleChoiceConfig:
+			// respondents can pick several options, so the percentages can add up to more than 100
+			result.Choices = choiceResults(config.Options, partCounts[""], result.Answered)
+			addPositions(result.Choices, positions[question.ID])
+		case *LikertConfig:
+			result.Choices = choiceResults(likertPoints(config), partCounts[""], result.Answered)
+			result.Stats = newNumericStats(stats, question.ID)
+		case *NumericConfig:
+			result.Stats = newNumericStats(stats, question.ID)
+		case *MatrixConfig:
+			result.Rows = partResults(config.Rows, config.Columns, partCounts)
+		case *RankingConfig:
+			// a row per option with how often it was given each rank
+			ranks := make([]Option, len(config.Options))
+			for i := range ranks {
+				rank := strconv.Itoa(i + 1)
+				ranks[i] = Option{ID: rank, Label: rank}
+			}
+			result.Rows = partResults(config.Options, ranks, partCounts)
+		}
+
+		results.Questions = append(results.Questions, result)
+	}
+
+	return results, nil
+}
+
+// NewCrossTab puts together a cross-tab from the rows of the CrossTabulateResults query. Rows
+// follow the options of the question, columns the options of the other question or the values of
+// the attribute.
+func NewCrossTab(request CrossTabRequest, question database.Question, byQuestion *database.Question, rows []database.CrossTabulateResultsRow) (CrossTab, error) {
+	rowOptions, err := crossTabOptions(question)
+	if err != nil {
+		return CrossTab{}, err
+	}
+
+	var columnOptions []Option
+
+	switch {
+	case byQuestion != nil:
+		columnOptions, err = crossTabOptions(*byQuestion)
+		if err != nil {
+			return CrossTab{}, err
+		}
+	case request.ByAttribute == "age_band":
+		for _, band := range ageBands {
+			columnOptions = append(columnOptions, Option{ID: band, Label: band})
+		}
+		columnOptions = append(columnOptions, Option{ID: "unknown", Label: "unknown"})
+	default:
+		// the other attributes only have the values respondents gave
+		for _, row := range rows {
+			if !slices.ContainsFunc(columnOptions, func(option Option) bool { return option.ID == row.ColumnKey }) {
+				columnOptions = append(columnOptions, Option{ID: row.ColumnKey, Label: row.ColumnKey})
+			}
+		}
+		slices.SortFunc(columnOptions, func(a, b Option) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+	}
+
+	counts := make(map[string]map[string]int32)
+	for _, row := range rows {
+		if counts[row.RowKey] == nil {
+			counts[row.RowKey] = make(map[string]int32)
+		}
+		counts[row.RowKey][row.ColumnKey] = row.Count
+	}
+
+	crossTab := CrossTab{
+		QuestionID: request.QuestionID,
+		By:         request.ByAttribute,
+		Rows:       partResults(rowOptions, columnOptions, counts),
+	}
+
+	if byQuestion != nil {
+		crossTab.By = strconv.FormatInt(byQuestion.ID, 10)
+	}
+
+	return crossTab, nil
+}
+
+// crossTabOptions returns the categories of a question that can be cross-tabulated: single and
+// multiple choice and likert questions.
+func crossTabOptions(question database.Question) ([]Option, error) {
+	config, err := ParseQuestionConfig(question.Type, question.Config)
+	if err != nil {
+		return nil, fmt.Errorf("question %d: %v", question.ID, err)
+	}
+
+	switch config := config.(type) {
+	case *SingleChoiceConfig:
+		return config.Options, nil
+	case *MultipleChoiceConfig:
+		return config.Options, nil
+	case *LikertConfig:
+		return likertPoints(config), nil
+	default:
+		return nil, fieldError("crosstab", fmt.Sprintf("question %d can't be cross-tabulated, only choice and likert questions can", question.ID))
+	}
+}
+
+// likertPoints returns the points of a likert scale as options, labelled with the scale's labels
+// when it has them.
+func likertPoints(config *LikertConfig) []Option {
+	points := make([]Option, config.Points)
+
+	for i := range points {
+		value := strconv.Itoa(i + 1)
+		points[i] = Option{ID: value, Label: value}
+
+		if len(config.Labels) == config.Points {
+			points[i].Label = config.Labels[i]
+		}
+	}
+
+	return points
+}
+
+func choiceResults(options []Option, counts map[string]int32, base int32) []ChoiceResult {
+	results := make([]ChoiceResult, 0, len(options))
+
+	for _, option := range options {
+		count := counts[option.ID]
+
+		results = append(results, ChoiceResult{
+			ID:         option.ID,
+			Label:      option.Label,
+			Count:      count,
+			Percentage: percentage(count, base),
+		})
+	}
+
+	return results
+}
+
+// addPositions adds to each choice the breakdown of its count by the position it was shown at.
+func addPositions(choices []ChoiceResult, positions map[string][]PositionResult) {
+	for i := range choices {
+		choice := &choices[i]
+		choice.Positions = slices.Clone(positions[choice.ID])
+		slices.SortFunc(choice.Positions, func(a, b PositionResult) int {
+			return a.Position - b.Position
+		})
+	}
+}
+
+// partResults returns a row per part with the counts of its choices, each as a percentage of the
+// row's total.
+func partResults(parts []Option, choices []Option, counts map[string]map[string]int32) []RowResult {
+	results := make([]RowResult, 0, len(parts))
+
+	for _, part := range parts {
+		var total int32
+		for _, count := range counts[part.ID] {
+			total += count
+		}
+
+		results = append(results, RowResult{
+			ID:      part.ID,
+			Label:   part.Label,
+			Total:   total,
+			Choices: choiceResults(choices, counts[part.ID], total),
+		})
+	}
+
+	return results
+}
+
+func newNumericStats(stats map[int64]database.ListSurveyResultsRow, questionID int64) *NumericStats {
+	row, ok := stats[questionID]
+	if !ok {
+		return nil
+	}
+
+	return &NumericStats{
+		Count:  row.Count,
+		Mean:   database.DecimalFromNumeric(row.Mean),
+		Median: database.DecimalFromNumeric(row.Median),
+		StdDev: database.DecimalFromNumeric(row.Stddev),
+		Min:    database.DecimalFromNumeric(row.Min),
+		Max:    database.DecimalFromNumeric(row.Max),
+	}
+}
+
+// percentage returns count as a percentage of base, rounded to 2 decimal places.
+func percentage(count, base int32) decimal.Decimal {
+	if base == 0 {
+		return decimal.Zero
+	}
+
+	return decimal.NewFromInt32(count).Mul(decimal.NewFromInt(100)).DivRound(decimal.NewFromInt32(base), 2)
+}
+
+// resultsParams turns a filter into the parameters of the ListSurveyResults query. Ages become
+// bounds on the date of birth as of now.
+func resultsParams(surveyID int64, filter ResultsFilter, now time.Time) database.ListSurveyResultsParams {
+	params := database.ListSurveyResultsParams{
+		SurveyID:   surveyID,
+		University: pgtype.Text{String: filter.University, Valid: filter.University != ""},
+		Faculty:    pgtype.Text{String: filter.Faculty, Valid: filter.Faculty != ""},
+		Location:   pgtype.Text{String: filter.Location, Valid: filter.Location != ""},
+
+		IncludeTests: filter.IncludeTests,
+	}
+
+	if filter.From != nil {
+		params.SubmittedFrom = pgtype.Timestamp{Time: *filter.From, Valid: true}
+	}
+
+	if filter.To != nil {
+		params.SubmittedBefore = pgtype.Timestamp{Time: filter.To.AddDate(0, 0, 1), Valid: true}
+	}
+
+	if filter.Gender != "" {
+		params.Gender = database.NullGender{Gender: database.Gender(filter.Gender), Valid: true}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if filter.MinAge > 0 {
+		params.BornOnOrBefore = pgtype.Date{Time: today.AddDate(-filter.MinAge, 0, 0), Valid: true}
+	}
+
+	if filter.MaxAge > 0 {
+		params.BornAfter = pgtype.Date{Time: today.AddDate(-filter.MaxAge-1, 0, 0), Valid: true}
+	}
+
+	return params
+}
+
+func (h *Handler) GetResultsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	query := request.URL.Query()
+
+	filter, err := ParseResultsFilter(query)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	crossTabRequest, err := ParseCrossTab(query)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	demographic := filter.Demographic() || (crossTabRequest != nil && crossTabRequest.ByAttribute != "")
+	if demographic && !survey.ShareDemographics {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "this survey doesn't share the demographics of its respondents",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+		return
+	}
+
+	history, err := h.questionHistory(ctx, survey)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	now := time.Now()
+
+	rows, err := h.Store.GetResults(ctx, survey.ID, filter, now)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	results, err := NewResults(survey.ID, history, rows)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	// the respondents to an anonymous survey are only counted by their demographics in groups too
+	// big to single any of them out
+	if survey.Anonymous && filter.Demographic() {
+		if results.Responses > 0 && results.Responses < MinCellSize {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: fmt.Sprintf("fewer than %d responses match these filters, too few to show for an anonymous survey", MinCellSize),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusForbidden)
+			return
+		}
+
+		SuppressResults(&results)
+	}
+
+	if crossTabRequest != nil {
+		crossTab, err := h.crossTab(ctx, survey.ID, history.All(), *crossTabRequest, filter, now)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+			return
+		}
+
+		if survey.Anonymous && demographic {
+			SuppressCrossTab(&crossTab)
+		}
+
+		results.CrossTab = &crossTab
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved survey results successfully",
+		Data:    results,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+func (h *Handler) crossTab(ctx context.Context, surveyID int64, questions []database.Question, request CrossTabRequest, filter ResultsFilter, now time.Time) (CrossTab, error) {
+	findQuestion := func(id int64) (*database.Question, error) {
+		index := slices.IndexFunc(questions, func(question database.Question) bool { return question.ID == id })
+		if index < 0 {
+			return nil, fmt.Errorf("%w: question %d is not part of this survey", custom_errors.ErrNotFound, id)
+		}
+		return &questions[index], nil
+	}
+
+	question, err := findQuestion(request.QuestionID)
+	if err != nil {
+		return CrossTab{}, err
+	}
+
+	var byQuestion *database.Question
+	if request.ByQuestionID != 0 {
+		byQuestion, err = findQuestion(request.ByQuestionID)
+		if err != nil {
+			return CrossTab{}, err
+		}
+	}
+
+	// checked before the query runs, NewCrossTab would only find out afterwards
+	for _, q := range []*database.Question{question, byQuestion} {
+		if q == nil {
+			continue
+		}
+		if _, err := crossTabOptions(*q); err != nil {
+			return CrossTab{}, err
+		}
+	}
+
+	rows, err := h.Store.CrossTabulate(ctx, surveyID, filter, request, now)
+	if err != nil {
+		return CrossTab{}, err
+	}
+
+	return NewCrossTab(request, *question, byQuestion, rows)
+}
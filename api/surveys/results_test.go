@@ -0,0 +1,180 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newResultsStore(shareDemographics bool) *StubSurveyStore {
+	store := NewStubSurveyStore()
+
+	survey := fundedSurvey(database.SurveyStatusPublished)
+	survey.ShareDemographics = shareDemographics
+	store.Surveys[1] = survey
+
+	store.Questions[1] = database.Question{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice,
+		Config: []byte(`{"options": [{"id": "yes", "label": "Yes"}, {"id": "no", "label": "No"}]}`)}
+	store.Questions[2] = database.Question{ID: 2, SurveyID: 1, Position: 2, Type: database.QuestionTypeLikert,
+		Config: []byte(`{"points": 3, "labels": ["Bad", "Okay", "Good"]}`)}
+	store.Questions[3] = database.Question{ID: 3, SurveyID: 1, Position: 3, Type: database.QuestionTypeText,
+		Config: []byte(`{"max_length": 200}`)}
+
+	store.Results = map[int64][]database.ListSurveyResultsRow{
+		1: {
+			{Kind: "total", Count: 4},
+			{Kind: "answered", QuestionID: 1, Count: 4},
+			{Kind: "answered", QuestionID: 2, Count: 3},
+			{Kind: "answered", QuestionID: 3, Count: 2},
+			{Kind: "choice", QuestionID: 1, Choice: "yes", Count: 3},
+			{Kind: "choice", QuestionID: 1, Choice: "no", Count: 1},
+			{Kind: "choice", QuestionID: 2, Choice: "1", Count: 1},
+			{Kind: "choice", QuestionID: 2, Choice: "3", Count: 2},
+			{
+				Kind: "stats", QuestionID: 2, Count: 3,
+				Mean:   database.NumericFromDecimal(decimal.RequireFromString("2.3333")),
+				Median: database.NumericFromDecimal(decimal.RequireFromString("3")),
+				Stddev: database.NumericFromDecimal(decimal.RequireFromString("1.1547")),
+				Min:    database.NumericFromDecimal(decimal.RequireFromString("1")),
+				Max:    database.NumericFromDecimal(decimal.RequireFromString("3")),
+			},
+		},
+	}
+
+	store.CrossTabs = map[int64][]database.CrossTabulateResultsRow{
+		1: {
+			{RowKey: "no", ColumnKey: "1", Count: 1},
+			{RowKey: "yes", ColumnKey: "3", Count: 2},
+		},
+	}
+
+	return store
+}
+
+func getResults(t *testing.T, store *StubSurveyStore, target string) (int, surveys.Results) {
+	t.Helper()
+
+	handler := &surveys.Handler{Store: store}
+
+	req := newRequest(http.MethodGet, target, nil, 1, map[string]string{"surveyID": "1"})
+	rec := httptest.NewRecorder()
+
+	handler.GetResultsHandler(rec, req)
+
+	var got struct {
+		Data surveys.Results `json:"data"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+	return rec.Code, got.Data
+}
+
+func TestGetResultsHandler(t *testing.T) {
+
+	t.Run("returns the distribution of every question", func(t *testing.T) {
+		code, results := getResults(t, newResultsStore(false), "/surveys/1/results")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if results.Responses != 4 || len(results.Questions) != 3 {
+			t.Fatalf("results = %+v, want 4 responses to 3 questions", results)
+		}
+
+		choices := results.Questions[0].Choices
+		if choices[0].Label != "Yes" || choices[0].Count != 3 || !choices[0].Percentage.Equal(decimal.NewFromInt(75)) {
+			t.Errorf("yes = %+v, want 3 responses, 75%%", choices[0])
+		}
+
+		likert := results.Questions[1]
+		if len(likert.Choices) != 3 || likert.Choices[1].Label != "Okay" || likert.Choices[1].Count != 0 {
+			t.Errorf("likert choices = %+v, want 3 points with none on Okay", likert.Choices)
+		}
+
+		if likert.Stats == nil || !likert.Stats.Median.Equal(decimal.NewFromInt(3)) {
+			t.Errorf("likert stats = %+v, want a median of 3", likert.Stats)
+		}
+
+		if text := results.Questions[2]; text.Answered != 2 || text.Choices != nil || text.Stats != nil {
+			t.Errorf("text = %+v, want only the answered count", text)
+		}
+	})
+
+	t.Run("cross-tabulates two questions", func(t *testing.T) {
+		code, results := getResults(t, newResultsStore(false), "/surveys/1/results?crosstab=1&by=2")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if results.CrossTab == nil || len(results.CrossTab.Rows) != 2 {
+			t.Fatalf("crosstab = %+v, want a row per option", results.CrossTab)
+		}
+
+		yes := results.CrossTab.Rows[0]
+		if yes.Total != 2 || yes.Choices[2].Label != "Good" || !yes.Choices[2].Percentage.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("yes row = %+v, want all of it on Good", yes)
+		}
+	})
+
+	t.Run("returns 403 for demographic filters when the survey doesn't share them", func(t *testing.T) {
+		for _, target := range []string{"/surveys/1/results?gender=female", "/surveys/1/results?crosstab=1&by=age_band"} {
+			code, _ := getResults(t, newResultsStore(false), target)
+
+			assertResponseCode(t, code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("allows demographic filters when the survey shares them", func(t *testing.T) {
+		code, _ := getResults(t, newResultsStore(true), "/surveys/1/results?gender=female&min_age=18&crosstab=1&by=faculty")
+
+		assertResponseCode(t, code, http.StatusOK)
+	})
+
+	t.Run("returns 400 for a cross-tab of a text question", func(t *testing.T) {
+		code, _ := getResults(t, newResultsStore(false), "/surveys/1/results?crosstab=3&by=1")
+
+		assertResponseCode(t, code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for a cross-tab of another survey's question", func(t *testing.T) {
+		code, _ := getResults(t, newResultsStore(false), "/surveys/1/results?crosstab=1&by=99")
+
+		assertResponseCode(t, code, http.StatusNotFound)
+	})
+}
+
+func TestParseResultsFilter(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"no filters", "", false},
+		{"date range", "from=2026-01-01&to=2026-01-31", false},
+		{"single day", "from=2026-01-01&to=2026-01-01", false},
+		{"age band", "min_age=18&max_age=24", false},
+		{"bad date", "from=01/01/2026", true},
+		{"range ends before it starts", "from=2026-02-01&to=2026-01-01", true},
+		{"unknown gender", "gender=robot", true},
+		{"age out of range", "min_age=5", true},
+		{"max age under min age", "min_age=30&max_age=20", true},
+		{"test responses", "include_tests=true", false},
+		{"bad include_tests", "include_tests=maybe", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+
+			_, err := surveys.ParseResultsFilter(query)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
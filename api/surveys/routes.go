@@ -0,0 +1,83 @@
+package surveys
+
+import (
+	"github.com/Adedunmol/answerly/api/middlewares"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func SetupRoutes(r *chi.Mux, queue queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	surveysRouter := chi.NewRouter()
+
+	store := NewSurveyStore(queries, db)
+	tokenService := tokens.NewTokenService()
+
+	handler := Handler{
+		Store: store,
+		Queue: queue,
+	}
+
+	surveysRouter.Use(middlewares.AuthMiddleware(tokenService))
+
+	surveysRouter.Group(func(respondentRouter chi.Router) {
+		respondentRouter.Use(middlewares.RequireRole("user"))
+
+		respondentRouter.Get("/feed", handler.FeedHandler)
+	})
+
+	surveysRouter.Group(func(researcherRouter chi.Router) {
+		researcherRouter.Use(middlewares.RequireRole("researcher"))
+
+		researcherRouter.Post("/", handler.CreateSurveyHandler)
+		researcherRouter.Post("/import", handler.ImportDefinitionHandler)
+		researcherRouter.Get("/", handler.ListSurveysHandler)
+		researcherRouter.Get("/{surveyID}", handler.GetSurveyHandler)
+		researcherRouter.Patch("/{surveyID}", handler.UpdateSurveyHandler)
+		researcherRouter.Delete("/{surveyID}", handler.DeleteSurveyHandler)
+		researcherRouter.Patch("/{surveyID}/status", handler.UpdateSurveyStatusHandler)
+		researcherRouter.Put("/{surveyID}/schedule", handler.ScheduleSurveyHandler)
+		researcherRouter.Put("/{surveyID}/locales", handler.UpdateLocalesHandler)
+		researcherRouter.Get("/{surveyID}/translations", handler.GetTranslationsHandler)
+		researcherRouter.Put("/{surveyID}/translations/{locale}", handler.UpdateTranslationHandler)
+		researcherRouter.Get("/{surveyID}/escrow", handler.GetEscrowHandler)
+		researcherRouter.Get("/{surveyID}/stats", handler.GetSurveyStatsHandler)
+		researcherRouter.Get("/{surveyID}/results", handler.GetResultsHandler)
+		researcherRouter.Get("/{surveyID}/export", handler.ExportResponsesHandler)
+		researcherRouter.Post("/{surveyID}/clone", handler.CloneSurveyHandler)
+		researcherRouter.Put("/{surveyID}/template", handler.UpdateTemplateHandler)
+		researcherRouter.Get("/{surveyID}/definition", handler.ExportDefinitionHandler)
+
+		researcherRouter.Get("/templates", handler.ListTemplatesHandler)
+		researcherRouter.Get("/templates/{surveyID}", handler.GetTemplateHandler)
+		researcherRouter.Post("/templates/{surveyID}/use", handler.UseTemplateHandler)
+
+		researcherRouter.Post("/{surveyID}/questions", handler.CreateQuestionHandler)
+		researcherRouter.Get("/{surveyID}/questions", handler.ListQuestionsHandler)
+		researcherRouter.Patch("/{surveyID}/questions/{questionID}", handler.UpdateQuestionHandler)
+		researcherRouter.Delete("/{surveyID}/questions/{questionID}", handler.DeleteQuestionHandler)
+		researcherRouter.Get("/{surveyID}/versions", handler.ListVersionsHandler)
+
+		researcherRouter.Post("/{surveyID}/quotas", handler.CreateQuotaCellHandler)
+		researcherRouter.Get("/{surveyID}/quotas", handler.ListQuotaCellsHandler)
+		researcherRouter.Delete("/{surveyID}/quotas/{quotaID}", handler.DeleteQuotaCellHandler)
+	})
+
+	r.Mount("/surveys", surveysRouter)
+
+	return
+}
+
+func SetupTasks(worker queue.Worker, q queue.Queue, db *pgxpool.Pool, queries *database.Queries) {
+
+	handler := TaskHandler{
+		Store: NewSurveyStore(queries, db),
+		Queue: q,
+	}
+
+	worker.HandleFunc(queue.TypeSurveyOpen, handler.HandleOpenTask)
+	worker.HandleFunc(queue.TypeSurveyClose, handler.HandleCloseTask)
+}
@@ -0,0 +1,385 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Schedule is when a survey opens and closes, in UTC to the second as it is stored, so the time a
+// task carries compares equal to the one read back from the database.
+type Schedule struct {
+	OpensAt  *time.Time
+	ClosesAt *time.Time
+}
+
+func NewSchedule(body ScheduleSurveyBody) Schedule {
+	return Schedule{OpensAt: scheduleTime(body.OpensAt), ClosesAt: scheduleTime(body.ClosesAt)}
+}
+
+func surveySchedule(survey database.Survey) Schedule {
+	var schedule Schedule
+
+	if survey.OpensAt.Valid {
+		schedule.OpensAt = scheduleTime(&survey.OpensAt.Time)
+	}
+
+	if survey.ClosesAt.Valid {
+		schedule.ClosesAt = scheduleTime(&survey.ClosesAt.Time)
+	}
+
+	return schedule
+}
+
+func scheduleTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	at := t.UTC().Truncate(time.Second)
+	return &at
+}
+
+// ValidateSchedule checks a schedule for a survey in the given status. A draft can only be
+// scheduled to open in the future, a survey that has opened keeps the time it opened at, and
+// either can only be scheduled to close in the future, after it opens.
+func ValidateSchedule(schedule Schedule, survey database.Survey, now time.Time) error {
+	var validationErrors jsonutil.ValidationErrors
+
+	opensAt := schedule.OpensAt
+	if survey.Status == database.SurveyStatusDraft {
+		if opensAt != nil && !opensAt.After(now) {
+			validationErrors = append(validationErrors, "opens_at: must be in the future")
+		}
+	} else {
+		current := surveySchedule(survey).OpensAt
+		if opensAt != nil && (current == nil || !opensAt.Equal(*current)) {
+			validationErrors = append(validationErrors, "opens_at: the survey has already opened")
+		}
+		opensAt = nil
+	}
+
+	if closesAt := schedule.ClosesAt; closesAt != nil {
+		switch {
+		case !closesAt.After(now):
+			validationErrors = append(validationErrors, "closes_at: must be in the future")
+		case opensAt != nil && !closesAt.After(*opensAt):
+			validationErrors = append(validationErrors, "closes_at: must be after opens_at")
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return nil
+}
+
+// ScheduleTasks returns the tasks that carry out the schedule of a survey in the given status,
+// keyed by task ID. A survey that has opened has no task left to open it.
+func ScheduleTasks(surveyID int64, status database.SurveyStatus, schedule Schedule) map[string]queue.Scheduled {
+	tasks := make(map[string]queue.Scheduled)
+
+	if schedule.OpensAt != nil && status == database.SurveyStatusDraft {
+		task := &queue.SurveyOpenPayload{SurveyID: surveyID, At: *schedule.OpensAt}
+		tasks[task.TaskID()] = task
+	}
+
+	if schedule.ClosesAt != nil {
+		task := &queue.SurveyClosePayload{SurveyID: surveyID, At: *schedule.ClosesAt}
+		tasks[task.TaskID()] = task
+	}
+
+	return tasks
+}
+
+func (h *Handler) ScheduleSurveyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+	userID := claims.UserID
+
+	if userID == 0 {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "unauthorized",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusUnauthorized)
+		return
+	}
+
+	surveyID, err := surveyIDParam(request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "invalid survey id",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[ScheduleSurveyBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.Store.GetSurvey(ctx, surveyID, int64(userID))
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if !h.canManage(ctx, responseWriter, survey, int64(userID)) {
+		return
+	}
+
+	if survey.Status != database.SurveyStatusDraft && survey.Status != database.SurveyStatusPublished {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: "only draft and published surveys can be scheduled",
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return
+	}
+
+	schedule := NewSchedule(data)
+
+	if err := ValidateSchedule(schedule, survey, time.Now()); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	previous := ScheduleTasks(surveyID, survey.Status, surveySchedule(survey))
+	next := ScheduleTasks(surveyID, survey.Status, schedule)
+
+	// the new tasks go on the queue before the schedule is saved, so a saved schedule always has
+	// its tasks. Tasks left over from a schedule that failed to save find it unchanged and stop.
+	for id, task := range next {
+		if _, queued := previous[id]; queued {
+			continue
+		}
+
+		if err := h.Queue.Enqueue(task); err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	survey, err = h.Store.ScheduleSurvey(ctx, surveyID, int64(userID), schedule)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	// a task of the old schedule that can't be taken off the queue finds the schedule changed when
+	// it runs and does nothing, so failing to take it off is not worth failing the request
+	for id, task := range previous {
+		if _, kept := next[id]; kept {
+			continue
+		}
+
+		if err := h.Queue.Dequeue(task); err != nil {
+			log.Printf("error dequeuing %s: %s", id, err)
+		}
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "survey scheduled successfully",
+		Data:    NewSurvey(survey),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+type TaskHandler struct {
+	Store Store
+	// Queue sends the emails telling researchers their survey opened or closed.
+	Queue queue.Queue
+}
+
+// HandleOpenTask publishes a survey scheduled to open. The task only acts on a draft still
+// scheduled to open at the time it was queued for, so a task of a schedule that has since
+// changed, or one that runs twice, does nothing.
+func (h *TaskHandler) HandleOpenTask(ctx context.Context, t *asynq.Task) error {
+	var payload queue.SurveyOpenPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("error decoding survey open payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	survey, err := h.Store.GetSurveyByID(ctx, payload.SurveyID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			log.Printf("survey %d to open no longer exists", payload.SurveyID)
+			return nil
+		}
+		return fmt.Errorf("error getting survey %d: %w", payload.SurveyID, err)
+	}
+
+	if survey.Status != database.SurveyStatusDraft || !scheduledAt(survey.OpensAt, payload.At) {
+		log.Printf("survey %d is no longer scheduled to open at %s", survey.ID, payload.At)
+		return nil
+	}
+
+	if len(survey.Locales) > 0 {
+		questions, err := h.Store.ListQuestions(ctx, survey.ID)
+		if err != nil {
+			return fmt.Errorf("error listing the questions of survey %d: %w", survey.ID, err)
+		}
+
+		if err := CheckTranslated(survey, questions); err != nil {
+			h.notify(ctx, survey, "Your survey could not open",
+				fmt.Sprintf("It was scheduled to open but could not: %s. It is still a draft.", err))
+			return nil
+		}
+	}
+
+	budget, err := NewBudget(survey)
+	if err != nil {
+		h.notify(ctx, survey, "Your survey could not open",
+			fmt.Sprintf("It was scheduled to open but could not: %s. It is still a draft.", err))
+		return nil
+	}
+
+	published, err := h.Store.OpenScheduledSurvey(ctx, survey, budget)
+	if err != nil {
+		switch {
+		// published by the researcher since it was read
+		case errors.Is(err, custom_errors.ErrInvalidTransition):
+			return nil
+		case errors.Is(err, custom_errors.ErrInsufficientFunds):
+			h.notify(ctx, survey, "Your survey could not open",
+				fmt.Sprintf("It was scheduled to open but %s can't cover its budget. It is still a draft.", walletOf(survey)))
+			return nil
+		}
+		return fmt.Errorf("error opening survey %d: %w", payload.SurveyID, err)
+	}
+
+	log.Printf("survey %d opened as scheduled", published.ID)
+
+	h.notify(ctx, published, "Your survey is open", "It opened as scheduled and is now taking responses.")
+
+	return nil
+}
+
+// HandleCloseTask closes a survey scheduled to close and returns what is left of its escrow to
+// the wallet that funded it. Like HandleOpenTask, it only acts on a published survey still
+// scheduled to close at the time it was queued for.
+func (h *TaskHandler) HandleCloseTask(ctx context.Context, t *asynq.Task) error {
+	var payload queue.SurveyClosePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("error decoding survey close payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	survey, err := h.Store.GetSurveyByID(ctx, payload.SurveyID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			log.Printf("survey %d to close no longer exists", payload.SurveyID)
+			return nil
+		}
+		return fmt.Errorf("error getting survey %d: %w", payload.SurveyID, err)
+	}
+
+	if survey.Status != database.SurveyStatusPublished || !scheduledAt(survey.ClosesAt, payload.At) {
+		log.Printf("survey %d is no longer scheduled to close at %s", survey.ID, payload.At)
+		return nil
+	}
+
+	closed, err := h.Store.CloseScheduledSurvey(ctx, survey)
+	if err != nil {
+		// closed or cancelled by the researcher since it was read
+		if errors.Is(err, custom_errors.ErrInvalidTransition) {
+			return nil
+		}
+		return fmt.Errorf("error closing survey %d: %w", payload.SurveyID, err)
+	}
+
+	log.Printf("survey %d closed as scheduled", closed.ID)
+
+	message := "It closed as scheduled. Nothing was left in its escrow to return."
+	if escrow, err := h.Store.GetEscrow(ctx, closed.ID); err == nil {
+		if refund := database.DecimalFromNumeric(escrow.Refunded); refund.IsPositive() {
+			message = fmt.Sprintf("It closed as scheduled and the %s left in its escrow was returned to %s.", refund.StringFixed(2), walletOf(closed))
+		}
+	}
+
+	h.notify(ctx, closed, "Your survey has closed", message)
+
+	return nil
+}
+
+// walletOf names the wallet a survey is paid from in the emails about it.
+func walletOf(survey database.Survey) string {
+	if survey.OrganizationID.Valid {
+		return "your organization's wallet"
+	}
+
+	return "your wallet"
+}
+
+// notify emails the researcher who owns a survey. The survey has moved on whether or not the
+// email goes out, so failing to send it is only logged.
+func (h *TaskHandler) notify(ctx context.Context, survey database.Survey, subject, message string) {
+	email, err := h.Store.GetResearcherEmail(ctx, survey.ResearcherID)
+	if err != nil {
+		log.Printf("error getting the email of researcher %d: %s", survey.ResearcherID, err)
+		return
+	}
+
+	err = h.Queue.Enqueue(&queue.EmailDeliveryPayload{
+		Name:     "email",
+		Template: "survey_schedule_mail",
+		Subject:  subject,
+		Email:    email,
+		Data: struct {
+			Heading string
+			Title   string
+			Message string
+		}{
+			Heading: subject,
+			Title:   survey.Title,
+			Message: message,
+		},
+	})
+
+	if err != nil {
+		log.Printf("error enqueuing email task: %s", err)
+	}
+}
+
+// scheduledAt reports whether a stored schedule time is the time a task was queued for.
+func scheduledAt(stored pgtype.Timestamp, at time.Time) bool {
+	return stored.Valid && stored.Time.Equal(at)
+}
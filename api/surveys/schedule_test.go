@@ -0,0 +1,466 @@
+package surveys_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// StubQueue keeps the tasks on it by ID, the way asynq does for tasks given one.
+type StubQueue struct {
+	Tasks      []queue.Processor
+	Scheduled  map[string]queue.Scheduled
+	Dequeued   []string
+	ShouldFail bool
+}
+
+func NewStubQueue() *StubQueue {
+	return &StubQueue{Scheduled: make(map[string]queue.Scheduled)}
+}
+
+func (q *StubQueue) Enqueue(processor queue.Processor) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+
+	if scheduled, ok := processor.(queue.Scheduled); ok {
+		q.Scheduled[scheduled.TaskID()] = scheduled
+		return nil
+	}
+
+	q.Tasks = append(q.Tasks, processor)
+	return nil
+}
+
+func (q *StubQueue) Dequeue(processor queue.Scheduled) error {
+	if q.ShouldFail {
+		return errors.New("queue error")
+	}
+
+	delete(q.Scheduled, processor.TaskID())
+	q.Dequeued = append(q.Dequeued, processor.TaskID())
+	return nil
+}
+
+func (q *StubQueue) ids() []string {
+	ids := make([]string, 0, len(q.Scheduled))
+	for id := range q.Scheduled {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// emails returns the emails on the queue.
+func (q *StubQueue) emails() []*queue.EmailDeliveryPayload {
+	var emails []*queue.EmailDeliveryPayload
+	for _, task := range q.Tasks {
+		if email, ok := task.(*queue.EmailDeliveryPayload); ok {
+			emails = append(emails, email)
+		}
+	}
+
+	return emails
+}
+
+func schedule(store *StubSurveyStore, q *StubQueue, body string) *httptest.ResponseRecorder {
+	handler := &surveys.Handler{Store: store, Queue: q}
+
+	req := newRequest(http.MethodPut, "/surveys/1/schedule", []byte(body), 1, map[string]string{"surveyID": "1"})
+	rec := httptest.NewRecorder()
+
+	handler.ScheduleSurveyHandler(rec, req)
+
+	return rec
+}
+
+// scheduledSurvey is fundedSurvey scheduled to open and close at the given times.
+func scheduledSurvey(status database.SurveyStatus, opensAt, closesAt time.Time) database.Survey {
+	survey := fundedSurvey(status)
+	survey.OpensAt = pgtype.Timestamp{Time: opensAt, Valid: true}
+	survey.ClosesAt = pgtype.Timestamp{Time: closesAt, Valid: true}
+
+	return survey
+}
+
+func scheduleTask(t *testing.T, processor queue.Processor) *asynq.Task {
+	t.Helper()
+
+	task, err := processor.Process()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return task
+}
+
+// ============================================================================
+// ScheduleSurveyHandler Tests
+// ============================================================================
+
+func TestScheduleSurveyHandler(t *testing.T) {
+
+	opensAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	closesAt := opensAt.Add(7 * 24 * time.Hour)
+	body := `{"opens_at": "` + opensAt.Format(time.RFC3339) + `", "closes_at": "` + closesAt.Format(time.RFC3339) + `"}`
+
+	opening := (&queue.SurveyOpenPayload{SurveyID: 1, At: opensAt}).TaskID()
+	closing := (&queue.SurveyClosePayload{SurveyID: 1, At: closesAt}).TaskID()
+
+	t.Run("queues the tasks that open and close a draft", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		q := NewStubQueue()
+
+		rec := schedule(store, q, body)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !slices.Equal(q.ids(), []string{closing, opening}) {
+			t.Errorf("queued %v, want %s and %s", q.ids(), opening, closing)
+		}
+
+		survey := store.Surveys[1]
+		if !survey.OpensAt.Time.Equal(opensAt) || !survey.ClosesAt.Time.Equal(closesAt) {
+			t.Errorf("schedule = %s to %s, want %s to %s", survey.OpensAt.Time, survey.ClosesAt.Time, opensAt, closesAt)
+		}
+	})
+
+	t.Run("replaces the tasks of the old schedule", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		q := NewStubQueue()
+		_ = q.Enqueue(&queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		_ = q.Enqueue(&queue.SurveyClosePayload{SurveyID: 1, At: closesAt})
+
+		later := closesAt.Add(24 * time.Hour)
+		rec := schedule(store, q, `{"opens_at": "`+opensAt.Format(time.RFC3339)+`", "closes_at": "`+later.Format(time.RFC3339)+`"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		moved := (&queue.SurveyClosePayload{SurveyID: 1, At: later}).TaskID()
+		if !slices.Equal(q.ids(), []string{moved, opening}) || !slices.Equal(q.Dequeued, []string{closing}) {
+			t.Errorf("queued %v after dequeuing %v, want the close task moved to %s", q.ids(), q.Dequeued, later)
+		}
+	})
+
+	t.Run("takes the tasks off the queue when the schedule is cleared", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		q := NewStubQueue()
+		_ = q.Enqueue(&queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		_ = q.Enqueue(&queue.SurveyClosePayload{SurveyID: 1, At: closesAt})
+
+		rec := schedule(store, q, `{}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if len(q.Scheduled) != 0 || store.Surveys[1].OpensAt.Valid || store.Surveys[1].ClosesAt.Valid {
+			t.Errorf("queued %v, survey %+v, want the schedule cleared", q.ids(), store.Surveys[1])
+		}
+	})
+
+	t.Run("only schedules a published survey to close", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusPublished)
+		q := NewStubQueue()
+
+		rec := schedule(store, q, `{"closes_at": "`+closesAt.Format(time.RFC3339)+`"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !slices.Equal(q.ids(), []string{closing}) {
+			t.Errorf("queued %v, want only %s", q.ids(), closing)
+		}
+	})
+
+	t.Run("returns 400 for times that can't be kept", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		q := NewStubQueue()
+
+		past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		rec := schedule(store, q, `{"opens_at": "`+past+`", "closes_at": "`+opensAt.Format(time.RFC3339)+`"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		if !strings.Contains(rec.Body.String(), "opens_at: must be in the future") {
+			t.Errorf("body = %s", rec.Body.String())
+		}
+
+		rec = schedule(store, q, `{"opens_at": "`+closesAt.Format(time.RFC3339)+`", "closes_at": "`+opensAt.Format(time.RFC3339)+`"}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+
+		if !strings.Contains(rec.Body.String(), "closes_at: must be after opens_at") {
+			t.Errorf("body = %s", rec.Body.String())
+		}
+
+		if len(q.Scheduled) != 0 {
+			t.Errorf("queued %v, want nothing", q.ids())
+		}
+	})
+
+	t.Run("returns 400 for moving the opening of a published survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusPublished)
+
+		rec := schedule(store, NewStubQueue(), body)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 409 for a closed survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusClosed)
+
+		rec := schedule(store, NewStubQueue(), body)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 500 without saving when the tasks can't be queued", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		q := NewStubQueue()
+		q.ShouldFail = true
+
+		rec := schedule(store, q, body)
+
+		assertResponseCode(t, rec.Code, http.StatusInternalServerError)
+
+		if store.Surveys[1].OpensAt.Valid {
+			t.Errorf("expected the schedule not to be saved")
+		}
+	})
+}
+
+// ============================================================================
+// Schedule task Tests
+// ============================================================================
+
+func TestHandleOpenTask(t *testing.T) {
+
+	opensAt := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	closesAt := opensAt.Add(7 * 24 * time.Hour)
+
+	t.Run("publishes the survey and tells the researcher", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		store.Balances[1] = decimal.NewFromInt(1000)
+		q := NewStubQueue()
+		handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+		task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusPublished {
+			t.Errorf("status = %s, want published", store.Surveys[1].Status)
+		}
+
+		emails := q.emails()
+		if len(emails) != 1 || emails[0].Email != "researcher1@example.com" || emails[0].Subject != "Your survey is open" {
+			t.Errorf("emails = %+v, want the researcher told the survey opened", emails)
+		}
+	})
+
+	t.Run("publishes once however often the task runs", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		store.Balances[1] = decimal.NewFromInt(1000)
+		q := NewStubQueue()
+		handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+		task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		for range 2 {
+			if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if !store.Balances[1].Equal(decimal.NewFromInt(1000).Sub(database.DecimalFromNumeric(store.Escrows[1].Amount))) {
+			t.Errorf("balance = %s, want the budget charged once", store.Balances[1])
+		}
+
+		if len(q.emails()) != 1 {
+			t.Errorf("sent %d emails, want 1", len(q.emails()))
+		}
+	})
+
+	t.Run("does nothing for a schedule that has changed", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt.Add(time.Hour), closesAt)
+		store.Balances[1] = decimal.NewFromInt(1000)
+		q := NewStubQueue()
+		handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+		task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusDraft || len(q.emails()) != 0 {
+			t.Errorf("status = %s, want the survey left a draft", store.Surveys[1].Status)
+		}
+	})
+
+	t.Run("leaves the survey a draft when the wallet can't cover it", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		q := NewStubQueue()
+		handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+		task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		emails := q.emails()
+		if store.Surveys[1].Status != database.SurveyStatusDraft || len(emails) != 1 || emails[0].Subject != "Your survey could not open" {
+			t.Errorf("status = %s, emails = %+v, want a draft and the researcher told why", store.Surveys[1].Status, emails)
+		}
+	})
+
+	t.Run("opens an organization's survey after its researcher has left", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		survey := scheduledSurvey(database.SurveyStatusDraft, opensAt, closesAt)
+		survey.OrganizationID = pgtype.Int8{Int64: 7, Valid: true}
+		store.Surveys[1] = survey
+		store.Members[7] = map[int64]database.OrganizationRole{2: database.OrganizationRoleOwner}
+		store.OrganizationBalances[7] = decimal.NewFromInt(1000)
+		handler := &surveys.TaskHandler{Store: store, Queue: NewStubQueue()}
+
+		task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+		if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusPublished || store.OrganizationBalances[7].Equal(decimal.NewFromInt(1000)) {
+			t.Errorf("status = %s, balance = %s, want it published from the organization's wallet", store.Surveys[1].Status, store.OrganizationBalances[7])
+		}
+
+		if _, member := store.Members[7][1]; member {
+			t.Errorf("members = %v, want researcher 1 still gone", store.Members[7])
+		}
+	})
+
+	t.Run("rejects a malformed payload without retrying", func(t *testing.T) {
+		handler := &surveys.TaskHandler{Store: NewStubSurveyStore(), Queue: NewStubQueue()}
+
+		task := asynq.NewTask(queue.TypeSurveyOpen, json.RawMessage(`{"SurveyID": "one"}`))
+
+		if err := handler.HandleOpenTask(context.Background(), task); !errors.Is(err, asynq.SkipRetry) {
+			t.Errorf("error = %v, want it to skip retries", err)
+		}
+	})
+}
+
+func TestHandleCloseTask(t *testing.T) {
+
+	opensAt := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	closesAt := opensAt.Add(7 * 24 * time.Hour)
+
+	newClosingStore := func() *StubSurveyStore {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = scheduledSurvey(database.SurveyStatusPublished, opensAt, closesAt)
+		store.Escrows[1] = database.Escrow{
+			SurveyID: 1,
+			Amount:   database.NumericFromDecimal(decimal.NewFromInt(30)),
+			Spent:    database.NumericFromDecimal(decimal.NewFromInt(12)),
+			Status:   database.EscrowStatusHeld,
+		}
+
+		return store
+	}
+
+	t.Run("closes the survey and refunds what is left in escrow", func(t *testing.T) {
+		store := newClosingStore()
+		q := NewStubQueue()
+		handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+		task := scheduleTask(t, &queue.SurveyClosePayload{SurveyID: 1, At: closesAt})
+		if err := handler.HandleCloseTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusClosed || !store.Balances[1].Equal(decimal.NewFromInt(18)) {
+			t.Errorf("status = %s, balance = %s, want closed with 18 refunded", store.Surveys[1].Status, store.Balances[1])
+		}
+
+		emails := q.emails()
+		if len(emails) != 1 || !strings.Contains(emails[0].Data.(struct {
+			Heading string
+			Title   string
+			Message string
+		}).Message, "18.00") {
+			t.Errorf("emails = %+v, want the researcher told about the refund", emails)
+		}
+	})
+
+	t.Run("refunds once however often the task runs", func(t *testing.T) {
+		store := newClosingStore()
+		handler := &surveys.TaskHandler{Store: store, Queue: NewStubQueue()}
+
+		task := scheduleTask(t, &queue.SurveyClosePayload{SurveyID: 1, At: closesAt})
+		for range 2 {
+			if err := handler.HandleCloseTask(context.Background(), task); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if !store.Balances[1].Equal(decimal.NewFromInt(18)) {
+			t.Errorf("balance = %s, want 18 refunded once", store.Balances[1])
+		}
+	})
+
+	t.Run("closes an organization's survey after its researcher became a viewer", func(t *testing.T) {
+		store := newClosingStore()
+		survey := store.Surveys[1]
+		survey.OrganizationID = pgtype.Int8{Int64: 7, Valid: true}
+		store.Surveys[1] = survey
+		store.Members[7] = map[int64]database.OrganizationRole{1: database.OrganizationRoleViewer}
+		handler := &surveys.TaskHandler{Store: store, Queue: NewStubQueue()}
+
+		task := scheduleTask(t, &queue.SurveyClosePayload{SurveyID: 1, At: closesAt})
+		if err := handler.HandleCloseTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusClosed || !store.OrganizationBalances[7].Equal(decimal.NewFromInt(18)) {
+			t.Errorf("status = %s, balance = %s, want closed with 18 refunded to the organization", store.Surveys[1].Status, store.OrganizationBalances[7])
+		}
+
+		if role := store.Members[7][1]; role != database.OrganizationRoleViewer {
+			t.Errorf("role = %s, want researcher 1 still a viewer", role)
+		}
+	})
+
+	t.Run("does nothing for a schedule that has changed", func(t *testing.T) {
+		store := newClosingStore()
+		handler := &surveys.TaskHandler{Store: store, Queue: NewStubQueue()}
+
+		task := scheduleTask(t, &queue.SurveyClosePayload{SurveyID: 1, At: closesAt.Add(-time.Hour)})
+		if err := handler.HandleCloseTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if store.Surveys[1].Status != database.SurveyStatusPublished {
+			t.Errorf("status = %s, want the survey left open", store.Surveys[1].Status)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package surveys
+
+import "github.com/Adedunmol/answerly/database"
+
+// transitions lists the states a survey can move to from each state.
+// A survey only ever moves forward: draft -> published -> closed or cancelled -> archived.
+var transitions = map[database.SurveyStatus][]database.SurveyStatus{
+	database.SurveyStatusDraft:     {database.SurveyStatusPublished},
+	database.SurveyStatusPublished: {database.SurveyStatusClosed, database.SurveyStatusCancelled},
+	database.SurveyStatusClosed:    {database.SurveyStatusArchived},
+	database.SurveyStatusCancelled: {database.SurveyStatusArchived},
+}
+
+func CanTransition(from, to database.SurveyStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
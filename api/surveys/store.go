@@ -0,0 +1,1251 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/wallets"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"slices"
+	"time"
+)
+
+type Store interface {
+	CreateSurvey(ctx context.Context, researcherID int64, body CreateSurveyBody) (database.Survey, error)
+	GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error)
+	ListSurveys(ctx context.Context, researcherID int64) ([]database.Survey, error)
+	UpdateSurvey(ctx context.Context, id, researcherID int64, body UpdateSurveyBody) (database.Survey, error)
+	UpdateSurveyStatus(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error)
+	PublishSurvey(ctx context.Context, id, researcherID int64, budget Budget) (database.Survey, error)
+	EndSurvey(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error)
+	OpenScheduledSurvey(ctx context.Context, survey database.Survey, budget Budget) (database.Survey, error)
+	CloseScheduledSurvey(ctx context.Context, survey database.Survey) (database.Survey, error)
+	ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule Schedule) (database.Survey, error)
+	UpdateLocales(ctx context.Context, id, researcherID int64, defaultLocale string, locales []string) (database.Survey, error)
+	SaveTranslation(ctx context.Context, surveyID int64, locale string, body TranslationBody) error
+	GetSurveyByID(ctx context.Context, id int64) (database.Survey, error)
+	GetResearcherEmail(ctx context.Context, researcherID int64) (string, error)
+	GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error)
+	GetEscrow(ctx context.Context, surveyID int64) (database.Escrow, error)
+	GetStats(ctx context.Context, surveyID int64) (database.GetSurveyStatsRow, error)
+	GetResults(ctx context.Context, surveyID int64, filter ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error)
+	CrossTabulate(ctx context.Context, surveyID int64, filter ResultsFilter, request CrossTabRequest, now time.Time) ([]database.CrossTabulateResultsRow, error)
+	ExportResponses(ctx context.Context, surveyID int64, includeTests bool, fn func(database.ListSurveyExportRowsRow) error) error
+	DeleteSurvey(ctx context.Context, id, researcherID int64) error
+	CloneSurvey(ctx context.Context, sourceID, researcherID int64, body CloneSurveyBody) (database.Survey, error)
+	SetTemplate(ctx context.Context, id, researcherID int64, template bool) (database.Survey, error)
+	GetTemplate(ctx context.Context, id int64) (database.Survey, error)
+	ListTemplates(ctx context.Context, filter TemplateFilter) ([]database.ListTemplatesRow, error)
+	ImportSurvey(ctx context.Context, researcherID int64, survey SurveyImport) (database.Survey, error)
+	ListFields(ctx context.Context, ids []int64) ([]database.Field, error)
+	FindFields(ctx context.Context, names []string) ([]database.Field, error)
+	CreateQuestion(ctx context.Context, surveyID int64, body CreateQuestionBody) (database.Question, error)
+	GetQuestion(ctx context.Context, surveyID, id int64) (database.Question, error)
+	ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error)
+	UpdateQuestion(ctx context.Context, surveyID, id int64, body UpdateQuestionBody) (database.Question, error)
+	DeleteQuestion(ctx context.Context, surveyID, id int64) error
+	RemoveQuestion(ctx context.Context, surveyID, id int64) error
+	ListVersions(ctx context.Context, surveyID int64) ([]database.SurveyVersion, error)
+	CountAnswerValues(ctx context.Context, questionID int64) ([]database.CountAnswerValuesRow, error)
+	CreateQuotaCell(ctx context.Context, surveyID int64, body CreateQuotaCellBody) (database.QuotaCell, error)
+	ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error)
+	ListQuotaCellsForSurveys(ctx context.Context, surveyIDs []int64) ([]database.QuotaCell, error)
+	DeleteQuotaCell(ctx context.Context, surveyID, id int64) error
+	GetAudience(ctx context.Context, userID int64) (Audience, error)
+	ListFeed(ctx context.Context, userID int64, cursor *FeedCursor, limit int32) ([]database.ListFeedSurveysRow, error)
+}
+
+const UniqueViolation = "23505"
+
+type Repository struct {
+	queries    *database.Queries
+	db         *pgxpool.Pool
+	transactor database.Transactor
+	wallets    wallets.Store
+}
+
+func NewSurveyStore(queries *database.Queries, db *pgxpool.Pool) *Repository {
+
+	return &Repository{
+		queries:    queries,
+		db:         db,
+		transactor: database.NewDBTransactor(db),
+		wallets:    wallets.NewWalletStore(queries),
+	}
+}
+
+func (r *Repository) CreateSurvey(ctx context.Context, researcherID int64, body CreateSurveyBody) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := r.checkFields(ctx, body.FieldIDs); err != nil {
+		return database.Survey{}, err
+	}
+
+	survey, err := r.queries.CreateSurvey(ctx, createSurveyParams(researcherID, body))
+	if err != nil {
+		return database.Survey{}, fmt.Errorf("error creating survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+func createSurveyParams(researcherID int64, body CreateSurveyBody) database.CreateSurveyParams {
+	return database.CreateSurveyParams{
+		ResearcherID:      researcherID,
+		Title:             body.Title,
+		Description:       pgtype.Text{String: body.Description, Valid: len(body.Description) > 0},
+		RewardPerResponse: amountParam(body.RewardPerResponse),
+		TargetResponses:   int4Param(body.TargetResponses),
+		Targeting:         body.Targeting,
+		FieldIds:          body.FieldIDs,
+		EstimatedMinutes:  int4Param(body.EstimatedMinutes),
+		ScreenOutFee:      amountParam(body.ScreenOutFee),
+		ShareDemographics: boolParam(body.ShareDemographics),
+		QualityChecks:     body.QualityChecks,
+		ResumeWindowHours: int4Param(body.ResumeWindowHours),
+		Anonymous:         boolParam(body.Anonymous),
+		OrganizationID:    int8Param(body.OrganizationID),
+	}
+}
+
+func (r *Repository) GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.GetSurvey(ctx, database.GetSurveyParams{
+		ID:           id,
+		ResearcherID: researcherID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error getting survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+func (r *Repository) ListSurveys(ctx context.Context, researcherID int64) ([]database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	surveys, err := r.queries.ListSurveysByResearcher(ctx, researcherID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing surveys: %v", err)
+	}
+
+	return surveys, nil
+}
+
+func (r *Repository) UpdateSurvey(ctx context.Context, id, researcherID int64, body UpdateSurveyBody) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := r.checkFields(ctx, body.FieldIDs); err != nil {
+		return database.Survey{}, err
+	}
+
+	survey, err := r.queries.UpdateSurvey(ctx, database.UpdateSurveyParams{
+		Title:             pgtype.Text{String: body.Title, Valid: len(body.Title) > 0},
+		Description:       pgtype.Text{String: body.Description, Valid: len(body.Description) > 0},
+		RewardPerResponse: amountParam(body.RewardPerResponse),
+		TargetResponses:   int4Param(body.TargetResponses),
+		Targeting:         body.Targeting,
+		FieldIds:          body.FieldIDs,
+		EstimatedMinutes:  int4Param(body.EstimatedMinutes),
+		ScreenOutFee:      amountParam(body.ScreenOutFee),
+		ShareDemographics: boolParam(body.ShareDemographics),
+		QualityChecks:     body.QualityChecks,
+		ResumeWindowHours: int4Param(body.ResumeWindowHours),
+		Anonymous:         boolParam(body.Anonymous),
+		ID:                id,
+		ResearcherID:      researcherID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error updating survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+func (r *Repository) UpdateSurveyStatus(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.UpdateSurveyStatus(ctx, database.UpdateSurveyStatusParams{
+		NextStatus:    to,
+		ID:            id,
+		ResearcherID:  researcherID,
+		CurrentStatus: from,
+	})
+	if err != nil {
+		// the row exists but its status moved on since it was read
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrInvalidTransition
+		}
+		return database.Survey{}, fmt.Errorf("error updating survey status: %v", err)
+	}
+
+	return survey, nil
+}
+
+// PublishSurvey moves a draft survey to published and its budget from the researcher's wallet, or
+// that of the organization the survey belongs to, into escrow, all in one transaction. It fails
+// with custom_errors.ErrInsufficientFunds when the wallet can't cover the budget.
+func (r *Repository) PublishSurvey(ctx context.Context, id, researcherID int64, budget Budget) (database.Survey, error) {
+	return r.publish(ctx, id, researcherID, budget, func(ctx context.Context, q *database.Queries) (database.Survey, error) {
+		return q.UpdateSurveyStatus(ctx, database.UpdateSurveyStatusParams{
+			NextStatus:    database.SurveyStatusPublished,
+			ID:            id,
+			ResearcherID:  researcherID,
+			CurrentStatus: database.SurveyStatusDraft,
+		})
+	})
+}
+
+// OpenScheduledSurvey is PublishSurvey for a survey opening on schedule. It acts for the survey, so
+// it opens whether or not its researcher can still manage it, and charges the wallet the survey
+// would have been charged from had they published it.
+func (r *Repository) OpenScheduledSurvey(ctx context.Context, survey database.Survey, budget Budget) (database.Survey, error) {
+	return r.publish(ctx, survey.ID, survey.ResearcherID, budget, func(ctx context.Context, q *database.Queries) (database.Survey, error) {
+		return q.SetSurveyStatus(ctx, database.SetSurveyStatusParams{
+			NextStatus:    database.SurveyStatusPublished,
+			ID:            survey.ID,
+			CurrentStatus: database.SurveyStatusDraft,
+		})
+	})
+}
+
+func (r *Repository) publish(ctx context.Context, id, researcherID int64, budget Budget, update func(ctx context.Context, q *database.Queries) (database.Survey, error)) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var survey database.Survey
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		survey, err = update(ctx, q)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrInvalidTransition
+			}
+			return fmt.Errorf("error updating survey status: %v", err)
+		}
+
+		if _, err := wallets.Charge(ctx, r.wallets, researcherID, survey.OrganizationID, budget.Total); err != nil {
+			return err
+		}
+
+		_, err = q.CreateEscrow(ctx, database.CreateEscrowParams{
+			SurveyID:          id,
+			ResearcherID:      researcherID,
+			RewardPerResponse: database.NumericFromDecimal(budget.RewardPerResponse),
+			FeePerResponse:    database.NumericFromDecimal(budget.FeePerResponse),
+			Amount:            database.NumericFromDecimal(budget.Total),
+			ScreenOutFee:      database.NumericFromDecimal(budget.ScreenOutFee),
+			OrganizationID:    survey.OrganizationID,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating escrow: %v", err)
+		}
+
+		// the questions respondents get to answer from now on are version 1
+		version, err := q.CreateSurveyVersion(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error creating survey version: %v", err)
+		}
+		survey.Version = version.Version
+
+		return nil
+	})
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	return survey, nil
+}
+
+// EndSurvey moves a published survey to closed or cancelled and returns whatever is left in its
+// escrow to the wallet it came from, in one transaction.
+func (r *Repository) EndSurvey(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error) {
+	return r.end(ctx, researcherID, func(ctx context.Context, q *database.Queries) (database.Survey, error) {
+		return q.UpdateSurveyStatus(ctx, database.UpdateSurveyStatusParams{
+			NextStatus:    to,
+			ID:            id,
+			ResearcherID:  researcherID,
+			CurrentStatus: from,
+		})
+	})
+}
+
+// CloseScheduledSurvey is EndSurvey for a published survey closing on schedule. Like
+// OpenScheduledSurvey, it acts for the survey rather than for its researcher.
+func (r *Repository) CloseScheduledSurvey(ctx context.Context, survey database.Survey) (database.Survey, error) {
+	return r.end(ctx, survey.ResearcherID, func(ctx context.Context, q *database.Queries) (database.Survey, error) {
+		return q.SetSurveyStatus(ctx, database.SetSurveyStatusParams{
+			NextStatus:    database.SurveyStatusClosed,
+			ID:            survey.ID,
+			CurrentStatus: database.SurveyStatusPublished,
+		})
+	})
+}
+
+func (r *Repository) end(ctx context.Context, researcherID int64, update func(ctx context.Context, q *database.Queries) (database.Survey, error)) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var survey database.Survey
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		survey, err = update(ctx, q)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrInvalidTransition
+			}
+			return fmt.Errorf("error updating survey status: %v", err)
+		}
+
+		escrow, err := q.SettleEscrow(ctx, survey.ID)
+		if err != nil {
+			// nothing is held for the survey, so there is nothing to give back
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("error settling escrow: %v", err)
+		}
+
+		refund := database.DecimalFromNumeric(escrow.Refunded)
+		if !refund.IsPositive() {
+			return nil
+		}
+
+		if _, err := wallets.TopUp(ctx, r.wallets, researcherID, survey.OrganizationID, refund); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	return survey, nil
+}
+
+// ScheduleSurvey sets when a survey opens and closes. Only a draft's opening time can change, a
+// survey that has opened already keeps it.
+func (r *Repository) ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule Schedule) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.ScheduleSurvey(ctx, database.ScheduleSurveyParams{
+		OpensAt:      timestampParam(schedule.OpensAt),
+		ClosesAt:     timestampParam(schedule.ClosesAt),
+		ID:           id,
+		ResearcherID: researcherID,
+	})
+	if err != nil {
+		// the survey moved past published since it was read
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrInvalidTransition
+		}
+		return database.Survey{}, fmt.Errorf("error scheduling survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+// UpdateLocales sets the locale a draft survey is written in and the ones it is translated into.
+func (r *Repository) UpdateLocales(ctx context.Context, id, researcherID int64, defaultLocale string, locales []string) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.UpdateSurveyLocales(ctx, database.UpdateSurveyLocalesParams{
+		DefaultLocale: defaultLocale,
+		Locales:       locales,
+		ID:            id,
+		ResearcherID:  researcherID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error updating survey locales: %v", err)
+	}
+
+	return survey, nil
+}
+
+// SaveTranslation replaces the strings of a survey in one locale, and those of the questions the
+// translation holds, all of them or none.
+func (r *Repository) SaveTranslation(ctx context.Context, surveyID int64, locale string, body TranslationBody) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		translation, err := json.Marshal(body.Survey)
+		if err != nil {
+			return err
+		}
+
+		rows, err := q.SetSurveyTranslation(ctx, database.SetSurveyTranslationParams{
+			Locale:      locale,
+			Translation: translation,
+			ID:          surveyID,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving survey translation: %v", err)
+		}
+
+		// the survey moved past published since it was read
+		if rows == 0 {
+			return custom_errors.ErrInvalidTransition
+		}
+
+		for id, question := range body.Questions {
+			translation, err := json.Marshal(question)
+			if err != nil {
+				return err
+			}
+
+			rows, err := q.SetQuestionTranslation(ctx, database.SetQuestionTranslationParams{
+				Locale:      locale,
+				Translation: translation,
+				ID:          id,
+				SurveyID:    surveyID,
+			})
+			if err != nil {
+				return fmt.Errorf("error saving question translation: %v", err)
+			}
+
+			if rows == 0 {
+				return custom_errors.ErrNotFound
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetSurveyByID returns a survey whoever owns it, for the background tasks acting on it.
+func (r *Repository) GetSurveyByID(ctx context.Context, id int64) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.GetSurveyByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error getting survey: %v", err)
+	}
+
+	return survey, nil
+}
+
+func (r *Repository) GetResearcherEmail(ctx context.Context, researcherID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := r.queries.GetUserByID(ctx, researcherID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", custom_errors.ErrNotFound
+		}
+		return "", fmt.Errorf("error getting researcher: %v", err)
+	}
+
+	return user.Email, nil
+}
+
+// GetMember returns the membership of a user in an organization, which fails with
+// custom_errors.ErrNotFound when they aren't a member.
+func (r *Repository) GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	member, err := r.queries.GetOrganizationMember(ctx, database.GetOrganizationMemberParams{
+		OrganizationID: organizationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.OrganizationMember{}, custom_errors.ErrNotFound
+		}
+		return database.OrganizationMember{}, fmt.Errorf("error getting organization member: %v", err)
+	}
+
+	return member, nil
+}
+
+func (r *Repository) GetEscrow(ctx context.Context, surveyID int64) (database.Escrow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	escrow, err := r.queries.GetEscrowBySurvey(ctx, surveyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Escrow{}, custom_errors.ErrNotFound
+		}
+		return database.Escrow{}, fmt.Errorf("error getting escrow: %v", err)
+	}
+
+	return escrow, nil
+}
+
+func (r *Repository) GetStats(ctx context.Context, surveyID int64) (database.GetSurveyStatsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stats, err := r.queries.GetSurveyStats(ctx, surveyID)
+	if err != nil {
+		return database.GetSurveyStatsRow{}, fmt.Errorf("error getting survey stats: %v", err)
+	}
+
+	return stats, nil
+}
+
+// GetResults aggregates the submitted responses to a survey that pass filter, see the
+// ListSurveyResults query for the rows it returns.
+func (r *Repository) GetResults(ctx context.Context, surveyID int64, filter ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.queries.ListSurveyResults(ctx, resultsParams(surveyID, filter, now))
+	if err != nil {
+		return nil, fmt.Errorf("error getting survey results: %v", err)
+	}
+
+	return rows, nil
+}
+
+func (r *Repository) CrossTabulate(ctx context.Context, surveyID int64, filter ResultsFilter, request CrossTabRequest, now time.Time) ([]database.CrossTabulateResultsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	params := resultsParams(surveyID, filter, now)
+
+	rows, err := r.queries.CrossTabulateResults(ctx, database.CrossTabulateResultsParams{
+		AsOf:             pgtype.Date{Time: now, Valid: true},
+		SurveyID:         params.SurveyID,
+		SubmittedFrom:    params.SubmittedFrom,
+		SubmittedBefore:  params.SubmittedBefore,
+		Gender:           params.Gender,
+		University:       params.University,
+		Faculty:          params.Faculty,
+		Location:         params.Location,
+		BornOnOrBefore:   params.BornOnOrBefore,
+		BornAfter:        params.BornAfter,
+		IncludeTests:     params.IncludeTests,
+		RowQuestionID:    request.QuestionID,
+		ColumnQuestionID: pgtype.Int8{Int64: request.ByQuestionID, Valid: request.ByQuestionID != 0},
+		ColumnAttribute:  pgtype.Text{String: request.ByAttribute, Valid: request.ByAttribute != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error cross-tabulating survey results: %v", err)
+	}
+
+	return rows, nil
+}
+
+// ExportResponses calls fn with every response to a survey, oldest first, as the rows come in from
+// the database. Test responses are only exported when includeTests is set. There is no timeout
+// since a large export can take a while, it runs until ctx is done.
+func (r *Repository) ExportResponses(ctx context.Context, surveyID int64, includeTests bool, fn func(database.ListSurveyExportRowsRow) error) error {
+	err := r.queries.StreamSurveyExportRows(ctx, database.ListSurveyExportRowsParams{
+		SurveyID:     surveyID,
+		IncludeTests: includeTests,
+	}, fn)
+	if err != nil {
+		return fmt.Errorf("error exporting responses: %v", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteSurvey(ctx context.Context, id, researcherID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.queries.DeleteSurvey(ctx, database.DeleteSurveyParams{
+		ID:           id,
+		ResearcherID: researcherID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting survey: %v", err)
+	}
+
+	if rows == 0 {
+		return custom_errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// CloneSurvey copies a survey into a new draft of the researcher: its settings and targeting, the
+// questions it asks now with their logic pointed at the copies, and its quota cells. Nothing about
+// how the original was answered or paid for comes along.
+func (r *Repository) CloneSurvey(ctx context.Context, sourceID, researcherID int64, body CloneSurveyBody) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var survey database.Survey
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		survey, err = q.CloneSurvey(ctx, database.CloneSurveyParams{
+			ResearcherID: researcherID,
+			Title:        pgtype.Text{String: body.Title, Valid: len(body.Title) > 0},
+			SourceID:     sourceID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error cloning survey: %v", err)
+		}
+
+		questions, err := q.ListQuestionsBySurvey(ctx, sourceID)
+		if err != nil {
+			return fmt.Errorf("error listing questions: %v", err)
+		}
+
+		if err := copyQuestions(ctx, q, survey.ID, questions); err != nil {
+			return err
+		}
+
+		cells, err := q.ListQuotaCellsBySurvey(ctx, sourceID)
+		if err != nil {
+			return fmt.Errorf("error listing quota cells: %v", err)
+		}
+
+		for _, cell := range cells {
+			_, err := q.CreateQuotaCell(ctx, database.CreateQuotaCellParams{
+				SurveyID: survey.ID,
+				Name:     cell.Name,
+				Criteria: cell.Criteria,
+				Capacity: cell.Capacity,
+			})
+			if err != nil {
+				return fmt.Errorf("error copying quota cell: %v", err)
+			}
+		}
+
+		return checkLogic(ctx, q, survey.ID)
+	})
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	return survey, nil
+}
+
+// ImportSurvey stores a checked survey definition as a new draft of the researcher, all of it or
+// nothing.
+func (r *Repository) ImportSurvey(ctx context.Context, researcherID int64, body SurveyImport) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var survey database.Survey
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		survey, err = q.CreateSurvey(ctx, createSurveyParams(researcherID, body.Survey))
+		if err != nil {
+			return fmt.Errorf("error creating survey: %v", err)
+		}
+
+		if err := copyQuestions(ctx, q, survey.ID, body.Questions); err != nil {
+			return err
+		}
+
+		for _, quota := range body.Quotas {
+			_, err := q.CreateQuotaCell(ctx, database.CreateQuotaCellParams{
+				SurveyID: survey.ID,
+				Name:     quota.Name,
+				Criteria: quota.Criteria,
+				Capacity: quota.Capacity,
+			})
+			if err != nil {
+				return fmt.Errorf("error creating quota cell: %v", err)
+			}
+		}
+
+		return checkLogic(ctx, q, survey.ID)
+	})
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	return survey, nil
+}
+
+// copyQuestions adds the questions, in order, to a survey with no questions yet, inside the
+// transaction q belongs to. Their logic is pointed at the new questions, so it may refer to them
+// by whatever IDs they had before.
+func copyQuestions(ctx context.Context, q *database.Queries, surveyID int64, questions []database.Question) error {
+	// the logic of a question may point at questions after it, so every question is added before
+	// any logic is
+	ids := make(map[int64]int64, len(questions))
+	for i, question := range questions {
+		created, err := q.CreateQuestion(ctx, database.CreateQuestionParams{
+			SurveyID:     surveyID,
+			Position:     pgtype.Int4{Int32: int32(i + 1), Valid: true},
+			Type:         question.Type,
+			Title:        question.Title,
+			Description:  question.Description,
+			Required:     question.Required,
+			Config:       question.Config,
+			Screener:     question.Screener,
+			Block:        question.Block,
+			Translations: question.Translations,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating question: %v", err)
+		}
+		ids[question.ID] = created.ID
+	}
+
+	for _, question := range questions {
+		if question.Logic == nil {
+			continue
+		}
+
+		logic, err := RemapLogic(question.Logic, ids)
+		if err != nil {
+			return fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		_, err = q.UpdateQuestion(ctx, database.UpdateQuestionParams{
+			ID:       ids[question.ID],
+			SurveyID: surveyID,
+			Logic:    logic,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating question logic: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) SetTemplate(ctx context.Context, id, researcherID int64, template bool) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.SetSurveyTemplate(ctx, database.SetSurveyTemplateParams{
+		IsTemplate:   template,
+		ID:           id,
+		ResearcherID: researcherID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error updating survey template: %v", err)
+	}
+
+	return survey, nil
+}
+
+func (r *Repository) GetTemplate(ctx context.Context, id int64) (database.Survey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	survey, err := r.queries.GetTemplate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Survey{}, custom_errors.ErrNotFound
+		}
+		return database.Survey{}, fmt.Errorf("error getting template: %v", err)
+	}
+
+	return survey, nil
+}
+
+func (r *Repository) ListTemplates(ctx context.Context, filter TemplateFilter) ([]database.ListTemplatesRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fieldIDs := filter.FieldIDs
+	if fieldIDs == nil {
+		fieldIDs = []int64{}
+	}
+
+	rows, err := r.queries.ListTemplates(ctx, database.ListTemplatesParams{
+		After:    filter.After,
+		FieldIds: fieldIDs,
+		Search:   pgtype.Text{String: filter.Search, Valid: len(filter.Search) > 0},
+		PageSize: filter.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing templates: %v", err)
+	}
+
+	return rows, nil
+}
+
+// CreateQuestion adds a question to a survey. Like every change to a survey's questions, it is
+// rolled back when the survey's logic no longer holds afterwards, and makes a new version of a
+// survey that is past draft.
+func (r *Repository) CreateQuestion(ctx context.Context, surveyID int64, body CreateQuestionBody) (database.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	required := true
+	if body.Required != nil {
+		required = *body.Required
+	}
+
+	var position pgtype.Int4
+	if body.Position != nil {
+		position = pgtype.Int4{Int32: *body.Position, Valid: true}
+	}
+
+	var question database.Question
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		question, err = q.CreateQuestion(ctx, database.CreateQuestionParams{
+			SurveyID:    surveyID,
+			Position:    position,
+			Type:        database.QuestionType(body.Type),
+			Title:       body.Title,
+			Description: pgtype.Text{String: body.Description, Valid: len(body.Description) > 0},
+			Required:    required,
+			Config:      body.Config,
+			Logic:       body.Logic,
+			Screener:    body.Screener != nil && *body.Screener,
+			Block:       pgtype.Text{String: body.Block, Valid: body.Block != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("error creating question: %v", err)
+		}
+
+		if err := checkLogic(ctx, q, surveyID); err != nil {
+			return err
+		}
+
+		return createVersion(ctx, q, surveyID)
+	})
+	if err != nil {
+		return database.Question{}, err
+	}
+
+	return question, nil
+}
+
+func (r *Repository) GetQuestion(ctx context.Context, surveyID, id int64) (database.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	question, err := r.queries.GetQuestion(ctx, database.GetQuestionParams{
+		ID:       id,
+		SurveyID: surveyID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Question{}, custom_errors.ErrNotFound
+		}
+		return database.Question{}, fmt.Errorf("error getting question: %v", err)
+	}
+
+	return question, nil
+}
+
+func (r *Repository) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	questions, err := r.queries.ListQuestionsBySurvey(ctx, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing questions: %v", err)
+	}
+
+	return questions, nil
+}
+
+func (r *Repository) UpdateQuestion(ctx context.Context, surveyID, id int64, body UpdateQuestionBody) (database.Question, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var required pgtype.Bool
+	if body.Required != nil {
+		required = pgtype.Bool{Bool: *body.Required, Valid: true}
+	}
+
+	var screener pgtype.Bool
+	if body.Screener != nil {
+		screener = pgtype.Bool{Bool: *body.Screener, Valid: true}
+	}
+
+	var position pgtype.Int4
+	if body.Position != nil {
+		position = pgtype.Int4{Int32: *body.Position, Valid: true}
+	}
+
+	var block pgtype.Text
+	if body.Block != nil {
+		block = pgtype.Text{String: *body.Block, Valid: true}
+	}
+
+	var question database.Question
+
+	err := r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		var err error
+		question, err = q.UpdateQuestion(ctx, database.UpdateQuestionParams{
+			Position:    position,
+			Title:       pgtype.Text{String: body.Title, Valid: len(body.Title) > 0},
+			Description: pgtype.Text{String: body.Description, Valid: len(body.Description) > 0},
+			Required:    required,
+			Config:      body.Config,
+			Logic:       body.Logic,
+			Screener:    screener,
+			Block:       block,
+			ID:          id,
+			SurveyID:    surveyID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return custom_errors.ErrNotFound
+			}
+			return fmt.Errorf("error updating question: %v", err)
+		}
+
+		if err := checkLogic(ctx, q, surveyID); err != nil {
+			return err
+		}
+
+		return createVersion(ctx, q, surveyID)
+	})
+	if err != nil {
+		return database.Question{}, err
+	}
+
+	return question, nil
+}
+
+func (r *Repository) DeleteQuestion(ctx context.Context, surveyID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		rows, err := q.DeleteQuestion(ctx, database.DeleteQuestionParams{
+			ID:       id,
+			SurveyID: surveyID,
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting question: %v", err)
+		}
+
+		if rows == 0 {
+			return custom_errors.ErrNotFound
+		}
+
+		// rules of the remaining questions may still point at the deleted one
+		return checkLogic(ctx, q, surveyID)
+	})
+}
+
+// RemoveQuestion takes a question off a survey that is past draft. Unlike DeleteQuestion it keeps
+// the question and the answers already given to it, which earlier versions of the survey still
+// refer to.
+func (r *Repository) RemoveQuestion(ctx context.Context, surveyID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		q := r.queries.WithTx(database.GetTx(ctx, r.db))
+
+		rows, err := q.RemoveQuestion(ctx, database.RemoveQuestionParams{
+			ID:       id,
+			SurveyID: surveyID,
+		})
+		if err != nil {
+			return fmt.Errorf("error removing question: %v", err)
+		}
+
+		if rows == 0 {
+			return custom_errors.ErrNotFound
+		}
+
+		if err := checkLogic(ctx, q, surveyID); err != nil {
+			return err
+		}
+
+		return createVersion(ctx, q, surveyID)
+	})
+}
+
+func (r *Repository) ListVersions(ctx context.Context, surveyID int64) ([]database.SurveyVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	versions, err := r.queries.ListSurveyVersions(ctx, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing survey versions: %v", err)
+	}
+
+	return versions, nil
+}
+
+func (r *Repository) CountAnswerValues(ctx context.Context, questionID int64) ([]database.CountAnswerValuesRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	values, err := r.queries.CountAnswerValues(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("error counting answers: %v", err)
+	}
+
+	return values, nil
+}
+
+// checkLogic validates the logic of a survey as it stands inside the transaction q belongs to.
+func (r *Repository) CreateQuotaCell(ctx context.Context, surveyID int64, body CreateQuotaCellBody) (database.QuotaCell, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cell, err := r.queries.CreateQuotaCell(ctx, database.CreateQuotaCellParams{
+		SurveyID: surveyID,
+		Name:     body.Name,
+		Criteria: body.Criteria,
+		Capacity: body.Capacity,
+	})
+	if err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.Code == UniqueViolation {
+			return database.QuotaCell{}, custom_errors.ErrConflict
+		}
+		return database.QuotaCell{}, fmt.Errorf("error creating quota cell: %v", err)
+	}
+
+	return cell, nil
+}
+
+func (r *Repository) ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cells, err := r.queries.ListQuotaCellsBySurvey(ctx, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing quota cells: %v", err)
+	}
+
+	return cells, nil
+}
+
+func (r *Repository) ListQuotaCellsForSurveys(ctx context.Context, surveyIDs []int64) ([]database.QuotaCell, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cells, err := r.queries.ListQuotaCellsBySurveys(ctx, surveyIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing quota cells: %v", err)
+	}
+
+	return cells, nil
+}
+
+func (r *Repository) DeleteQuotaCell(ctx context.Context, surveyID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.queries.DeleteQuotaCell(ctx, database.DeleteQuotaCellParams{
+		ID:       id,
+		SurveyID: surveyID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting quota cell: %v", err)
+	}
+
+	if rows == 0 {
+		return custom_errors.ErrNotFound
+	}
+
+	return nil
+}
+
+func checkLogic(ctx context.Context, q *database.Queries, surveyID int64) error {
+	questions, err := q.ListQuestionsBySurvey(ctx, surveyID)
+	if err != nil {
+		return fmt.Errorf("error listing questions: %v", err)
+	}
+
+	return ValidateLogic(questions)
+}
+
+// createVersion records the questions of a survey as a new version, inside the transaction q
+// belongs to. Drafts aren't versioned, nobody has answered them yet.
+func createVersion(ctx context.Context, q *database.Queries, surveyID int64) error {
+	_, err := q.CreateSurveyVersion(ctx, surveyID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error creating survey version: %v", err)
+	}
+
+	return nil
+}
+
+// GetAudience loads what a survey's targeting rules are checked against. A user who hasn't filled
+// in a profile yet is checked against an empty one.
+func (r *Repository) GetAudience(ctx context.Context, userID int64) (Audience, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	profile, err := r.queries.GetProfile(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return Audience{}, fmt.Errorf("error getting profile: %v", err)
+	}
+
+	fieldIDs, err := r.queries.ListInterestFieldIDs(ctx, userID)
+	if err != nil {
+		return Audience{}, fmt.Errorf("error listing interest areas: %v", err)
+	}
+
+	return Audience{Profile: profile, FieldIDs: fieldIDs}, nil
+}
+
+// ListFeed returns the next page of published surveys a user hasn't answered yet, best match
+// first, starting after the cursor. Targeting is not applied here, see BuildFeed.
+func (r *Repository) ListFeed(ctx context.Context, userID int64, cursor *FeedCursor, limit int32) ([]database.ListFeedSurveysRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	params := database.ListFeedSurveysParams{
+		UserID:   userID,
+		PageSize: limit,
+	}
+
+	if cursor != nil {
+		params.CursorID = pgtype.Int8{Int64: cursor.ID, Valid: true}
+		params.CursorOverlap = pgtype.Int4{Int32: cursor.Overlap, Valid: true}
+		params.CursorRewardPerMinute = database.NumericFromDecimal(cursor.RewardPerMinute)
+	}
+
+	surveys, err := r.queries.ListFeedSurveys(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("error listing feed: %v", err)
+	}
+
+	return surveys, nil
+}
+
+// checkFields makes sure every field a survey is tagged with exists.
+func (r *Repository) ListFields(ctx context.Context, ids []int64) ([]database.Field, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fields, err := r.queries.ListFieldsIn(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fields: %v", err)
+	}
+
+	return fields, nil
+}
+
+// FindFields returns the fields with the given names. Names no field has are left out.
+func (r *Repository) FindFields(ctx context.Context, names []string) ([]database.Field, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fields, err := r.queries.ListFieldsNamed(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("error finding fields: %v", err)
+	}
+
+	return fields, nil
+}
+
+func (r *Repository) checkFields(ctx context.Context, fieldIDs []int64) error {
+	if len(fieldIDs) == 0 {
+		return nil
+	}
+
+	existing, err := r.queries.ListFieldIDsIn(ctx, fieldIDs)
+	if err != nil {
+		return fmt.Errorf("error getting fields: %v", err)
+	}
+
+	var validationErrors jsonutil.ValidationErrors
+	for i, fieldID := range fieldIDs {
+		if !slices.Contains(existing, fieldID) {
+			validationErrors = append(validationErrors, fmt.Sprintf("field_ids[%d]: field %d does not exist", i, fieldID))
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return nil
+}
+
+func amountParam(amount *decimal.Decimal) pgtype.Numeric {
+	if amount == nil {
+		return pgtype.Numeric{}
+	}
+
+	return database.NumericFromDecimal(*amount)
+}
+
+func int4Param(value *int32) pgtype.Int4 {
+	if value == nil {
+		return pgtype.Int4{}
+	}
+
+	return pgtype.Int4{Int32: *value, Valid: true}
+}
+
+func int8Param(value *int64) pgtype.Int8 {
+	if value == nil {
+		return pgtype.Int8{}
+	}
+
+	return pgtype.Int8{Int64: *value, Valid: true}
+}
+
+func boolParam(value *bool) pgtype.Bool {
+	if value == nil {
+		return pgtype.Bool{}
+	}
+
+	return pgtype.Bool{Bool: *value, Valid: true}
+}
+
+func timestampParam(value *time.Time) pgtype.Timestamp {
+	if value == nil {
+		return pgtype.Timestamp{}
+	}
+
+	return pgtype.Timestamp{Time: *value, Valid: true}
+}
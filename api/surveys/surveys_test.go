@@ -0,0 +1,1609 @@
+package surveys_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Stub Survey Store
+// ============================================================================
+
+type StubSurveyStore struct {
+	Surveys   map[int64]database.Survey
+	Questions map[int64]database.Question
+	Escrows   map[int64]database.Escrow
+	Balances  map[int64]decimal.Decimal
+	Audiences map[int64]surveys.Audience
+	Stats     map[int64]database.GetSurveyStatsRow
+	Cells     map[int64]database.QuotaCell
+	// Results and CrossTabs hold the aggregated rows returned for each survey.
+	Results   map[int64][]database.ListSurveyResultsRow
+	CrossTabs map[int64][]database.CrossTabulateResultsRow
+	// Exports holds the rows ExportResponses streams for each survey.
+	Exports map[int64][]database.ListSurveyExportRowsRow
+	// Feed is what ListFeed pages through, already in ranking order.
+	Feed []database.ListFeedSurveysRow
+	// Versions holds the versions of each survey, AnswerValues the distinct answers to each question.
+	Versions     map[int64][]database.SurveyVersion
+	AnswerValues map[int64][]database.CountAnswerValuesRow
+	// Fields are the fields that exist, by ID.
+	Fields map[int64]string
+	// Members holds the role of each member of an organization, OrganizationBalances the balance of
+	// its wallet.
+	Members              map[int64]map[int64]database.OrganizationRole
+	OrganizationBalances map[int64]decimal.Decimal
+	ShouldFail           bool
+}
+
+func NewStubSurveyStore() *StubSurveyStore {
+	return &StubSurveyStore{
+		Surveys:   make(map[int64]database.Survey),
+		Questions: make(map[int64]database.Question),
+		Escrows:   make(map[int64]database.Escrow),
+		Balances:  make(map[int64]decimal.Decimal),
+		Audiences: make(map[int64]surveys.Audience),
+		Stats:     make(map[int64]database.GetSurveyStatsRow),
+		Cells:     make(map[int64]database.QuotaCell),
+		Versions:  make(map[int64][]database.SurveyVersion),
+
+		Members:              make(map[int64]map[int64]database.OrganizationRole),
+		OrganizationBalances: make(map[int64]decimal.Decimal),
+	}
+}
+
+func (s *StubSurveyStore) CreateSurvey(ctx context.Context, researcherID int64, body surveys.CreateSurveyBody) (database.Survey, error) {
+	if s.ShouldFail {
+		return database.Survey{}, errors.New("database error")
+	}
+
+	survey := database.Survey{
+		ID:           int64(len(s.Surveys) + 1),
+		ResearcherID: researcherID,
+		Title:        body.Title,
+		Description:  pgtype.Text{String: body.Description, Valid: body.Description != ""},
+		Status:       database.SurveyStatusDraft,
+	}
+
+	if body.OrganizationID != nil {
+		survey.OrganizationID = pgtype.Int8{Int64: *body.OrganizationID, Valid: true}
+	}
+
+	s.Surveys[survey.ID] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) GetSurvey(ctx context.Context, id, researcherID int64) (database.Survey, error) {
+	if s.ShouldFail {
+		return database.Survey{}, errors.New("database error")
+	}
+
+	survey, exists := s.Surveys[id]
+	if !exists || !s.canSee(survey, researcherID) {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+// canSee tells whether a researcher owns a survey or is a member of the organization it belongs to.
+func (s *StubSurveyStore) canSee(survey database.Survey, researcherID int64) bool {
+	if survey.OrganizationID.Valid {
+		_, member := s.Members[survey.OrganizationID.Int64][researcherID]
+		return member
+	}
+
+	return survey.ResearcherID == researcherID
+}
+
+// balances returns the balances holding the wallet a survey is paid from, and its key in them.
+func (s *StubSurveyStore) balances(survey database.Survey, researcherID int64) (map[int64]decimal.Decimal, int64) {
+	if survey.OrganizationID.Valid {
+		return s.OrganizationBalances, survey.OrganizationID.Int64
+	}
+
+	return s.Balances, researcherID
+}
+
+func (s *StubSurveyStore) ListSurveys(ctx context.Context, researcherID int64) ([]database.Survey, error) {
+	if s.ShouldFail {
+		return nil, errors.New("database error")
+	}
+
+	var data []database.Survey
+	for _, survey := range s.Surveys {
+		if s.canSee(survey, researcherID) {
+			data = append(data, survey)
+		}
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) UpdateSurvey(ctx context.Context, id, researcherID int64, body surveys.UpdateSurveyBody) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	if body.Title != "" {
+		survey.Title = body.Title
+	}
+
+	if body.Description != "" {
+		survey.Description = pgtype.Text{String: body.Description, Valid: true}
+	}
+
+	s.Surveys[id] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) UpdateSurveyStatus(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	if survey.Status != from {
+		return database.Survey{}, custom_errors.ErrInvalidTransition
+	}
+
+	survey.Status = to
+	s.Surveys[id] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) PublishSurvey(ctx context.Context, id, researcherID int64, budget surveys.Budget) (database.Survey, error) {
+	balances, wallet := s.balances(s.Surveys[id], researcherID)
+	if balances[wallet].LessThan(budget.Total) {
+		return database.Survey{}, custom_errors.ErrInsufficientFunds
+	}
+
+	if _, err := s.UpdateSurveyStatus(ctx, id, researcherID, database.SurveyStatusDraft, database.SurveyStatusPublished); err != nil {
+		return database.Survey{}, err
+	}
+
+	balances[wallet] = balances[wallet].Sub(budget.Total)
+	s.Escrows[id] = database.Escrow{
+		SurveyID:          id,
+		ResearcherID:      researcherID,
+		RewardPerResponse: database.NumericFromDecimal(budget.RewardPerResponse),
+		FeePerResponse:    database.NumericFromDecimal(budget.FeePerResponse),
+		Amount:            database.NumericFromDecimal(budget.Total),
+		Spent:             database.NumericFromDecimal(decimal.Zero),
+		Refunded:          database.NumericFromDecimal(decimal.Zero),
+		Status:            database.EscrowStatusHeld,
+	}
+
+	if err := s.createVersion(id); err != nil {
+		return database.Survey{}, err
+	}
+
+	return s.Surveys[id], nil
+}
+
+func (s *StubSurveyStore) EndSurvey(ctx context.Context, id, researcherID int64, from, to database.SurveyStatus) (database.Survey, error) {
+	survey, err := s.UpdateSurveyStatus(ctx, id, researcherID, from, to)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	escrow, exists := s.Escrows[id]
+	if exists && escrow.Status == database.EscrowStatusHeld {
+		refund := database.DecimalFromNumeric(escrow.Amount).Sub(database.DecimalFromNumeric(escrow.Spent))
+		escrow.Refunded = database.NumericFromDecimal(refund)
+		escrow.Status = database.EscrowStatusSettled
+		s.Escrows[id] = escrow
+
+		balances, wallet := s.balances(survey, researcherID)
+		balances[wallet] = balances[wallet].Add(refund)
+	}
+
+	return survey, nil
+}
+
+// OpenScheduledSurvey publishes the survey as its researcher would, whether or not they can still
+// manage it, the way the query acts for the survey rather than for them.
+func (s *StubSurveyStore) OpenScheduledSurvey(ctx context.Context, survey database.Survey, budget surveys.Budget) (database.Survey, error) {
+	return s.asResearcher(survey, func() (database.Survey, error) {
+		return s.PublishSurvey(ctx, survey.ID, survey.ResearcherID, budget)
+	})
+}
+
+func (s *StubSurveyStore) CloseScheduledSurvey(ctx context.Context, survey database.Survey) (database.Survey, error) {
+	return s.asResearcher(survey, func() (database.Survey, error) {
+		return s.EndSurvey(ctx, survey.ID, survey.ResearcherID, database.SurveyStatusPublished, database.SurveyStatusClosed)
+	})
+}
+
+// asResearcher runs change with the researcher of an organization survey made an editor of the
+// organization for the time it takes, then puts back whatever role they had.
+func (s *StubSurveyStore) asResearcher(survey database.Survey, change func() (database.Survey, error)) (database.Survey, error) {
+	if !survey.OrganizationID.Valid {
+		return change()
+	}
+
+	members := s.Members[survey.OrganizationID.Int64]
+	if members == nil {
+		members = make(map[int64]database.OrganizationRole)
+		s.Members[survey.OrganizationID.Int64] = members
+	}
+
+	role, member := members[survey.ResearcherID]
+	members[survey.ResearcherID] = database.OrganizationRoleEditor
+	defer func() {
+		if member {
+			members[survey.ResearcherID] = role
+		} else {
+			delete(members, survey.ResearcherID)
+		}
+	}()
+
+	return change()
+}
+
+func (s *StubSurveyStore) GetEscrow(ctx context.Context, surveyID int64) (database.Escrow, error) {
+	escrow, exists := s.Escrows[surveyID]
+	if !exists {
+		return database.Escrow{}, custom_errors.ErrNotFound
+	}
+
+	return escrow, nil
+}
+
+func (s *StubSurveyStore) UpdateLocales(ctx context.Context, id, researcherID int64, defaultLocale string, locales []string) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	survey.DefaultLocale = defaultLocale
+	survey.Locales = locales
+	s.Surveys[id] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) SaveTranslation(ctx context.Context, surveyID int64, locale string, body surveys.TranslationBody) error {
+	survey, exists := s.Surveys[surveyID]
+	if !exists {
+		return custom_errors.ErrNotFound
+	}
+
+	translations := map[string]surveys.SurveyTranslation{}
+	if len(survey.Translations) > 0 {
+		if err := json.Unmarshal(survey.Translations, &translations); err != nil {
+			return err
+		}
+	}
+	translations[locale] = body.Survey
+
+	var err error
+	survey.Translations, err = json.Marshal(translations)
+	if err != nil {
+		return err
+	}
+	s.Surveys[surveyID] = survey
+
+	for id, translation := range body.Questions {
+		question, err := s.GetQuestion(ctx, surveyID, id)
+		if err != nil {
+			return err
+		}
+
+		translations := map[string]surveys.QuestionTranslation{}
+		if len(question.Translations) > 0 {
+			if err := json.Unmarshal(question.Translations, &translations); err != nil {
+				return err
+			}
+		}
+		translations[locale] = translation
+
+		question.Translations, err = json.Marshal(translations)
+		if err != nil {
+			return err
+		}
+		s.Questions[id] = question
+	}
+
+	return nil
+}
+
+func (s *StubSurveyStore) ScheduleSurvey(ctx context.Context, id, researcherID int64, schedule surveys.Schedule) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	if survey.Status != database.SurveyStatusDraft && survey.Status != database.SurveyStatusPublished {
+		return database.Survey{}, custom_errors.ErrInvalidTransition
+	}
+
+	if survey.Status == database.SurveyStatusDraft {
+		survey.OpensAt = pgtype.Timestamp{}
+		if schedule.OpensAt != nil {
+			survey.OpensAt = pgtype.Timestamp{Time: *schedule.OpensAt, Valid: true}
+		}
+	}
+
+	survey.ClosesAt = pgtype.Timestamp{}
+	if schedule.ClosesAt != nil {
+		survey.ClosesAt = pgtype.Timestamp{Time: *schedule.ClosesAt, Valid: true}
+	}
+
+	s.Surveys[id] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) GetSurveyByID(ctx context.Context, id int64) (database.Survey, error) {
+	if s.ShouldFail {
+		return database.Survey{}, errors.New("database error")
+	}
+
+	survey, exists := s.Surveys[id]
+	if !exists {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubSurveyStore) GetResearcherEmail(ctx context.Context, researcherID int64) (string, error) {
+	return fmt.Sprintf("researcher%d@example.com", researcherID), nil
+}
+
+func (s *StubSurveyStore) GetMember(ctx context.Context, organizationID, userID int64) (database.OrganizationMember, error) {
+	role, exists := s.Members[organizationID][userID]
+	if !exists {
+		return database.OrganizationMember{}, custom_errors.ErrNotFound
+	}
+
+	return database.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: role}, nil
+}
+
+func (s *StubSurveyStore) GetStats(ctx context.Context, surveyID int64) (database.GetSurveyStatsRow, error) {
+	if s.ShouldFail {
+		return database.GetSurveyStatsRow{}, errors.New("database error")
+	}
+
+	return s.Stats[surveyID], nil
+}
+
+func (s *StubSurveyStore) GetResults(ctx context.Context, surveyID int64, filter surveys.ResultsFilter, now time.Time) ([]database.ListSurveyResultsRow, error) {
+	if s.ShouldFail {
+		return nil, errors.New("database error")
+	}
+
+	return s.Results[surveyID], nil
+}
+
+func (s *StubSurveyStore) CrossTabulate(ctx context.Context, surveyID int64, filter surveys.ResultsFilter, request surveys.CrossTabRequest, now time.Time) ([]database.CrossTabulateResultsRow, error) {
+	if s.ShouldFail {
+		return nil, errors.New("database error")
+	}
+
+	return s.CrossTabs[surveyID], nil
+}
+
+func (s *StubSurveyStore) ExportResponses(ctx context.Context, surveyID int64, includeTests bool, fn func(database.ListSurveyExportRowsRow) error) error {
+	if s.ShouldFail {
+		return errors.New("database error")
+	}
+
+	for _, row := range s.Exports[surveyID] {
+		if row.IsTest && !includeTests {
+			continue
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *StubSurveyStore) DeleteSurvey(ctx context.Context, id, researcherID int64) error {
+	if _, err := s.GetSurvey(ctx, id, researcherID); err != nil {
+		return err
+	}
+
+	delete(s.Surveys, id)
+	return nil
+}
+
+func (s *StubSurveyStore) CloneSurvey(ctx context.Context, sourceID, researcherID int64, body surveys.CloneSurveyBody) (database.Survey, error) {
+	if s.ShouldFail {
+		return database.Survey{}, errors.New("database error")
+	}
+
+	source, exists := s.Surveys[sourceID]
+	if !exists {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	survey := source
+	survey.ID = int64(len(s.Surveys) + 1)
+	survey.ResearcherID = researcherID
+	survey.Status = database.SurveyStatusDraft
+	survey.Version = 0
+	survey.IsTemplate = false
+	survey.ClonedFrom = pgtype.Int8{Int64: sourceID, Valid: true}
+	survey.PublishedAt = pgtype.Timestamp{}
+	survey.ClosedAt = pgtype.Timestamp{}
+	if role, member := s.Members[source.OrganizationID.Int64][researcherID]; !member || role == database.OrganizationRoleViewer {
+		survey.OrganizationID = pgtype.Int8{}
+	}
+	if body.Title != "" {
+		survey.Title = body.Title
+	}
+	s.Surveys[survey.ID] = survey
+
+	questions, _ := s.ListQuestions(ctx, sourceID)
+	slices.SortFunc(questions, func(a, b database.Question) int { return int(a.Position - b.Position) })
+
+	ids := make(map[int64]int64, len(questions))
+	copies := make([]database.Question, 0, len(questions))
+	for i, question := range questions {
+		question.ID = int64(len(s.Questions) + 1)
+		question.SurveyID = survey.ID
+		question.Position = int32(i + 1)
+		ids[questions[i].ID] = question.ID
+		s.Questions[question.ID] = question
+		copies = append(copies, question)
+	}
+
+	for _, question := range copies {
+		logic, err := surveys.RemapLogic(question.Logic, ids)
+		if err != nil {
+			return database.Survey{}, err
+		}
+		question.Logic = logic
+		s.Questions[question.ID] = question
+	}
+
+	cells, _ := s.ListQuotaCells(ctx, sourceID)
+	for _, cell := range cells {
+		cell.ID = int64(len(s.Cells) + 1)
+		cell.SurveyID = survey.ID
+		cell.Filled = 0
+		cell.Held = 0
+		s.Cells[cell.ID] = cell
+	}
+
+	return survey, nil
+}
+
+func (s *StubSurveyStore) ImportSurvey(ctx context.Context, researcherID int64, body surveys.SurveyImport) (database.Survey, error) {
+	survey, err := s.CreateSurvey(ctx, researcherID, body.Survey)
+	if err != nil {
+		return database.Survey{}, err
+	}
+	survey.FieldIds = body.Survey.FieldIDs
+	survey.Targeting = body.Survey.Targeting
+	s.Surveys[survey.ID] = survey
+
+	ids := make(map[int64]int64, len(body.Questions))
+	for i, question := range body.Questions {
+		ids[question.ID] = int64(len(s.Questions) + 1)
+		question.ID = ids[question.ID]
+		question.SurveyID = survey.ID
+		question.Position = int32(i + 1)
+		s.Questions[question.ID] = question
+	}
+
+	for _, id := range ids {
+		question := s.Questions[id]
+		if question.Logic, err = surveys.RemapLogic(question.Logic, ids); err != nil {
+			return database.Survey{}, err
+		}
+		s.Questions[id] = question
+	}
+
+	for _, quota := range body.Quotas {
+		if _, err := s.CreateQuotaCell(ctx, survey.ID, quota); err != nil {
+			return database.Survey{}, err
+		}
+	}
+
+	return survey, nil
+}
+
+func (s *StubSurveyStore) ListFields(ctx context.Context, ids []int64) ([]database.Field, error) {
+	var data []database.Field
+	for _, id := range ids {
+		if name, exists := s.Fields[id]; exists {
+			data = append(data, database.Field{ID: id, Name: name})
+		}
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) FindFields(ctx context.Context, names []string) ([]database.Field, error) {
+	var data []database.Field
+	for id, name := range s.Fields {
+		if slices.Contains(names, name) {
+			data = append(data, database.Field{ID: id, Name: name})
+		}
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) SetTemplate(ctx context.Context, id, researcherID int64, template bool) (database.Survey, error) {
+	survey, err := s.GetSurvey(ctx, id, researcherID)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	survey.IsTemplate = template
+	s.Surveys[id] = survey
+	return survey, nil
+}
+
+func (s *StubSurveyStore) GetTemplate(ctx context.Context, id int64) (database.Survey, error) {
+	survey, exists := s.Surveys[id]
+	if !exists || !survey.IsTemplate {
+		return database.Survey{}, custom_errors.ErrNotFound
+	}
+
+	return survey, nil
+}
+
+func (s *StubSurveyStore) ListTemplates(ctx context.Context, filter surveys.TemplateFilter) ([]database.ListTemplatesRow, error) {
+	if s.ShouldFail {
+		return nil, errors.New("database error")
+	}
+
+	var data []database.ListTemplatesRow
+	for _, survey := range s.Surveys {
+		if !survey.IsTemplate || survey.ID <= filter.After {
+			continue
+		}
+
+		if len(filter.FieldIDs) > 0 && !slices.ContainsFunc(survey.FieldIds, func(id int64) bool { return slices.Contains(filter.FieldIDs, id) }) {
+			continue
+		}
+
+		search := strings.ToLower(filter.Search)
+		if search != "" && !strings.Contains(strings.ToLower(survey.Title), search) && !strings.Contains(strings.ToLower(survey.Description.String), search) {
+			continue
+		}
+
+		questions, _ := s.ListQuestions(ctx, survey.ID)
+		data = append(data, database.ListTemplatesRow{
+			ID:           survey.ID,
+			ResearcherID: survey.ResearcherID,
+			Title:        survey.Title,
+			Description:  survey.Description,
+			FieldIds:     survey.FieldIds,
+			Questions:    int32(len(questions)),
+		})
+	}
+
+	slices.SortFunc(data, func(a, b database.ListTemplatesRow) int { return int(a.ID - b.ID) })
+
+	if int32(len(data)) > filter.Limit {
+		data = data[:filter.Limit]
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) CreateQuestion(ctx context.Context, surveyID int64, body surveys.CreateQuestionBody) (database.Question, error) {
+	if s.ShouldFail {
+		return database.Question{}, errors.New("database error")
+	}
+
+	question := database.Question{
+		ID:       int64(len(s.Questions) + 1),
+		SurveyID: surveyID,
+		Position: int32(len(s.Questions) + 1),
+		Type:     database.QuestionType(body.Type),
+		Title:    body.Title,
+		Required: body.Required == nil || *body.Required,
+		Config:   body.Config,
+		Block:    pgtype.Text{String: body.Block, Valid: body.Block != ""},
+	}
+
+	s.Questions[question.ID] = question
+	return question, s.createVersion(surveyID)
+}
+
+func (s *StubSurveyStore) GetQuestion(ctx context.Context, surveyID, id int64) (database.Question, error) {
+	question, exists := s.Questions[id]
+	if !exists || question.SurveyID != surveyID || question.RemovedAt.Valid {
+		return database.Question{}, custom_errors.ErrNotFound
+	}
+
+	return question, nil
+}
+
+func (s *StubSurveyStore) ListQuestions(ctx context.Context, surveyID int64) ([]database.Question, error) {
+	var data []database.Question
+	for _, question := range s.Questions {
+		if question.SurveyID == surveyID && !question.RemovedAt.Valid {
+			data = append(data, question)
+		}
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) UpdateQuestion(ctx context.Context, surveyID, id int64, body surveys.UpdateQuestionBody) (database.Question, error) {
+	question, err := s.GetQuestion(ctx, surveyID, id)
+	if err != nil {
+		return database.Question{}, err
+	}
+
+	if body.Title != "" {
+		question.Title = body.Title
+	}
+
+	if len(body.Config) > 0 {
+		question.Config = body.Config
+	}
+
+	if body.Required != nil {
+		question.Required = *body.Required
+	}
+
+	if body.Block != nil {
+		question.Block = pgtype.Text{String: *body.Block, Valid: *body.Block != ""}
+	}
+
+	s.Questions[id] = question
+	return question, s.createVersion(surveyID)
+}
+
+func (s *StubSurveyStore) DeleteQuestion(ctx context.Context, surveyID, id int64) error {
+	if _, err := s.GetQuestion(ctx, surveyID, id); err != nil {
+		return err
+	}
+
+	delete(s.Questions, id)
+	return nil
+}
+
+func (s *StubSurveyStore) RemoveQuestion(ctx context.Context, surveyID, id int64) error {
+	question, err := s.GetQuestion(ctx, surveyID, id)
+	if err != nil {
+		return err
+	}
+
+	question.RemovedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	s.Questions[id] = question
+
+	return s.createVersion(surveyID)
+}
+
+// createVersion records the current questions of a survey as its next version, like the store
+// does after every change to a published survey.
+func (s *StubSurveyStore) createVersion(surveyID int64) error {
+	survey := s.Surveys[surveyID]
+	if survey.Status == database.SurveyStatusDraft {
+		return nil
+	}
+
+	questions, _ := s.ListQuestions(context.Background(), surveyID)
+	slices.SortFunc(questions, func(a, b database.Question) int {
+		return int(a.Position - b.Position)
+	})
+
+	recorded := make([]map[string]any, 0, len(questions))
+	for _, question := range questions {
+		recorded = append(recorded, map[string]any{
+			"id": question.ID, "survey_id": question.SurveyID, "position": question.Position, "type": question.Type,
+			"title": question.Title, "required": question.Required, "config": json.RawMessage(question.Config),
+			"block": question.Block,
+		})
+	}
+
+	raw, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+
+	survey.Version++
+	s.Surveys[surveyID] = survey
+	s.Versions[surveyID] = append(s.Versions[surveyID], database.SurveyVersion{SurveyID: surveyID, Version: survey.Version, Questions: raw})
+
+	return nil
+}
+
+func (s *StubSurveyStore) ListVersions(ctx context.Context, surveyID int64) ([]database.SurveyVersion, error) {
+	return s.Versions[surveyID], nil
+}
+
+func (s *StubSurveyStore) CountAnswerValues(ctx context.Context, questionID int64) ([]database.CountAnswerValuesRow, error) {
+	return s.AnswerValues[questionID], nil
+}
+
+func (s *StubSurveyStore) CreateQuotaCell(ctx context.Context, surveyID int64, body surveys.CreateQuotaCellBody) (database.QuotaCell, error) {
+	for _, cell := range s.Cells {
+		if cell.SurveyID == surveyID && cell.Name == body.Name {
+			return database.QuotaCell{}, custom_errors.ErrConflict
+		}
+	}
+
+	cell := database.QuotaCell{
+		ID:       int64(len(s.Cells) + 1),
+		SurveyID: surveyID,
+		Name:     body.Name,
+		Criteria: body.Criteria,
+		Capacity: body.Capacity,
+	}
+
+	s.Cells[cell.ID] = cell
+	return cell, nil
+}
+
+func (s *StubSurveyStore) ListQuotaCells(ctx context.Context, surveyID int64) ([]database.QuotaCell, error) {
+	return s.ListQuotaCellsForSurveys(ctx, []int64{surveyID})
+}
+
+func (s *StubSurveyStore) ListQuotaCellsForSurveys(ctx context.Context, surveyIDs []int64) ([]database.QuotaCell, error) {
+	var data []database.QuotaCell
+	for _, cell := range s.Cells {
+		if slices.Contains(surveyIDs, cell.SurveyID) {
+			data = append(data, cell)
+		}
+	}
+
+	return data, nil
+}
+
+func (s *StubSurveyStore) DeleteQuotaCell(ctx context.Context, surveyID, id int64) error {
+	cell, exists := s.Cells[id]
+	if !exists || cell.SurveyID != surveyID {
+		return custom_errors.ErrNotFound
+	}
+
+	delete(s.Cells, id)
+	return nil
+}
+
+func (s *StubSurveyStore) GetAudience(ctx context.Context, userID int64) (surveys.Audience, error) {
+	if s.ShouldFail {
+		return surveys.Audience{}, errors.New("database error")
+	}
+
+	return s.Audiences[userID], nil
+}
+
+func (s *StubSurveyStore) ListFeed(ctx context.Context, userID int64, cursor *surveys.FeedCursor, limit int32) ([]database.ListFeedSurveysRow, error) {
+	if s.ShouldFail {
+		return nil, errors.New("database error")
+	}
+
+	start := 0
+	if cursor != nil {
+		for i, survey := range s.Feed {
+			if survey.ID == cursor.ID {
+				start = i + 1
+			}
+		}
+	}
+
+	end := min(start+int(limit), len(s.Feed))
+	return s.Feed[start:end], nil
+}
+
+// ============================================================================
+// Test Helpers
+// ============================================================================
+
+func assertResponseCode(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("response code = %d, want %d", got, want)
+	}
+}
+
+func assertResponseStatus(t *testing.T, got map[string]interface{}, wantStatus string) {
+	t.Helper()
+	if got["status"] != wantStatus {
+		t.Errorf("status = %v, want %v", got["status"], wantStatus)
+	}
+}
+
+func newRequest(method, target string, body []byte, userID int, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+
+	claims := &tokens.Claims{
+		UserID: userID,
+		Email:  "researcher@example.com",
+		Role:   "researcher",
+	}
+	ctx := context.WithValue(req.Context(), "claims", claims)
+
+	routeCtx := chi.NewRouteContext()
+	for key, value := range params {
+		routeCtx.URLParams.Add(key, value)
+	}
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+
+	return req.WithContext(ctx)
+}
+
+// ============================================================================
+// CreateSurveyHandler Tests
+// ============================================================================
+
+func TestCreateSurveyHandler(t *testing.T) {
+
+	t.Run("creates a draft survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"title": "Sleep habits", "description": "How students sleep"}`)
+		req := newRequest(http.MethodPost, "/surveys", data, 1, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateSurveyHandler(rec, req)
+
+		var got map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+		assertResponseStatus(t, got, "success")
+
+		if store.Surveys[1].Status != database.SurveyStatusDraft {
+			t.Errorf("expected survey to be a draft, got %s", store.Surveys[1].Status)
+		}
+	})
+
+	t.Run("returns 400 when title is missing", func(t *testing.T) {
+		handler := &surveys.Handler{Store: NewStubSurveyStore()}
+
+		req := newRequest(http.MethodPost, "/surveys", []byte(`{"description": "no title"}`), 1, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 401 when userID is 0", func(t *testing.T) {
+		handler := &surveys.Handler{Store: NewStubSurveyStore()}
+
+		req := newRequest(http.MethodPost, "/surveys", []byte(`{"title": "Sleep habits"}`), 0, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusUnauthorized)
+	})
+}
+
+// ============================================================================
+// GetSurveyHandler Tests
+// ============================================================================
+
+func TestGetSurveyHandler(t *testing.T) {
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 2, Title: "Not yours"}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, "/surveys/1", nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 400 for a malformed id", func(t *testing.T) {
+		handler := &surveys.Handler{Store: NewStubSurveyStore()}
+
+		req := newRequest(http.MethodGet, "/surveys/abc", nil, 1, map[string]string{"surveyID": "abc"})
+		rec := httptest.NewRecorder()
+
+		handler.GetSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+// ============================================================================
+// UpdateSurveyHandler Tests
+// ============================================================================
+
+func TestUpdateSurveyHandler(t *testing.T) {
+
+	t.Run("updates a draft survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Title: "Old", Status: database.SurveyStatusDraft}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1", []byte(`{"title": "New"}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if store.Surveys[1].Title != "New" {
+			t.Errorf("expected title to be 'New', got '%s'", store.Surveys[1].Title)
+		}
+	})
+
+	t.Run("returns 409 for a published survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Title: "Old", Status: database.SurveyStatusPublished}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1", []byte(`{"title": "New"}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
+
+// ============================================================================
+// UpdateSurveyStatusHandler Tests
+// ============================================================================
+
+func TestUpdateSurveyStatusHandler(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		current  database.SurveyStatus
+		next     string
+		wantCode int
+	}{
+		{"publishes a draft", database.SurveyStatusDraft, "published", http.StatusOK},
+		{"closes a published survey", database.SurveyStatusPublished, "closed", http.StatusOK},
+		{"cancels a published survey", database.SurveyStatusPublished, "cancelled", http.StatusOK},
+		{"archives a closed survey", database.SurveyStatusClosed, "archived", http.StatusOK},
+		{"rejects closing a draft", database.SurveyStatusDraft, "closed", http.StatusConflict},
+		{"rejects reopening a closed survey", database.SurveyStatusClosed, "published", http.StatusConflict},
+		{"rejects leaving archived", database.SurveyStatusArchived, "draft", http.StatusConflict},
+		{"rejects unknown status", database.SurveyStatusDraft, "deleted", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStubSurveyStore()
+			store.Surveys[1] = fundedSurvey(tt.current)
+			store.Balances[1] = decimal.NewFromInt(1000)
+			handler := &surveys.Handler{Store: store}
+
+			data := []byte(`{"status": "` + tt.next + `"}`)
+			req := newRequest(http.MethodPatch, "/surveys/1/status", data, 1, map[string]string{"surveyID": "1"})
+			rec := httptest.NewRecorder()
+
+			handler.UpdateSurveyStatusHandler(rec, req)
+
+			assertResponseCode(t, rec.Code, tt.wantCode)
+
+			if tt.wantCode == http.StatusOK && string(store.Surveys[1].Status) != tt.next {
+				t.Errorf("status = %s, want %s", store.Surveys[1].Status, tt.next)
+			}
+		})
+	}
+}
+
+// fundedSurvey is a survey paying 2.50 for each of 10 responses.
+func fundedSurvey(status database.SurveyStatus) database.Survey {
+	return database.Survey{
+		ID:                1,
+		ResearcherID:      1,
+		Title:             "Survey",
+		Status:            status,
+		RewardPerResponse: database.NumericFromDecimal(decimal.RequireFromString("2.50")),
+		TargetResponses:   pgtype.Int4{Int32: 10, Valid: true},
+	}
+}
+
+func TestSurveyEscrow(t *testing.T) {
+
+	publish := func(store *StubSurveyStore) *httptest.ResponseRecorder {
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1/status", []byte(`{"status": "published"}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyStatusHandler(rec, req)
+		return rec
+	}
+
+	t.Run("moves reward and fees into escrow on publish", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		store.Balances[1] = decimal.NewFromInt(100)
+
+		rec := publish(store)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		// 10 x (2.50 reward + 0.25 fee)
+		want := decimal.RequireFromString("27.50")
+		if got := database.DecimalFromNumeric(store.Escrows[1].Amount); !got.Equal(want) {
+			t.Errorf("escrow = %s, want %s", got, want)
+		}
+
+		if got := store.Balances[1]; !got.Equal(decimal.RequireFromString("72.50")) {
+			t.Errorf("balance = %s, want 72.50", got)
+		}
+	})
+
+	t.Run("returns 402 when the wallet can't cover the budget", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		store.Balances[1] = decimal.NewFromInt(20)
+
+		rec := publish(store)
+
+		assertResponseCode(t, rec.Code, http.StatusPaymentRequired)
+
+		if store.Surveys[1].Status != database.SurveyStatusDraft {
+			t.Errorf("status = %s, want draft", store.Surveys[1].Status)
+		}
+	})
+
+	t.Run("returns 400 when the survey has no budget", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Title: "Survey", Status: database.SurveyStatusDraft}
+		store.Balances[1] = decimal.NewFromInt(100)
+
+		rec := publish(store)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 when the screen-out fee is not below the reward", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		survey := fundedSurvey(database.SurveyStatusDraft)
+		survey.ScreenOutFee = database.NumericFromDecimal(decimal.RequireFromString("2.50"))
+		store.Surveys[1] = survey
+		store.Balances[1] = decimal.NewFromInt(100)
+
+		rec := publish(store)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("refunds what is left when the survey closes", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		store.Balances[1] = decimal.NewFromInt(100)
+
+		publish(store)
+
+		escrow := store.Escrows[1]
+		escrow.Spent = database.NumericFromDecimal(decimal.RequireFromString("5.50"))
+		store.Escrows[1] = escrow
+
+		handler := &surveys.Handler{Store: store}
+		req := newRequest(http.MethodPatch, "/surveys/1/status", []byte(`{"status": "closed"}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyStatusHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got := store.Balances[1]; !got.Equal(decimal.RequireFromString("94.50")) {
+			t.Errorf("balance = %s, want 94.50", got)
+		}
+	})
+}
+
+// ============================================================================
+// Organization Survey Tests
+// ============================================================================
+
+func TestOrganizationSurveys(t *testing.T) {
+
+	// newOrganizationStore has researcher 1 own organization 7, where researcher 2 is an editor and
+	// researcher 3 a viewer, and a funded draft of it
+	newOrganizationStore := func() *StubSurveyStore {
+		store := NewStubSurveyStore()
+		store.Members[7] = map[int64]database.OrganizationRole{
+			1: database.OrganizationRoleOwner,
+			2: database.OrganizationRoleEditor,
+			3: database.OrganizationRoleViewer,
+		}
+		store.OrganizationBalances[7] = decimal.NewFromInt(100)
+
+		survey := fundedSurvey(database.SurveyStatusDraft)
+		survey.OrganizationID = pgtype.Int8{Int64: 7, Valid: true}
+		store.Surveys[1] = survey
+
+		return store
+	}
+
+	create := func(store *StubSurveyStore, userID int) *httptest.ResponseRecorder {
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPost, "/surveys", []byte(`{"title": "Team survey", "organization_id": 7}`), userID, nil)
+		rec := httptest.NewRecorder()
+
+		handler.CreateSurveyHandler(rec, req)
+		return rec
+	}
+
+	t.Run("creates a survey in the organization of an editor", func(t *testing.T) {
+		store := newOrganizationStore()
+
+		rec := create(store, 2)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if survey := store.Surveys[2]; survey.OrganizationID.Int64 != 7 {
+			t.Errorf("organization = %+v, want 7", survey.OrganizationID)
+		}
+	})
+
+	t.Run("returns 403 when a viewer creates a survey", func(t *testing.T) {
+		rec := create(newOrganizationStore(), 3)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 404 for an organization the researcher isn't in", func(t *testing.T) {
+		rec := create(newOrganizationStore(), 4)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("publishes a member's survey from the organization's wallet", func(t *testing.T) {
+		store := newOrganizationStore()
+		store.Balances[2] = decimal.NewFromInt(100)
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1/status", []byte(`{"status": "published"}`), 2, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyStatusHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if got := store.OrganizationBalances[7]; !got.Equal(decimal.RequireFromString("72.50")) {
+			t.Errorf("organization balance = %s, want 72.50", got)
+		}
+
+		if got := store.Balances[2]; !got.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("balance = %s, want the researcher's wallet untouched", got)
+		}
+	})
+
+	t.Run("lets a viewer see the organization's surveys", func(t *testing.T) {
+		handler := &surveys.Handler{Store: newOrganizationStore()}
+
+		req := newRequest(http.MethodGet, "/surveys/1", nil, 3, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+	})
+
+	t.Run("returns 403 when a viewer changes a survey", func(t *testing.T) {
+		store := newOrganizationStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1", []byte(`{"title": "New"}`), 3, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+
+		if store.Surveys[1].Title != "Survey" {
+			t.Errorf("title = %q, want it unchanged", store.Surveys[1].Title)
+		}
+	})
+
+	t.Run("returns 403 when a viewer adds a question", func(t *testing.T) {
+		handler := &surveys.Handler{Store: newOrganizationStore()}
+
+		data := []byte(`{"type": "text", "title": "Anything else?"}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 3, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusForbidden)
+	})
+}
+
+// ============================================================================
+// Quota Cell Tests
+// ============================================================================
+
+func TestQuotaCellHandlers(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1"}
+
+	t.Run("creates a quota cell on a draft survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"name": "Female engineers", "criteria": {"genders": ["female"], "faculties": ["Engineering"]}, "capacity": 50}`)
+		req := newRequest(http.MethodPost, "/surveys/1/quotas", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuotaCellHandler(rec, req)
+
+		var got struct {
+			Data surveys.QuotaCell `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if got.Data.Capacity != 50 || got.Data.Full {
+			t.Errorf("cell = %+v, want an open cell of 50", got.Data)
+		}
+	})
+
+	t.Run("returns 400 for a cell without criteria", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusDraft)
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"name": "Everyone", "criteria": {}, "capacity": 50}`)
+		req := newRequest(http.MethodPost, "/surveys/1/quotas", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuotaCellHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 409 once the survey is published", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusPublished)
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"name": "Women", "criteria": {"genders": ["female"]}, "capacity": 50}`)
+		req := newRequest(http.MethodPost, "/surveys/1/quotas", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuotaCellHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
+
+// ============================================================================
+// GetSurveyStatsHandler Tests
+// ============================================================================
+
+func TestGetSurveyStatsHandler(t *testing.T) {
+
+	t.Run("reports the screen-out rate of finished attempts", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusPublished)
+		store.Stats[1] = database.GetSurveyStatsRow{
+			Started:           10,
+			InProgress:        2,
+			Submitted:         6,
+			ScreenedOut:       2,
+			RewardsPaid:       database.NumericFromDecimal(decimal.RequireFromString("15.00")),
+			ScreenOutFeesPaid: database.NumericFromDecimal(decimal.RequireFromString("1.00")),
+		}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, "/surveys/1/stats", nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetSurveyStatsHandler(rec, req)
+
+		var got struct {
+			Data surveys.SurveyStats `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if !got.Data.ScreenOutRate.Equal(decimal.RequireFromString("0.25")) {
+			t.Errorf("screen_out_rate = %s, want 0.25", got.Data.ScreenOutRate)
+		}
+	})
+
+	t.Run("returns 404 for another researcher's survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = fundedSurvey(database.SurveyStatusPublished)
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodGet, "/surveys/1/stats", nil, 2, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.GetSurveyStatsHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusNotFound)
+	})
+}
+
+// ============================================================================
+// FeedHandler Tests
+// ============================================================================
+
+func feedSurvey(id int64, overlap int32, targeting string) database.ListFeedSurveysRow {
+	survey := database.ListFeedSurveysRow{
+		ID:                id,
+		Title:             "Survey",
+		RewardPerResponse: database.NumericFromDecimal(decimal.RequireFromString("5.00")),
+		Overlap:           overlap,
+		Minutes:           5,
+		RewardPerMinute:   database.NumericFromDecimal(decimal.RequireFromString("1.0000")),
+	}
+
+	if targeting != "" {
+		survey.Targeting = []byte(targeting)
+	}
+
+	return survey
+}
+
+func TestFeedHandler(t *testing.T) {
+
+	newFeedStore := func() *StubSurveyStore {
+		store := NewStubSurveyStore()
+		store.Feed = []database.ListFeedSurveysRow{
+			feedSurvey(3, 2, ""),
+			feedSurvey(1, 1, `{"genders": ["male"]}`),
+			feedSurvey(2, 0, `{"genders": ["female"]}`),
+		}
+		store.Audiences[1] = surveys.Audience{
+			Profile: database.Profile{Gender: database.NullGender{Gender: database.GenderFemale, Valid: true}},
+		}
+		return store
+	}
+
+	getFeed := func(t *testing.T, handler *surveys.Handler, target string) (int, surveys.Feed) {
+		t.Helper()
+
+		req := newRequest(http.MethodGet, target, nil, 1, nil)
+		rec := httptest.NewRecorder()
+
+		handler.FeedHandler(rec, req)
+
+		var got struct {
+			Data surveys.Feed `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		return rec.Code, got.Data
+	}
+
+	t.Run("lists only surveys the user is eligible for, in ranking order", func(t *testing.T) {
+		handler := &surveys.Handler{Store: newFeedStore()}
+
+		code, feed := getFeed(t, handler, "/surveys/feed")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if len(feed.Surveys) != 2 || feed.Surveys[0].ID != 3 || feed.Surveys[1].ID != 2 {
+			t.Fatalf("surveys = %+v, want surveys 3 and 2", feed.Surveys)
+		}
+
+		if feed.NextCursor != "" {
+			t.Errorf("next_cursor = %q, want none on the last page", feed.NextCursor)
+		}
+	})
+
+	t.Run("pages with a cursor past ineligible surveys", func(t *testing.T) {
+		handler := &surveys.Handler{Store: newFeedStore()}
+
+		code, first := getFeed(t, handler, "/surveys/feed?limit=1")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if len(first.Surveys) != 1 || first.Surveys[0].ID != 3 || first.NextCursor == "" {
+			t.Fatalf("first page = %+v, want survey 3 and a cursor", first)
+		}
+
+		code, second := getFeed(t, handler, "/surveys/feed?limit=1&cursor="+first.NextCursor)
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if len(second.Surveys) != 1 || second.Surveys[0].ID != 2 {
+			t.Fatalf("second page = %+v, want survey 2", second)
+		}
+	})
+
+	t.Run("leaves out surveys whose quota cell for the user is full", func(t *testing.T) {
+		store := newFeedStore()
+		store.Cells[1] = database.QuotaCell{ID: 1, SurveyID: 3, Name: "women", Criteria: []byte(`{"genders": ["female"]}`), Capacity: 5, Filled: 5}
+		handler := &surveys.Handler{Store: store}
+
+		code, feed := getFeed(t, handler, "/surveys/feed")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if len(feed.Surveys) != 1 || feed.Surveys[0].ID != 2 {
+			t.Fatalf("surveys = %+v, want survey 2", feed.Surveys)
+		}
+	})
+
+	t.Run("shows each survey in the locale the user prefers", func(t *testing.T) {
+		store := newFeedStore()
+		store.Feed[0].DefaultLocale, store.Feed[0].Locales = "en", []string{"fr"}
+		store.Feed[0].Translations = []byte(`{"fr": {"title": "Enquête"}}`)
+		audience := store.Audiences[1]
+		audience.Profile.Locale = pgtype.Text{String: "fr-CA", Valid: true}
+		store.Audiences[1] = audience
+		handler := &surveys.Handler{Store: store}
+
+		code, feed := getFeed(t, handler, "/surveys/feed")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if feed.Surveys[0].Title != "Enquête" || feed.Surveys[0].Locale != "fr" {
+			t.Errorf("survey = %+v, want the French title", feed.Surveys[0])
+		}
+
+		code, feed = getFeed(t, handler, "/surveys/feed?locale=en")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if feed.Surveys[0].Title != "Survey" || feed.Surveys[0].Locale != "en" {
+			t.Errorf("survey = %+v, want the locale asked for over the profile", feed.Surveys[0])
+		}
+	})
+
+	t.Run("returns 400 for a bad cursor or limit", func(t *testing.T) {
+		handler := &surveys.Handler{Store: newFeedStore()}
+
+		code, _ := getFeed(t, handler, "/surveys/feed?cursor=not-a-cursor")
+		assertResponseCode(t, code, http.StatusBadRequest)
+
+		code, _ = getFeed(t, handler, "/surveys/feed?limit=500")
+		assertResponseCode(t, code, http.StatusBadRequest)
+	})
+}
+
+// ============================================================================
+// DeleteSurveyHandler Tests
+// ============================================================================
+
+func TestDeleteSurveyHandler(t *testing.T) {
+
+	t.Run("deletes a draft survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusDraft}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodDelete, "/surveys/1", nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.DeleteSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if _, exists := store.Surveys[1]; exists {
+			t.Error("expected survey to be deleted")
+		}
+	})
+
+	t.Run("returns 409 for a published survey", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusPublished}
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodDelete, "/surveys/1", nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.DeleteSurveyHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
+
+// ============================================================================
+// CreateQuestionHandler Tests
+// ============================================================================
+
+func TestCreateQuestionHandler(t *testing.T) {
+
+	t.Run("creates a question with a valid config", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusDraft}
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{
+			"type": "single_choice",
+			"title": "Do you own a laptop?",
+			"config": {"options": [{"id": "yes", "label": "Yes"}, {"id": "no", "label": "No"}]}
+		}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if len(store.Questions) != 1 {
+			t.Fatalf("expected 1 question, got %d", len(store.Questions))
+		}
+	})
+
+	t.Run("returns 400 for a config that does not match the type", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusDraft}
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"type": "likert", "title": "Rate us", "config": {"options": []}}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 for malformed logic", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusDraft}
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{
+			"type": "text",
+			"title": "Which other way?",
+			"config": {"max_length": 200},
+			"logic": {"skip": [{"when": {"question_id": 1, "operator": "answered"}}]}
+		}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("makes a new version when the survey is published", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusPublished, Version: 1}
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"type": "text", "title": "Anything else?", "config": {"max_length": 200}}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusCreated)
+
+		if version := store.Surveys[1].Version; version != 2 {
+			t.Errorf("version = %d, want 2", version)
+		}
+	})
+
+	t.Run("returns 409 when the survey is closed", func(t *testing.T) {
+		store := NewStubSurveyStore()
+		store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusClosed}
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"type": "text", "title": "Anything else?", "config": {"max_length": 200}}`)
+		req := newRequest(http.MethodPost, "/surveys/1/questions", data, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.CreateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
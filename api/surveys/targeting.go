@@ -0,0 +1,173 @@
+package surveys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Targeting is the set of eligibility rules a researcher attaches to a survey, stored in
+// surveys.targeting. Every rule that is set must hold for a user to take the survey; a rule that
+// is left empty lets everyone through.
+type Targeting struct {
+	MinAge       int      `json:"min_age,omitempty" validate:"omitempty,gte=13,lte=120"`
+	MaxAge       int      `json:"max_age,omitempty" validate:"omitempty,gte=13,lte=120"`
+	Genders      []string `json:"genders,omitempty" validate:"omitempty,unique,dive,oneof=male female prefer_not_to_say"`
+	Universities []string `json:"universities,omitempty" validate:"omitempty,dive,required,max=255"`
+	Faculties    []string `json:"faculties,omitempty" validate:"omitempty,dive,required,max=255"`
+	Locations    []string `json:"locations,omitempty" validate:"omitempty,dive,required,max=255"`
+	// RequiredFields lists interest areas a user must all have, ExcludedFields ones they must not have.
+	RequiredFields []int64 `json:"required_fields,omitempty" validate:"omitempty,unique,dive,gt=0"`
+	ExcludedFields []int64 `json:"excluded_fields,omitempty" validate:"omitempty,unique,dive,gt=0"`
+}
+
+// Audience is what the targeting rules are checked against: a user's profile and the fields of
+// their interest areas.
+type Audience struct {
+	Profile  database.Profile
+	FieldIDs []int64
+}
+
+// ErrNotEligible is wrapped by the errors Eligible returns, each carrying the reason a user can't
+// take a survey.
+var ErrNotEligible = errors.New("you are not eligible for this survey")
+
+// ParseTargeting decodes and validates the targeting rules of a survey.
+func ParseTargeting(raw json.RawMessage) (Targeting, error) {
+	return parseRules("targeting", raw)
+}
+
+// parseRules decodes and validates a set of targeting rules, naming the fields in its errors
+// after path.
+func parseRules(path string, raw json.RawMessage) (Targeting, error) {
+	var targeting Targeting
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return targeting, nil
+	}
+
+	if err := decodeStrict(raw, &targeting); err != nil {
+		return Targeting{}, fmt.Errorf("invalid %s: %v", path, err)
+	}
+
+	if err := jsonutil.Validate(targeting); err != nil {
+		return Targeting{}, jsonutil.ValidationErrors(prefixErrors(path, err))
+	}
+
+	if targeting.MinAge > 0 && targeting.MaxAge > 0 && targeting.MaxAge < targeting.MinAge {
+		return Targeting{}, fieldError(path+".max_age", fmt.Sprintf("max_age must be greater than or equal to %d", targeting.MinAge))
+	}
+
+	for _, fieldID := range targeting.RequiredFields {
+		if slices.Contains(targeting.ExcludedFields, fieldID) {
+			return Targeting{}, fieldError(path+".excluded_fields", fmt.Sprintf("field %d can't be both required and excluded", fieldID))
+		}
+	}
+
+	return targeting, nil
+}
+
+// IsEmpty reports whether the targeting lets every user through.
+func (t Targeting) IsEmpty() bool {
+	return t.MinAge == 0 && t.MaxAge == 0 && len(t.Genders) == 0 && len(t.Universities) == 0 &&
+		len(t.Faculties) == 0 && len(t.Locations) == 0 && len(t.RequiredFields) == 0 && len(t.ExcludedFields) == 0
+}
+
+// Eligible checks an audience against the targeting rules as of now. It returns nil when the
+// user may take the survey, otherwise an error wrapping ErrNotEligible that says which rule failed.
+func (t Targeting) Eligible(audience Audience, now time.Time) error {
+	profile := audience.Profile
+
+	if t.MinAge > 0 || t.MaxAge > 0 {
+		if !profile.DateOfBirth.Valid {
+			return notEligible("add your date of birth to your profile to take this survey")
+		}
+
+		age := ageOn(profile.DateOfBirth.Time, now)
+
+		if t.MinAge > 0 && age < t.MinAge {
+			return notEligible(fmt.Sprintf("this survey is for people aged %d or older", t.MinAge))
+		}
+
+		if t.MaxAge > 0 && age > t.MaxAge {
+			return notEligible(fmt.Sprintf("this survey is for people aged %d or younger", t.MaxAge))
+		}
+	}
+
+	if len(t.Genders) > 0 {
+		if !profile.Gender.Valid {
+			return notEligible("add your gender to your profile to take this survey")
+		}
+
+		if !slices.Contains(t.Genders, string(profile.Gender.Gender)) {
+			return notEligible("this survey is for a different gender group")
+		}
+	}
+
+	if err := matchesOneOf("university", t.Universities, profile.University.String); err != nil {
+		return err
+	}
+
+	if err := matchesOneOf("faculty", t.Faculties, profile.Faculty.String); err != nil {
+		return err
+	}
+
+	if err := matchesOneOf("location", t.Locations, profile.Location.String); err != nil {
+		return err
+	}
+
+	for _, fieldID := range t.RequiredFields {
+		if !slices.Contains(audience.FieldIDs, fieldID) {
+			return notEligible("this survey is for people with interest areas you haven't added")
+		}
+	}
+
+	for _, fieldID := range t.ExcludedFields {
+		if slices.Contains(audience.FieldIDs, fieldID) {
+			return notEligible("this survey excludes one of your interest areas")
+		}
+	}
+
+	return nil
+}
+
+// matchesOneOf checks a profile attribute against a list of allowed values, ignoring case and
+// surrounding spaces.
+func matchesOneOf(attribute string, allowed []string, value string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return notEligible(fmt.Sprintf("add your %s to your profile to take this survey", attribute))
+	}
+
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return nil
+		}
+	}
+
+	return notEligible(fmt.Sprintf("this survey is not open to your %s", attribute))
+}
+
+func notEligible(reason string) error {
+	return fmt.Errorf("%w: %s", ErrNotEligible, reason)
+}
+
+// ageOn returns the age in whole years of someone born on dateOfBirth, on the given day.
+func ageOn(dateOfBirth, now time.Time) int {
+	age := now.Year() - dateOfBirth.Year()
+
+	if now.Month() < dateOfBirth.Month() || (now.Month() == dateOfBirth.Month() && now.Day() < dateOfBirth.Day()) {
+		age--
+	}
+
+	return age
+}
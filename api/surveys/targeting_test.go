@@ -0,0 +1,93 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTargeting(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		targeting string
+		wantErr   string
+	}{
+		{"no rules", ``, ""},
+		{"valid rules", `{"min_age": 18, "max_age": 30, "genders": ["female"], "required_fields": [1, 2]}`, ""},
+		{"unknown gender", `{"genders": ["other"]}`, "targeting.genders[0]: genders[0] must be one of"},
+		{"max age below min age", `{"min_age": 30, "max_age": 18}`, "max_age must be greater than or equal to 30"},
+		{"field both required and excluded", `{"required_fields": [3], "excluded_fields": [3]}`, "field 3 can't be both required and excluded"},
+		{"unknown rule", `{"min_height": 180}`, "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := surveys.ParseTargeting(json.RawMessage(tt.targeting))
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTargetingEligible(t *testing.T) {
+
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	student := surveys.Audience{
+		Profile: database.Profile{
+			DateOfBirth: pgtype.Date{Time: time.Date(2006, time.October, 18, 0, 0, 0, 0, time.UTC), Valid: true},
+			Gender:      database.NullGender{Gender: database.GenderFemale, Valid: true},
+			Faculty:     pgtype.Text{String: "Engineering", Valid: true},
+		},
+		FieldIDs: []int64{1, 4},
+	}
+
+	tests := []struct {
+		name      string
+		targeting surveys.Targeting
+		audience  surveys.Audience
+		wantErr   string
+	}{
+		{"no rules", surveys.Targeting{}, student, ""},
+		{"age is counted in whole years", surveys.Targeting{MinAge: 20}, student, "this survey is for people aged 20 or older"},
+		{"age within range", surveys.Targeting{MinAge: 18, MaxAge: 19}, student, ""},
+		{"gender outside the set", surveys.Targeting{Genders: []string{"male"}}, student, "this survey is for a different gender group"},
+		{"faculty ignores case", surveys.Targeting{Faculties: []string{"engineering"}}, student, ""},
+		{"missing location", surveys.Targeting{Locations: []string{"Lagos"}}, student, "add your location to your profile"},
+		{"missing required field", surveys.Targeting{RequiredFields: []int64{1, 2}}, student, "interest areas you haven't added"},
+		{"excluded field", surveys.Targeting{ExcludedFields: []int64{4}}, student, "excludes one of your interest areas"},
+		{"missing date of birth", surveys.Targeting{MaxAge: 30}, surveys.Audience{}, "add your date of birth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targeting.Eligible(tt.audience, now)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, surveys.ErrNotEligible) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
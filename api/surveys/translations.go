@@ -0,0 +1,627 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/api/tokens"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/text/language"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxMissingListed caps how many missing strings an error names, the rest are counted.
+const maxMissingListed = 10
+
+// SurveyTranslation holds the strings of a survey in one locale.
+type SurveyTranslation struct {
+	Title       string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Description string `json:"description,omitempty"`
+}
+
+// QuestionTranslation holds the strings of a question in one locale. Options, rows and columns are
+// keyed by the ID of the option they label, likert labels go in the order of the points.
+type QuestionTranslation struct {
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Options     map[string]string `json:"options,omitempty" validate:"omitempty,dive,max=255"`
+	Rows        map[string]string `json:"rows,omitempty" validate:"omitempty,dive,max=255"`
+	Columns     map[string]string `json:"columns,omitempty" validate:"omitempty,dive,max=255"`
+	Labels      []string          `json:"labels,omitempty" validate:"omitempty,dive,max=255"`
+}
+
+// ParseLocale checks a locale and returns it in its canonical form, fr-CA for fr_ca and the like,
+// so the same locale is always stored the same way.
+func ParseLocale(value string) (string, error) {
+	tag, err := language.Parse(value)
+	if err != nil || tag == language.Und {
+		return "", fieldError("locale", fmt.Sprintf("%q is not a valid locale", value))
+	}
+
+	return tag.String(), nil
+}
+
+// Languages lists the languages a respondent would like to read a survey in, most preferred first:
+// the locale asked for on the request, the one saved in their profile, then those their browser
+// accepts. Any that don't parse are skipped.
+func Languages(asked, preferred, acceptLanguage string) []language.Tag {
+	var wants []language.Tag
+
+	for _, locale := range []string{asked, preferred} {
+		if tag, err := language.Parse(locale); err == nil && locale != "" {
+			wants = append(wants, tag)
+		}
+	}
+
+	if accepted, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil {
+		wants = append(wants, accepted...)
+	}
+
+	return wants
+}
+
+// NegotiateLocale picks the locale of the survey that best matches the languages a respondent
+// wants, falling back to the survey's default locale when none of them come close.
+func NegotiateLocale(survey database.Survey, wants []language.Tag) string {
+	return negotiate(survey.DefaultLocale, survey.Locales, wants)
+}
+
+func negotiate(defaultLocale string, locales []string, wants []language.Tag) string {
+	if len(locales) == 0 || len(wants) == 0 {
+		return defaultLocale
+	}
+
+	names := make([]string, 0, len(locales)+1)
+	supported := make([]language.Tag, 0, len(locales)+1)
+	for _, locale := range append([]string{defaultLocale}, locales...) {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			continue
+		}
+		names = append(names, locale)
+		supported = append(supported, tag)
+	}
+
+	if len(supported) == 0 {
+		return defaultLocale
+	}
+
+	_, index, confidence := language.NewMatcher(supported).Match(wants...)
+	if confidence == language.No {
+		return defaultLocale
+	}
+
+	return names[index]
+}
+
+func surveyTranslations(raw []byte) (map[string]SurveyTranslation, error) {
+	translations := map[string]SurveyTranslation{}
+	if len(raw) == 0 {
+		return translations, nil
+	}
+
+	if err := json.Unmarshal(raw, &translations); err != nil {
+		return nil, fmt.Errorf("invalid survey translations: %v", err)
+	}
+
+	return translations, nil
+}
+
+func questionTranslations(question database.Question) (map[string]QuestionTranslation, error) {
+	translations := map[string]QuestionTranslation{}
+	if len(question.Translations) == 0 {
+		return translations, nil
+	}
+
+	if err := json.Unmarshal(question.Translations, &translations); err != nil {
+		return nil, fmt.Errorf("question %d: invalid translations: %v", question.ID, err)
+	}
+
+	return translations, nil
+}
+
+// LocalizeSurvey returns the survey with its title and description in the given locale. A string
+// with no translation, like every string in the default locale, stays as it was written.
+func LocalizeSurvey(survey database.Survey, locale string) (database.Survey, error) {
+	translations, err := surveyTranslations(survey.Translations)
+	if err != nil {
+		return database.Survey{}, err
+	}
+
+	translation, ok := translations[locale]
+	if !ok {
+		return survey, nil
+	}
+
+	if translation.Title != "" {
+		survey.Title = translation.Title
+	}
+	if translation.Description != "" && survey.Description.Valid {
+		survey.Description.String = translation.Description
+	}
+
+	return survey, nil
+}
+
+// LocalizeQuestions returns the questions with their text and the labels in their config in the
+// given locale, falling back string by string like LocalizeSurvey. Option IDs never change, so the
+// answers given to a localized question are checked and stored as for any other.
+func LocalizeQuestions(questions []database.Question, locale string) ([]database.Question, error) {
+	localized := make([]database.Question, 0, len(questions))
+
+	for _, question := range questions {
+		translations, err := questionTranslations(question)
+		if err != nil {
+			return nil, err
+		}
+
+		translation, ok := translations[locale]
+		if !ok {
+			localized = append(localized, question)
+			continue
+		}
+
+		if translation.Title != "" {
+			question.Title = translation.Title
+		}
+		if translation.Description != "" && question.Description.Valid {
+			question.Description.String = translation.Description
+		}
+
+		config, err := ParseQuestionConfig(question.Type, question.Config)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		switch c := config.(type) {
+		case *SingleChoiceConfig:
+			translateOptions(c.Options, translation.Options)
+		case *MultipleChoiceConfig:
+			translateOptions(c.Options, translation.Options)
+		case *RankingConfig:
+			translateOptions(c.Options, translation.Options)
+		case *MatrixConfig:
+			translateOptions(c.Rows, translation.Rows)
+			translateOptions(c.Columns, translation.Columns)
+		case *LikertConfig:
+			if len(translation.Labels) == len(c.Labels) {
+				for i, label := range translation.Labels {
+					if label != "" {
+						c.Labels[i] = label
+					}
+				}
+			}
+		}
+
+		question.Config, err = json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+
+		localized = append(localized, question)
+	}
+
+	return localized, nil
+}
+
+func translateOptions(options []Option, labels map[string]string) {
+	for i, option := range options {
+		if label := labels[option.ID]; label != "" {
+			options[i].Label = label
+		}
+	}
+}
+
+// MissingTranslations lists the strings of a survey and its questions that are not yet translated
+// into every locale it is offered in, like "fr: questions.3.options.bus". A description only needs
+// a translation when there is one to translate.
+func MissingTranslations(survey database.Survey, questions []database.Question) ([]string, error) {
+	translations, err := surveyTranslations(survey.Translations)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+
+	for _, locale := range survey.Locales {
+		translation := translations[locale]
+
+		if translation.Title == "" {
+			missing = append(missing, locale+": title")
+		}
+		if translation.Description == "" && survey.Description.String != "" {
+			missing = append(missing, locale+": description")
+		}
+	}
+
+	for _, question := range questions {
+		translations, err := questionTranslations(question)
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := ParseQuestionConfig(question.Type, question.Config)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		for _, locale := range survey.Locales {
+			translation := translations[locale]
+			prefix := fmt.Sprintf("%s: questions.%d.", locale, question.ID)
+
+			if translation.Title == "" {
+				missing = append(missing, prefix+"title")
+			}
+			if translation.Description == "" && question.Description.String != "" {
+				missing = append(missing, prefix+"description")
+			}
+
+			switch c := config.(type) {
+			case *SingleChoiceConfig:
+				missing = append(missing, missingLabels(prefix+"options.", c.Options, translation.Options)...)
+			case *MultipleChoiceConfig:
+				missing = append(missing, missingLabels(prefix+"options.", c.Options, translation.Options)...)
+			case *RankingConfig:
+				missing = append(missing, missingLabels(prefix+"options.", c.Options, translation.Options)...)
+			case *MatrixConfig:
+				missing = append(missing, missingLabels(prefix+"rows.", c.Rows, translation.Rows)...)
+				missing = append(missing, missingLabels(prefix+"columns.", c.Columns, translation.Columns)...)
+			case *LikertConfig:
+				for i := range c.Labels {
+					if i >= len(translation.Labels) || translation.Labels[i] == "" {
+						missing = append(missing, fmt.Sprintf("%slabels.%d", prefix, i))
+					}
+				}
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+func missingLabels(prefix string, options []Option, labels map[string]string) []string {
+	var missing []string
+	for _, option := range options {
+		if labels[option.ID] == "" {
+			missing = append(missing, prefix+option.ID)
+		}
+	}
+
+	return missing
+}
+
+// CheckTranslated makes sure a survey has every string translated into every locale it is offered
+// in, which it must before it is published.
+func CheckTranslated(survey database.Survey, questions []database.Question) error {
+	missing, err := MissingTranslations(survey, questions)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	listed := missing[:min(len(missing), maxMissingListed)]
+	message := fmt.Sprintf("%d strings still need a translation: %s", len(missing), strings.Join(listed, ", "))
+	if len(missing) > len(listed) {
+		message += fmt.Sprintf(" and %d more", len(missing)-len(listed))
+	}
+
+	return errors.New(message)
+}
+
+// CheckTranslation makes sure a translation only holds strings the survey has: each question must
+// be one the survey asks, each option, row and column one the question has, and likert labels as
+// many as the question's.
+func CheckTranslation(questions []database.Question, body TranslationBody) error {
+	var errs jsonutil.ValidationErrors
+
+	byID := make(map[int64]database.Question, len(questions))
+	for _, question := range questions {
+		byID[question.ID] = question
+	}
+
+	for id, translation := range body.Questions {
+		question, ok := byID[id]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("questions.%d: the survey has no such question", id))
+			continue
+		}
+
+		config, err := ParseQuestionConfig(question.Type, question.Config)
+		if err != nil {
+			return fmt.Errorf("question %d: %v", question.ID, err)
+		}
+
+		var options, rows, columns []Option
+		var labels int
+
+		switch c := config.(type) {
+		case *SingleChoiceConfig:
+			options = c.Options
+		case *MultipleChoiceConfig:
+			options = c.Options
+		case *RankingConfig:
+			options = c.Options
+		case *MatrixConfig:
+			rows, columns = c.Rows, c.Columns
+		case *LikertConfig:
+			labels = len(c.Labels)
+		}
+
+		prefix := fmt.Sprintf("questions.%d.", id)
+		errs = append(errs, unknownLabels(prefix+"options", options, translation.Options)...)
+		errs = append(errs, unknownLabels(prefix+"rows", rows, translation.Rows)...)
+		errs = append(errs, unknownLabels(prefix+"columns", columns, translation.Columns)...)
+
+		if len(translation.Labels) > 0 && len(translation.Labels) != labels {
+			errs = append(errs, fmt.Sprintf("%slabels: labels must be exactly %d items", prefix, labels))
+		}
+	}
+
+	if len(errs) > 0 {
+		slices.Sort(errs)
+		return errs
+	}
+
+	return nil
+}
+
+func unknownLabels(field string, options []Option, labels map[string]string) []string {
+	var unknown []string
+	for id := range labels {
+		if !slices.ContainsFunc(options, func(option Option) bool { return option.ID == id }) {
+			unknown = append(unknown, fmt.Sprintf("%s.%s: the question has no such option", field, id))
+		}
+	}
+
+	return unknown
+}
+
+// NewTranslations gathers the strings of a survey and its questions by locale, with those still
+// missing.
+func NewTranslations(survey database.Survey, questions []database.Question) (Translations, error) {
+	surveyStrings, err := surveyTranslations(survey.Translations)
+	if err != nil {
+		return Translations{}, err
+	}
+
+	data := Translations{
+		DefaultLocale: survey.DefaultLocale,
+		Locales:       survey.Locales,
+		Survey:        surveyStrings,
+		Questions:     make(map[int64]map[string]QuestionTranslation, len(questions)),
+		Missing:       []string{},
+	}
+
+	if data.Locales == nil {
+		data.Locales = []string{}
+	}
+
+	for _, question := range questions {
+		data.Questions[question.ID], err = questionTranslations(question)
+		if err != nil {
+			return Translations{}, err
+		}
+	}
+
+	missing, err := MissingTranslations(survey, questions)
+	if err != nil {
+		return Translations{}, err
+	}
+	data.Missing = append(data.Missing, missing...)
+
+	return data, nil
+}
+
+// translated makes sure a survey about to be published has every string in every locale it is
+// offered in. When it hasn't, it writes the error response itself and returns false.
+func (h *Handler) translated(ctx context.Context, responseWriter http.ResponseWriter, survey database.Survey) bool {
+	if len(survey.Locales) == 0 {
+		return true
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return false
+	}
+
+	if err := CheckTranslated(survey, questions); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// UpdateLocalesHandler sets the default locale of a draft survey, which its strings are written in
+// and respondents fall back to, and the other locales it is offered in.
+func (h *Handler) UpdateLocalesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.editableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[UpdateLocalesBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	defaultLocale, err := ParseLocale(data.DefaultLocale)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	locales := make([]string, 0, len(data.Locales))
+	for _, value := range data.Locales {
+		locale, err := ParseLocale(value)
+		if err == nil && slices.Contains(append(locales, defaultLocale), locale) {
+			err = fieldError("locales", fmt.Sprintf("%s is listed more than once", locale))
+		}
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+			return
+		}
+		locales = append(locales, locale)
+	}
+
+	claims := request.Context().Value("claims").(*tokens.Claims)
+
+	survey, err = h.Store.UpdateLocales(ctx, survey.ID, int64(claims.UserID), defaultLocale, locales)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "survey locales updated successfully",
+		Data:    NewSurvey(survey),
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
+
+// UpdateTranslationHandler replaces the strings of a survey and of the questions given in one of
+// the locales it is offered in. Questions left out keep the strings they had.
+func (h *Handler) UpdateTranslationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.revisableSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	locale, err := ParseLocale(chi.URLParam(request, "locale"))
+	if err == nil && !slices.Contains(survey.Locales, locale) {
+		err = fieldError("locale", fmt.Sprintf("%s is not one of the locales the survey is translated into", locale))
+	}
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	data, err := jsonutil.UnmarshalJsonResponse[TranslationBody](request)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	if err := CheckTranslation(questions, data); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.SaveTranslation(ctx, survey.ID, locale, data); err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	h.writeTranslations(ctx, responseWriter, request, "translation saved successfully")
+	return
+}
+
+// GetTranslationsHandler returns every translated string of a survey, with the ones still missing.
+func (h *Handler) GetTranslationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	h.writeTranslations(ctx, responseWriter, request, "retrieved translations successfully")
+	return
+}
+
+// writeTranslations loads the survey named in the URL and writes its translations as they stand.
+func (h *Handler) writeTranslations(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request, message string) {
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	data, err := NewTranslations(survey, questions)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+}
@@ -0,0 +1,339 @@
+package surveys_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/Adedunmol/answerly/queue"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// translatedQuestions asks how respondents commute, with the French translation of only some of the
+// options.
+func translatedQuestions() []database.Question {
+	return []database.Question{
+		{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Title: "How do you commute?",
+			Config:       []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}]}`),
+			Translations: []byte(`{"fr": {"title": "Comment vous déplacez-vous ?", "options": {"bus": "Autobus"}}}`)},
+	}
+}
+
+// newTranslatedStore has a funded draft written in English and offered in French, with one question
+// and nothing translated yet.
+func newTranslatedStore() *StubSurveyStore {
+	store := NewStubSurveyStore()
+
+	survey := fundedSurvey(database.SurveyStatusDraft)
+	survey.DefaultLocale, survey.Locales = "en", []string{"fr"}
+	store.Surveys[1] = survey
+	store.Balances[1] = decimal.NewFromInt(1000)
+
+	store.Questions[1] = database.Question{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Title: "How do you commute?",
+		Config: []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}]}`)}
+
+	return store
+}
+
+func translate(store *StubSurveyStore, locale string, body string) *httptest.ResponseRecorder {
+	handler := &surveys.Handler{Store: store}
+
+	req := newRequest(http.MethodPut, "/surveys/1/translations/"+locale, []byte(body), 1, map[string]string{"surveyID": "1", "locale": locale})
+	rec := httptest.NewRecorder()
+
+	handler.UpdateTranslationHandler(rec, req)
+	return rec
+}
+
+// ============================================================================
+// Locale negotiation Tests
+// ============================================================================
+
+func TestParseLocale(t *testing.T) {
+
+	t.Run("returns the canonical form", func(t *testing.T) {
+		locale, err := surveys.ParseLocale("fr_ca")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if locale != "fr-CA" {
+			t.Errorf("locale = %q, want fr-CA", locale)
+		}
+	})
+
+	t.Run("rejects what isn't a locale", func(t *testing.T) {
+		for _, value := range []string{"", "not a locale", "und"} {
+			if _, err := surveys.ParseLocale(value); err == nil {
+				t.Errorf("ParseLocale(%q) = nil error, want one", value)
+			}
+		}
+	})
+}
+
+func TestNegotiateLocale(t *testing.T) {
+
+	survey := database.Survey{DefaultLocale: "en", Locales: []string{"fr", "pt-BR"}}
+
+	tests := []struct {
+		name                             string
+		asked, preferred, acceptLanguage string
+		want                             string
+	}{
+		{"falls back to the default with nothing asked", "", "", "", "en"},
+		{"matches a regional variant", "", "", "fr-CA,fr;q=0.9", "fr"},
+		{"falls back to the default for a language not offered", "", "", "de-DE,de;q=0.9", "en"},
+		{"prefers the profile over the browser", "", "pt", "fr", "pt-BR"},
+		{"prefers the request over the profile", "fr", "pt-BR", "", "fr"},
+		{"skips what doesn't parse", "not a locale", "", "fr", "fr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wants := surveys.Languages(tt.asked, tt.preferred, tt.acceptLanguage)
+
+			if got := surveys.NegotiateLocale(survey, wants); got != tt.want {
+				t.Errorf("locale = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("keeps the default for a survey in one language", func(t *testing.T) {
+		wants := surveys.Languages("", "", "fr")
+
+		if got := surveys.NegotiateLocale(database.Survey{DefaultLocale: "en"}, wants); got != "en" {
+			t.Errorf("locale = %q, want en", got)
+		}
+	})
+}
+
+// ============================================================================
+// LocalizeQuestions Tests
+// ============================================================================
+
+func TestLocalizeQuestions(t *testing.T) {
+
+	t.Run("falls back string by string", func(t *testing.T) {
+		questions, err := surveys.LocalizeQuestions(translatedQuestions(), "fr")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if questions[0].Title != "Comment vous déplacez-vous ?" {
+			t.Errorf("title = %q, want the French one", questions[0].Title)
+		}
+
+		var config surveys.SingleChoiceConfig
+		_ = json.Unmarshal(questions[0].Config, &config)
+
+		if config.Options[0].Label != "Autobus" || config.Options[1].Label != "Walk" {
+			t.Errorf("options = %+v, want Autobus then the untranslated Walk", config.Options)
+		}
+
+		if config.Options[0].ID != "bus" {
+			t.Errorf("option ID = %q, want it kept", config.Options[0].ID)
+		}
+	})
+
+	t.Run("leaves the default locale alone", func(t *testing.T) {
+		questions, err := surveys.LocalizeQuestions(translatedQuestions(), "en")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if questions[0].Title != "How do you commute?" {
+			t.Errorf("title = %q, want the original", questions[0].Title)
+		}
+	})
+}
+
+// ============================================================================
+// MissingTranslations Tests
+// ============================================================================
+
+func TestMissingTranslations(t *testing.T) {
+
+	survey := database.Survey{Title: "Commuting", DefaultLocale: "en", Locales: []string{"fr"},
+		Translations: []byte(`{"fr": {"title": "Déplacements"}}`)}
+
+	missing, err := surveys.MissingTranslations(survey, translatedQuestions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(missing, []string{"fr: questions.1.options.walk"}) {
+		t.Errorf("missing = %v, want the walk option", missing)
+	}
+
+	t.Run("lists everything for a locale not started", func(t *testing.T) {
+		survey := survey
+		survey.Locales = []string{"fr", "de"}
+
+		missing, err := surveys.MissingTranslations(survey, translatedQuestions())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"de: title", "fr: questions.1.options.walk", "de: questions.1.title", "de: questions.1.options.bus", "de: questions.1.options.walk"}
+		if !slices.Equal(missing, want) {
+			t.Errorf("missing = %v, want %v", missing, want)
+		}
+	})
+}
+
+// ============================================================================
+// Translation handler Tests
+// ============================================================================
+
+func TestUpdateLocalesHandler(t *testing.T) {
+
+	update := func(store *StubSurveyStore, body string) *httptest.ResponseRecorder {
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPut, "/surveys/1/locales", []byte(body), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateLocalesHandler(rec, req)
+		return rec
+	}
+
+	t.Run("stores the locales in canonical form", func(t *testing.T) {
+		store := newTranslatedStore()
+
+		rec := update(store, `{"default_locale": "en", "locales": ["fr_ca", "de"]}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if survey := store.Surveys[1]; !slices.Equal(survey.Locales, []string{"fr-CA", "de"}) {
+			t.Errorf("locales = %v, want fr-CA and de", survey.Locales)
+		}
+	})
+
+	t.Run("returns 400 for the default locale offered twice", func(t *testing.T) {
+		rec := update(newTranslatedStore(), `{"default_locale": "en", "locales": ["fr", "en"]}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 for an invalid locale", func(t *testing.T) {
+		rec := update(newTranslatedStore(), `{"default_locale": "en", "locales": ["not a locale"]}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 409 for a published survey", func(t *testing.T) {
+		store := newTranslatedStore()
+		survey := store.Surveys[1]
+		survey.Status = database.SurveyStatusPublished
+		store.Surveys[1] = survey
+
+		rec := update(store, `{"default_locale": "en", "locales": ["de"]}`)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+	})
+}
+
+func TestUpdateTranslationHandler(t *testing.T) {
+
+	t.Run("saves the strings of a locale", func(t *testing.T) {
+		store := newTranslatedStore()
+
+		rec := translate(store, "fr", `{"survey": {"title": "Enquête"}, "questions": {"1": {"title": "Comment ?", "options": {"bus": "Autobus"}}}}`)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var response struct {
+			Data surveys.Translations `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if response.Data.Survey["fr"].Title != "Enquête" || response.Data.Questions[1]["fr"].Options["bus"] != "Autobus" {
+			t.Errorf("translations = %+v, want the French strings", response.Data)
+		}
+
+		if !slices.Equal(response.Data.Missing, []string{"fr: questions.1.options.walk"}) {
+			t.Errorf("missing = %v, want the walk option", response.Data.Missing)
+		}
+	})
+
+	t.Run("returns 400 for a locale the survey isn't offered in", func(t *testing.T) {
+		rec := translate(newTranslatedStore(), "de", `{"survey": {"title": "Umfrage"}}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 for an option the question doesn't have", func(t *testing.T) {
+		rec := translate(newTranslatedStore(), "fr", `{"questions": {"1": {"options": {"car": "Voiture"}}}}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 for a question the survey doesn't have", func(t *testing.T) {
+		rec := translate(newTranslatedStore(), "fr", `{"questions": {"9": {"title": "Pourquoi ?"}}}`)
+
+		assertResponseCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
+
+func TestPublishTranslatedSurvey(t *testing.T) {
+
+	publish := func(store *StubSurveyStore) *httptest.ResponseRecorder {
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodPatch, "/surveys/1/status", []byte(`{"status": "published"}`), 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.UpdateSurveyStatusHandler(rec, req)
+		return rec
+	}
+
+	store := newTranslatedStore()
+
+	rec := publish(store)
+
+	assertResponseCode(t, rec.Code, http.StatusConflict)
+
+	if store.Surveys[1].Status != database.SurveyStatusDraft {
+		t.Errorf("status = %s, want the survey left a draft", store.Surveys[1].Status)
+	}
+
+	rec = translate(store, "fr", `{"survey": {"title": "Enquête"}, "questions": {"1": {"title": "Comment ?", "options": {"bus": "Autobus", "walk": "À pied"}}}}`)
+	assertResponseCode(t, rec.Code, http.StatusOK)
+
+	rec = publish(store)
+
+	assertResponseCode(t, rec.Code, http.StatusOK)
+
+	if store.Surveys[1].Status != database.SurveyStatusPublished {
+		t.Errorf("status = %s, want published once translated", store.Surveys[1].Status)
+	}
+}
+
+func TestHandleOpenTaskUntranslated(t *testing.T) {
+
+	opensAt := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+
+	store := newTranslatedStore()
+	survey := scheduledSurvey(database.SurveyStatusDraft, opensAt, opensAt.Add(7*24*time.Hour))
+	survey.DefaultLocale, survey.Locales = "en", []string{"fr"}
+	store.Surveys[1] = survey
+	q := NewStubQueue()
+	handler := &surveys.TaskHandler{Store: store, Queue: q}
+
+	task := scheduleTask(t, &queue.SurveyOpenPayload{SurveyID: 1, At: opensAt})
+	if err := handler.HandleOpenTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	emails := q.emails()
+	if store.Surveys[1].Status != database.SurveyStatusDraft || len(emails) != 1 || emails[0].Subject != "Your survey could not open" {
+		t.Errorf("status = %s with emails %+v, want a draft and the researcher told why", store.Surveys[1].Status, emails)
+	}
+}
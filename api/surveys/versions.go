@@ -0,0 +1,322 @@
+package surveys
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
+	"github.com/Adedunmol/answerly/api/jsonutil"
+	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"slices"
+)
+
+// versionQuestion is a question as a survey version records it, the row of the questions table as
+// to_jsonb writes it.
+type versionQuestion struct {
+	ID          int64                 `json:"id"`
+	SurveyID    int64                 `json:"survey_id"`
+	Position    int32                 `json:"position"`
+	Type        database.QuestionType `json:"type"`
+	Title       string                `json:"title"`
+	Description *string               `json:"description"`
+	Required    bool                  `json:"required"`
+	Config      json.RawMessage       `json:"config"`
+	Logic       json.RawMessage       `json:"logic"`
+	Screener    bool                  `json:"screener"`
+	Block       *string               `json:"block"`
+}
+
+// VersionQuestions decodes the questions a survey version recorded.
+func VersionQuestions(version database.SurveyVersion) ([]database.Question, error) {
+	var recorded []versionQuestion
+	if err := json.Unmarshal(version.Questions, &recorded); err != nil {
+		return nil, fmt.Errorf("survey %d version %d: invalid questions: %v", version.SurveyID, version.Version, err)
+	}
+
+	questions := make([]database.Question, 0, len(recorded))
+	for _, question := range recorded {
+		data := database.Question{
+			ID:       question.ID,
+			SurveyID: question.SurveyID,
+			Position: question.Position,
+			Type:     question.Type,
+			Title:    question.Title,
+			Required: question.Required,
+			Config:   question.Config,
+			Screener: question.Screener,
+		}
+
+		if question.Description != nil {
+			data.Description = pgtype.Text{String: *question.Description, Valid: true}
+		}
+
+		if len(question.Logic) > 0 && string(question.Logic) != "null" {
+			data.Logic = question.Logic
+		}
+
+		if question.Block != nil {
+			data.Block = pgtype.Text{String: *question.Block, Valid: true}
+		}
+
+		questions = append(questions, data)
+	}
+
+	return questions, nil
+}
+
+// QuestionHistory is every question a survey has asked across its versions, so the answers given
+// to any version can be read back. A question keeps its ID from one version to the next whatever
+// else about it changes, which is what answers are matched on.
+type QuestionHistory struct {
+	// Current are the questions the survey asks now. Options taken out of them since an earlier
+	// version are added back after the current ones, so answers that picked them are still counted.
+	Current []database.Question
+	// Removed are the questions taken off the survey, as the last version that asked them had them.
+	Removed []database.Question
+	// Versions are the questions each version asked, as it asked them, keyed by version.
+	Versions map[int32][]database.Question
+}
+
+// NewQuestionHistory puts together the history of a survey from its current questions and its
+// versions, in any order.
+func NewQuestionHistory(current []database.Question, versions []database.SurveyVersion) (QuestionHistory, error) {
+	history := QuestionHistory{
+		Current:  slices.Clone(current),
+		Versions: make(map[int32][]database.Question, len(versions)),
+	}
+
+	index := make(map[int64]int, len(current))
+	for i, question := range history.Current {
+		index[question.ID] = i
+	}
+
+	removed := make(map[int64]int)
+
+	// newest first, so a removed question is kept as it was last asked
+	versions = slices.Clone(versions)
+	slices.SortFunc(versions, func(a, b database.SurveyVersion) int {
+		return cmp.Compare(b.Version, a.Version)
+	})
+
+	for _, version := range versions {
+		questions, err := VersionQuestions(version)
+		if err != nil {
+			return QuestionHistory{}, err
+		}
+		history.Versions[version.Version] = questions
+
+		for _, question := range questions {
+			var target *database.Question
+
+			if i, ok := index[question.ID]; ok {
+				target = &history.Current[i]
+			} else if i, ok := removed[question.ID]; ok {
+				target = &history.Removed[i]
+			} else {
+				removed[question.ID] = len(history.Removed)
+				history.Removed = append(history.Removed, question)
+				continue
+			}
+
+			config, err := mergeConfigs(target.Type, target.Config, question.Config)
+			if err != nil {
+				return QuestionHistory{}, fmt.Errorf("question %d: %v", question.ID, err)
+			}
+			target.Config = config
+		}
+	}
+
+	slices.SortFunc(history.Removed, func(a, b database.Question) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+
+	return history, nil
+}
+
+// All returns the current questions followed by the removed ones.
+func (h QuestionHistory) All() []database.Question {
+	return append(slices.Clone(h.Current), h.Removed...)
+}
+
+// mergeConfigs widens the config of a question so answers given to an older config of it still
+// fit: options the older one had are added back, and numeric and likert scales stretched to cover
+// both.
+func mergeConfigs(questionType database.QuestionType, newer, older []byte) ([]byte, error) {
+	newerConfig, err := ParseQuestionConfig(questionType, newer)
+	if err != nil {
+		return nil, err
+	}
+
+	olderConfig, err := ParseQuestionConfig(questionType, older)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := newerConfig.(type) {
+	case *SingleChoiceConfig:
+		n.Options = mergeOptions(n.Options, olderConfig.(*SingleChoiceConfig).Options)
+	case *MultipleChoiceConfig:
+		n.Options = mergeOptions(n.Options, olderConfig.(*MultipleChoiceConfig).Options)
+	case *RankingConfig:
+		n.Options = mergeOptions(n.Options, olderConfig.(*RankingConfig).Options)
+	case *MatrixConfig:
+		o := olderConfig.(*MatrixConfig)
+		n.Rows = mergeOptions(n.Rows, o.Rows)
+		n.Columns = mergeOptions(n.Columns, o.Columns)
+	case *LikertConfig:
+		// labels only fit the number of points they were written for, so the longer scale is kept whole
+		if o := olderConfig.(*LikertConfig); o.Points > n.Points {
+			return older, nil
+		}
+		return newer, nil
+	case *NumericConfig:
+		o := olderConfig.(*NumericConfig)
+		n.Min = min(n.Min, o.Min)
+		n.Max = max(n.Max, o.Max)
+		n.Integer = n.Integer && o.Integer
+	default:
+		return newer, nil
+	}
+
+	return json.Marshal(newerConfig)
+}
+
+// mergeOptions returns the newer options followed by the older ones that were taken out since.
+func mergeOptions(newer, older []Option) []Option {
+	merged := slices.Clone(newer)
+
+	for _, option := range older {
+		if !slices.ContainsFunc(merged, func(o Option) bool { return o.ID == option.ID }) {
+			merged = append(merged, option)
+		}
+	}
+
+	return merged
+}
+
+// InvalidatedAnswers counts the answers already given to a question that it would turn down once
+// changed as given. values are the distinct answers with how many times each was given.
+func InvalidatedAnswers(changed database.Question, values []database.CountAnswerValuesRow) int32 {
+	var invalidated int32
+
+	for _, value := range values {
+		if ValidateAnswer(changed, value.Value) != nil {
+			invalidated += value.Count
+		}
+	}
+
+	return invalidated
+}
+
+// confirmed reports whether a request confirms a change that invalidates answers.
+func confirmed(request *http.Request) bool {
+	return request.URL.Query().Get("confirm") == "true"
+}
+
+// checkInvalidated stops a change to a question of a published survey that would make answers
+// already given to it invalid, unless the request confirms it with confirm=true. A nil changed
+// question means the question is being removed, which leaves none of its answers standing. When it
+// stops the change, it writes the error response itself and returns false.
+func (h *Handler) checkInvalidated(ctx context.Context, responseWriter http.ResponseWriter, request *http.Request, survey database.Survey, questionID int64, changed *database.Question) bool {
+	// nobody answers a draft
+	if survey.Status == database.SurveyStatusDraft || confirmed(request) {
+		return true
+	}
+
+	values, err := h.Store.CountAnswerValues(ctx, questionID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return false
+	}
+
+	var invalidated int32
+	if changed == nil {
+		for _, value := range values {
+			invalidated += value.Count
+		}
+	} else {
+		invalidated = InvalidatedAnswers(*changed, values)
+	}
+
+	if invalidated == 0 {
+		return true
+	}
+
+	response := jsonutil.Response{
+		Status:  "error",
+		Message: fmt.Sprintf("this change invalidates %d answers already given to question %d, send it again with confirm=true to make it anyway", invalidated, questionID),
+		Data:    AnswerImpact{QuestionID: questionID, InvalidatedAnswers: invalidated},
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusConflict)
+	return false
+}
+
+// questionHistory loads the history of a survey's questions.
+func (h *Handler) questionHistory(ctx context.Context, survey database.Survey) (QuestionHistory, error) {
+	questions, err := h.Store.ListQuestions(ctx, survey.ID)
+	if err != nil {
+		return QuestionHistory{}, err
+	}
+
+	versions, err := h.Store.ListVersions(ctx, survey.ID)
+	if err != nil {
+		return QuestionHistory{}, err
+	}
+
+	return NewQuestionHistory(questions, versions)
+}
+
+// ListVersionsHandler lists the versions of a survey's questions, oldest first. Publishing the
+// survey makes the first and every change to its questions after that makes another.
+func (h *Handler) ListVersionsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	ctx := context.Background()
+
+	survey, ok := h.ownedSurvey(ctx, responseWriter, request)
+	if !ok {
+		return
+	}
+
+	versions, err := h.Store.ListVersions(ctx, survey.ID)
+	if err != nil {
+		response := jsonutil.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}
+		jsonutil.WriteJSONResponse(responseWriter, response, custom_errors.HTTPStatus(err))
+		return
+	}
+
+	data := make([]SurveyVersion, 0, len(versions))
+	for _, version := range versions {
+		questions, err := VersionQuestions(version)
+		if err != nil {
+			response := jsonutil.Response{
+				Status:  "error",
+				Message: err.Error(),
+			}
+			jsonutil.WriteJSONResponse(responseWriter, response, http.StatusInternalServerError)
+			return
+		}
+
+		data = append(data, SurveyVersion{
+			Version:   version.Version,
+			Questions: NewQuestions(questions),
+			CreatedAt: version.CreatedAt.Time,
+		})
+	}
+
+	response := jsonutil.Response{
+		Status:  "success",
+		Message: "retrieved survey versions successfully",
+		Data:    data,
+	}
+	jsonutil.WriteJSONResponse(responseWriter, response, http.StatusOK)
+	return
+}
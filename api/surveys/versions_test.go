@@ -0,0 +1,279 @@
+package surveys_test
+
+import (
+	"encoding/json"
+	"github.com/Adedunmol/answerly/api/surveys"
+	"github.com/Adedunmol/answerly/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newVersionedStore has a published survey on version 1 asking how respondents commute, which bus
+// riders answered 3 times and walkers once.
+func newVersionedStore() *StubSurveyStore {
+	store := NewStubSurveyStore()
+	store.Surveys[1] = database.Survey{ID: 1, ResearcherID: 1, Status: database.SurveyStatusPublished}
+
+	store.Questions[1] = database.Question{ID: 1, SurveyID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Title: "How do you commute?",
+		Required: true, Config: []byte(`{"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}, {"id": "bike", "label": "Bike"}]}`)}
+	store.Questions[2] = database.Question{ID: 2, SurveyID: 1, Position: 2, Type: database.QuestionTypeNumeric, Title: "Minutes?",
+		Config: []byte(`{"min": 0, "max": 120}`)}
+	_ = store.createVersion(1)
+
+	store.AnswerValues = map[int64][]database.CountAnswerValuesRow{
+		1: {{Value: []byte(`{"option_id": "bus"}`), Count: 3}, {Value: []byte(`{"option_id": "walk"}`), Count: 1}},
+	}
+
+	return store
+}
+
+// ============================================================================
+// NewQuestionHistory Tests
+// ============================================================================
+
+func TestNewQuestionHistory(t *testing.T) {
+
+	version := func(number int32, questions string) database.SurveyVersion {
+		return database.SurveyVersion{SurveyID: 1, Version: number, Questions: []byte(questions)}
+	}
+
+	current := []database.Question{
+		{ID: 1, Position: 1, Type: database.QuestionTypeSingleChoice, Config: []byte(`{"options": [{"id": "bus", "label": "By bus"}, {"id": "walk", "label": "Walk"}]}`)},
+		{ID: 3, Position: 2, Type: database.QuestionTypeNumeric, Config: []byte(`{"min": 0, "max": 60, "integer": true}`)},
+	}
+
+	versions := []database.SurveyVersion{
+		version(2, `[
+			{"id": 1, "position": 1, "type": "single_choice", "title": "Commute", "config": {"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}, {"id": "bike", "label": "Bike"}]}},
+			{"id": 2, "position": 2, "type": "text", "title": "Why?", "config": {"max_length": 100}, "logic": null},
+			{"id": 3, "position": 3, "type": "numeric", "title": "Minutes?", "config": {"min": 5, "max": 120}}
+		]`),
+		version(1, `[
+			{"id": 1, "position": 1, "type": "single_choice", "title": "Commute", "config": {"options": [{"id": "bus", "label": "Bus"}, {"id": "car", "label": "Car"}]}},
+			{"id": 2, "position": 2, "type": "text", "title": "Why not?", "config": {"max_length": 200}}
+		]`),
+	}
+
+	history, err := surveys.NewQuestionHistory(current, versions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("adds back the options taken out since", func(t *testing.T) {
+		var config surveys.SingleChoiceConfig
+		_ = json.Unmarshal(history.Current[0].Config, &config)
+
+		var ids []string
+		for _, option := range config.Options {
+			ids = append(ids, option.ID)
+		}
+
+		if len(ids) != 4 || ids[0] != "bus" || ids[2] != "bike" || ids[3] != "car" {
+			t.Errorf("options = %v, want bus walk bike car", ids)
+		}
+
+		if config.Options[0].Label != "By bus" {
+			t.Errorf("label = %q, want the current one", config.Options[0].Label)
+		}
+	})
+
+	t.Run("stretches a numeric range over every version", func(t *testing.T) {
+		var config surveys.NumericConfig
+		_ = json.Unmarshal(history.Current[1].Config, &config)
+
+		if config.Min != 0 || config.Max != 120 || config.Integer {
+			t.Errorf("config = %+v, want 0 to 120 and not only whole numbers", config)
+		}
+	})
+
+	t.Run("keeps removed questions as last asked", func(t *testing.T) {
+		if len(history.Removed) != 1 || history.Removed[0].ID != 2 || history.Removed[0].Title != "Why?" {
+			t.Fatalf("removed = %+v, want question 2 as version 2 had it", history.Removed)
+		}
+
+		if history.Removed[0].Logic != nil {
+			t.Errorf("logic = %s, want none", history.Removed[0].Logic)
+		}
+
+		if all := history.All(); len(all) != 3 {
+			t.Errorf("all = %d questions, want 3", len(all))
+		}
+	})
+}
+
+// ============================================================================
+// Versioned edit Tests
+// ============================================================================
+
+func TestVersionedQuestionEdits(t *testing.T) {
+
+	params := map[string]string{"surveyID": "1", "questionID": "1"}
+
+	t.Run("rewording makes a new version without asking", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"title": "How do you usually commute?"}`)
+		req := newRequest(http.MethodPatch, "/surveys/1/questions/1", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.UpdateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if version := store.Surveys[1].Version; version != 2 || len(store.Versions[1]) != 2 {
+			t.Errorf("version = %d with %d recorded, want 2", version, len(store.Versions[1]))
+		}
+	})
+
+	t.Run("taking out an option nobody picked needs no confirmation", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"config": {"options": [{"id": "bus", "label": "Bus"}, {"id": "walk", "label": "Walk"}]}}`)
+		req := newRequest(http.MethodPatch, "/surveys/1/questions/1", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.UpdateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+	})
+
+	t.Run("taking out a picked option needs confirmation", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"config": {"options": [{"id": "walk", "label": "Walk"}, {"id": "bike", "label": "Bike"}]}}`)
+		req := newRequest(http.MethodPatch, "/surveys/1/questions/1", data, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.UpdateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+
+		var got struct {
+			Data surveys.AnswerImpact `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if got.Data.InvalidatedAnswers != 3 {
+			t.Errorf("impact = %+v, want the 3 bus answers", got.Data)
+		}
+
+		if store.Surveys[1].Version != 1 {
+			t.Errorf("expected no new version")
+		}
+
+		req = newRequest(http.MethodPatch, "/surveys/1/questions/1?confirm=true", data, 1, params)
+		rec = httptest.NewRecorder()
+
+		handler.UpdateQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		if store.Surveys[1].Version != 2 {
+			t.Errorf("version = %d, want 2", store.Surveys[1].Version)
+		}
+	})
+
+	t.Run("removing an answered question keeps it and needs confirmation", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodDelete, "/surveys/1/questions/1", nil, 1, params)
+		rec := httptest.NewRecorder()
+
+		handler.DeleteQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusConflict)
+
+		req = newRequest(http.MethodDelete, "/surveys/1/questions/1?confirm=true", nil, 1, params)
+		rec = httptest.NewRecorder()
+
+		handler.DeleteQuestionHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		question, kept := store.Questions[1]
+		if !kept || !question.RemovedAt.Valid {
+			t.Errorf("question = %+v, want it kept and marked removed", question)
+		}
+
+		if store.Surveys[1].Version != 2 {
+			t.Errorf("version = %d, want 2", store.Surveys[1].Version)
+		}
+	})
+}
+
+// ============================================================================
+// Versioned results Tests
+// ============================================================================
+
+func TestVersionedResults(t *testing.T) {
+
+	t.Run("counts the answers to removed questions and options", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		req := newRequest(http.MethodDelete, "/surveys/1/questions/2", nil, 1, map[string]string{"surveyID": "1", "questionID": "2"})
+		handler.DeleteQuestionHandler(httptest.NewRecorder(), req)
+
+		store.Results = map[int64][]database.ListSurveyResultsRow{
+			1: {
+				{Kind: "total", Count: 2},
+				{Kind: "answered", QuestionID: 1, Count: 2},
+				{Kind: "answered", QuestionID: 2, Count: 1},
+				{Kind: "choice", QuestionID: 1, Choice: "bike", Count: 2},
+			},
+		}
+
+		code, results := getResults(t, store, "/surveys/1/results")
+
+		assertResponseCode(t, code, http.StatusOK)
+
+		if len(results.Questions) != 2 || results.Questions[0].Removed || !results.Questions[1].Removed {
+			t.Fatalf("questions = %+v, want question 1 then the removed question 2", results.Questions)
+		}
+
+		if results.Questions[1].Answered != 1 {
+			t.Errorf("removed question answered = %d, want 1", results.Questions[1].Answered)
+		}
+	})
+}
+
+// ============================================================================
+// ListVersionsHandler Tests
+// ============================================================================
+
+func TestListVersionsHandler(t *testing.T) {
+
+	t.Run("lists every version with its questions", func(t *testing.T) {
+		store := newVersionedStore()
+		handler := &surveys.Handler{Store: store}
+
+		data := []byte(`{"title": "How do you usually commute?"}`)
+		req := newRequest(http.MethodPatch, "/surveys/1/questions/1", data, 1, map[string]string{"surveyID": "1", "questionID": "1"})
+		handler.UpdateQuestionHandler(httptest.NewRecorder(), req)
+
+		req = newRequest(http.MethodGet, "/surveys/1/versions", nil, 1, map[string]string{"surveyID": "1"})
+		rec := httptest.NewRecorder()
+
+		handler.ListVersionsHandler(rec, req)
+
+		assertResponseCode(t, rec.Code, http.StatusOK)
+
+		var got struct {
+			Data []surveys.SurveyVersion `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+
+		if len(got.Data) != 2 {
+			t.Fatalf("got %d versions, want 2", len(got.Data))
+		}
+
+		if got.Data[0].Questions[0].Title != "How do you commute?" || got.Data[1].Questions[0].Title != "How do you usually commute?" {
+			t.Errorf("titles = %q then %q, want the old title kept in version 1", got.Data[0].Questions[0].Title, got.Data[1].Questions[0].Title)
+		}
+	})
+}
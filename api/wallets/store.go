@@ -2,8 +2,11 @@ package wallets
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/Adedunmol/answerly/api/custom_errors"
 	"github.com/Adedunmol/answerly/database"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/shopspring/decimal"
 	"time"
@@ -13,7 +16,11 @@ type Store interface {
 	CreateWallet(ctx context.Context, userID int64) (database.Wallet, error)
 	GetWallet(ctx context.Context, userID int64) (database.Wallet, error)
 	TopUpWallet(ctx context.Context, userID int64, amount decimal.Decimal) (database.Wallet, error)
-	ChargeWallet(ctx context.Context, companyID int64, amount decimal.Decimal) (database.Wallet, error)
+	ChargeWallet(ctx context.Context, userID int64, amount decimal.Decimal) (database.Wallet, error)
+	CreateOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error)
+	GetOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error)
+	TopUpOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error)
+	ChargeOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error)
 }
 
 const UniqueViolationCode = "23505"
@@ -27,8 +34,18 @@ func NewWalletStore(queries *database.Queries) *Repository {
 	return &Repository{queries: queries}
 }
 
+// queriesFor runs wallet changes inside the transaction carried by ctx when there is one, so a
+// charge or refund can be part of a larger unit of work such as publishing a survey.
+func (r *Repository) queriesFor(ctx context.Context) *database.Queries {
+	if tx, ok := database.TxFromContext(ctx); ok {
+		return r.queries.WithTx(tx)
+	}
+
+	return r.queries
+}
+
 func (r *Repository) CreateWallet(ctx context.Context, userID int64) (database.Wallet, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	balance := pgtype.Numeric{}
@@ -37,9 +54,9 @@ func (r *Repository) CreateWallet(ctx context.Context, userID int64) (database.W
 		return database.Wallet{}, fmt.Errorf("error while scanning amount: %v", err)
 	}
 
-	wallet, err := r.queries.CreateWallet(ctx, database.CreateWalletParams{
+	wallet, err := r.queriesFor(ctx).CreateWallet(ctx, database.CreateWalletParams{
 		Balance: balance,
-		UserID:  userID,
+		UserID:  pgtype.Int8{Int64: userID, Valid: true},
 	})
 	if err != nil {
 		return database.Wallet{}, fmt.Errorf("error creating wallet: %v", err)
@@ -49,11 +66,14 @@ func (r *Repository) CreateWallet(ctx context.Context, userID int64) (database.W
 }
 
 func (r *Repository) GetWallet(ctx context.Context, userID int64) (database.Wallet, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	wallet, err := r.queries.GetWallet(ctx, userID)
+	wallet, err := r.queriesFor(ctx).GetWallet(ctx, pgtype.Int8{Int64: userID, Valid: true})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Wallet{}, custom_errors.ErrNotFound
+		}
 		return database.Wallet{}, fmt.Errorf("error getting wallet: %v", err)
 	}
 
@@ -61,7 +81,7 @@ func (r *Repository) GetWallet(ctx context.Context, userID int64) (database.Wall
 }
 
 func (r *Repository) TopUpWallet(ctx context.Context, userID int64, amount decimal.Decimal) (database.Wallet, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	amountCast := pgtype.Numeric{}
@@ -70,8 +90,8 @@ func (r *Repository) TopUpWallet(ctx context.Context, userID int64, amount decim
 		return database.Wallet{}, fmt.Errorf("error while scanning amount: %v", err)
 	}
 
-	wallet, err := r.queries.TopUpWallet(ctx, database.TopUpWalletParams{
-		UserID: userID,
+	wallet, err := r.queriesFor(ctx).TopUpWallet(ctx, database.TopUpWalletParams{
+		UserID: pgtype.Int8{Int64: userID, Valid: true},
 		Amount: amountCast,
 	})
 	if err != nil {
@@ -82,7 +102,7 @@ func (r *Repository) TopUpWallet(ctx context.Context, userID int64, amount decim
 }
 
 func (r *Repository) ChargeWallet(ctx context.Context, userID int64, amount decimal.Decimal) (database.Wallet, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	amountCast := pgtype.Numeric{}
@@ -91,13 +111,97 @@ func (r *Repository) ChargeWallet(ctx context.Context, userID int64, amount deci
 		return database.Wallet{}, fmt.Errorf("error while scanning amount: %v", err)
 	}
 
-	wallet, err := r.queries.ChargeWallet(ctx, database.ChargeWalletParams{
+	wallet, err := r.queriesFor(ctx).ChargeWallet(ctx, database.ChargeWalletParams{
 		Amount: amountCast,
-		UserID: userID,
+		UserID: pgtype.Int8{Int64: userID, Valid: true},
 	})
 	if err != nil {
+		// the update only matches a wallet holding at least the amount
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Wallet{}, custom_errors.ErrInsufficientFunds
+		}
 		return database.Wallet{}, fmt.Errorf("error charging wallet: %v", err)
 	}
 
 	return wallet, nil
 }
+
+func (r *Repository) CreateOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet, err := r.queriesFor(ctx).CreateOrganizationWallet(ctx, pgtype.Int8{Int64: organizationID, Valid: true})
+	if err != nil {
+		return database.Wallet{}, fmt.Errorf("error creating organization wallet: %v", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *Repository) GetOrganizationWallet(ctx context.Context, organizationID int64) (database.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet, err := r.queriesFor(ctx).GetOrganizationWallet(ctx, pgtype.Int8{Int64: organizationID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Wallet{}, custom_errors.ErrNotFound
+		}
+		return database.Wallet{}, fmt.Errorf("error getting organization wallet: %v", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *Repository) TopUpOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet, err := r.queriesFor(ctx).TopUpOrganizationWallet(ctx, database.TopUpOrganizationWalletParams{
+		Amount:         database.NumericFromDecimal(amount),
+		OrganizationID: pgtype.Int8{Int64: organizationID, Valid: true},
+	})
+	if err != nil {
+		return database.Wallet{}, fmt.Errorf("error topping up organization wallet: %v", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *Repository) ChargeOrganizationWallet(ctx context.Context, organizationID int64, amount decimal.Decimal) (database.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet, err := r.queriesFor(ctx).ChargeOrganizationWallet(ctx, database.ChargeOrganizationWalletParams{
+		Amount:         database.NumericFromDecimal(amount),
+		OrganizationID: pgtype.Int8{Int64: organizationID, Valid: true},
+	})
+	if err != nil {
+		// the update only matches a wallet holding at least the amount
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Wallet{}, custom_errors.ErrInsufficientFunds
+		}
+		return database.Wallet{}, fmt.Errorf("error charging organization wallet: %v", err)
+	}
+
+	return wallet, nil
+}
+
+// Charge takes an amount from the wallet paying for a researcher's work: their organization's when
+// organizationID is set, their own otherwise.
+func Charge(ctx context.Context, store Store, researcherID int64, organizationID pgtype.Int8, amount decimal.Decimal) (database.Wallet, error) {
+	if organizationID.Valid {
+		return store.ChargeOrganizationWallet(ctx, organizationID.Int64, amount)
+	}
+
+	return store.ChargeWallet(ctx, researcherID, amount)
+}
+
+// TopUp gives an amount back to the wallet Charge took it from.
+func TopUp(ctx context.Context, store Store, researcherID int64, organizationID pgtype.Int8, amount decimal.Decimal) (database.Wallet, error) {
+	if organizationID.Valid {
+		return store.TopUpOrganizationWallet(ctx, organizationID.Int64, amount)
+	}
+
+	return store.TopUpWallet(ctx, researcherID, amount)
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE survey_status AS ENUM (
+  'draft',
+  'published',
+  'closed',
+  'archived'
+);
+
+CREATE TABLE surveys (
+    id BIGSERIAL PRIMARY KEY,
+    researcher_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    status survey_status NOT NULL DEFAULT 'draft',
+    published_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_surveys_researcher_id ON surveys(researcher_id, created_at);
+CREATE INDEX idx_surveys_status ON surveys(status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS surveys;
+
+DROP TYPE IF EXISTS survey_status;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE question_type AS ENUM (
+  'single_choice',
+  'multiple_choice',
+  'likert',
+  'numeric',
+  'text',
+  'ranking',
+  'matrix'
+);
+
+CREATE TABLE questions (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    type question_type NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    required BOOLEAN NOT NULL DEFAULT true,
+    config JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_questions_survey_id ON questions(survey_id, position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS questions;
+
+DROP TYPE IF EXISTS question_type;
+-- +goose StatementEnd
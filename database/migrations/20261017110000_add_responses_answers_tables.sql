@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE response_status AS ENUM (
+  'in_progress',
+  'submitted'
+);
+
+CREATE TABLE responses (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    respondent_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status response_status NOT NULL DEFAULT 'in_progress',
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    submitted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(survey_id, respondent_id) -- One response per user per survey
+);
+
+CREATE TABLE answers (
+    id BIGSERIAL PRIMARY KEY,
+    response_id BIGINT NOT NULL REFERENCES responses(id) ON DELETE CASCADE,
+    question_id BIGINT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    value JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(response_id, question_id)
+);
+
+CREATE INDEX idx_responses_survey_id ON responses(survey_id, status);
+CREATE INDEX idx_responses_respondent_id ON responses(respondent_id);
+CREATE INDEX idx_answers_question_id ON answers(question_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS answers;
+DROP TABLE IF EXISTS responses;
+
+DROP TYPE IF EXISTS response_status;
+-- +goose StatementEnd
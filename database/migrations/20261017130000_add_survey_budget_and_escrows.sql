@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE survey_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+ALTER TABLE surveys
+    ADD COLUMN reward_per_response DECIMAL(15,2),
+    ADD COLUMN target_responses INT;
+
+CREATE TYPE escrow_status AS ENUM (
+  'held',
+  'settled'
+);
+
+CREATE TABLE escrows (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL UNIQUE REFERENCES surveys(id) ON DELETE CASCADE,
+    researcher_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reward_per_response DECIMAL(15,2) NOT NULL,
+    fee_per_response DECIMAL(15,2) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    spent DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    refunded DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    status escrow_status NOT NULL DEFAULT 'held',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (spent + refunded <= amount)
+);
+
+CREATE INDEX idx_escrows_researcher_id ON escrows(researcher_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS escrows;
+
+DROP TYPE IF EXISTS escrow_status;
+
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS reward_per_response,
+    DROP COLUMN IF EXISTS target_responses;
+
+-- postgres can't drop a value from an enum, so cancelled surveys are folded into closed
+UPDATE surveys SET status = 'closed' WHERE status = 'cancelled';
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE payouts (
+    id BIGSERIAL PRIMARY KEY,
+    response_id BIGINT NOT NULL UNIQUE REFERENCES responses(id),
+    survey_id BIGINT NOT NULL REFERENCES surveys(id),
+    respondent_id BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payouts_respondent_id ON payouts(respondent_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS payouts;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE surveys ADD COLUMN targeting JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE surveys DROP COLUMN IF EXISTS targeting;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE surveys
+    ADD COLUMN field_ids BIGINT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN estimated_minutes INT CHECK (estimated_minutes > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS estimated_minutes,
+    DROP COLUMN IF EXISTS field_ids;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE response_status ADD VALUE IF NOT EXISTS 'screened_out';
+
+ALTER TABLE questions ADD COLUMN screener BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE surveys ADD COLUMN screen_out_fee DECIMAL(15,2);
+
+ALTER TABLE escrows ADD COLUMN screen_out_fee DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+CREATE TYPE payout_kind AS ENUM (
+  'reward',
+  'screen_out'
+);
+
+ALTER TABLE payouts ADD COLUMN kind payout_kind NOT NULL DEFAULT 'reward';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payouts DROP COLUMN IF EXISTS kind;
+
+DROP TYPE IF EXISTS payout_kind;
+
+ALTER TABLE escrows DROP COLUMN IF EXISTS screen_out_fee;
+
+ALTER TABLE surveys DROP COLUMN IF EXISTS screen_out_fee;
+
+ALTER TABLE questions DROP COLUMN IF EXISTS screener;
+
+-- postgres can't drop a value from an enum, so screened out responses are folded into in_progress
+UPDATE responses SET status = 'in_progress' WHERE status = 'screened_out';
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE quota_cells (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    criteria JSONB NOT NULL,
+    capacity INT NOT NULL CHECK (capacity > 0),
+    filled INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (filled >= 0 AND filled <= capacity), -- a cell can never be over-filled
+    UNIQUE(survey_id, name)
+);
+
+CREATE INDEX idx_quota_cells_survey_id ON quota_cells(survey_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS quota_cells;
+-- +goose StatementEnd
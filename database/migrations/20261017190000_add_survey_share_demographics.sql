@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- whether researchers may see the demographics of the people who answered, e.g. in exports
+ALTER TABLE surveys
+    ADD COLUMN share_demographics BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS share_demographics;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE review_status AS ENUM (
+  'pending',
+  'approved',
+  'needs_review'
+);
+
+-- review_status is set once a response is submitted: pending until its quality checks have run,
+-- then approved (and paid) or flagged as needing review. quality_report holds the check results.
+ALTER TABLE responses
+    ADD COLUMN review_status review_status,
+    ADD COLUMN quality_report JSONB;
+
+-- responses submitted so far were paid straight away
+UPDATE responses SET review_status = 'approved' WHERE status = 'submitted';
+
+CREATE INDEX idx_responses_review_status ON responses(survey_id, review_status);
+
+-- the thresholds of the quality checks, NULL for the defaults
+ALTER TABLE surveys ADD COLUMN quality_checks JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE surveys DROP COLUMN IF EXISTS quality_checks;
+
+DROP INDEX IF EXISTS idx_responses_review_status;
+
+ALTER TABLE responses
+    DROP COLUMN IF EXISTS quality_report,
+    DROP COLUMN IF EXISTS review_status;
+
+DROP TYPE IF EXISTS review_status;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE review_status ADD VALUE IF NOT EXISTS 'rejected';
+
+-- who settled the review of a response and why; reviewed_by is NULL when the quality checks or the
+-- auto-approval job did
+ALTER TABLE responses
+    ADD COLUMN review_reason TEXT,
+    ADD COLUMN reviewed_by BIGINT REFERENCES users(id),
+    ADD COLUMN reviewed_at TIMESTAMP;
+
+CREATE INDEX idx_responses_awaiting_review ON responses(submitted_at)
+    WHERE review_status IN ('pending', 'needs_review');
+
+-- the rewards of submitted responses awaiting review are set aside in their survey's escrow until
+-- the review releases them to the respondent or returns them
+ALTER TABLE escrows ADD COLUMN reserved DECIMAL(15,2) NOT NULL DEFAULT 0.00;
+
+ALTER TABLE escrows DROP CONSTRAINT IF EXISTS escrows_check;
+ALTER TABLE escrows ADD CONSTRAINT escrows_check CHECK (spent + reserved + refunded <= amount);
+
+CREATE TYPE payout_status AS ENUM (
+  'held',
+  'paid',
+  'returned'
+);
+
+ALTER TABLE payouts ADD COLUMN status payout_status NOT NULL DEFAULT 'paid';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE payouts DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS payout_status;
+
+ALTER TABLE escrows DROP CONSTRAINT IF EXISTS escrows_check;
+ALTER TABLE escrows DROP COLUMN IF EXISTS reserved;
+ALTER TABLE escrows ADD CONSTRAINT escrows_check CHECK (spent + refunded <= amount);
+
+DROP INDEX IF EXISTS idx_responses_awaiting_review;
+
+ALTER TABLE responses
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS review_reason;
+
+-- postgres can't drop a value from an enum, so rejected responses are folded into needs_review
+UPDATE responses SET review_status = 'needs_review' WHERE review_status = 'rejected';
+-- +goose StatementEnd
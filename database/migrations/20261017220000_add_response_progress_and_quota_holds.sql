@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE response_status ADD VALUE IF NOT EXISTS 'expired';
+
+-- how long a respondent may leave a response unfinished before it expires, counted from the last
+-- time they saved it
+ALTER TABLE surveys ADD COLUMN resume_window_hours INT NOT NULL DEFAULT 72 CHECK (resume_window_hours > 0);
+
+-- where a respondent saving page by page stopped and when the response expires unless they come back
+ALTER TABLE responses
+    ADD COLUMN last_question_id BIGINT,
+    ADD COLUMN expires_at TIMESTAMP;
+
+UPDATE responses
+SET expires_at = updated_at + make_interval(hours => 72)
+WHERE status = 'in_progress';
+
+CREATE INDEX idx_responses_expiring ON responses(expires_at)
+    WHERE status = 'in_progress';
+
+-- a response in progress holds a slot in each of its quota cells, so a cell can't be promised to
+-- more respondents than it takes; submitting turns the hold into a fill, expiring frees it
+ALTER TABLE quota_cells ADD COLUMN held INT NOT NULL DEFAULT 0;
+
+ALTER TABLE quota_cells DROP CONSTRAINT IF EXISTS quota_cells_check;
+ALTER TABLE quota_cells ADD CONSTRAINT quota_cells_check CHECK (filled >= 0 AND held >= 0 AND filled + held <= capacity);
+
+CREATE TABLE response_quota_holds (
+    response_id BIGINT NOT NULL REFERENCES responses(id) ON DELETE CASCADE,
+    quota_cell_id BIGINT NOT NULL REFERENCES quota_cells(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (response_id, quota_cell_id)
+);
+
+CREATE INDEX idx_response_quota_holds_quota_cell_id ON response_quota_holds(quota_cell_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS response_quota_holds;
+
+ALTER TABLE quota_cells DROP CONSTRAINT IF EXISTS quota_cells_check;
+ALTER TABLE quota_cells DROP COLUMN IF EXISTS held;
+ALTER TABLE quota_cells ADD CONSTRAINT quota_cells_check CHECK (filled >= 0 AND filled <= capacity);
+
+DROP INDEX IF EXISTS idx_responses_expiring;
+
+ALTER TABLE responses
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS last_question_id;
+
+ALTER TABLE surveys DROP COLUMN IF EXISTS resume_window_hours;
+
+-- postgres can't drop a value from an enum, so expired responses are folded into screened_out,
+-- the other way a response ends without being submitted
+UPDATE responses SET status = 'screened_out' WHERE status = 'expired';
+-- +goose StatementEnd
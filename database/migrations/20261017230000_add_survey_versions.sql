@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- version 0 is a draft nobody has answered yet, publishing makes version 1
+ALTER TABLE surveys ADD COLUMN version INT NOT NULL DEFAULT 0;
+
+-- questions removed after publishing are kept so the answers already given to them are too
+ALTER TABLE questions ADD COLUMN removed_at TIMESTAMP;
+
+CREATE TABLE survey_versions (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version > 0),
+    questions JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(survey_id, version)
+);
+
+-- every survey past draft so far has only ever had the questions it has now
+INSERT INTO survey_versions (survey_id, version, questions)
+SELECT
+    s.id, 1,
+    COALESCE((SELECT jsonb_agg(to_jsonb(q) ORDER BY q.position, q.id) FROM questions q WHERE q.survey_id = s.id), '[]')
+FROM surveys s
+WHERE s.status <> 'draft';
+
+UPDATE surveys SET version = 1 WHERE status <> 'draft';
+
+ALTER TABLE responses ADD COLUMN survey_version INT;
+
+UPDATE responses r SET survey_version = s.version
+FROM surveys s
+WHERE s.id = r.survey_id;
+
+ALTER TABLE responses ALTER COLUMN survey_version SET NOT NULL;
+
+ALTER TABLE responses
+    ADD CONSTRAINT responses_survey_version_fkey
+    FOREIGN KEY (survey_id, survey_version) REFERENCES survey_versions(survey_id, version);
+
+CREATE INDEX idx_questions_removed ON questions(survey_id) WHERE removed_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_questions_removed;
+
+ALTER TABLE responses DROP CONSTRAINT IF EXISTS responses_survey_version_fkey;
+ALTER TABLE responses DROP COLUMN IF EXISTS survey_version;
+
+DROP TABLE IF EXISTS survey_versions;
+
+DELETE FROM questions WHERE removed_at IS NOT NULL;
+ALTER TABLE questions DROP COLUMN IF EXISTS removed_at;
+
+ALTER TABLE surveys DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE surveys
+    ADD COLUMN is_template BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN cloned_from BIGINT REFERENCES surveys(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_surveys_templates ON surveys USING GIN (field_ids) WHERE is_template;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_surveys_templates;
+
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS cloned_from,
+    DROP COLUMN IF EXISTS is_template;
+-- +goose StatementEnd
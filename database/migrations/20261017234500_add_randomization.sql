@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- consecutive questions sharing a block are shown in a different order to every respondent
+ALTER TABLE questions ADD COLUMN block VARCHAR(64);
+
+-- the order a respondent is shown is worked out from the seed, so it can be rebuilt later
+ALTER TABLE responses ADD COLUMN order_seed BIGINT NOT NULL DEFAULT floor(random() * 2147483647)::BIGINT;
+
+-- where the question and its options were when the respondent answered, for results by position
+ALTER TABLE answers ADD COLUMN display JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE answers DROP COLUMN IF EXISTS display;
+
+ALTER TABLE responses DROP COLUMN IF EXISTS order_seed;
+
+ALTER TABLE questions DROP COLUMN IF EXISTS block;
+-- +goose StatementEnd
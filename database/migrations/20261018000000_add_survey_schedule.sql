@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE surveys
+    ADD COLUMN opens_at TIMESTAMP,
+    ADD COLUMN closes_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS closes_at,
+    DROP COLUMN IF EXISTS opens_at;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE surveys ADD COLUMN anonymous BOOLEAN NOT NULL DEFAULT false;
+
+-- Responses to anonymous surveys carry a pseudonym instead of the respondent, who is only kept in
+-- respondent_identities.
+ALTER TABLE responses ALTER COLUMN respondent_id DROP NOT NULL;
+ALTER TABLE responses ADD COLUMN pseudonym VARCHAR(32);
+ALTER TABLE responses ADD CONSTRAINT responses_respondent_or_pseudonym
+    CHECK (respondent_id IS NOT NULL OR pseudonym IS NOT NULL);
+
+CREATE UNIQUE INDEX idx_responses_pseudonym ON responses(survey_id, pseudonym);
+
+-- Who answered an anonymous survey. Only payouts and the checks that a response is the caller's
+-- own read this table; nothing shown to researchers joins it.
+CREATE TABLE respondent_identities (
+    response_id BIGINT PRIMARY KEY REFERENCES responses(id) ON DELETE CASCADE,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    respondent_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(survey_id, respondent_id) -- One response per user per survey
+);
+
+CREATE INDEX idx_respondent_identities_respondent_id ON respondent_identities(respondent_id);
+
+-- The demographics of the respondent to an anonymous survey as they were when the response
+-- started, so results can be broken down by them without a way back to the profile.
+CREATE TABLE response_profiles (
+    response_id BIGINT PRIMARY KEY REFERENCES responses(id) ON DELETE CASCADE,
+    date_of_birth DATE,
+    gender gender,
+    university VARCHAR(255),
+    faculty VARCHAR(255),
+    location VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The demographics of every response, from the respondent's profile or from the snapshot of an
+-- anonymous one.
+CREATE VIEW response_demographics AS
+SELECT r.id AS response_id, p.date_of_birth, p.gender, p.university, p.faculty, p.location
+FROM responses r
+JOIN profiles p ON p.user_id = r.respondent_id
+UNION ALL
+SELECT rp.response_id, rp.date_of_birth, rp.gender, rp.university, rp.faculty, rp.location
+FROM response_profiles rp;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW IF EXISTS response_demographics;
+DROP TABLE IF EXISTS response_profiles;
+DROP TABLE IF EXISTS respondent_identities;
+
+DROP INDEX IF EXISTS idx_responses_pseudonym;
+ALTER TABLE responses DROP CONSTRAINT IF EXISTS responses_respondent_or_pseudonym;
+ALTER TABLE responses DROP COLUMN IF EXISTS pseudonym;
+DELETE FROM responses WHERE respondent_id IS NULL;
+ALTER TABLE responses ALTER COLUMN respondent_id SET NOT NULL;
+
+ALTER TABLE surveys DROP COLUMN IF EXISTS anonymous;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A panel follows the same cohort through recurring waves of a survey.
+CREATE TABLE panels (
+    id BIGSERIAL PRIMARY KEY,
+    researcher_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    interval_days INT NOT NULL CHECK (interval_days > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_panels_researcher_id ON panels(researcher_id, created_at);
+
+-- Wave 1 is the survey the panel started from, every later wave a copy of the wave before it.
+CREATE TABLE panel_waves (
+    panel_id BIGINT NOT NULL REFERENCES panels(id) ON DELETE CASCADE,
+    wave INT NOT NULL CHECK (wave > 0),
+    survey_id BIGINT NOT NULL UNIQUE REFERENCES surveys(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (panel_id, wave)
+);
+
+-- The respondents of a panel, each known to the researcher by participant_id in every wave. wave is
+-- the one they were invited from, they can answer every wave after it.
+CREATE TABLE panel_participants (
+    id BIGSERIAL PRIMARY KEY,
+    panel_id BIGINT NOT NULL REFERENCES panels(id) ON DELETE CASCADE,
+    respondent_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    participant_id VARCHAR(32) NOT NULL UNIQUE,
+    wave INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(panel_id, respondent_id)
+);
+
+CREATE INDEX idx_panel_participants_respondent_id ON panel_participants(respondent_id);
+
+-- The question of wave 1 each copied question of a later wave goes back to, so answers to it can be
+-- compared from one wave to the next. Questions of wave 1, or added to a wave after it was copied,
+-- have no row and go back to themselves.
+CREATE TABLE panel_questions (
+    question_id BIGINT PRIMARY KEY REFERENCES questions(id) ON DELETE CASCADE,
+    panel_id BIGINT NOT NULL REFERENCES panels(id) ON DELETE CASCADE,
+    item_id BIGINT NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS panel_questions;
+DROP TABLE IF EXISTS panel_participants;
+DROP TABLE IF EXISTS panel_waves;
+DROP TABLE IF EXISTS panels;
+-- +goose StatementEnd
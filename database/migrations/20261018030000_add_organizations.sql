@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE organization_role AS ENUM (
+  'owner',
+  'admin',
+  'editor',
+  'viewer'
+);
+
+CREATE TABLE organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE organization_members (
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role organization_role NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+-- ownership is handed over by changing roles, so nobody is invited as an owner
+CREATE TABLE organization_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role organization_role NOT NULL CHECK (role <> 'owner'),
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- an email has at most one pending invitation to an organization, inviting it again renews it
+CREATE UNIQUE INDEX idx_organization_invitations_pending ON organization_invitations(organization_id, email) WHERE accepted_at IS NULL;
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+-- a wallet belongs to either a user or an organization
+ALTER TABLE wallets
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN organization_id BIGINT UNIQUE REFERENCES organizations(id) ON DELETE CASCADE,
+    ADD CONSTRAINT wallets_owner_check CHECK ((user_id IS NULL) <> (organization_id IS NULL));
+
+-- the surveys of an organization are paid for from its wallet, and so are refunded to it
+ALTER TABLE surveys
+    ADD COLUMN organization_id BIGINT REFERENCES organizations(id);
+
+ALTER TABLE escrows
+    ADD COLUMN organization_id BIGINT REFERENCES organizations(id);
+
+CREATE INDEX idx_surveys_organization_id ON surveys(organization_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE escrows
+    DROP COLUMN IF EXISTS organization_id;
+
+ALTER TABLE surveys
+    DROP COLUMN IF EXISTS organization_id;
+
+DELETE FROM wallets WHERE organization_id IS NOT NULL;
+
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_owner_check,
+    DROP COLUMN IF EXISTS organization_id,
+    ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+
+DROP TYPE IF EXISTS organization_role;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Test responses are the walk-throughs researchers make of their own surveys. They never touch money
+-- or quotas and a researcher can make as many as they like, so only real responses are one per
+-- respondent.
+ALTER TABLE responses ADD COLUMN is_test BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE responses DROP CONSTRAINT IF EXISTS responses_survey_id_respondent_id_key;
+CREATE UNIQUE INDEX idx_responses_survey_respondent ON responses(survey_id, respondent_id) WHERE NOT is_test;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Test responses never have payouts, so they go without touching any. The real responses left are
+-- the ones idx_responses_survey_respondent kept to one per respondent, including those researchers
+-- gave to surveys they also tested, so the unique constraint can be put back on them as they are.
+DELETE FROM responses WHERE is_test;
+
+DROP INDEX IF EXISTS idx_responses_survey_respondent;
+ALTER TABLE responses ADD CONSTRAINT responses_survey_id_respondent_id_key UNIQUE (survey_id, respondent_id);
+
+ALTER TABLE responses DROP COLUMN IF EXISTS is_test;
+-- +goose StatementEnd
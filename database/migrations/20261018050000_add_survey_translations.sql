@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A survey is written in its default locale, which is also what respondents fall back to. The other
+-- locales it is offered in hold a translation of every string, keyed by locale: the survey's title
+-- and description in surveys.translations, the text and the option labels of each question in
+-- questions.translations. Answers keep referring to options by ID, whatever language they were
+-- shown in.
+ALTER TABLE surveys ADD COLUMN default_locale TEXT NOT NULL DEFAULT 'en';
+ALTER TABLE surveys ADD COLUMN locales TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE surveys ADD COLUMN translations JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE questions ADD COLUMN translations JSONB NOT NULL DEFAULT '{}';
+
+-- the language a user would rather answer surveys in, over what their browser asks for
+ALTER TABLE profiles ADD COLUMN locale TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE profiles DROP COLUMN IF EXISTS locale;
+
+ALTER TABLE questions DROP COLUMN IF EXISTS translations;
+
+ALTER TABLE surveys DROP COLUMN IF EXISTS translations;
+ALTER TABLE surveys DROP COLUMN IF EXISTS locales;
+ALTER TABLE surveys DROP COLUMN IF EXISTS default_locale;
+-- +goose StatementEnd
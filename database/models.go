@@ -53,6 +53,48 @@ func (ns NullAuthProvider) Value() (driver.Value, error) {
 	return string(ns.AuthProvider), nil
 }
 
+type EscrowStatus string
+
+const (
+	EscrowStatusHeld    EscrowStatus = "held"
+	EscrowStatusSettled EscrowStatus = "settled"
+)
+
+func (e *EscrowStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EscrowStatus(s)
+	case string:
+		*e = EscrowStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EscrowStatus: %T", src)
+	}
+	return nil
+}
+
+type NullEscrowStatus struct {
+	EscrowStatus EscrowStatus
+	Valid        bool // Valid is true if EscrowStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEscrowStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.EscrowStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EscrowStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEscrowStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EscrowStatus), nil
+}
+
 type Gender string
 
 const (
@@ -96,6 +138,342 @@ func (ns NullGender) Value() (driver.Value, error) {
 	return string(ns.Gender), nil
 }
 
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "owner"
+	OrganizationRoleAdmin  OrganizationRole = "admin"
+	OrganizationRoleEditor OrganizationRole = "editor"
+	OrganizationRoleViewer OrganizationRole = "viewer"
+)
+
+func (e *OrganizationRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = OrganizationRole(s)
+	case string:
+		*e = OrganizationRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for OrganizationRole: %T", src)
+	}
+	return nil
+}
+
+type NullOrganizationRole struct {
+	OrganizationRole OrganizationRole
+	Valid            bool // Valid is true if OrganizationRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullOrganizationRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.OrganizationRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.OrganizationRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullOrganizationRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.OrganizationRole), nil
+}
+
+type PayoutKind string
+
+const (
+	PayoutKindReward    PayoutKind = "reward"
+	PayoutKindScreenOut PayoutKind = "screen_out"
+)
+
+func (e *PayoutKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PayoutKind(s)
+	case string:
+		*e = PayoutKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PayoutKind: %T", src)
+	}
+	return nil
+}
+
+type NullPayoutKind struct {
+	PayoutKind PayoutKind
+	Valid      bool // Valid is true if PayoutKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPayoutKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.PayoutKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PayoutKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPayoutKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PayoutKind), nil
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusHeld     PayoutStatus = "held"
+	PayoutStatusPaid     PayoutStatus = "paid"
+	PayoutStatusReturned PayoutStatus = "returned"
+)
+
+func (e *PayoutStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PayoutStatus(s)
+	case string:
+		*e = PayoutStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PayoutStatus: %T", src)
+	}
+	return nil
+}
+
+type NullPayoutStatus struct {
+	PayoutStatus PayoutStatus
+	Valid        bool // Valid is true if PayoutStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPayoutStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.PayoutStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PayoutStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPayoutStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PayoutStatus), nil
+}
+
+type QuestionType string
+
+const (
+	QuestionTypeSingleChoice   QuestionType = "single_choice"
+	QuestionTypeMultipleChoice QuestionType = "multiple_choice"
+	QuestionTypeLikert         QuestionType = "likert"
+	QuestionTypeNumeric        QuestionType = "numeric"
+	QuestionTypeText           QuestionType = "text"
+	QuestionTypeRanking        QuestionType = "ranking"
+	QuestionTypeMatrix         QuestionType = "matrix"
+)
+
+func (e *QuestionType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = QuestionType(s)
+	case string:
+		*e = QuestionType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for QuestionType: %T", src)
+	}
+	return nil
+}
+
+type NullQuestionType struct {
+	QuestionType QuestionType
+	Valid        bool // Valid is true if QuestionType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullQuestionType) Scan(value interface{}) error {
+	if value == nil {
+		ns.QuestionType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.QuestionType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullQuestionType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.QuestionType), nil
+}
+
+type ResponseStatus string
+
+const (
+	ResponseStatusInProgress  ResponseStatus = "in_progress"
+	ResponseStatusSubmitted   ResponseStatus = "submitted"
+	ResponseStatusScreenedOut ResponseStatus = "screened_out"
+	ResponseStatusExpired     ResponseStatus = "expired"
+)
+
+func (e *ResponseStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ResponseStatus(s)
+	case string:
+		*e = ResponseStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ResponseStatus: %T", src)
+	}
+	return nil
+}
+
+type NullResponseStatus struct {
+	ResponseStatus ResponseStatus
+	Valid          bool // Valid is true if ResponseStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullResponseStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ResponseStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ResponseStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullResponseStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ResponseStatus), nil
+}
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending     ReviewStatus = "pending"
+	ReviewStatusApproved    ReviewStatus = "approved"
+	ReviewStatusNeedsReview ReviewStatus = "needs_review"
+	ReviewStatusRejected    ReviewStatus = "rejected"
+)
+
+func (e *ReviewStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReviewStatus(s)
+	case string:
+		*e = ReviewStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReviewStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReviewStatus struct {
+	ReviewStatus ReviewStatus
+	Valid        bool // Valid is true if ReviewStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReviewStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReviewStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReviewStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReviewStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReviewStatus), nil
+}
+
+type SurveyStatus string
+
+const (
+	SurveyStatusDraft     SurveyStatus = "draft"
+	SurveyStatusPublished SurveyStatus = "published"
+	SurveyStatusClosed    SurveyStatus = "closed"
+	SurveyStatusArchived  SurveyStatus = "archived"
+	SurveyStatusCancelled SurveyStatus = "cancelled"
+)
+
+func (e *SurveyStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SurveyStatus(s)
+	case string:
+		*e = SurveyStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SurveyStatus: %T", src)
+	}
+	return nil
+}
+
+type NullSurveyStatus struct {
+	SurveyStatus SurveyStatus
+	Valid        bool // Valid is true if SurveyStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSurveyStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.SurveyStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SurveyStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSurveyStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SurveyStatus), nil
+}
+
+type Answer struct {
+	ID         int64
+	ResponseID int64
+	QuestionID int64
+	Value      []byte
+	CreatedAt  pgtype.Timestamp
+	UpdatedAt  pgtype.Timestamp
+	Display    []byte
+}
+
+type Escrow struct {
+	ID                int64
+	SurveyID          int64
+	ResearcherID      int64
+	RewardPerResponse pgtype.Numeric
+	FeePerResponse    pgtype.Numeric
+	Amount            pgtype.Numeric
+	Spent             pgtype.Numeric
+	Refunded          pgtype.Numeric
+	Status            EscrowStatus
+	CreatedAt         pgtype.Timestamp
+	UpdatedAt         pgtype.Timestamp
+	ScreenOutFee      pgtype.Numeric
+	Reserved          pgtype.Numeric
+	OrganizationID    pgtype.Int8
+}
+
 type Field struct {
 	ID        int64
 	Name      string
@@ -110,6 +488,33 @@ type InterestArea struct {
 	CreatedAt pgtype.Timestamp
 }
 
+type Organization struct {
+	ID        int64
+	Name      string
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+}
+
+type OrganizationInvitation struct {
+	ID             int64
+	OrganizationID int64
+	Email          string
+	Role           OrganizationRole
+	Token          string
+	InvitedBy      pgtype.Int8
+	ExpiresAt      pgtype.Timestamp
+	AcceptedAt     pgtype.Timestamp
+	CreatedAt      pgtype.Timestamp
+}
+
+type OrganizationMember struct {
+	OrganizationID int64
+	UserID         int64
+	Role           OrganizationRole
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+}
+
 type OtpVerification struct {
 	ID        int64
 	UserID    int64
@@ -120,6 +525,49 @@ type OtpVerification struct {
 	UpdatedAt pgtype.Timestamp
 }
 
+type Panel struct {
+	ID           int64
+	ResearcherID int64
+	Name         string
+	Description  pgtype.Text
+	IntervalDays int32
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+}
+
+type PanelParticipant struct {
+	ID            int64
+	PanelID       int64
+	RespondentID  int64
+	ParticipantID string
+	Wave          int32
+	CreatedAt     pgtype.Timestamp
+}
+
+type PanelQuestion struct {
+	QuestionID int64
+	PanelID    int64
+	ItemID     int64
+}
+
+type PanelWave struct {
+	PanelID   int64
+	Wave      int32
+	SurveyID  int64
+	CreatedAt pgtype.Timestamp
+}
+
+type Payout struct {
+	ID           int64
+	ResponseID   int64
+	SurveyID     int64
+	RespondentID int64
+	Amount       pgtype.Numeric
+	CreatedAt    pgtype.Timestamp
+	Kind         PayoutKind
+	Status       PayoutStatus
+}
+
 type Profile struct {
 	ID          int64
 	FirstName   pgtype.Text
@@ -132,6 +580,130 @@ type Profile struct {
 	UserID      int64
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
+	Locale      pgtype.Text
+}
+
+type Question struct {
+	ID           int64
+	SurveyID     int64
+	Position     int32
+	Type         QuestionType
+	Title        string
+	Description  pgtype.Text
+	Required     bool
+	Config       []byte
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	Logic        []byte
+	Screener     bool
+	RemovedAt    pgtype.Timestamp
+	Block        pgtype.Text
+	Translations []byte
+}
+
+type QuotaCell struct {
+	ID        int64
+	SurveyID  int64
+	Name      string
+	Criteria  []byte
+	Capacity  int32
+	Filled    int32
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	Held      int32
+}
+
+type RespondentIdentity struct {
+	ResponseID   int64
+	SurveyID     int64
+	RespondentID int64
+	CreatedAt    pgtype.Timestamp
+}
+
+type Response struct {
+	ID             int64
+	SurveyID       int64
+	RespondentID   pgtype.Int8
+	Status         ResponseStatus
+	StartedAt      pgtype.Timestamp
+	SubmittedAt    pgtype.Timestamp
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+	ReviewStatus   NullReviewStatus
+	QualityReport  []byte
+	ReviewReason   pgtype.Text
+	ReviewedBy     pgtype.Int8
+	ReviewedAt     pgtype.Timestamp
+	LastQuestionID pgtype.Int8
+	ExpiresAt      pgtype.Timestamp
+	SurveyVersion  int32
+	OrderSeed      int64
+	Pseudonym      pgtype.Text
+	IsTest         bool
+}
+
+type ResponseDemographic struct {
+	ResponseID  int64
+	DateOfBirth pgtype.Date
+	Gender      NullGender
+	University  pgtype.Text
+	Faculty     pgtype.Text
+	Location    pgtype.Text
+}
+
+type ResponseProfile struct {
+	ResponseID  int64
+	DateOfBirth pgtype.Date
+	Gender      NullGender
+	University  pgtype.Text
+	Faculty     pgtype.Text
+	Location    pgtype.Text
+	CreatedAt   pgtype.Timestamp
+}
+
+type ResponseQuotaHold struct {
+	ResponseID  int64
+	QuotaCellID int64
+	CreatedAt   pgtype.Timestamp
+}
+
+type Survey struct {
+	ID                int64
+	ResearcherID      int64
+	Title             string
+	Description       pgtype.Text
+	Status            SurveyStatus
+	PublishedAt       pgtype.Timestamp
+	ClosedAt          pgtype.Timestamp
+	CreatedAt         pgtype.Timestamp
+	UpdatedAt         pgtype.Timestamp
+	RewardPerResponse pgtype.Numeric
+	TargetResponses   pgtype.Int4
+	Targeting         []byte
+	FieldIds          []int64
+	EstimatedMinutes  pgtype.Int4
+	ScreenOutFee      pgtype.Numeric
+	ShareDemographics bool
+	QualityChecks     []byte
+	ResumeWindowHours int32
+	Version           int32
+	IsTemplate        bool
+	ClonedFrom        pgtype.Int8
+	OpensAt           pgtype.Timestamp
+	ClosesAt          pgtype.Timestamp
+	Anonymous         bool
+	OrganizationID    pgtype.Int8
+	DefaultLocale     string
+	Locales           []string
+	Translations      []byte
+}
+
+type SurveyVersion struct {
+	ID        int64
+	SurveyID  int64
+	Version   int32
+	Questions []byte
+	CreatedAt pgtype.Timestamp
 }
 
 type User struct {
@@ -149,9 +721,10 @@ type User struct {
 }
 
 type Wallet struct {
-	ID        int64
-	Balance   pgtype.Numeric
-	UserID    int64
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
+	ID             int64
+	Balance        pgtype.Numeric
+	UserID         pgtype.Int8
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+	OrganizationID pgtype.Int8
 }
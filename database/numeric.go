@@ -0,0 +1,20 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+)
+
+// NumericFromDecimal converts a decimal into the type sqlc uses for DECIMAL columns.
+func NumericFromDecimal(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
+
+// DecimalFromNumeric converts a DECIMAL column into a decimal, NULL becomes zero.
+func DecimalFromNumeric(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
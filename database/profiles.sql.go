@@ -22,7 +22,7 @@ func (q *Queries) CreateProfile(ctx context.Context, userID int64) error {
 }
 
 const getProfile = `-- name: GetProfile :one
-SELECT id, first_name, last_name, date_of_birth, gender, university, faculty, location, user_id, created_at, updated_at FROM profiles WHERE user_id = $1
+SELECT id, first_name, last_name, date_of_birth, gender, university, faculty, location, user_id, created_at, updated_at, locale FROM profiles WHERE user_id = $1
 `
 
 func (q *Queries) GetProfile(ctx context.Context, userID int64) (Profile, error) {
@@ -40,6 +40,7 @@ func (q *Queries) GetProfile(ctx context.Context, userID int64) (Profile, error)
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -53,9 +54,11 @@ SET
     gender = COALESCE($4, gender),
     university = COALESCE($5, university),
     faculty = COALESCE($6, faculty),
-    location = COALESCE($7, location)
-WHERE user_id = $8
-RETURNING id, first_name, last_name, date_of_birth, gender, university, faculty, location, user_id, created_at, updated_at
+    location = COALESCE($7, location),
+    -- an empty locale clears the preference
+    locale = CASE WHEN $8::TEXT IS NULL THEN locale ELSE NULLIF($8::TEXT, '') END
+WHERE user_id = $9
+RETURNING id, first_name, last_name, date_of_birth, gender, university, faculty, location, user_id, created_at, updated_at, locale
 `
 
 type UpdateProfileParams struct {
@@ -66,6 +69,7 @@ type UpdateProfileParams struct {
 	University  pgtype.Text
 	Faculty     pgtype.Text
 	Location    pgtype.Text
+	Locale      pgtype.Text
 	UserID      int64
 }
 
@@ -78,6 +82,7 @@ func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (P
 		arg.University,
 		arg.Faculty,
 		arg.Location,
+		arg.Locale,
 		arg.UserID,
 	)
 	var i Profile
@@ -93,6 +98,7 @@ func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (P
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -0,0 +1,29 @@
+-- name: UpsertAnswer :exec
+-- Display records where the question and its options were shown to the respondent, NULL when it
+-- isn't known.
+INSERT INTO answers (response_id, question_id, value, display)
+VALUES (sqlc.arg(response_id), sqlc.arg(question_id), sqlc.arg(value), sqlc.narg(display))
+ON CONFLICT (response_id, question_id)
+DO UPDATE SET value = EXCLUDED.value, display = EXCLUDED.display, updated_at = CURRENT_TIMESTAMP;
+
+-- name: ListAnswersByResponse :many
+SELECT * FROM answers
+WHERE response_id = sqlc.arg(response_id)
+ORDER BY question_id;
+
+-- name: DeleteAnswers :exec
+DELETE FROM answers
+WHERE response_id = sqlc.arg(response_id) AND question_id = ANY(sqlc.arg(question_ids)::BIGINT[]);
+
+-- name: DeleteAnswersByResponse :exec
+DELETE FROM answers
+WHERE response_id = sqlc.arg(response_id);
+
+-- name: CountAnswerValues :many
+-- The distinct answers given to a question by the responses still in progress or submitted, and
+-- how many times each was given.
+SELECT a.value, COUNT(*)::INT AS count
+FROM answers a
+JOIN responses r ON r.id = a.response_id
+WHERE a.question_id = sqlc.arg(question_id) AND r.status IN ('in_progress', 'submitted') AND NOT r.is_test
+GROUP BY a.value;
@@ -0,0 +1,58 @@
+-- name: CreateEscrow :one
+INSERT INTO escrows (survey_id, researcher_id, reward_per_response, fee_per_response, amount, screen_out_fee, organization_id)
+VALUES (
+    sqlc.arg(survey_id), sqlc.arg(researcher_id), sqlc.arg(reward_per_response), sqlc.arg(fee_per_response), sqlc.arg(amount),
+    sqlc.arg(screen_out_fee), sqlc.narg(organization_id)
+)
+RETURNING *;
+
+-- name: GetEscrowBySurvey :one
+SELECT * FROM escrows
+WHERE survey_id = sqlc.arg(survey_id);
+
+-- name: SettleEscrow :one
+UPDATE escrows
+SET
+    refunded = amount - spent - reserved,
+    status = 'settled',
+    updated_at = CURRENT_TIMESTAMP
+WHERE survey_id = sqlc.arg(survey_id) AND status = 'held'
+RETURNING *;
+
+-- name: SpendEscrow :one
+UPDATE escrows
+SET
+    spent = spent + sqlc.arg(cost),
+    updated_at = CURRENT_TIMESTAMP
+WHERE survey_id = sqlc.arg(survey_id) AND status = 'held' AND spent + reserved + sqlc.arg(cost) <= amount
+RETURNING *;
+
+-- name: ReserveEscrow :one
+UPDATE escrows
+SET
+    reserved = reserved + sqlc.arg(cost),
+    updated_at = CURRENT_TIMESTAMP
+WHERE survey_id = sqlc.arg(survey_id) AND status = 'held' AND spent + reserved + sqlc.arg(cost) <= amount
+RETURNING *;
+
+-- name: ReleaseEscrow :one
+-- Spends money reserved for a response. The escrow may have been settled since, what it reserved
+-- stays reserved until released or returned.
+UPDATE escrows
+SET
+    reserved = reserved - sqlc.arg(cost),
+    spent = spent + sqlc.arg(cost),
+    updated_at = CURRENT_TIMESTAMP
+WHERE survey_id = sqlc.arg(survey_id) AND reserved >= sqlc.arg(cost)
+RETURNING *;
+
+-- name: ReturnEscrow :one
+-- Gives back money reserved for a response. A held escrow can spend it on another response, a
+-- settled one refunds it.
+UPDATE escrows
+SET
+    reserved = reserved - sqlc.arg(cost),
+    refunded = refunded + CASE WHEN status = 'settled' THEN sqlc.arg(cost) ELSE 0 END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE survey_id = sqlc.arg(survey_id) AND reserved >= sqlc.arg(cost)
+RETURNING *;
@@ -0,0 +1,14 @@
+-- name: ListFieldIDsIn :many
+SELECT id FROM fields
+WHERE id = ANY(sqlc.arg(ids)::BIGINT[])
+ORDER BY id;
+
+-- name: ListFieldsIn :many
+SELECT * FROM fields
+WHERE id = ANY(sqlc.arg(ids)::BIGINT[])
+ORDER BY id;
+
+-- name: ListFieldsNamed :many
+SELECT * FROM fields
+WHERE name = ANY(sqlc.arg(names)::TEXT[])
+ORDER BY id;
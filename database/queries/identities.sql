@@ -0,0 +1,17 @@
+-- name: CreateRespondentIdentity :exec
+-- Records who answered an anonymous survey. Only payouts and the checks that a response is the
+-- caller's own may read it back.
+INSERT INTO respondent_identities (response_id, survey_id, respondent_id)
+VALUES (sqlc.arg(response_id), sqlc.arg(survey_id), sqlc.arg(respondent_id));
+
+-- name: GetRespondentIdentity :one
+SELECT respondent_id FROM respondent_identities
+WHERE response_id = sqlc.arg(response_id);
+
+-- name: CreateResponseProfile :exec
+-- Copies the demographics of the respondent to an anonymous survey as they are now, so its results
+-- can be broken down by them without a way back to the profile.
+INSERT INTO response_profiles (response_id, date_of_birth, gender, university, faculty, location)
+SELECT sqlc.arg(response_id), p.date_of_birth, p.gender, p.university, p.faculty, p.location
+FROM profiles p
+WHERE p.user_id = sqlc.arg(respondent_id);
@@ -0,0 +1,4 @@
+-- name: ListInterestFieldIDs :many
+SELECT field_id FROM interest_areas
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY field_id;
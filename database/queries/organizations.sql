@@ -0,0 +1,88 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations (name)
+VALUES (sqlc.arg(name))
+RETURNING *;
+
+-- name: GetOrganization :one
+SELECT * FROM organizations
+WHERE id = sqlc.arg(id);
+
+-- name: LockOrganization :exec
+-- Serializes the changes to the members of an organization for the rest of the transaction, so
+-- concurrent ones can't leave it without an owner.
+SELECT id FROM organizations
+WHERE id = sqlc.arg(id)
+FOR UPDATE;
+
+-- name: ListOrganizationsByMember :many
+-- The organizations a user is a member of, with their role in each.
+SELECT o.id, o.name, m.role, o.created_at, o.updated_at
+FROM organizations o
+JOIN organization_members m ON m.organization_id = o.id
+WHERE m.user_id = sqlc.arg(user_id)
+ORDER BY o.created_at DESC, o.id DESC;
+
+-- name: CreateOrganizationMember :one
+INSERT INTO organization_members (organization_id, user_id, role)
+VALUES (sqlc.arg(organization_id), sqlc.arg(user_id), sqlc.arg(role))
+RETURNING *;
+
+-- name: GetOrganizationMember :one
+SELECT * FROM organization_members
+WHERE organization_id = sqlc.arg(organization_id) AND user_id = sqlc.arg(user_id);
+
+-- name: ListOrganizationMembers :many
+SELECT m.user_id, u.email, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = sqlc.arg(organization_id)
+ORDER BY m.created_at, m.user_id;
+
+-- name: CountOrganizationOwners :one
+SELECT COUNT(*)::INT AS owners FROM organization_members
+WHERE organization_id = sqlc.arg(organization_id) AND role = 'owner';
+
+-- name: UpdateOrganizationMemberRole :one
+UPDATE organization_members
+SET
+    role = sqlc.arg(role),
+    updated_at = CURRENT_TIMESTAMP
+WHERE organization_id = sqlc.arg(organization_id) AND user_id = sqlc.arg(user_id)
+RETURNING *;
+
+-- name: DeleteOrganizationMember :execrows
+DELETE FROM organization_members
+WHERE organization_id = sqlc.arg(organization_id) AND user_id = sqlc.arg(user_id);
+
+-- name: CreateOrganizationInvitation :one
+-- Inviting an email that has a pending invitation renews it with a new token, role and expiry.
+INSERT INTO organization_invitations (organization_id, email, role, token, invited_by, expires_at)
+VALUES (
+    sqlc.arg(organization_id), sqlc.arg(email), sqlc.arg(role), sqlc.arg(token), sqlc.arg(invited_by),
+    sqlc.arg(expires_at)
+)
+ON CONFLICT (organization_id, email) WHERE accepted_at IS NULL DO UPDATE
+SET
+    role = EXCLUDED.role,
+    token = EXCLUDED.token,
+    invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at,
+    created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: ListOrganizationInvitations :many
+-- The invitations to an organization nobody has accepted yet, expired ones included.
+SELECT * FROM organization_invitations
+WHERE organization_id = sqlc.arg(organization_id) AND accepted_at IS NULL
+ORDER BY created_at DESC, id DESC;
+
+-- name: AcceptOrganizationInvitation :one
+-- Only the email an invitation was sent to can accept it, once and before it expires.
+UPDATE organization_invitations
+SET accepted_at = CURRENT_TIMESTAMP
+WHERE token = sqlc.arg(token) AND email = sqlc.arg(email) AND accepted_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteOrganizationInvitation :execrows
+DELETE FROM organization_invitations
+WHERE id = sqlc.arg(id) AND organization_id = sqlc.arg(organization_id) AND accepted_at IS NULL;
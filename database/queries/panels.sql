@@ -0,0 +1,117 @@
+-- name: CreatePanel :one
+INSERT INTO panels (researcher_id, name, description, interval_days)
+VALUES (sqlc.arg(researcher_id), sqlc.arg(name), sqlc.narg(description), sqlc.arg(interval_days))
+RETURNING *;
+
+-- name: GetPanel :one
+SELECT * FROM panels
+WHERE id = sqlc.arg(id) AND researcher_id = sqlc.arg(researcher_id);
+
+-- name: ListPanels :many
+SELECT * FROM panels
+WHERE researcher_id = sqlc.arg(researcher_id)
+ORDER BY created_at DESC, id DESC;
+
+-- name: CreatePanelWave :one
+INSERT INTO panel_waves (panel_id, wave, survey_id)
+VALUES (sqlc.arg(panel_id), sqlc.arg(wave), sqlc.arg(survey_id))
+RETURNING *;
+
+-- name: GetLatestPanelWave :one
+SELECT * FROM panel_waves
+WHERE panel_id = sqlc.arg(panel_id)
+ORDER BY wave DESC
+LIMIT 1;
+
+-- name: GetPanelWaveBySurvey :one
+SELECT * FROM panel_waves
+WHERE survey_id = sqlc.arg(survey_id);
+
+-- name: ListPanelWaves :many
+-- The waves of a panel in order with the state of their surveys.
+SELECT
+    w.wave, w.survey_id, s.title, s.status, s.opens_at, s.closes_at, s.published_at, s.closed_at
+FROM panel_waves w
+JOIN surveys s ON s.id = w.survey_id
+WHERE w.panel_id = sqlc.arg(panel_id)
+ORDER BY w.wave;
+
+-- name: CreatePanelQuestion :exec
+INSERT INTO panel_questions (question_id, panel_id, item_id)
+VALUES (sqlc.arg(question_id), sqlc.arg(panel_id), sqlc.arg(item_id));
+
+-- name: ListPanelQuestions :many
+SELECT * FROM panel_questions
+WHERE panel_id = sqlc.arg(panel_id);
+
+-- name: EnrollPanelParticipants :many
+-- Adds to a panel every respondent who submitted one of its waves and isn't part of it yet, or only
+-- those who submitted the given wave, each under a new participant ID. Returns who was added.
+WITH enrolled AS (
+    INSERT INTO panel_participants (panel_id, respondent_id, participant_id, wave)
+    SELECT
+        w.panel_id, r.respondent_id, 'p_' || substr(md5(gen_random_uuid()::TEXT), 1, 16), MIN(w.wave)
+    FROM panel_waves w
+    JOIN responses r ON r.survey_id = w.survey_id
+    WHERE w.panel_id = sqlc.arg(panel_id)
+      AND r.status = 'submitted'
+      AND NOT r.is_test
+      AND r.respondent_id IS NOT NULL
+      AND (sqlc.narg(wave)::INT IS NULL OR w.wave = sqlc.narg(wave))
+    GROUP BY w.panel_id, r.respondent_id
+    ON CONFLICT (panel_id, respondent_id) DO NOTHING
+    RETURNING *
+)
+SELECT e.participant_id, e.wave, u.email
+FROM enrolled e
+JOIN users u ON u.id = e.respondent_id
+ORDER BY e.id;
+
+-- name: ListPanelWaveInvitees :many
+-- The participants of a panel who can answer the given wave, with their emails.
+SELECT p.participant_id, u.email
+FROM panel_participants p
+JOIN users u ON u.id = p.respondent_id
+WHERE p.panel_id = sqlc.arg(panel_id) AND p.wave < sqlc.arg(wave)
+ORDER BY p.id;
+
+-- name: CanAnswerSurvey :one
+-- Whether the user may answer a survey as far as panels go: every wave after the first is only open
+-- to the participants invited from an earlier one.
+SELECT NOT EXISTS (
+    SELECT 1 FROM panel_waves w
+    WHERE w.survey_id = sqlc.arg(survey_id) AND w.wave > 1
+      AND NOT EXISTS (
+          SELECT 1 FROM panel_participants p
+          WHERE p.panel_id = w.panel_id AND p.respondent_id = sqlc.arg(respondent_id) AND p.wave < w.wave
+      )
+)::BOOLEAN AS allowed;
+
+-- name: GetPanelAttrition :many
+-- For every wave of a panel, how many participants could answer it, and how many of them started and
+-- submitted a response. Participants invited from a wave count as having submitted it.
+SELECT
+    w.wave, w.survey_id,
+    COUNT(p.id)::INT AS participants,
+    COUNT(r.id)::INT AS started,
+    COUNT(r.id) FILTER (WHERE r.status = 'submitted')::INT AS completed
+FROM panel_waves w
+LEFT JOIN panel_participants p ON p.panel_id = w.panel_id AND p.wave <= w.wave
+LEFT JOIN responses r ON r.survey_id = w.survey_id AND r.respondent_id = p.respondent_id AND NOT r.is_test
+WHERE w.panel_id = sqlc.arg(panel_id)
+GROUP BY w.wave, w.survey_id
+ORDER BY w.wave;
+
+-- name: ListPanelAnswers :many
+-- Every answer participants submitted to the waves of a panel, under their participant ID and the
+-- wave 1 question each question goes back to, for exports.
+SELECT
+    p.participant_id, w.wave, w.survey_id, r.id AS response_id, r.submitted_at,
+    COALESCE(q.item_id, a.question_id)::BIGINT AS item_id, a.question_id, a.value
+FROM panel_waves w
+JOIN responses r ON r.survey_id = w.survey_id AND r.status = 'submitted' AND NOT r.is_test
+JOIN panel_participants p ON p.panel_id = w.panel_id AND p.respondent_id = r.respondent_id
+JOIN answers a ON a.response_id = r.id
+LEFT JOIN panel_questions q ON q.question_id = a.question_id
+WHERE w.panel_id = sqlc.arg(panel_id)
+ORDER BY p.participant_id, w.wave, a.question_id;
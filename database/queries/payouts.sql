@@ -0,0 +1,15 @@
+-- name: CreatePayout :one
+INSERT INTO payouts (response_id, survey_id, respondent_id, amount, kind, status)
+VALUES (sqlc.arg(response_id), sqlc.arg(survey_id), sqlc.arg(respondent_id), sqlc.arg(amount), sqlc.arg(kind), sqlc.arg(status))
+ON CONFLICT (response_id) DO NOTHING
+RETURNING *;
+
+-- name: GetPayoutByResponse :one
+SELECT * FROM payouts
+WHERE response_id = sqlc.arg(response_id);
+
+-- name: UpdatePayoutStatus :one
+UPDATE payouts
+SET status = sqlc.arg(next_status)
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(current_status)
+RETURNING *;
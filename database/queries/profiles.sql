@@ -14,6 +14,8 @@ SET
     gender = COALESCE(sqlc.narg(gender), gender),
     university = COALESCE(sqlc.narg(university), university),
     faculty = COALESCE(sqlc.narg(faculty), faculty),
-    location = COALESCE(sqlc.narg(location), location)
+    location = COALESCE(sqlc.narg(location), location),
+    -- an empty locale clears the preference
+    locale = CASE WHEN sqlc.narg(locale)::TEXT IS NULL THEN locale ELSE NULLIF(sqlc.narg(locale)::TEXT, '') END
 WHERE user_id = sqlc.arg(user_id)
 RETURNING *;
\ No newline at end of file
@@ -0,0 +1,62 @@
+-- name: CreateQuestion :one
+INSERT INTO questions (survey_id, position, type, title, description, required, config, logic, screener, block, translations)
+VALUES (
+    sqlc.arg(survey_id),
+    COALESCE(sqlc.narg(position), (SELECT COALESCE(MAX(position), 0) + 1 FROM questions WHERE survey_id = sqlc.arg(survey_id) AND removed_at IS NULL)),
+    sqlc.arg(type),
+    sqlc.arg(title),
+    sqlc.narg(description),
+    sqlc.arg(required),
+    sqlc.arg(config),
+    sqlc.narg(logic),
+    sqlc.arg(screener),
+    sqlc.narg(block),
+    COALESCE(sqlc.narg(translations)::JSONB, '{}')
+)
+RETURNING *;
+
+-- name: GetQuestion :one
+SELECT * FROM questions
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id) AND removed_at IS NULL;
+
+-- name: ListQuestionsBySurvey :many
+SELECT * FROM questions
+WHERE survey_id = sqlc.arg(survey_id) AND removed_at IS NULL
+ORDER BY position, id;
+
+-- name: UpdateQuestion :one
+UPDATE questions
+SET
+    position = COALESCE(sqlc.narg(position), position),
+    title = COALESCE(sqlc.narg(title), title),
+    description = COALESCE(sqlc.narg(description), description),
+    required = COALESCE(sqlc.narg(required), required),
+    config = COALESCE(sqlc.narg(config), config),
+    logic = COALESCE(sqlc.narg(logic), logic),
+    screener = COALESCE(sqlc.narg(screener), screener),
+    -- an empty block takes the question out of its block
+    block = CASE WHEN sqlc.narg(block)::TEXT IS NULL THEN block ELSE NULLIF(sqlc.narg(block)::TEXT, '') END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id) AND removed_at IS NULL
+RETURNING *;
+
+-- name: DeleteQuestion :execrows
+DELETE FROM questions
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id);
+
+-- name: RemoveQuestion :execrows
+-- Takes a question off a survey past draft. The question is kept, and so are the answers already
+-- given to it, it just isn't asked anymore.
+UPDATE questions
+SET
+    removed_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id) AND removed_at IS NULL;
+
+-- name: SetQuestionTranslation :execrows
+-- Replaces the strings of the question in one locale.
+UPDATE questions
+SET
+    translations = jsonb_set(translations, ARRAY[sqlc.arg(locale)::TEXT], sqlc.arg(translation)::JSONB),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id) AND removed_at IS NULL;
@@ -0,0 +1,78 @@
+-- name: CreateQuotaCell :one
+INSERT INTO quota_cells (survey_id, name, criteria, capacity)
+VALUES (sqlc.arg(survey_id), sqlc.arg(name), sqlc.arg(criteria), sqlc.arg(capacity))
+RETURNING *;
+
+-- name: ListQuotaCellsBySurvey :many
+SELECT * FROM quota_cells
+WHERE survey_id = sqlc.arg(survey_id)
+ORDER BY id;
+
+-- name: ListQuotaCellsBySurveys :many
+SELECT * FROM quota_cells
+WHERE survey_id = ANY(sqlc.arg(survey_ids)::BIGINT[])
+ORDER BY survey_id, id;
+
+-- name: DeleteQuotaCell :execrows
+DELETE FROM quota_cells
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id);
+
+-- name: FillQuotaCells :many
+-- The cells are locked in id order first, so respondents finishing at the same time and sharing
+-- cells queue up instead of deadlocking, and each one sees the count left by the one before.
+WITH locked AS (
+    SELECT id FROM quota_cells
+    WHERE id = ANY(sqlc.arg(ids)::BIGINT[])
+    ORDER BY id
+    FOR UPDATE
+)
+UPDATE quota_cells
+SET
+    filled = quota_cells.filled + 1,
+    updated_at = CURRENT_TIMESTAMP
+FROM locked
+WHERE quota_cells.id = locked.id AND quota_cells.filled + quota_cells.held < quota_cells.capacity
+RETURNING quota_cells.id;
+
+-- name: HoldQuotaCells :many
+-- Holds a slot in each of the cells for a response in progress, locking them in id order like
+-- FillQuotaCells does. Cells with no free slot left are left out.
+WITH locked AS (
+    SELECT id FROM quota_cells
+    WHERE id = ANY(sqlc.arg(ids)::BIGINT[])
+    ORDER BY id
+    FOR UPDATE
+), held AS (
+    UPDATE quota_cells
+    SET
+        held = quota_cells.held + 1,
+        updated_at = CURRENT_TIMESTAMP
+    FROM locked
+    WHERE quota_cells.id = locked.id AND quota_cells.filled + quota_cells.held < quota_cells.capacity
+    RETURNING quota_cells.id
+)
+INSERT INTO response_quota_holds (response_id, quota_cell_id)
+SELECT sqlc.arg(response_id), held.id FROM held
+RETURNING quota_cell_id;
+
+-- name: ReleaseQuotaHolds :many
+-- Gives up the slots a response holds in its quota cells. With fill set each slot is counted as
+-- filled instead of freed, which is how a submitted response keeps the slots it held.
+WITH released AS (
+    DELETE FROM response_quota_holds
+    WHERE response_id = sqlc.arg(response_id)
+    RETURNING quota_cell_id
+), locked AS (
+    SELECT id FROM quota_cells
+    WHERE id IN (SELECT quota_cell_id FROM released)
+    ORDER BY id
+    FOR UPDATE
+)
+UPDATE quota_cells
+SET
+    held = quota_cells.held - 1,
+    filled = quota_cells.filled + CASE WHEN sqlc.arg(fill)::BOOLEAN THEN 1 ELSE 0 END,
+    updated_at = CURRENT_TIMESTAMP
+FROM locked
+WHERE quota_cells.id = locked.id
+RETURNING quota_cells.id;
@@ -0,0 +1,223 @@
+-- name: CreateResponse :one
+-- The response expires once the survey's resume window passes without the respondent saving it.
+-- It is answered against the survey's current version. A response to an anonymous survey is
+-- stored under the pseudonym instead of the respondent, who is recorded in respondent_identities.
+INSERT INTO responses (survey_id, respondent_id, pseudonym, expires_at, survey_version)
+SELECT
+    s.id,
+    CASE WHEN s.anonymous THEN NULL ELSE sqlc.arg(respondent_id)::BIGINT END,
+    CASE WHEN s.anonymous THEN sqlc.arg(pseudonym)::TEXT END,
+    CURRENT_TIMESTAMP + make_interval(hours => s.resume_window_hours),
+    s.version
+FROM surveys s
+WHERE s.id = sqlc.arg(survey_id)
+RETURNING *;
+
+-- name: CreateTestResponse :one
+-- A test response is made by a researcher walking through their own survey, so it is always
+-- stored under them, even for anonymous surveys.
+INSERT INTO responses (survey_id, respondent_id, expires_at, survey_version, is_test)
+SELECT
+    s.id,
+    sqlc.arg(respondent_id)::BIGINT,
+    CURRENT_TIMESTAMP + make_interval(hours => s.resume_window_hours),
+    s.version,
+    true
+FROM surveys s
+WHERE s.id = sqlc.arg(survey_id)
+RETURNING *;
+
+-- name: DeleteTestResponses :execrows
+-- Their answers and everything else hanging off them go with them.
+DELETE FROM responses
+WHERE survey_id = sqlc.arg(survey_id) AND is_test;
+
+-- name: GetResponse :one
+-- A response of the respondent's, whether it is stored under them or, for an anonymous survey,
+-- under a pseudonym. The queries acting for a respondent check ownership the same way.
+SELECT * FROM responses
+WHERE id = sqlc.arg(id) AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR EXISTS (SELECT 1 FROM respondent_identities i WHERE i.response_id = responses.id AND i.respondent_id = sqlc.arg(respondent_id))
+  );
+
+-- name: GetResponseByID :one
+SELECT * FROM responses
+WHERE id = sqlc.arg(id);
+
+-- name: GetResponseBySurveyAndRespondent :one
+SELECT * FROM responses
+WHERE survey_id = sqlc.arg(survey_id) AND NOT is_test AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR id = (
+          SELECT i.response_id FROM respondent_identities i
+          WHERE i.survey_id = sqlc.arg(survey_id) AND i.respondent_id = sqlc.arg(respondent_id)
+      )
+  );
+
+-- name: SubmitResponse :one
+-- The answers are checked against the survey's current questions on submit, so that is the version
+-- the response ends up answered against.
+UPDATE responses
+SET
+    status = 'submitted',
+    survey_version = (SELECT s.version FROM surveys s WHERE s.id = responses.survey_id),
+    review_status = 'pending',
+    submitted_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR EXISTS (SELECT 1 FROM respondent_identities i WHERE i.response_id = responses.id AND i.respondent_id = sqlc.arg(respondent_id))
+  )
+  AND status = 'in_progress'
+RETURNING *;
+
+-- name: ScreenOutResponse :one
+UPDATE responses
+SET
+    status = 'screened_out',
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR EXISTS (SELECT 1 FROM respondent_identities i WHERE i.response_id = responses.id AND i.respondent_id = sqlc.arg(respondent_id))
+  )
+  AND status = 'in_progress'
+RETURNING *;
+
+-- name: SaveResponseProgress :one
+-- Records the last question a respondent saved and pushes the expiry of their response back by
+-- the survey's resume window. The response moves on to the survey's current version, which the
+-- saved answers were just checked against.
+UPDATE responses
+SET
+    last_question_id = sqlc.narg(last_question_id),
+    survey_version = (SELECT s.version FROM surveys s WHERE s.id = responses.survey_id),
+    expires_at = CURRENT_TIMESTAMP + make_interval(hours => (SELECT s.resume_window_hours FROM surveys s WHERE s.id = responses.survey_id)),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR EXISTS (SELECT 1 FROM respondent_identities i WHERE i.response_id = responses.id AND i.respondent_id = sqlc.arg(respondent_id))
+  )
+  AND status = 'in_progress'
+RETURNING *;
+
+-- name: RestartResponse :one
+-- Starts an expired response over from the first question. Its old answers are deleted separately.
+UPDATE responses
+SET
+    status = 'in_progress',
+    started_at = CURRENT_TIMESTAMP,
+    last_question_id = NULL,
+    survey_version = (SELECT s.version FROM surveys s WHERE s.id = responses.survey_id),
+    expires_at = CURRENT_TIMESTAMP + make_interval(hours => (SELECT s.resume_window_hours FROM surveys s WHERE s.id = responses.survey_id)),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+      respondent_id = sqlc.arg(respondent_id)
+      OR EXISTS (SELECT 1 FROM respondent_identities i WHERE i.response_id = responses.id AND i.respondent_id = sqlc.arg(respondent_id))
+  )
+  AND status = 'expired'
+RETURNING *;
+
+-- name: ExpireResponses :many
+-- Expires the responses in progress whose resume window has passed, oldest first. Responses locked
+-- by a respondent saving them right now are left for the next run.
+UPDATE responses
+SET
+    status = 'expired',
+    updated_at = CURRENT_TIMESTAMP
+WHERE status = 'in_progress' AND id IN (
+    SELECT r.id FROM responses r
+    WHERE r.status = 'in_progress' AND r.expires_at < CURRENT_TIMESTAMP
+    ORDER BY r.expires_at
+    LIMIT sqlc.arg(page_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ListSurveyExportRows :many
+-- One row per response to a survey with its answers keyed by question id and the demographics of
+-- the respondent, for exports. Callers decide whether the demographics may be shown. Test responses
+-- are left out unless asked for.
+SELECT
+    r.id, r.status, r.started_at, r.submitted_at, r.survey_version, r.order_seed, r.pseudonym, r.is_test,
+    COALESCE(
+        (SELECT jsonb_object_agg(a.question_id, a.value) FROM answers a WHERE a.response_id = r.id),
+        '{}'
+    )::JSONB AS answers,
+    p.date_of_birth, p.gender, p.university, p.faculty, p.location
+FROM responses r
+LEFT JOIN response_demographics p ON p.response_id = r.id
+WHERE r.survey_id = sqlc.arg(survey_id) AND (sqlc.arg(include_tests)::BOOLEAN OR NOT r.is_test)
+ORDER BY r.id;
+
+-- name: GetSurveyMedianDuration :one
+-- The median time the other submitted responses to a survey took, in seconds, and how many there
+-- are. The median is 0 when there are none.
+SELECT
+    COUNT(*)::INT AS responses,
+    COALESCE(
+        percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM submitted_at - started_at)),
+        0
+    )::FLOAT8 AS median_seconds
+FROM responses
+WHERE survey_id = sqlc.arg(survey_id) AND status = 'submitted' AND NOT is_test AND id <> sqlc.arg(exclude_id);
+
+-- name: SettleResponseReview :one
+UPDATE responses
+SET
+    review_status = sqlc.arg(review_status),
+    quality_report = sqlc.arg(quality_report),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND review_status = 'pending'
+RETURNING *;
+
+-- name: ReviewResponse :one
+-- Settles the review of a response awaiting one. reviewed_by is NULL when no researcher made the call.
+UPDATE responses
+SET
+    review_status = sqlc.arg(review_status),
+    review_reason = sqlc.narg(review_reason),
+    reviewed_by = sqlc.narg(reviewed_by),
+    reviewed_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND survey_id = sqlc.arg(survey_id) AND NOT is_test AND review_status IN ('pending', 'needs_review')
+RETURNING *;
+
+-- name: ListResponsesForReview :many
+-- The submitted responses to a survey with the given review status, or awaiting review when none
+-- is given, oldest first and after the given response ID. Responses to anonymous surveys have only
+-- a pseudonym.
+SELECT
+    r.id,
+    r.respondent_id,
+    r.pseudonym,
+    r.submitted_at,
+    r.review_status,
+    r.quality_report,
+    r.review_reason,
+    r.reviewed_at,
+    COALESCE(
+        (SELECT jsonb_object_agg(a.question_id::TEXT, a.value) FROM answers a WHERE a.response_id = r.id),
+        '{}'
+    )::JSONB AS answers
+FROM responses r
+WHERE r.survey_id = sqlc.arg(survey_id)
+  AND r.status = 'submitted'
+  AND NOT r.is_test
+  AND (
+      (sqlc.narg(review_status)::review_status IS NULL AND r.review_status IN ('pending', 'needs_review'))
+      OR r.review_status = sqlc.narg(review_status)::review_status
+  )
+  AND r.id > sqlc.arg(after)
+ORDER BY r.id
+LIMIT sqlc.arg(page_size);
+
+-- name: ListStaleReviews :many
+-- The responses that have been awaiting review for longer than the given number of days, oldest
+-- first.
+SELECT r.id, r.survey_id FROM responses r
+WHERE r.review_status IN ('pending', 'needs_review')
+  AND NOT r.is_test
+  AND r.submitted_at < CURRENT_TIMESTAMP - make_interval(days => sqlc.arg(older_than_days)::INT)
+ORDER BY r.submitted_at
+LIMIT sqlc.arg(page_size);
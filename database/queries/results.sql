@@ -0,0 +1,148 @@
+-- name: ListSurveyResults :many
+-- Aggregates the submitted responses to a survey that pass the filters. Each row is one of:
+-- a 'total' row with the number of responses, an 'answered' row per question with the number of
+-- responses that answered it, a 'choice' row per question, part and choice with the number of
+-- times it was picked (part is the matrix row or ranked option it belongs to, '' for the other
+-- types, and a ranking's choice is the rank given), a 'position' row per choice question, option
+-- and position the option was shown at with the number of times it was picked there (part is the
+-- option, choice the position, counted only for answers that recorded where their options were
+-- shown), and a 'stats' row per numeric or likert question. Test responses only count when asked for.
+WITH filtered AS (
+    SELECT r.id
+    FROM responses r
+    LEFT JOIN response_demographics p ON p.response_id = r.id
+    WHERE r.survey_id = sqlc.arg(survey_id) AND r.status = 'submitted'
+      AND (sqlc.narg(submitted_from)::TIMESTAMP IS NULL OR r.submitted_at >= sqlc.narg(submitted_from)::TIMESTAMP)
+      AND (sqlc.narg(submitted_before)::TIMESTAMP IS NULL OR r.submitted_at < sqlc.narg(submitted_before)::TIMESTAMP)
+      AND (sqlc.narg(gender)::gender IS NULL OR p.gender = sqlc.narg(gender)::gender)
+      AND (sqlc.narg(university)::TEXT IS NULL OR LOWER(TRIM(p.university)) = LOWER(TRIM(sqlc.narg(university)::TEXT)))
+      AND (sqlc.narg(faculty)::TEXT IS NULL OR LOWER(TRIM(p.faculty)) = LOWER(TRIM(sqlc.narg(faculty)::TEXT)))
+      AND (sqlc.narg(location)::TEXT IS NULL OR LOWER(TRIM(p.location)) = LOWER(TRIM(sqlc.narg(location)::TEXT)))
+      AND (sqlc.narg(born_on_or_before)::DATE IS NULL OR p.date_of_birth <= sqlc.narg(born_on_or_before)::DATE)
+      AND (sqlc.narg(born_after)::DATE IS NULL OR p.date_of_birth > sqlc.narg(born_after)::DATE)
+      AND (sqlc.arg(include_tests)::BOOLEAN OR NOT r.is_test)
+), answered AS (
+    SELECT a.response_id, a.question_id, q.type, a.value, a.display
+    FROM answers a
+    JOIN filtered f ON f.id = a.response_id
+    JOIN questions q ON q.id = a.question_id
+), choices AS (
+    SELECT question_id, '' AS part, value->>'option_id' AS choice FROM answered WHERE type = 'single_choice'
+    UNION ALL
+    SELECT question_id, '', jsonb_array_elements_text(value->'option_ids') FROM answered WHERE type = 'multiple_choice'
+    UNION ALL
+    SELECT question_id, '', value->>'value' FROM answered WHERE type = 'likert'
+    UNION ALL
+    SELECT a.question_id, m.key, m.value FROM answered a, jsonb_each_text(a.value->'rows') m WHERE a.type = 'matrix'
+    UNION ALL
+    SELECT a.question_id, o.option_id, o.rank::TEXT
+    FROM answered a, jsonb_array_elements_text(a.value->'ranking') WITH ORDINALITY o(option_id, rank)
+    WHERE a.type = 'ranking'
+), positions AS (
+    SELECT a.question_id, o.option_id, o.position
+    FROM answered a, jsonb_array_elements_text(a.display->'options') WITH ORDINALITY o(option_id, position)
+    WHERE (a.type = 'single_choice' AND a.value->>'option_id' = o.option_id)
+       OR (a.type = 'multiple_choice' AND a.value->'option_ids' @> to_jsonb(o.option_id))
+), numbers AS (
+    SELECT question_id, (value->>'value')::NUMERIC AS n
+    FROM answered
+    WHERE type IN ('numeric', 'likert') AND jsonb_typeof(value->'value') = 'number'
+)
+SELECT
+    'total' AS kind, 0::BIGINT AS question_id, '' AS part, '' AS choice, COUNT(*)::INT AS count,
+    NULL::NUMERIC AS mean, NULL::NUMERIC AS median, NULL::NUMERIC AS stddev, NULL::NUMERIC AS min, NULL::NUMERIC AS max
+FROM filtered
+UNION ALL
+SELECT 'answered', question_id, '', '', COUNT(*)::INT, NULL, NULL, NULL, NULL, NULL
+FROM answered
+GROUP BY question_id
+UNION ALL
+SELECT 'choice', question_id, part, choice, COUNT(*)::INT, NULL, NULL, NULL, NULL, NULL
+FROM choices
+GROUP BY question_id, part, choice
+UNION ALL
+SELECT 'position', question_id, option_id, position::TEXT, COUNT(*)::INT, NULL, NULL, NULL, NULL, NULL
+FROM positions
+GROUP BY question_id, option_id, position
+UNION ALL
+SELECT
+    'stats', question_id, '', '', COUNT(*)::INT,
+    ROUND(AVG(n), 4),
+    ROUND((percentile_cont(0.5) WITHIN GROUP (ORDER BY n::FLOAT8))::NUMERIC, 4),
+    ROUND(COALESCE(stddev_samp(n), 0), 4),
+    MIN(n),
+    MAX(n)
+FROM numbers
+GROUP BY question_id;
+
+-- name: CrossTabulateResults :many
+-- Counts the submitted responses that pass the filters by their answer to a choice or likert
+-- question (the row) against their answer to another such question or a profile attribute (the
+-- column). Multiple choice answers count once for every option picked; a missing attribute is
+-- counted as 'unknown'. Test responses only count when asked for.
+WITH filtered AS (
+    SELECT
+        r.id,
+        p.gender::TEXT AS gender,
+        LOWER(TRIM(p.university)) AS university,
+        LOWER(TRIM(p.faculty)) AS faculty,
+        LOWER(TRIM(p.location)) AS location,
+        CASE
+            WHEN p.date_of_birth IS NULL THEN NULL
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '18 years' THEN 'under 18'
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '25 years' THEN '18-24'
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '35 years' THEN '25-34'
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '45 years' THEN '35-44'
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '55 years' THEN '45-54'
+            WHEN age(sqlc.arg(as_of)::DATE, p.date_of_birth) < INTERVAL '65 years' THEN '55-64'
+            ELSE '65+'
+        END AS age_band
+    FROM responses r
+    LEFT JOIN response_demographics p ON p.response_id = r.id
+    WHERE r.survey_id = sqlc.arg(survey_id) AND r.status = 'submitted'
+      AND (sqlc.narg(submitted_from)::TIMESTAMP IS NULL OR r.submitted_at >= sqlc.narg(submitted_from)::TIMESTAMP)
+      AND (sqlc.narg(submitted_before)::TIMESTAMP IS NULL OR r.submitted_at < sqlc.narg(submitted_before)::TIMESTAMP)
+      AND (sqlc.narg(gender)::gender IS NULL OR p.gender = sqlc.narg(gender)::gender)
+      AND (sqlc.narg(university)::TEXT IS NULL OR LOWER(TRIM(p.university)) = LOWER(TRIM(sqlc.narg(university)::TEXT)))
+      AND (sqlc.narg(faculty)::TEXT IS NULL OR LOWER(TRIM(p.faculty)) = LOWER(TRIM(sqlc.narg(faculty)::TEXT)))
+      AND (sqlc.narg(location)::TEXT IS NULL OR LOWER(TRIM(p.location)) = LOWER(TRIM(sqlc.narg(location)::TEXT)))
+      AND (sqlc.narg(born_on_or_before)::DATE IS NULL OR p.date_of_birth <= sqlc.narg(born_on_or_before)::DATE)
+      AND (sqlc.narg(born_after)::DATE IS NULL OR p.date_of_birth > sqlc.narg(born_after)::DATE)
+      AND (sqlc.arg(include_tests)::BOOLEAN OR NOT r.is_test)
+), answer_keys AS (
+    SELECT a.response_id, a.question_id, k.key
+    FROM answers a
+    JOIN filtered f ON f.id = a.response_id
+    JOIN questions q ON q.id = a.question_id
+    CROSS JOIN LATERAL (
+        SELECT a.value->>'option_id' WHERE q.type = 'single_choice'
+        UNION ALL
+        SELECT jsonb_array_elements_text(a.value->'option_ids') WHERE q.type = 'multiple_choice'
+        UNION ALL
+        SELECT a.value->>'value' WHERE q.type = 'likert'
+    ) k(key)
+    WHERE a.question_id IN (sqlc.arg(row_question_id)::BIGINT, sqlc.narg(column_question_id)::BIGINT)
+), column_keys AS (
+    SELECT response_id, key FROM answer_keys WHERE question_id = sqlc.narg(column_question_id)::BIGINT
+    UNION ALL
+    SELECT
+        id,
+        COALESCE(
+            CASE sqlc.narg(column_attribute)::TEXT
+                WHEN 'gender' THEN gender
+                WHEN 'university' THEN university
+                WHEN 'faculty' THEN faculty
+                WHEN 'location' THEN location
+                WHEN 'age_band' THEN age_band
+            END,
+            'unknown'
+        )
+    FROM filtered
+    WHERE sqlc.narg(column_attribute)::TEXT IS NOT NULL
+)
+SELECT k.key AS row_key, c.key AS column_key, COUNT(*)::INT AS count
+FROM answer_keys k
+JOIN column_keys c ON c.response_id = k.response_id
+WHERE k.question_id = sqlc.arg(row_question_id)::BIGINT
+GROUP BY k.key, c.key
+ORDER BY k.key, c.key;
@@ -0,0 +1,276 @@
+-- name: CreateSurvey :one
+INSERT INTO surveys (researcher_id, title, description, reward_per_response, target_responses, targeting, field_ids, estimated_minutes, screen_out_fee, share_demographics, quality_checks, resume_window_hours, anonymous, organization_id)
+VALUES (
+    sqlc.arg(researcher_id), sqlc.arg(title), sqlc.narg(description), sqlc.narg(reward_per_response), sqlc.narg(target_responses),
+    sqlc.narg(targeting), COALESCE(sqlc.narg(field_ids)::BIGINT[], '{}'), sqlc.narg(estimated_minutes), sqlc.narg(screen_out_fee),
+    COALESCE(sqlc.narg(share_demographics)::BOOLEAN, false), sqlc.narg(quality_checks), COALESCE(sqlc.narg(resume_window_hours)::INT, 72),
+    COALESCE(sqlc.narg(anonymous)::BOOLEAN, false), sqlc.narg(organization_id)
+)
+RETURNING *;
+
+-- name: GetSurvey :one
+-- A researcher can see their own surveys and those of the organizations they are a member of.
+SELECT * FROM surveys
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = sqlc.arg(researcher_id))
+);
+
+-- name: ListSurveysByResearcher :many
+SELECT * FROM surveys
+WHERE (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = sqlc.arg(researcher_id))
+)
+ORDER BY created_at DESC;
+
+-- name: UpdateSurvey :one
+UPDATE surveys
+SET
+    title = COALESCE(sqlc.narg(title), title),
+    description = COALESCE(sqlc.narg(description), description),
+    reward_per_response = COALESCE(sqlc.narg(reward_per_response), reward_per_response),
+    target_responses = COALESCE(sqlc.narg(target_responses), target_responses),
+    targeting = COALESCE(sqlc.narg(targeting), targeting),
+    field_ids = COALESCE(sqlc.narg(field_ids)::BIGINT[], field_ids),
+    estimated_minutes = COALESCE(sqlc.narg(estimated_minutes), estimated_minutes),
+    screen_out_fee = COALESCE(sqlc.narg(screen_out_fee), screen_out_fee),
+    share_demographics = COALESCE(sqlc.narg(share_demographics)::BOOLEAN, share_demographics),
+    quality_checks = COALESCE(sqlc.narg(quality_checks), quality_checks),
+    resume_window_hours = COALESCE(sqlc.narg(resume_window_hours)::INT, resume_window_hours),
+    anonymous = COALESCE(sqlc.narg(anonymous)::BOOLEAN, anonymous),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+) AND status = 'draft'
+RETURNING *;
+
+-- name: UpdateSurveyLocales :one
+UPDATE surveys
+SET
+    default_locale = sqlc.arg(default_locale),
+    locales = sqlc.arg(locales)::TEXT[],
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+) AND status = 'draft'
+RETURNING *;
+
+-- name: SetSurveyTranslation :execrows
+-- Replaces the strings of the survey in one locale.
+UPDATE surveys
+SET
+    translations = jsonb_set(translations, ARRAY[sqlc.arg(locale)::TEXT], sqlc.arg(translation)::JSONB),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status IN ('draft', 'published');
+
+-- name: UpdateSurveyStatus :one
+UPDATE surveys
+SET
+    status = sqlc.arg(next_status),
+    published_at = CASE WHEN sqlc.arg(next_status) = 'published' THEN CURRENT_TIMESTAMP ELSE published_at END,
+    closed_at = CASE WHEN sqlc.arg(next_status) = 'closed' THEN CURRENT_TIMESTAMP ELSE closed_at END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+) AND status = sqlc.arg(current_status)
+RETURNING *;
+
+-- name: SetSurveyStatus :one
+-- UpdateSurveyStatus for the scheduled tasks that open and close surveys, which act for the survey
+-- rather than for one of its researchers.
+UPDATE surveys
+SET
+    status = sqlc.arg(next_status),
+    published_at = CASE WHEN sqlc.arg(next_status) = 'published' THEN CURRENT_TIMESTAMP ELSE published_at END,
+    closed_at = CASE WHEN sqlc.arg(next_status) = 'closed' THEN CURRENT_TIMESTAMP ELSE closed_at END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(current_status)
+RETURNING *;
+
+-- name: ScheduleSurvey :one
+-- A survey that has already opened keeps the time it was scheduled to open at.
+UPDATE surveys
+SET
+    opens_at = CASE WHEN status = 'draft' THEN sqlc.narg(opens_at)::TIMESTAMP ELSE opens_at END,
+    closes_at = sqlc.narg(closes_at)::TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+) AND status IN ('draft', 'published')
+RETURNING *;
+
+-- name: DeleteSurvey :execrows
+DELETE FROM surveys
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+) AND status = 'draft';
+
+-- name: GetSurveyByID :one
+SELECT * FROM surveys
+WHERE id = sqlc.arg(id);
+
+-- name: GetPublishedSurvey :one
+SELECT * FROM surveys
+WHERE id = sqlc.arg(id) AND status = 'published';
+
+-- name: ListFeedSurveys :many
+WITH candidates AS (
+    SELECT
+        s.id,
+        (
+            SELECT COUNT(*) FROM interest_areas ia
+            WHERE ia.user_id = sqlc.arg(user_id) AND ia.field_id = ANY(s.field_ids)
+        )::INT AS overlap,
+        COALESCE(
+            s.estimated_minutes,
+            GREATEST(1, CEIL((SELECT COUNT(*) FROM questions q WHERE q.survey_id = s.id AND q.removed_at IS NULL) / 2.0))
+        )::INT AS minutes
+    FROM surveys s
+    JOIN escrows e ON e.survey_id = s.id
+    WHERE s.status = 'published'
+      AND e.status = 'held'
+      AND e.amount - e.spent - e.reserved >= e.reward_per_response + e.fee_per_response
+      AND NOT EXISTS (
+          SELECT 1 FROM responses r
+          LEFT JOIN respondent_identities i ON i.response_id = r.id
+          WHERE r.survey_id = s.id AND sqlc.arg(user_id) IN (r.respondent_id, i.respondent_id)
+            AND r.status IN ('submitted', 'screened_out')
+      )
+      -- later waves of a panel only go to its participants
+      AND NOT EXISTS (
+          SELECT 1 FROM panel_waves w
+          WHERE w.survey_id = s.id AND w.wave > 1
+            AND NOT EXISTS (
+                SELECT 1 FROM panel_participants p
+                WHERE p.panel_id = w.panel_id AND p.respondent_id = sqlc.arg(user_id) AND p.wave < w.wave
+            )
+      )
+), ranked AS (
+    SELECT
+        s.id, s.researcher_id, s.title, s.description, s.reward_per_response, s.targeting, s.field_ids, s.published_at,
+        s.share_demographics, s.anonymous, s.default_locale, s.locales, s.translations,
+        c.overlap, c.minutes, ROUND(COALESCE(s.reward_per_response, 0) / c.minutes, 4) AS reward_per_minute
+    FROM candidates c
+    JOIN surveys s ON s.id = c.id
+)
+SELECT
+    id, researcher_id, title, description, reward_per_response, targeting, field_ids, published_at,
+    share_demographics, anonymous, default_locale, locales, translations, overlap, minutes, reward_per_minute
+FROM ranked
+WHERE sqlc.narg(cursor_id)::BIGINT IS NULL
+   OR (overlap, reward_per_minute, id) < (sqlc.narg(cursor_overlap)::INT, sqlc.narg(cursor_reward_per_minute)::NUMERIC, sqlc.narg(cursor_id)::BIGINT)
+ORDER BY overlap DESC, reward_per_minute DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetSurveyStats :one
+SELECT
+    COUNT(*)::INT AS started,
+    COUNT(*) FILTER (WHERE r.status = 'in_progress')::INT AS in_progress,
+    COUNT(*) FILTER (WHERE r.status = 'submitted')::INT AS submitted,
+    COUNT(*) FILTER (WHERE r.status = 'screened_out')::INT AS screened_out,
+    COUNT(*) FILTER (WHERE r.status = 'expired')::INT AS expired,
+    (SELECT COALESCE(SUM(p.amount), 0) FROM payouts p WHERE p.survey_id = sqlc.arg(survey_id) AND p.kind = 'reward' AND p.status = 'paid')::DECIMAL(15,2) AS rewards_paid,
+    (SELECT COALESCE(SUM(p.amount), 0) FROM payouts p WHERE p.survey_id = sqlc.arg(survey_id) AND p.kind = 'screen_out' AND p.status = 'paid')::DECIMAL(15,2) AS screen_out_fees_paid,
+    COUNT(*) FILTER (WHERE r.review_status IN ('pending', 'needs_review'))::INT AS awaiting_review,
+    COUNT(*) FILTER (WHERE r.review_status = 'rejected')::INT AS rejected
+FROM responses r
+WHERE r.survey_id = sqlc.arg(survey_id) AND NOT r.is_test;
+
+-- name: CreateSurveyVersion :one
+-- Bumps the version of a survey past draft and records its questions as they now stand. Drafts
+-- aren't versioned, so nothing is returned for them.
+WITH bumped AS (
+    UPDATE surveys
+    SET version = version + 1
+    WHERE id = sqlc.arg(survey_id) AND status <> 'draft'
+    RETURNING id, version
+)
+INSERT INTO survey_versions (survey_id, version, questions)
+SELECT
+    b.id, b.version,
+    COALESCE(
+        (SELECT jsonb_agg(to_jsonb(q) ORDER BY q.position, q.id) FROM questions q WHERE q.survey_id = b.id AND q.removed_at IS NULL),
+        '[]'
+    )
+FROM bumped b
+RETURNING id, survey_id, version, questions, created_at;
+
+-- name: ListSurveyVersions :many
+SELECT * FROM survey_versions
+WHERE survey_id = sqlc.arg(survey_id)
+ORDER BY version;
+
+-- name: CloneSurvey :one
+-- Copies the settings of a survey into a new draft of the given researcher. Its questions and quota
+-- cells are copied separately. The copy stays in the organization of the survey when the researcher
+-- can edit its surveys, otherwise it is their own.
+INSERT INTO surveys (
+    researcher_id, title, description, reward_per_response, target_responses, targeting, field_ids, estimated_minutes,
+    screen_out_fee, share_demographics, quality_checks, resume_window_hours, anonymous, cloned_from, organization_id,
+    default_locale, locales, translations
+)
+SELECT
+    sqlc.arg(researcher_id), COALESCE(sqlc.narg(title), s.title), s.description, s.reward_per_response, s.target_responses,
+    s.targeting, s.field_ids, s.estimated_minutes, s.screen_out_fee, s.share_demographics, s.quality_checks,
+    s.resume_window_hours, s.anonymous, s.id, m.organization_id, s.default_locale, s.locales, s.translations
+FROM surveys s
+LEFT JOIN organization_members m
+    ON m.organization_id = s.organization_id AND m.user_id = sqlc.arg(researcher_id) AND m.role <> 'viewer'
+WHERE s.id = sqlc.arg(source_id)
+RETURNING *;
+
+-- name: SetSurveyTemplate :one
+UPDATE surveys
+SET
+    is_template = sqlc.arg(is_template),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND (
+    (organization_id IS NULL AND researcher_id = sqlc.arg(researcher_id))
+    OR organization_id IN (
+        SELECT organization_id FROM organization_members
+        WHERE user_id = sqlc.arg(researcher_id) AND role <> 'viewer'
+    )
+)
+RETURNING *;
+
+-- name: GetTemplate :one
+SELECT * FROM surveys
+WHERE id = sqlc.arg(id) AND is_template;
+
+-- name: ListTemplates :many
+-- Lists the surveys shared as templates in id order. search matches the title or description,
+-- field_ids any of the fields of a template; either is left out when empty.
+SELECT
+    s.id, s.researcher_id, s.title, s.description, s.field_ids, s.estimated_minutes, s.updated_at,
+    (SELECT COUNT(*) FROM questions q WHERE q.survey_id = s.id AND q.removed_at IS NULL)::INT AS questions
+FROM surveys s
+WHERE s.is_template AND s.id > sqlc.arg(after)
+  AND (cardinality(sqlc.arg(field_ids)::BIGINT[]) = 0 OR s.field_ids && sqlc.arg(field_ids)::BIGINT[])
+  AND (
+      sqlc.narg(search)::TEXT IS NULL
+      OR strpos(LOWER(s.title), LOWER(sqlc.narg(search)::TEXT)) > 0
+      OR strpos(LOWER(COALESCE(s.description, '')), LOWER(sqlc.narg(search)::TEXT)) > 0
+  )
+ORDER BY s.id
+LIMIT sqlc.arg(page_size);
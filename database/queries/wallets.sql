@@ -1,11 +1,11 @@
 
 -- name: CreateWallet :one
 INSERT INTO wallets (balance, user_id)
-VALUES ($1, $2)
-    RETURNING id, balance, user_id, created_at, updated_at;
+VALUES (sqlc.arg(balance), sqlc.arg(user_id))
+RETURNING *;
 
 -- name: GetWallet :one
-SELECT * FROM wallets WHERE user_id = $1;
+SELECT * FROM wallets WHERE user_id = sqlc.arg(user_id);
 
 -- name: TopUpWallet :one
 UPDATE wallets
@@ -17,4 +17,24 @@ RETURNING *;
 UPDATE wallets
 SET balance = balance - sqlc.arg(amount)
 WHERE user_id = sqlc.arg(user_id) AND balance >= sqlc.arg(amount)
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: CreateOrganizationWallet :one
+INSERT INTO wallets (organization_id)
+VALUES (sqlc.arg(organization_id))
+RETURNING *;
+
+-- name: GetOrganizationWallet :one
+SELECT * FROM wallets WHERE organization_id = sqlc.arg(organization_id);
+
+-- name: TopUpOrganizationWallet :one
+UPDATE wallets
+SET balance = balance + sqlc.arg(amount)
+WHERE organization_id = sqlc.arg(organization_id)
+RETURNING *;
+
+-- name: ChargeOrganizationWallet :one
+UPDATE wallets
+SET balance = balance - sqlc.arg(amount)
+WHERE organization_id = sqlc.arg(organization_id) AND balance >= sqlc.arg(amount)
+RETURNING *;
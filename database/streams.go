@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+)
+
+// StreamSurveyExportRows runs the ListSurveyExportRows query but hands each row to fn as soon as it
+// is read instead of collecting them, so exporting a large survey never holds all of its responses
+// in memory. It stops at the first error fn returns and returns it.
+func (q *Queries) StreamSurveyExportRows(ctx context.Context, arg ListSurveyExportRowsParams, fn func(ListSurveyExportRowsRow) error) error {
+	rows, err := q.db.Query(ctx, listSurveyExportRows, arg.SurveyID, arg.IncludeTests)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i ListSurveyExportRowsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.StartedAt,
+			&i.SubmittedAt,
+			&i.SurveyVersion,
+			&i.OrderSeed,
+			&i.Pseudonym,
+			&i.IsTest,
+			&i.Answers,
+			&i.DateOfBirth,
+			&i.Gender,
+			&i.University,
+			&i.Faculty,
+			&i.Location,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -24,6 +24,11 @@ func NewDBTransactor(db *pgxpool.Pool) *DBTransactor {
 
 // WithTransaction executes the given function within a transaction
 func (t *DBTransactor) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	// Join a transaction that is already running, the outermost call commits or rolls back
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
 	tx, err := t.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return err
@@ -46,6 +51,12 @@ func (t *DBTransactor) WithTransaction(ctx context.Context, fn func(context.Cont
 // txKey is used to store transaction in context
 type txKey struct{}
 
+// TxFromContext retrieves the transaction stored in context by WithTransaction, if there is one
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
 // GetTx retrieves transaction from context, or starts new transaction if no transaction
 func GetTx(ctx context.Context, db *pgxpool.Pool) pgx.Tx {
 	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
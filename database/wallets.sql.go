@@ -11,16 +11,42 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const chargeOrganizationWallet = `-- name: ChargeOrganizationWallet :one
+UPDATE wallets
+SET balance = balance - $1
+WHERE organization_id = $2 AND balance >= $1
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
+`
+
+type ChargeOrganizationWalletParams struct {
+	Amount         pgtype.Numeric
+	OrganizationID pgtype.Int8
+}
+
+func (q *Queries) ChargeOrganizationWallet(ctx context.Context, arg ChargeOrganizationWalletParams) (Wallet, error) {
+	row := q.db.QueryRow(ctx, chargeOrganizationWallet, arg.Amount, arg.OrganizationID)
+	var i Wallet
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
 const chargeWallet = `-- name: ChargeWallet :one
 UPDATE wallets
 SET balance = balance - $1
 WHERE user_id = $2 AND balance >= $1
-RETURNING id, balance, user_id, created_at, updated_at
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
 `
 
 type ChargeWalletParams struct {
 	Amount pgtype.Numeric
-	UserID int64
+	UserID pgtype.Int8
 }
 
 func (q *Queries) ChargeWallet(ctx context.Context, arg ChargeWalletParams) (Wallet, error) {
@@ -32,6 +58,27 @@ func (q *Queries) ChargeWallet(ctx context.Context, arg ChargeWalletParams) (Wal
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
+const createOrganizationWallet = `-- name: CreateOrganizationWallet :one
+INSERT INTO wallets (organization_id)
+VALUES ($1)
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
+`
+
+func (q *Queries) CreateOrganizationWallet(ctx context.Context, organizationID pgtype.Int8) (Wallet, error) {
+	row := q.db.QueryRow(ctx, createOrganizationWallet, organizationID)
+	var i Wallet
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
@@ -39,12 +86,12 @@ func (q *Queries) ChargeWallet(ctx context.Context, arg ChargeWalletParams) (Wal
 const createWallet = `-- name: CreateWallet :one
 INSERT INTO wallets (balance, user_id)
 VALUES ($1, $2)
-    RETURNING id, balance, user_id, created_at, updated_at
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
 `
 
 type CreateWalletParams struct {
 	Balance pgtype.Numeric
-	UserID  int64
+	UserID  pgtype.Int8
 }
 
 func (q *Queries) CreateWallet(ctx context.Context, arg CreateWalletParams) (Wallet, error) {
@@ -56,15 +103,34 @@ func (q *Queries) CreateWallet(ctx context.Context, arg CreateWalletParams) (Wal
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
+const getOrganizationWallet = `-- name: GetOrganizationWallet :one
+SELECT id, balance, user_id, created_at, updated_at, organization_id FROM wallets WHERE organization_id = $1
+`
+
+func (q *Queries) GetOrganizationWallet(ctx context.Context, organizationID pgtype.Int8) (Wallet, error) {
+	row := q.db.QueryRow(ctx, getOrganizationWallet, organizationID)
+	var i Wallet
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
 
 const getWallet = `-- name: GetWallet :one
-SELECT id, balance, user_id, created_at, updated_at FROM wallets WHERE user_id = $1
+SELECT id, balance, user_id, created_at, updated_at, organization_id FROM wallets WHERE user_id = $1
 `
 
-func (q *Queries) GetWallet(ctx context.Context, userID int64) (Wallet, error) {
+func (q *Queries) GetWallet(ctx context.Context, userID pgtype.Int8) (Wallet, error) {
 	row := q.db.QueryRow(ctx, getWallet, userID)
 	var i Wallet
 	err := row.Scan(
@@ -73,6 +139,33 @@ func (q *Queries) GetWallet(ctx context.Context, userID int64) (Wallet, error) {
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
+const topUpOrganizationWallet = `-- name: TopUpOrganizationWallet :one
+UPDATE wallets
+SET balance = balance + $1
+WHERE organization_id = $2
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
+`
+
+type TopUpOrganizationWalletParams struct {
+	Amount         pgtype.Numeric
+	OrganizationID pgtype.Int8
+}
+
+func (q *Queries) TopUpOrganizationWallet(ctx context.Context, arg TopUpOrganizationWalletParams) (Wallet, error) {
+	row := q.db.QueryRow(ctx, topUpOrganizationWallet, arg.Amount, arg.OrganizationID)
+	var i Wallet
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
@@ -81,12 +174,12 @@ const topUpWallet = `-- name: TopUpWallet :one
 UPDATE wallets
 SET balance = balance + $1
 WHERE user_id = $2
-RETURNING id, balance, user_id, created_at, updated_at
+RETURNING id, balance, user_id, created_at, updated_at, organization_id
 `
 
 type TopUpWalletParams struct {
 	Amount pgtype.Numeric
-	UserID int64
+	UserID pgtype.Int8
 }
 
 func (q *Queries) TopUpWallet(ctx context.Context, arg TopUpWalletParams) (Wallet, error) {
@@ -98,6 +191,7 @@ func (q *Queries) TopUpWallet(ctx context.Context, arg TopUpWalletParams) (Walle
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
@@ -46,6 +46,7 @@ func main() {
 	queries := database.New(pool)
 
 	r := api.Routes(queries, q, pool)
+	api.Tasks(q, q, queries, pool)
 
 	port := os.Getenv("PORT")
 
@@ -0,0 +1,21 @@
+package queue
+
+import (
+	"github.com/hibiken/asynq"
+	"time"
+)
+
+const TypeResponseExpiry = "response:expire"
+
+// ResponseExpiryPayload asks for the responses left unfinished past their survey's resume window
+// to be expired.
+type ResponseExpiryPayload struct{}
+
+func (r *ResponseExpiryPayload) Process() (*asynq.Task, error) {
+	// every instance schedules the task, only one of them gets to enqueue it at a time
+	return asynq.NewTask(TypeResponseExpiry, nil, asynq.Unique(10*time.Minute)), nil
+}
+
+func (r *ResponseExpiryPayload) ProcessorName() string {
+	return TypeResponseExpiry
+}
@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"fmt"
+	"github.com/hibiken/asynq"
+	"time"
+)
+
+const TypePanelWave = "panel:wave"
+
+// PanelWavePayload asks for the participants of a panel to be invited to a wave once it opens, at
+// the time it is scheduled to.
+type PanelWavePayload struct {
+	PanelID  int64
+	Wave     int32
+	SurveyID int64
+	At       time.Time
+}
+
+func (p *PanelWavePayload) Process() (*asynq.Task, error) {
+	return scheduledTask(TypePanelWave, p, p.TaskID(), p.At)
+}
+
+func (p *PanelWavePayload) ProcessorName() string {
+	return TypePanelWave
+}
+
+// TaskID names the task after the survey of the wave and the time it opens at, like the task that
+// opens it.
+func (p *PanelWavePayload) TaskID() string {
+	return fmt.Sprintf("%s:%d:%d", TypePanelWave, p.SurveyID, p.At.Unix())
+}
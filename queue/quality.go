@@ -0,0 +1,28 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+)
+
+const TypeResponseQualityCheck = "response:quality"
+
+// ResponseQualityPayload asks for the quality checks to run on a submitted response.
+type ResponseQualityPayload struct {
+	ResponseID int64
+}
+
+func (r *ResponseQualityPayload) Process() (*asynq.Task, error) {
+	payload, err := json.Marshal(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal response quality payload: %w", err)
+	}
+
+	return asynq.NewTask(TypeResponseQualityCheck, payload), nil
+}
+
+func (r *ResponseQualityPayload) ProcessorName() string {
+	return TypeResponseQualityCheck
+}
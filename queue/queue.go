@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
@@ -15,13 +16,33 @@ type Processor interface {
 	ProcessorName() string
 }
 
+// Scheduled is a processor whose task waits on the queue until a set time and can be taken off it
+// until then by its ID.
+type Scheduled interface {
+	Processor
+	TaskID() string
+}
+
 type Queue interface {
 	Enqueue(processor Processor) error
+	// Dequeue takes a scheduled task off the queue before it runs. A task that is no longer on the
+	// queue is not an error.
+	Dequeue(processor Scheduled) error
+}
+
+// Worker runs the tasks of a type with the handler registered for it, and enqueues the tasks
+// scheduled to run periodically.
+type Worker interface {
+	HandleFunc(pattern string, handler func(context.Context, *asynq.Task) error)
+	Schedule(cronspec string, processor Processor) error
 }
 
 type Client struct {
-	client *asynq.Client
-	once   sync.Once
+	client    *asynq.Client
+	mux       *asynq.ServeMux
+	scheduler *asynq.Scheduler
+	inspector *asynq.Inspector
+	once      sync.Once
 }
 
 func NewClient(ctx context.Context) (*Client, error) {
@@ -44,20 +65,65 @@ func NewClient(ctx context.Context) (*Client, error) {
 		log.Printf("connected to redis queue")
 	})
 
+	c.mux = asynq.NewServeMux()
+	c.mux.HandleFunc(TypeEmailDelivery, HandleEmailTask)
+
+	c.scheduler = asynq.NewScheduler(asynq.RedisClientOpt{Addr: addr.Addr}, nil)
+	c.inspector = asynq.NewInspector(asynq.RedisClientOpt{Addr: addr.Addr})
+
 	return &c, nil
 }
 
 func (c *Client) Enqueue(processor Processor) error {
 
 	task, err := processor.Process()
+	if err != nil {
+		return err
+	}
+
 	_, err = c.client.Enqueue(task)
 	if err != nil {
+		// a task with the same id is already waiting, e.g. a schedule saved twice
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
 		return fmt.Errorf("could not enqueue %s task for: %v", processor.ProcessorName(), err)
 	}
 
 	return nil
 }
 
+func (c *Client) Dequeue(processor Scheduled) error {
+	err := c.inspector.DeleteTask("default", processor.TaskID())
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			return nil
+		}
+		return fmt.Errorf("could not dequeue %s task: %v", processor.ProcessorName(), err)
+	}
+
+	return nil
+}
+
+// HandleFunc registers the handler of a task type. Handlers have to be registered before Run.
+func (c *Client) HandleFunc(pattern string, handler func(context.Context, *asynq.Task) error) {
+	c.mux.HandleFunc(pattern, handler)
+}
+
+// Schedule enqueues the processor's task on the cron schedule, e.g. "@hourly", once Run is called.
+func (c *Client) Schedule(cronspec string, processor Processor) error {
+	task, err := processor.Process()
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.scheduler.Register(cronspec, task); err != nil {
+		return fmt.Errorf("could not schedule %s task: %v", processor.ProcessorName(), err)
+	}
+
+	return nil
+}
+
 func (c *Client) GetClient() *asynq.Client {
 	return c.client
 }
@@ -75,11 +141,12 @@ func (c *Client) Run(ctx context.Context) error {
 
 	queueServer := asynq.NewServer(asynq.RedisClientOpt{Addr: addr.Addr}, asynq.Config{})
 
-	mux := asynq.NewServeMux()
-
-	mux.HandleFunc(TypeEmailDelivery, HandleEmailTask)
+	if err := c.scheduler.Start(); err != nil {
+		return fmt.Errorf("error starting queue scheduler: %v", err)
+	}
+	defer c.scheduler.Shutdown()
 
-	if err := queueServer.Run(mux); err != nil {
+	if err := queueServer.Run(c.mux); err != nil {
 		return fmt.Errorf("error running queue server: %v", err)
 	}
 	return nil
@@ -0,0 +1,20 @@
+package queue
+
+import (
+	"github.com/hibiken/asynq"
+	"time"
+)
+
+const TypeReviewAutoApproval = "review:auto_approve"
+
+// ReviewAutoApprovalPayload asks for the responses left unreviewed for too long to be approved.
+type ReviewAutoApprovalPayload struct{}
+
+func (r *ReviewAutoApprovalPayload) Process() (*asynq.Task, error) {
+	// every instance schedules the task, only one of them gets to enqueue it at a time
+	return asynq.NewTask(TypeReviewAutoApproval, nil, asynq.Unique(30*time.Minute)), nil
+}
+
+func (r *ReviewAutoApprovalPayload) ProcessorName() string {
+	return TypeReviewAutoApproval
+}
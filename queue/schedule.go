@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"time"
+)
+
+const (
+	TypeSurveyOpen  = "survey:open"
+	TypeSurveyClose = "survey:close"
+)
+
+// SurveyOpenPayload asks for a draft survey to be published at the time it is scheduled to open.
+type SurveyOpenPayload struct {
+	SurveyID int64
+	At       time.Time
+}
+
+func (s *SurveyOpenPayload) Process() (*asynq.Task, error) {
+	return scheduledTask(TypeSurveyOpen, s, s.TaskID(), s.At)
+}
+
+func (s *SurveyOpenPayload) ProcessorName() string {
+	return TypeSurveyOpen
+}
+
+// TaskID names the task after the survey and the time it opens at, so a schedule that changes
+// never collides with the task of the old one.
+func (s *SurveyOpenPayload) TaskID() string {
+	return fmt.Sprintf("%s:%d:%d", TypeSurveyOpen, s.SurveyID, s.At.Unix())
+}
+
+// SurveyClosePayload asks for a published survey to be closed at the time it is scheduled to close.
+type SurveyClosePayload struct {
+	SurveyID int64
+	At       time.Time
+}
+
+func (s *SurveyClosePayload) Process() (*asynq.Task, error) {
+	return scheduledTask(TypeSurveyClose, s, s.TaskID(), s.At)
+}
+
+func (s *SurveyClosePayload) ProcessorName() string {
+	return TypeSurveyClose
+}
+
+// TaskID names the task after the survey and the time it closes at.
+func (s *SurveyClosePayload) TaskID() string {
+	return fmt.Sprintf("%s:%d:%d", TypeSurveyClose, s.SurveyID, s.At.Unix())
+}
+
+func scheduledTask(typename string, payload any, id string, at time.Time) (*asynq.Task, error) {
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", typename, err)
+	}
+
+	return asynq.NewTask(typename, data, asynq.TaskID(id), asynq.ProcessAt(at)), nil
+}